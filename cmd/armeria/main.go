@@ -3,17 +3,51 @@ package main
 import (
 	"armeria/internal/pkg/armeria"
 	"flag"
+	"log"
+	"os"
 )
 
 func main() {
 	configPath := flag.String("config", "./config/development.yml", "path to the config file")
 	migrateFlag := flag.Bool("migrate", false, "perform a schema migration")
+	rollbackFlag := flag.Int("rollback", 0, "roll the schema back to an older version")
+	dryRunFlag := flag.Bool("dry-run", false, "print the changes a migration, rollback or restore would make, without writing them")
+	convertStoreFlag := flag.String("convert-store", "", "copy the data from the configured store into another store (json or bolt)")
+	protocolSchemaFlag := flag.String("protocol-schema", "", "write the JSON Schema of the client protocol to a file, then exit")
+	restoreSnapshotFlag := flag.String("restore-snapshot", "", "replace the data with a snapshot before starting the server")
+	replayEventsFlag := flag.String("replay-events", "", "replay the events recorded after a snapshot on a copy of it, printing what each character was shown, then exit")
+	permissionTableFlag := flag.String("permission-table", "", "update the table of permissions within a Markdown file, then exit")
 
 	flag.Parse()
 
-	if *migrateFlag {
+	if len(*protocolSchemaFlag) > 0 {
+		if err := armeria.WriteProtocolSchema(*protocolSchemaFlag); err != nil {
+			log.Fatalf("error writing protocol schema: %s", err)
+		}
+	} else if len(*permissionTableFlag) > 0 {
+		if err := armeria.WritePermissionTable(*permissionTableFlag); err != nil {
+			log.Fatalf("error writing permission table: %s", err)
+		}
+	} else if *migrateFlag {
 		armeria.Init(*configPath, false)
-		armeria.Migrate()
+		armeria.Migrate(*dryRunFlag)
+	} else if *rollbackFlag > 0 {
+		armeria.Init(*configPath, false)
+		armeria.Rollback(*rollbackFlag, *dryRunFlag)
+	} else if len(*convertStoreFlag) > 0 {
+		armeria.Init(*configPath, false)
+		armeria.ConvertStore(*convertStoreFlag)
+	} else if len(*restoreSnapshotFlag) > 0 {
+		armeria.Init(*configPath, false)
+		armeria.RestoreSnapshot(*restoreSnapshotFlag, *dryRunFlag)
+		if !*dryRunFlag {
+			armeria.Init(*configPath, true)
+		}
+	} else if len(*replayEventsFlag) > 0 {
+		armeria.Init(*configPath, false)
+		if err := armeria.ReplayEvents(*replayEventsFlag, os.Stdout); err != nil {
+			log.Fatalf("error replaying events: %s", err)
+		}
 	} else {
 		armeria.Init(*configPath, true)
 	}
@@ -16,6 +16,7 @@ require (
 	github.com/onsi/gomega v1.7.0 // indirect
 	github.com/stretchr/testify v1.4.0 // indirect
 	github.com/yuin/gopher-lua v0.0.0-20190514113301-1cd887cd7036
+	go.etcd.io/bbolt v1.3.6
 	go.uber.org/atomic v1.4.0 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
 	go.uber.org/zap v1.10.0
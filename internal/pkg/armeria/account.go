@@ -0,0 +1,168 @@
+package armeria
+
+import (
+	"armeria/internal/pkg/misc"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// An Account is the login used by a player and owns one or more characters.
+type Account struct {
+	sync.RWMutex
+	UUID              string   `json:"uuid"`
+	UnsafeName        string   `json:"name"`
+	UnsafePassword    string   `json:"password"`
+	UnsafePermissions []string `json:"permissions"`
+	UnsafeBanned      bool     `json:"banned"`
+	UnsafeBanReason   string   `json:"banReason"`
+}
+
+// Init is called when the Account is created or loaded from disk.
+func (a *Account) Init() {
+	if a.UnsafePermissions == nil {
+		a.UnsafePermissions = []string{}
+	}
+}
+
+// ID returns the uuid of the Account.
+func (a *Account) ID() string {
+	return a.UUID
+}
+
+// Name returns the raw Account name.
+func (a *Account) Name() string {
+	a.RLock()
+	defer a.RUnlock()
+
+	return a.UnsafeName
+}
+
+// FormattedName returns the formatted Account name.
+func (a *Account) FormattedName() string {
+	a.RLock()
+	defer a.RUnlock()
+
+	return TextStyle(a.UnsafeName, WithBold())
+}
+
+// CheckPassword returns a bool indicating whether the password is correct or not.
+func (a *Account) CheckPassword(pw string) bool {
+	a.RLock()
+	defer a.RUnlock()
+
+	err := bcrypt.CompareHashAndPassword([]byte(a.UnsafePassword), []byte(pw))
+	return err == nil
+}
+
+// SetPassword hashes and sets a new password for the Account.
+func (a *Account) SetPassword(pw string) {
+	a.Lock()
+	defer a.Unlock()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+	if err != nil {
+		Armeria.log.Fatal("error generating password hash",
+			zap.Error(err),
+		)
+	}
+
+	a.UnsafePassword = string(hash)
+}
+
+// Characters returns the Characters that belong to the Account.
+func (a *Account) Characters() []*Character {
+	return Armeria.characterManager.CharactersByAccount(a)
+}
+
+// Permissions returns the permissions granted at the Account level.
+func (a *Account) Permissions() []string {
+	a.RLock()
+	defer a.RUnlock()
+
+	perms := make([]string, len(a.UnsafePermissions))
+	copy(perms, a.UnsafePermissions)
+
+	return perms
+}
+
+// HasPermission returns true if the Account has been granted a particular permission.
+func (a *Account) HasPermission(p string) bool {
+	a.RLock()
+	defer a.RUnlock()
+
+	return misc.Contains(a.UnsafePermissions, p)
+}
+
+// GrantPermission grants a permission to the Account, which applies to all of its characters.
+func (a *Account) GrantPermission(p string) {
+	a.Lock()
+	defer a.Unlock()
+
+	if !misc.Contains(a.UnsafePermissions, p) {
+		a.UnsafePermissions = append(a.UnsafePermissions, p)
+	}
+}
+
+// RevokePermission revokes an Account-level permission.
+func (a *Account) RevokePermission(p string) {
+	a.Lock()
+	defer a.Unlock()
+
+	for i, perm := range a.UnsafePermissions {
+		if perm == p {
+			a.UnsafePermissions = append(a.UnsafePermissions[:i], a.UnsafePermissions[i+1:]...)
+			return
+		}
+	}
+}
+
+// Banned returns true if the Account is banned from the game.
+func (a *Account) Banned() bool {
+	a.RLock()
+	defer a.RUnlock()
+
+	return a.UnsafeBanned
+}
+
+// BanReason returns the reason the Account was banned.
+func (a *Account) BanReason() string {
+	a.RLock()
+	defer a.RUnlock()
+
+	return a.UnsafeBanReason
+}
+
+// SetBanned bans (or unbans) the Account, along with an optional reason.
+func (a *Account) SetBanned(banned bool, reason string) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.UnsafeBanned = banned
+	a.UnsafeBanReason = reason
+}
+
+// BanMessage returns the message shown to a player attempting to use a banned Account.
+func (a *Account) BanMessage() string {
+	a.RLock()
+	defer a.RUnlock()
+
+	if len(a.UnsafeBanReason) > 0 {
+		return fmt.Sprintf("This account has been banned: %s", a.UnsafeBanReason)
+	}
+
+	return "This account has been banned."
+}
+
+type accountJSON Account
+
+// MarshalJSON encodes the Account while holding its lock.
+func (a *Account) MarshalJSON() ([]byte, error) {
+	a.RLock()
+	defer a.RUnlock()
+
+	return json.Marshal((*accountJSON)(a))
+}
@@ -0,0 +1,169 @@
+package armeria
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"go.uber.org/zap"
+)
+
+// ErrAccountNameTaken is an error for when an account with a particular name already exists.
+var ErrAccountNameTaken = errors.New("an account with that name already exists")
+
+type AccountManager struct {
+	sync.RWMutex
+	UnsafeAccounts []*Account `json:"accounts"`
+}
+
+func NewAccountManager() *AccountManager {
+	m := &AccountManager{}
+
+	m.LoadAccounts()
+
+	return m
+}
+
+func (m *AccountManager) LoadAccounts() {
+	m.Lock()
+	defer m.Unlock()
+
+	records, err := Armeria.store.Load(CollectionAccounts)
+	if err != nil {
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionAccounts.Name),
+			zap.Error(err),
+		)
+	}
+
+	err = DecodeStoreRecords(records, &m.UnsafeAccounts)
+	if err != nil {
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionAccounts.Name),
+			zap.Error(err),
+		)
+	}
+
+	for _, a := range m.UnsafeAccounts {
+		a.Init()
+	}
+
+	Armeria.log.Info("accounts loaded",
+		zap.Int("count", len(m.UnsafeAccounts)),
+	)
+}
+
+func (m *AccountManager) SaveAccounts() {
+	m.RLock()
+	defer m.RUnlock()
+
+	records, err := EncodeStoreRecords(CollectionAccounts, m.UnsafeAccounts)
+	if err != nil {
+		Armeria.log.Fatal("failed to marshal data",
+			zap.Error(err),
+		)
+	}
+
+	err = Armeria.store.Save(CollectionAccounts, records)
+	if err != nil {
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionAccounts.Name),
+			zap.Error(err),
+		)
+	}
+
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionAccounts.Name),
+		zap.Int("records", len(records)),
+	)
+}
+
+// SaveAccount immediately persists a single Account to the store. The Account is locked while it's encoded, so
+// this must not be called while holding its lock.
+func (m *AccountManager) SaveAccount(a *Account) {
+	persistRecord(CollectionAccounts, a)
+}
+
+// AccountByName returns the matching Account, by name.
+func (m *AccountManager) AccountByName(name string) *Account {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, a := range m.UnsafeAccounts {
+		if strings.ToLower(a.Name()) == strings.ToLower(name) {
+			return a
+		}
+	}
+
+	return nil
+}
+
+// AccountById returns the matching Account, by uuid.
+func (m *AccountManager) AccountById(uuid string) *Account {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, a := range m.UnsafeAccounts {
+		if a.ID() == uuid {
+			return a
+		}
+	}
+
+	return nil
+}
+
+// Accounts returns all of the Accounts.
+func (m *AccountManager) Accounts() []*Account {
+	m.RLock()
+	defer m.RUnlock()
+
+	accounts := make([]*Account, len(m.UnsafeAccounts))
+	copy(accounts, m.UnsafeAccounts)
+
+	return accounts
+}
+
+// CreateAccount creates a new Account, adds it to memory, initializes it and returns the Account. The name is
+// checked while the lock is held, so two accounts can never be created with the same name.
+func (m *AccountManager) CreateAccount(name, password string) (*Account, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, a := range m.UnsafeAccounts {
+		if strings.ToLower(a.Name()) == strings.ToLower(name) {
+			return nil, ErrAccountNameTaken
+		}
+	}
+
+	a := &Account{
+		UUID:       uuid.New().String(),
+		UnsafeName: name,
+	}
+
+	a.SetPassword(password)
+	a.Init()
+
+	m.UnsafeAccounts = append(m.UnsafeAccounts, a)
+
+	Armeria.log.Info("account created",
+		zap.String("name", name),
+	)
+
+	return a, nil
+}
+
+// RemoveAccount removes an Account from memory and from the store.
+func (m *AccountManager) RemoveAccount(a *Account) {
+	m.Lock()
+	defer m.Unlock()
+
+	for i, account := range m.UnsafeAccounts {
+		if account == a {
+			m.UnsafeAccounts = append(m.UnsafeAccounts[:i], m.UnsafeAccounts[i+1:]...)
+			removeRecord(CollectionAccounts, a.ID())
+			return
+		}
+	}
+}
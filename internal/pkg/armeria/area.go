@@ -3,12 +3,18 @@ package armeria
 import (
 	"armeria/internal/pkg/misc"
 	"encoding/json"
-	"log"
+	"fmt"
+	"os"
+	"strings"
 	"sync"
 
+	lua "github.com/yuin/gopher-lua"
 	"go.uber.org/zap"
 )
 
+// Force verify that Area implements ScriptOwner.
+var _ ScriptOwner = (*Area)(nil)
+
 // Area is a container for rooms.
 type Area struct {
 	sync.RWMutex
@@ -16,6 +22,10 @@ type Area struct {
 	UnsafeName       string            `json:"name"`
 	UnsafeRooms      []*Room           `json:"rooms"`
 	UnsafeAttributes map[string]string `json:"attributes"`
+	UnsafeOwner      string            `json:"owner"`
+	UnsafeBuilders   []string          `json:"builders"`
+	UnsafeScript     *CachedScript     `json:"-"`
+	UnsafeScriptVM   *ScriptVM         `json:"-"`
 }
 
 // Direction strings.
@@ -30,12 +40,19 @@ const (
 
 // Init is called when the Area is created or loaded from disk.
 func (a *Area) Init() {
+	if a.UnsafeBuilders == nil {
+		a.UnsafeBuilders = []string{}
+	}
+
+	a.UnsafeScriptVM = NewScriptVM(SetupRoomLState)
+	a.CacheScript()
 	Armeria.registry.Register(a, a.ID(), RegistryTypeArea)
 }
 
 // Deinit is called when the Area is deleted.
 func (a *Area) Deinit() {
 	Armeria.registry.Unregister(a.ID())
+	go a.ScriptVM().Close()
 }
 
 // ID returns the UUID of the Area.
@@ -56,7 +73,10 @@ func (a *Area) Rooms() []*Room {
 	a.RLock()
 	defer a.RUnlock()
 
-	return a.UnsafeRooms
+	rooms := make([]*Room, len(a.UnsafeRooms))
+	copy(rooms, a.UnsafeRooms)
+
+	return rooms
 }
 
 // RoomAt returns the Room at a particular Coords within the same area.
@@ -74,12 +94,12 @@ func (a *Area) RoomAt(c *Coords) *Room {
 	return nil
 }
 
-// MinimapJSON returns the JSON used for minimap rendering on the client.
-func (a *Area) MinimapJSON() string {
+// MinimapData returns the data used for minimap rendering on the client.
+func (a *Area) MinimapData() *MinimapData {
 	a.RLock()
 	defer a.RUnlock()
 
-	var rooms []map[string]interface{}
+	var rooms []*MinimapRoomData
 	for _, r := range a.UnsafeRooms {
 		var north, south, east, west, up, down string
 		if cr := r.ConnectedRoom(NorthDirection); cr != nil {
@@ -100,34 +120,26 @@ func (a *Area) MinimapJSON() string {
 		if cr := r.ConnectedRoom(DownDirection); cr != nil {
 			down = cr.LocationString()
 		}
-		rooms = append(rooms, map[string]interface{}{
-			"title": r.Attribute("title"),
-			"color": r.Attribute("color"),
-			"type":  r.Attribute("type"),
-			"x":     r.Coords.X(),
-			"y":     r.Coords.Y(),
-			"z":     r.Coords.Z(),
-			"north": north,
-			"south": south,
-			"east":  east,
-			"west":  west,
-			"up":    up,
-			"down":  down,
+		rooms = append(rooms, &MinimapRoomData{
+			Title: r.Attribute("title"),
+			Color: r.Attribute("color"),
+			Type:  r.Attribute("type"),
+			X:     r.Coords.X(),
+			Y:     r.Coords.Y(),
+			Z:     r.Coords.Z(),
+			North: north,
+			South: south,
+			East:  east,
+			West:  west,
+			Up:    up,
+			Down:  down,
 		})
 	}
 
-	minimap := map[string]interface{}{
-		"name":  a.UnsafeName,
-		"rooms": rooms,
-	}
-
-	mapJSON, err := json.Marshal(minimap)
-	if err != nil {
-		log.Fatalf("[area] failed to marshal minimap data: %s", err)
+	return &MinimapData{
+		Name:  a.UnsafeName,
+		Rooms: rooms,
 	}
-
-	return string(mapJSON)
-
 }
 
 // EditorData returns the JSON used for the object editor.
@@ -177,14 +189,81 @@ func (a *Area) Attribute(name string) string {
 	return a.UnsafeAttributes[name]
 }
 
+// Owner returns the UUID of the Character that owns the area, or an empty string if it has no owner.
+func (a *Area) Owner() string {
+	a.RLock()
+	defer a.RUnlock()
+
+	return a.UnsafeOwner
+}
+
+// SetOwner sets the UUID of the Character that owns the area.
+func (a *Area) SetOwner(uuid string) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.UnsafeOwner = uuid
+}
+
+// Builders returns the UUIDs of the characters, other than the owner, that have been allowed to build in the area.
+func (a *Area) Builders() []string {
+	a.RLock()
+	defer a.RUnlock()
+
+	builders := make([]string, len(a.UnsafeBuilders))
+	copy(builders, a.UnsafeBuilders)
+
+	return builders
+}
+
+// AddBuilder allows a Character, by UUID, to build in the area.
+func (a *Area) AddBuilder(uuid string) {
+	a.Lock()
+	defer a.Unlock()
+
+	if !misc.Contains(a.UnsafeBuilders, uuid) {
+		a.UnsafeBuilders = append(a.UnsafeBuilders, uuid)
+	}
+}
+
+// RemoveBuilder stops a Character, by UUID, from building in the area.
+func (a *Area) RemoveBuilder(uuid string) {
+	a.Lock()
+	defer a.Unlock()
+
+	for i, b := range a.UnsafeBuilders {
+		if b == uuid {
+			a.UnsafeBuilders = append(a.UnsafeBuilders[:i], a.UnsafeBuilders[i+1:]...)
+			return
+		}
+	}
+}
+
+// CanBuild returns whether the Character may change the area, its rooms and what is within them. It needs
+// PermissionBuild, and also either PermissionBuildAnywhere or to own the area or be one of its builders.
+func (a *Area) CanBuild(c *Character) bool {
+	if !c.HasPermission(PermissionBuild) {
+		return false
+	} else if c.HasPermission(PermissionBuildAnywhere) {
+		return true
+	}
+
+	a.RLock()
+	defer a.RUnlock()
+
+	return a.UnsafeOwner == c.ID() || misc.Contains(a.UnsafeBuilders, c.ID())
+}
+
 // CharacterEntered is called when the unsafeCharacter is moved into the area (or logged in).
 func (a *Area) CharacterEntered(c *Character, causedByLogin bool) {
 	c.Player().client.SyncMap()
+
+	QueueScriptFunc(c, a, "character_entered")
 }
 
 // CharacterLeft is called when the unsafeCharacter left the area (or logged out).
 func (a *Area) CharacterLeft(c *Character, causedByLogout bool) {
-
+	QueueScriptFunc(c, a, "character_left")
 }
 
 // AddRoom adds a Room to the area.
@@ -204,6 +283,9 @@ func (a *Area) RemoveRoom(r *Room) {
 
 	r.Deinit()
 
+	// Delete the script file, if it has one.
+	_ = os.Remove(r.ScriptFile())
+
 	for i, rm := range a.UnsafeRooms {
 		if rm.ID() == r.ID() {
 			a.UnsafeRooms[i] = a.UnsafeRooms[len(a.UnsafeRooms)-1]
@@ -215,13 +297,84 @@ func (a *Area) RemoveRoom(r *Room) {
 
 // Characters returns online characters within the area, with an optional Character exception.
 func (a *Area) Characters(exceptions ...*Character) []*Character {
-	a.RLock()
-	defer a.RUnlock()
-
 	var c []*Character
-	for _, r := range a.UnsafeRooms {
+	for _, r := range a.Rooms() {
 		c = append(c, r.Here().Characters(true, exceptions...)...)
 	}
 
 	return c
 }
+
+// scriptFile returns the full path to the associated Lua script file. This DOES NOT request a lock and IS NOT
+// thread safe.
+func (a *Area) scriptFile() string {
+	return fmt.Sprintf(
+		"%s/scripts/area-%s.lua",
+		Armeria.dataPath,
+		strings.ToLower(strings.ReplaceAll(a.UnsafeName, " ", "-")),
+	)
+}
+
+// ScriptFile returns the full path to the associated Lua script file.
+func (a *Area) ScriptFile() string {
+	a.RLock()
+	defer a.RUnlock()
+	return a.scriptFile()
+}
+
+// CacheScript reads the script contents and caches the contents, individual functions and compiled script.
+func (a *Area) CacheScript() {
+	a.Lock()
+	defer a.Unlock()
+
+	a.UnsafeScript = LoadScript(a.scriptFile())
+}
+
+// Script returns the cached script contents.
+func (a *Area) Script() string {
+	a.RLock()
+	defer a.RUnlock()
+	return a.UnsafeScript.Source
+}
+
+// ScriptFuncs returns the cached functions within the Area's script file.
+func (a *Area) ScriptFuncs() []string {
+	a.RLock()
+	defer a.RUnlock()
+	return a.UnsafeScript.Funcs
+}
+
+// ScriptProto returns the compiled script, or the error encountered while compiling it. The proto is nil when
+// the Area has no script.
+func (a *Area) ScriptProto() (*lua.FunctionProto, error) {
+	a.RLock()
+	defer a.RUnlock()
+	return a.UnsafeScript.Proto, a.UnsafeScript.Err
+}
+
+// ScriptVM returns the Area's persistent Lua environment.
+func (a *Area) ScriptVM() *ScriptVM {
+	a.RLock()
+	defer a.RUnlock()
+	return a.UnsafeScriptVM
+}
+
+// ScriptKind returns the kind of script the Area runs, which prefixes its Lua globals.
+func (a *Area) ScriptKind() string {
+	return "area"
+}
+
+// ScriptRoom returns nil, since an Area spans many rooms. Area scripts act upon the invoking Character's room.
+func (a *Area) ScriptRoom() *Room {
+	return nil
+}
+
+type areaJSON Area
+
+// MarshalJSON encodes the Area while holding its lock.
+func (a *Area) MarshalJSON() ([]byte, error) {
+	a.RLock()
+	defer a.RUnlock()
+
+	return json.Marshal((*areaJSON)(a))
+}
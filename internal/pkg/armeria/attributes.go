@@ -7,8 +7,10 @@ import (
 )
 
 const (
+	AttributeAttack      string = "attack"
 	AttributeChannels    string = "channels"
 	AttributeColor       string = "color"
+	AttributeDefense     string = "defense"
 	AttributeDescription string = "description"
 	AttributeDown        string = "down"
 	AttributeEast        string = "east"
@@ -16,12 +18,13 @@ const (
 	AttributeFollowCrumb string = "followCrumb"
 	AttributeFollowSpeed string = "followSpeed"
 	AttributeGender      string = "gender"
+	AttributeHealth      string = "health"
 	AttributeHoldable    string = "holdable"
+	AttributeHome        string = "home"
 	AttributeMoney       string = "money"
 	AttributeMusic       string = "music"
 	AttributeNorth       string = "north"
 	AttributeOwner       string = "owner"
-	AttributePermissions string = "permissions"
 	AttributePicture     string = "picture"
 	AttributeRarity      string = "rarity"
 	AttributeScript      string = "script"
@@ -67,17 +70,22 @@ func AttributeList(ot ObjectType) []string {
 		return []string{
 			AttributePicture,
 			AttributeTitle,
-			AttributePermissions,
 			AttributeChannels,
 			AttributeGender,
 			AttributeMoney,
+			AttributeHealth,
+			AttributeAttack,
+			AttributeDefense,
+			AttributeHome,
 		}
 	case ObjectTypeArea:
 		return []string{
+			AttributeScript,
 			AttributeMusic,
 		}
 	case ObjectTypeRoom:
 		return []string{
+			AttributeScript,
 			AttributeTitle,
 			AttributeDescription,
 			AttributeColor,
@@ -92,6 +100,7 @@ func AttributeList(ot ObjectType) []string {
 	case ObjectTypeItem:
 		return []string{
 			AttributePicture,
+			AttributeScript,
 			AttributeType,
 			AttributeEquipSlot,
 			AttributeRarity,
@@ -120,10 +129,16 @@ func AttributeList(ot ObjectType) []string {
 			AttributeSpawnSFX,
 			AttributeFollowCrumb,
 			AttributeFollowSpeed,
+			AttributeHealth,
+			AttributeAttack,
+			AttributeDefense,
 		}
 	case ObjectTypeMobInstance:
 		return []string{
 			AttributeTitle,
+			AttributeHealth,
+			AttributeAttack,
+			AttributeDefense,
 		}
 	}
 
@@ -179,6 +194,8 @@ func AttributeGroup(attr string) string {
 		return "Mob Spawning"
 	case AttributeMoney:
 		return "Bank Cards"
+	case AttributeHealth, AttributeAttack, AttributeDefense, AttributeHome:
+		return "Combat"
 	}
 
 	return "General"
@@ -220,6 +237,27 @@ func AttributeDefault(ot ObjectType, attr string) string {
 		return "0"
 	case AttributeFollowSpeed:
 		return "12"
+	case AttributeHealth:
+		switch ot {
+		case ObjectTypeCharacter:
+			return "100"
+		case ObjectTypeMob:
+			return "50"
+		}
+	case AttributeAttack:
+		switch ot {
+		case ObjectTypeCharacter:
+			return "10"
+		case ObjectTypeMob:
+			return "6"
+		}
+	case AttributeDefense:
+		switch ot {
+		case ObjectTypeCharacter:
+			return "4"
+		case ObjectTypeMob:
+			return "2"
+		}
 	}
 
 	return ""
@@ -243,6 +281,12 @@ func AttributeValidate(ot ObjectType, attr, val string) validate.ValidationResul
 		case AttributeFollowSpeed:
 			validatorString = "num|min:1|max:60"
 			break
+		case AttributeHealth:
+			validatorString = "num|min:1|max:100000"
+			break
+		case AttributeAttack, AttributeDefense:
+			validatorString = "num|min:0|max:10000"
+			break
 		}
 	case ObjectTypeCharacter:
 		switch attr {
@@ -252,9 +296,18 @@ func AttributeValidate(ot ObjectType, attr, val string) validate.ValidationResul
 		case AttributeMoney:
 			validatorString = "num|min:0"
 			break
+		case AttributeHealth:
+			validatorString = "num|min:1|max:100000"
+			break
+		case AttributeAttack, AttributeDefense:
+			validatorString = "num|min:0|max:10000"
+			break
 		}
 	case ObjectTypeItem:
 		switch attr {
+		case AttributeScript:
+			validatorString = "empty"
+			break
 		case AttributeType:
 			validatorString = "in:" + strings.Join(ItemTypes(), ",")
 			break
@@ -274,8 +327,17 @@ func AttributeValidate(ot ObjectType, attr, val string) validate.ValidationResul
 			validatorString = "in:" + strings.Join(ValidEquipmentSlotsAsString(), ",")
 			break
 		}
+	case ObjectTypeArea:
+		switch attr {
+		case AttributeScript:
+			validatorString = "empty"
+			break
+		}
 	case ObjectTypeRoom:
 		switch attr {
+		case AttributeScript:
+			validatorString = "empty"
+			break
 		case AttributeType:
 			validatorString = "in:generic,track,bank,armor,sword,home,wand"
 			break
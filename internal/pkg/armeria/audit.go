@@ -0,0 +1,396 @@
+package armeria
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditLogDir is the directory within the data directory where the audit log is written.
+const AuditLogDir string = "audit"
+
+// AuditLogFile is the name of the audit log being written to. When it grows too large, it is rotated to
+// audit.log.1, which is rotated to audit.log.2 and so on.
+const AuditLogFile string = "audit.log"
+
+// Defaults for the audit log, when they aren't set in the config file.
+const (
+	DefaultAuditLogMaxSize  int64 = 10 * 1024 * 1024
+	DefaultAuditLogMaxFiles int   = 5
+	DefaultAuditQueryLimit  int   = 25
+)
+
+var (
+	// ErrInvalidAuditFilter is an error for when an /audit filter can't be understood.
+	ErrInvalidAuditFilter = errors.New("invalid audit filter")
+)
+
+// An AuditedObject is a game object whose changes can be recorded in the audit log.
+type AuditedObject interface {
+	Name() string
+	Attribute(name string) string
+}
+
+// An AuditChange is a change made to a single object.
+type AuditChange struct {
+	ObjectType ObjectType `json:"objectType"`
+	ObjectID   string     `json:"objectId,omitempty"`
+	ObjectName string     `json:"objectName"`
+	Attribute  string     `json:"attribute,omitempty"`
+	Before     string     `json:"before,omitempty"`
+	After      string     `json:"after,omitempty"`
+	Deleted    bool       `json:"deleted,omitempty"`
+}
+
+// NewAuditChange returns an AuditChange for the object, filling in its ID when it has one.
+func NewAuditChange(ot ObjectType, o AuditedObject) *AuditChange {
+	change := &AuditChange{
+		ObjectType: ot,
+		ObjectName: o.Name(),
+	}
+
+	if ido, ok := o.(interface{ ID() string }); ok {
+		change.ObjectID = ido.ID()
+	}
+
+	return change
+}
+
+// String returns the change in words, such as `room Town Square (uuid) title: "Old" -> "New"`.
+func (ac *AuditChange) String() string {
+	s := fmt.Sprintf("%s %s", ac.ObjectType, ac.ObjectName)
+	if len(ac.ObjectID) > 0 {
+		s += fmt.Sprintf(" (%s)", ac.ObjectID)
+	}
+
+	if ac.Deleted {
+		return s + " deleted"
+	} else if len(ac.Attribute) > 0 && len(ac.Before) == 0 && len(ac.After) == 0 {
+		return s + fmt.Sprintf(" %s changed", ac.Attribute)
+	} else if len(ac.Attribute) > 0 {
+		return s + fmt.Sprintf(" %s: %q -> %q", ac.Attribute, ac.Before, ac.After)
+	}
+
+	return s
+}
+
+// An AuditEntry is a single privileged action, along with any changes it made.
+type AuditEntry struct {
+	Time    time.Time      `json:"time"`
+	ActorID string         `json:"actorId,omitempty"`
+	Actor   string         `json:"actor"`
+	Command string         `json:"command"`
+	Changes []*AuditChange `json:"changes,omitempty"`
+}
+
+// NewAuditEntry returns an AuditEntry for an action taken by the Character.
+func NewAuditEntry(actor *Character, command string, changes ...*AuditChange) *AuditEntry {
+	e := &AuditEntry{
+		Time:    time.Now(),
+		Actor:   "Anonymous",
+		Command: command,
+		Changes: changes,
+	}
+
+	if actor != nil {
+		e.ActorID = actor.ID()
+		e.Actor = actor.Name()
+	}
+
+	return e
+}
+
+// AuditLog is an append-only log of privileged actions, written as one JSON object per line.
+type AuditLog struct {
+	sync.Mutex
+	dir        string
+	maxSize    int64
+	maxFiles   int
+	unsafeFile *os.File
+	unsafeSize int64
+}
+
+// NewAuditLog returns an AuditLog that writes to the directory. The file is rotated once it would grow beyond
+// maxSize bytes, and at most maxFiles rotated files are kept.
+func NewAuditLog(dir string, maxSize int64, maxFiles int) *AuditLog {
+	if maxSize <= 0 {
+		maxSize = DefaultAuditLogMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultAuditLogMaxFiles
+	}
+
+	return &AuditLog{
+		dir:      dir,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+}
+
+// Record appends the entry to the log. An entry that can't be written is logged instead.
+func (l *AuditLog) Record(e *AuditEntry) {
+	l.Lock()
+	defer l.Unlock()
+
+	if err := l.unsafeWrite(e); err != nil {
+		Armeria.log.Error("error writing to audit log",
+			zap.String("actor", e.Actor),
+			zap.String("command", e.Command),
+			zap.Error(err),
+		)
+	}
+}
+
+// unsafeWrite writes the entry, rotating the file first if needed. This DOES NOT request a lock and IS NOT
+// thread safe.
+func (l *AuditLog) unsafeWrite(e *AuditEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if l.unsafeFile != nil && l.unsafeSize > 0 && l.unsafeSize+int64(len(line)) > l.maxSize {
+		if err := l.unsafeRotate(); err != nil {
+			return err
+		}
+	}
+
+	if l.unsafeFile == nil {
+		if err := l.unsafeOpen(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.unsafeFile.Write(line)
+	l.unsafeSize += int64(n)
+
+	return err
+}
+
+// unsafeOpen opens the log for appending. This DOES NOT request a lock and IS NOT thread safe.
+func (l *AuditLog) unsafeOpen() error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(l.dir, AuditLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	l.unsafeFile = f
+	l.unsafeSize = info.Size()
+
+	return nil
+}
+
+// unsafeRotate closes the log and shifts each file along by one, dropping the oldest. This DOES NOT request a
+// lock and IS NOT thread safe.
+func (l *AuditLog) unsafeRotate() error {
+	if err := l.unsafeFile.Close(); err != nil {
+		return err
+	}
+	l.unsafeFile = nil
+	l.unsafeSize = 0
+
+	_ = os.Remove(l.filePath(l.maxFiles))
+	for i := l.maxFiles - 1; i >= 0; i-- {
+		if err := os.Rename(l.filePath(i), l.filePath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filePath returns the path to a file of the log, where 0 is the file being written to and higher numbers are
+// older.
+func (l *AuditLog) filePath(n int) string {
+	if n == 0 {
+		return filepath.Join(l.dir, AuditLogFile)
+	}
+	return filepath.Join(l.dir, fmt.Sprintf("%s.%d", AuditLogFile, n))
+}
+
+// Close closes the file being written to. The next entry recorded will open it again.
+func (l *AuditLog) Close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.unsafeFile == nil {
+		return nil
+	}
+
+	err := l.unsafeFile.Close()
+	l.unsafeFile = nil
+
+	return err
+}
+
+// Query returns the entries that match the filter, newest first, searching the rotated files as well.
+func (l *AuditLog) Query(f *AuditFilter) ([]*AuditEntry, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	var matches []*AuditEntry
+	for n := 0; n <= l.maxFiles; n++ {
+		entries, err := readAuditFile(l.filePath(n))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !f.Matches(entries[i]) {
+				continue
+			}
+			matches = append(matches, entries[i])
+			if f.Limit > 0 && len(matches) >= f.Limit {
+				return matches, nil
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// readAuditFile returns the entries within a file of the log, oldest first. Lines that can't be decoded, such as
+// one cut short by a crash, are skipped.
+func readAuditFile(path string) ([]*AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		e := &AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// An AuditFilter narrows down the entries returned by a query. Empty fields match everything.
+type AuditFilter struct {
+	Character string
+	Object    string
+	Command   string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// Matches returns whether the entry passes the filter.
+func (f *AuditFilter) Matches(e *AuditEntry) bool {
+	if len(f.Character) > 0 && !strings.EqualFold(e.Actor, f.Character) {
+		return false
+	}
+
+	if len(f.Command) > 0 && !strings.Contains(strings.ToLower(e.Command), strings.ToLower(f.Command)) {
+		return false
+	}
+
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	} else if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+
+	if len(f.Object) > 0 {
+		for _, c := range e.Changes {
+			if c.ObjectID == f.Object || strings.EqualFold(c.ObjectName, f.Object) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// ParseAuditFilter parses the filters given to /audit, such as "character:Bob since:24h". Times can be a date
+// (2006-01-02), a date and time (2006-01-02T15:04), or how long ago (such as 90m, 36h or 7d).
+func ParseAuditFilter(args string, now time.Time) (*AuditFilter, error) {
+	f := &AuditFilter{}
+
+	for _, arg := range strings.Fields(args) {
+		sections := strings.SplitN(arg, ":", 2)
+		if len(sections) != 2 || len(sections[1]) == 0 {
+			return nil, fmt.Errorf("%w: %q should look like name:value", ErrInvalidAuditFilter, arg)
+		}
+
+		var err error
+		name, value := strings.ToLower(sections[0]), sections[1]
+		switch name {
+		case "character":
+			f.Character = value
+		case "object":
+			f.Object = value
+		case "command":
+			f.Command = value
+		case "since":
+			f.Since, err = parseAuditTime(value, now)
+		case "until":
+			f.Until, err = parseAuditTime(value, now)
+		case "limit":
+			f.Limit, err = strconv.Atoi(value)
+			if err == nil && f.Limit <= 0 {
+				err = errors.New("must be above zero")
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown filter %q", ErrInvalidAuditFilter, name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidAuditFilter, name, err)
+		}
+	}
+
+	return f, nil
+}
+
+// parseAuditTime parses a time given to an /audit filter.
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date, a time or a duration", value)
+	}
+
+	return now.Add(-d), nil
+}
@@ -0,0 +1,153 @@
+package armeria
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLogRotation(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	dir, err := ioutil.TempDir("", "armeria-audit")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	l := NewAuditLog(dir, 400, 2)
+	for i := 0; i < 20; i++ {
+		l.Record(&AuditEntry{Time: time.Now(), Actor: "Bob", Command: fmt.Sprintf("/room set . title %d", i)})
+	}
+	_ = l.Close()
+
+	for _, name := range []string{"audit.log", "audit.log.1", "audit.log.2"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("expected %s to exist: %s", name, err)
+		} else if info.Size() > 400 {
+			t.Errorf("expected %s to have been rotated at 400 bytes, got %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "audit.log.3")); !os.IsNotExist(err) {
+		t.Error("expected only 2 rotated files to be kept")
+	}
+
+	// The newest entries come first, even when they span files.
+	entries, err := l.Query(&AuditFilter{Limit: 6})
+	if err != nil {
+		t.Fatalf("error querying: %s", err)
+	}
+	for i, e := range entries {
+		if expected := fmt.Sprintf("/room set . title %d", 19-i); e.Command != expected {
+			t.Errorf("entry %d: expected %q, got %q", i, expected, e.Command)
+		}
+	}
+	if len(entries) != 6 {
+		t.Errorf("expected 6 entries, got %d", len(entries))
+	}
+}
+
+func TestParseAuditFilter(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	f, err := ParseAuditFilter("character:Bob object:abc command:mob since:36h until:2026-10-17T09:30 limit:5", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if f.Character != "Bob" || f.Object != "abc" || f.Command != "mob" || f.Limit != 5 {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+	if !f.Since.Equal(now.Add(-36 * time.Hour)) {
+		t.Errorf("unexpected since: %s", f.Since)
+	}
+	if !f.Until.Equal(time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected until: %s", f.Until)
+	}
+
+	if f, _ := ParseAuditFilter("since:7d", now); !f.Since.Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("unexpected since: %s", f.Since)
+	}
+
+	for _, bad := range []string{"bob", "colour:red", "since:yesterday", "limit:0", "object:"} {
+		if _, err := ParseAuditFilter(bad, now); !errors.Is(err, ErrInvalidAuditFilter) {
+			t.Errorf("%s: expected an invalid filter, got %v", bad, err)
+		}
+	}
+
+	e := &AuditEntry{
+		Time:    now,
+		Actor:   "Bob",
+		Command: "/mob delete Rat",
+		Changes: []*AuditChange{{ObjectType: ObjectTypeMob, ObjectName: "Rat", Deleted: true}},
+	}
+	tests := []struct {
+		filter  string
+		matches bool
+	}{
+		{"", true},
+		{"character:bob", true},
+		{"character:Alice", false},
+		{"object:rat", true},
+		{"object:abc", false},
+		{"command:DELETE", true},
+		{"since:2026-10-18", false},
+		{"until:2026-10-18", true},
+	}
+	for _, tt := range tests {
+		f, _ := ParseAuditFilter(tt.filter, now)
+		if f.Matches(e) != tt.matches {
+			t.Errorf("%q: expected %t", tt.filter, tt.matches)
+		}
+	}
+}
+
+func TestHeadlessAudit(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+	room := alice.Player().Character().Room()
+
+	// Commands that anyone can use aren't audited.
+	alice.Send("/say hello")
+	alice.Send("/room set . title Old Square")
+	alice.Send("/mob create Rat")
+	alice.Send("/mob delete Rat")
+
+	entries, err := Armeria.auditLog.Query(&AuditFilter{})
+	if err != nil {
+		t.Fatalf("error querying: %s", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	set := entries[2]
+	if set.Actor != "Alice" || set.Command != "/room set . title Old Square" || len(set.Changes) != 1 {
+		t.Fatalf("unexpected entry: %+v", set)
+	}
+	if c := set.Changes[0]; c.ObjectID != room.ID() || c.Attribute != "title" || c.Before != "Town Square" || c.After != "Old Square" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if c := entries[0].Changes; len(c) != 1 || !c[0].Deleted || c[0].ObjectName != "Rat" {
+		t.Errorf("expected the mob to be recorded as deleted, got %+v", entries[0])
+	}
+
+	alice.Clear()
+	alice.Send("/audit object:" + room.ID())
+	expectText(t, alice, `title: "Town Square" -> "Old Square"`)
+	if strings.Contains(strings.Join(alice.Texts(), "\n"), "/mob") {
+		t.Errorf("expected only the room change, got %q", alice.Texts())
+	}
+
+	alice.Send("/audit since:tomorrow")
+	expectText(t, alice, "The filter could not be understood")
+	alice.Send("/audit character:Bob")
+	expectText(t, alice, "There are no entries in the audit log matching that filter.")
+}
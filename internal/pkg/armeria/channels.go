@@ -11,7 +11,7 @@ type Channel struct {
 	Description       string
 	SlashCommand      string
 	Color             int
-	RequirePermission string
+	RequirePermission *PermissionRequirement
 }
 
 // Channels constants.
@@ -35,14 +35,14 @@ func NewChannels() map[string]*Channel {
 			Description:       "Message the Core channel to chat with other Armeria developers.",
 			SlashCommand:      "core",
 			Color:             ColorChannelCore,
-			RequirePermission: "CAN_SYSOP",
+			RequirePermission: RequireAnyPermission(PermissionSysop),
 		},
 		ChannelBuilders: {
 			Name:              "Builders",
 			Description:       "Message the Builders channel to chat with other Armeria builders.",
 			SlashCommand:      "builders",
 			Color:             ColorChannelBuilders,
-			RequirePermission: "CAN_BUILD",
+			RequirePermission: RequireAnyPermission(PermissionBuild),
 		},
 	}
 }
@@ -60,14 +60,12 @@ func ChannelByName(name string) *Channel {
 
 // HasPermission returns a bool indicating whether the unsafeCharacter can participate in the channel.
 func (c *Channel) HasPermission(char *Character) bool {
-	if len(c.RequirePermission) > 0 {
-		return char.HasPermission(c.RequirePermission)
-	}
-	return true
+	return c.RequirePermission.MetBy(char)
 }
 
 // Broadcast sends a message to all logged-in players that have joined the channel. You can pass
 // nil as the Character if this is coming from a system rather than a particular unsafeCharacter.
+// Text from a Character must already have been checked with ValidatePlayerText.
 func (c *Channel) Broadcast(from *Character, text string) {
 	var msgToOthers string
 	var msgToFrom string
@@ -86,7 +84,7 @@ func (c *Channel) Broadcast(from *Character, text string) {
 			verbs = []string{"say", "says"}
 			break
 		}
-		normalizedText = TextCapitalization(normalizedText)
+		normalizedText = FormatPlayerText(TextCapitalization(normalizedText))
 		msgToOthers = fmt.Sprintf("[%s] %s %s, \"%s\"", TextStyle(c.Name, WithBold()), from.FormattedNameWithTitle(), verbs[1], normalizedText)
 		msgToFrom = fmt.Sprintf(
 			"%s You %s, \"%s\"",
@@ -0,0 +1,243 @@
+package armeria
+
+import (
+	"armeria/internal/pkg/misc"
+	"armeria/internal/pkg/validate"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// CreationStep is a step within the character creation wizard.
+type CreationStep int
+
+// Character creation steps, in the order they are presented to the player.
+const (
+	CreationStepName CreationStep = iota
+	CreationStepPassword
+	CreationStepConfirmPassword
+	CreationStepGender
+)
+
+// Validation strings used by the character creation wizard.
+const (
+	CreationNameValidation     string = "alpha|minlen:3|maxlen:16"
+	CreationPasswordValidation string = "minlen:6|maxlen:64"
+)
+
+// CharacterCreation tracks a Player's progress through the character creation wizard. When the Player is
+// not logged in to an Account, a new Account sharing the Character's name is created along with it.
+type CharacterCreation struct {
+	sync.RWMutex
+	account        *Account
+	unsafeStep     CreationStep
+	unsafeName     string
+	unsafePassword string
+}
+
+// NewCharacterCreation returns a new CharacterCreation starting at the first step. The Account may be nil.
+func NewCharacterCreation(a *Account) *CharacterCreation {
+	return &CharacterCreation{
+		account:    a,
+		unsafeStep: CreationStepName,
+	}
+}
+
+// Step returns the current step of the wizard.
+func (cc *CharacterCreation) Step() CreationStep {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	return cc.unsafeStep
+}
+
+// AwaitingPassword returns whether the current step asks the player for a password.
+func (cc *CharacterCreation) AwaitingPassword() bool {
+	step := cc.Step()
+	return step == CreationStepPassword || step == CreationStepConfirmPassword
+}
+
+// Prompt returns the text asking the player for the answer to the current step.
+func (cc *CharacterCreation) Prompt() string {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	switch cc.unsafeStep {
+	case CreationStepName:
+		return fmt.Sprintf(
+			"What would you like to name your character? Use %s to answer.",
+			TextStyle("/create [name]", WithBold()),
+		)
+	case CreationStepPassword:
+		return fmt.Sprintf(
+			"Choose a password for %s using %s.",
+			TextStyle(cc.unsafeName, WithBold()),
+			TextStyle("/create [password]", WithBold()),
+		)
+	case CreationStepConfirmPassword:
+		return fmt.Sprintf(
+			"Confirm your password by entering it again using %s.",
+			TextStyle("/create [password]", WithBold()),
+		)
+	case CreationStepGender:
+		return fmt.Sprintf(
+			"Is %s %s or %s?",
+			TextStyle(cc.unsafeName, WithBold()),
+			TextStyle("male", WithLinkCmd("/create male")),
+			TextStyle("female", WithLinkCmd("/create female")),
+		)
+	}
+
+	return ""
+}
+
+// Answer processes the answer for the current step. If the answer is accepted, the wizard moves to the next
+// step. Once the final step is answered, the new Character is returned.
+func (cc *CharacterCreation) Answer(answer string) (*Character, error) {
+	switch cc.Step() {
+	case CreationStepName:
+		return nil, cc.answerName(answer)
+	case CreationStepPassword:
+		return nil, cc.answerPassword(answer)
+	case CreationStepConfirmPassword:
+		return nil, cc.answerConfirmPassword(answer)
+	case CreationStepGender:
+		return cc.answerGender(answer)
+	}
+
+	return nil, fmt.Errorf("invalid creation step")
+}
+
+func (cc *CharacterCreation) answerName(name string) error {
+	if strings.Contains(name, " ") {
+		return fmt.Errorf("your character name must be a single word")
+	}
+
+	valid := validate.Check(name, CreationNameValidation)
+	if !valid.Result {
+		return fmt.Errorf("that name cannot be used: %s", valid)
+	}
+
+	if misc.Contains(Armeria.reservedNames, name) {
+		return fmt.Errorf("that name is reserved")
+	}
+
+	if Armeria.characterManager.CharacterByName(name) != nil {
+		return fmt.Errorf("a character with that name already exists")
+	}
+
+	if cc.account == nil && Armeria.accountManager.AccountByName(name) != nil {
+		return fmt.Errorf("an account with that name already exists")
+	}
+
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.unsafeName = TextCapitalization(strings.ToLower(name))
+	if cc.account != nil {
+		cc.unsafeStep = CreationStepGender
+	} else {
+		cc.unsafeStep = CreationStepPassword
+	}
+
+	return nil
+}
+
+func (cc *CharacterCreation) answerPassword(password string) error {
+	valid := validate.Check(password, CreationPasswordValidation)
+	if !valid.Result {
+		return fmt.Errorf("that password cannot be used: %s", valid)
+	}
+
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.unsafePassword = password
+	cc.unsafeStep = CreationStepConfirmPassword
+
+	return nil
+}
+
+func (cc *CharacterCreation) answerConfirmPassword(password string) error {
+	cc.Lock()
+	defer cc.Unlock()
+
+	if cc.unsafePassword != password {
+		cc.unsafePassword = ""
+		cc.unsafeStep = CreationStepPassword
+		return fmt.Errorf("the passwords did not match")
+	}
+
+	cc.unsafeStep = CreationStepGender
+
+	return nil
+}
+
+func (cc *CharacterCreation) answerGender(gender string) (*Character, error) {
+	gender = strings.ToLower(gender)
+
+	valid := AttributeValidate(ObjectTypeCharacter, AttributeGender, gender)
+	if !valid.Result {
+		return nil, fmt.Errorf("that is not a valid gender")
+	}
+
+	room := Armeria.worldManager.RoomFromLocationString(Armeria.startingRoom)
+	if room == nil {
+		Armeria.log.Error("starting room is not valid",
+			zap.String("startingRoom", Armeria.startingRoom),
+		)
+		return nil, fmt.Errorf("there is no starting room available")
+	}
+
+	cc.RLock()
+	name := cc.unsafeName
+	password := cc.unsafePassword
+	cc.RUnlock()
+
+	// The name may have been taken while the wizard was in progress, which the managers check as they create the
+	// account and character.
+	a := cc.account
+	created := false
+	if a == nil {
+		var err error
+		if a, err = Armeria.accountManager.CreateAccount(name, password); err != nil {
+			return nil, cc.nameTaken()
+		}
+		created = true
+	}
+
+	c, err := Armeria.characterManager.CreateCharacter(a, name)
+	if err != nil {
+		if created {
+			Armeria.accountManager.RemoveAccount(a)
+		}
+		return nil, cc.nameTaken()
+	}
+
+	_ = c.SetAttribute(AttributeGender, gender)
+
+	Armeria.accountManager.SaveAccount(a)
+	Armeria.characterManager.SaveCharacter(c)
+
+	if err := room.Here().Add(c.ID()); err != nil {
+		Armeria.log.Error("error adding new character to starting room",
+			zap.String("character", c.Name()),
+			zap.Error(err),
+		)
+	}
+
+	return c, nil
+}
+
+// nameTaken sends the wizard back to the name step, since the name was taken by someone else while the wizard was
+// in progress.
+func (cc *CharacterCreation) nameTaken() error {
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.unsafeStep = CreationStepName
+
+	return fmt.Errorf("that name was taken while you were deciding")
+}
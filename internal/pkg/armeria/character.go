@@ -3,18 +3,16 @@ package armeria
 import (
 	"armeria/internal/pkg/misc"
 	"armeria/internal/pkg/sfx"
-	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Force verify that Character implements ContainerObject.
@@ -23,16 +21,19 @@ var _ ContainerObject = (*Character)(nil)
 // A Character is the player's logged in character.
 type Character struct {
 	sync.RWMutex
-	UUID                 string            `json:"uuid"`
-	UnsafeName           string            `json:"name"`
-	UnsafePassword       string            `json:"password"`
-	UnsafeAttributes     map[string]string `json:"attributes"`
-	UnsafeSettings       map[string]string `json:"settings"`
-	UnsafeInventory      *ObjectContainer  `json:"inventory"`
-	UnsafeEquipment      *ObjectContainer  `json:"equipment"`
-	UnsafeTempAttributes map[string]string `json:"-"`
-	UnsafeLastSeen       time.Time         `json:"lastSeen"`
-	UnsafeMobConvo       *Conversation     `json:"-"`
+	UUID                 string                   `json:"uuid"`
+	UnsafeName           string                   `json:"name"`
+	UnsafeAccount        string                   `json:"account"`
+	UnsafeAttributes     map[string]string        `json:"attributes"`
+	UnsafeSettings       map[string]string        `json:"settings"`
+	UnsafeQuests         map[string]QuestProgress `json:"quests"`
+	UnsafeRoles          []string                 `json:"roles"`
+	UnsafeInventory      *ObjectContainer         `json:"inventory"`
+	UnsafeEquipment      *ObjectContainer         `json:"equipment"`
+	UnsafeTempAttributes map[string]string        `json:"-"`
+	UnsafeLastSeen       time.Time                `json:"lastSeen"`
+	UnsafeMobConvo       *Conversation            `json:"-"`
+	UnsafeEditJournal    *EditJournal             `json:"-"`
 	player               *Player
 }
 
@@ -71,6 +72,14 @@ func (c *Character) Init() {
 	if c.UnsafeEquipment == nil {
 		c.UnsafeEquipment = NewObjectContainer(0)
 	}
+	// Initialize the quest progress, if not defined.
+	if c.UnsafeQuests == nil {
+		c.UnsafeQuests = make(map[string]QuestProgress)
+	}
+	// Initialize the roles, if not defined.
+	if c.UnsafeRoles == nil {
+		c.UnsafeRoles = []string{}
+	}
 	// Attach parents to the child containers.
 	c.UnsafeInventory.AttachParent(c, ContainerParentTypeCharacter)
 	c.UnsafeEquipment.AttachParent(c, ContainerParentTypeCharacter)
@@ -118,42 +127,25 @@ func (c *Character) FormattedNameWithTitle() string {
 	return TextStyle(c.UnsafeName, WithBold())
 }
 
-// CheckPassword returns a bool indicating whether the password is correct or not.
-func (c *Character) CheckPassword(pw string) bool {
+// Account returns the Account the Character belongs to.
+func (c *Character) Account() *Account {
+	return Armeria.accountManager.AccountById(c.AccountID())
+}
+
+// AccountID returns the uuid of the Account the Character belongs to.
+func (c *Character) AccountID() string {
 	c.RLock()
 	defer c.RUnlock()
 
-	byteHash := []byte(c.UnsafePassword)
-	err := bcrypt.CompareHashAndPassword(byteHash, []byte(pw))
-	if err != nil {
-		return false
-	}
-
-	return true
+	return c.UnsafeAccount
 }
 
-// SetPassword hashes and sets a new password for the Character.
-func (c *Character) SetPassword(pw string) {
+// SetAccount sets the Account the Character belongs to.
+func (c *Character) SetAccount(a *Account) {
 	c.Lock()
 	defer c.Unlock()
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
-	if err != nil {
-		Armeria.log.Fatal("error generating password hash",
-			zap.Error(err),
-		)
-	}
-
-	c.UnsafePassword = string(hash)
-}
-
-// PasswordHash returns the Character's already-encrypted password as an md5 hash.
-func (c *Character) PasswordHash() string {
-	c.RLock()
-	defer c.RUnlock()
-
-	b := []byte(c.UnsafePassword)
-	return fmt.Sprintf("%x", md5.Sum(b))
+	c.UnsafeAccount = a.ID()
 }
 
 // Inventory returns the Character's inventory.
@@ -188,6 +180,19 @@ func (c *Character) SetPlayer(p *Player) {
 	c.player = p
 }
 
+// EditJournal returns the Character's journal of edits to the world, creating it the first time it's needed. The
+// journal isn't saved, so it only lasts until the server restarts.
+func (c *Character) EditJournal() *EditJournal {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.UnsafeEditJournal == nil {
+		c.UnsafeEditJournal = NewEditJournal(Armeria.editJournalDepth)
+	}
+
+	return c.UnsafeEditJournal
+}
+
 // MobConvo returns the active mob conversation for the Character.
 func (c *Character) MobConvo() *Conversation {
 	c.RLock()
@@ -250,7 +255,7 @@ func (c *Character) UserColor(color int) string {
 // Colorize will color text according to the Character's color settings.
 // TODO Deprecate Character.Colorize() in favor of using TextStyles() with WithUserColor().
 func (c *Character) Colorize(text string, color int) string {
-	return fmt.Sprintf("<span style='color:%s'>%s</span>", c.UserColor(color), text)
+	return TextStyle(text, WithUserColor(c, color))
 }
 
 // LastSeen returns the Time the Character last successfully logged into the game.
@@ -353,6 +358,8 @@ func (c *Character) LoggedOut() {
 		c.MobConvo().Cancel()
 	}
 
+	Armeria.characterManager.SaveCharacter(c)
+
 	Armeria.log.Info("character left the game",
 		zap.String("character", c.Name()),
 	)
@@ -457,6 +464,45 @@ func (c *Character) Setting(name string) string {
 	return c.UnsafeSettings[name]
 }
 
+// QuestProgress returns the Character's progress on a quest, and whether they have started it.
+func (c *Character) QuestProgress(name string) (QuestProgress, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	p, ok := c.UnsafeQuests[name]
+	return p, ok
+}
+
+// SetQuestProgress sets the Character's progress on a quest.
+func (c *Character) SetQuestProgress(name string, p QuestProgress) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.UnsafeQuests[name] = p
+}
+
+// RemoveQuestProgress removes the Character's progress on a quest, as if they never started it.
+func (c *Character) RemoveQuestProgress(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.UnsafeQuests, name)
+}
+
+// Quests returns the names of the quests the Character has started or completed.
+func (c *Character) Quests() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	var names []string
+	for name := range c.UnsafeQuests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 // MoveAllowed will check if moving to a particular location is valid/allowed.
 func (c *Character) MoveAllowed(r *Room) (bool, string) {
 	if r == nil {
@@ -471,6 +517,26 @@ func (c *Character) MoveAllowed(r *Room) (bool, string) {
 		return false, "You cannot walk onto the train tracks!"
 	}
 
+	// Room and area scripts can block the move with block_move().
+	if from := c.Room(); from != nil {
+		if reason := CallMoveCheck(c, from, "character_leaving"); len(reason) > 0 {
+			return false, reason
+		}
+
+		if from.ParentArea.ID() != r.ParentArea.ID() {
+			if reason := CallMoveCheck(c, from.ParentArea, "character_leaving"); len(reason) > 0 {
+				return false, reason
+			}
+			if reason := CallMoveCheck(c, r.ParentArea, "character_entering"); len(reason) > 0 {
+				return false, reason
+			}
+		}
+	}
+
+	if reason := CallMoveCheck(c, r, "character_entering"); len(reason) > 0 {
+		return false, reason
+	}
+
 	return true, ""
 }
 
@@ -481,10 +547,13 @@ func (c *Character) Move(to *Room, msgToChar string, msgToOld string, msgToNew s
 		// If the character logged out in a room that no longer exists, allow movement to still work so they
 		// can be teleported by a staff member to a "real" room.
 		oldRoom = to
-	}
-
-	oldRoom.Here().Remove(c.ID())
-	if err := to.Here().Add(c.ID()); err != nil {
+		if err := to.Here().Add(c.ID()); err != nil && err != ErrContainerDuplicate {
+			Armeria.log.Fatal("error adding character to destination room")
+		}
+	} else if err := oldRoom.Here().Transfer(c.ID(), to.Here()); err == ErrContainerMissing {
+		// Something else moved the character first.
+		return
+	} else if err != nil {
 		Armeria.log.Fatal("error adding character to destination room")
 	}
 
@@ -518,7 +587,7 @@ func (c *Character) Move(to *Room, msgToChar string, msgToOld string, msgToNew s
 		newArea.CharacterEntered(c, false)
 	}
 
-	oldRoom.CharacterEntered(c, false)
+	oldRoom.CharacterLeft(c, false)
 	to.CharacterEntered(c, false)
 
 	// Stop any on-going mob conversations.
@@ -552,7 +621,8 @@ func (c *Character) EditorData() *ObjectEditorData {
 	}
 }
 
-func (c *Character) SettingsJSON() string {
+// SettingsData returns the value of each of the Character's settings, by name, for the client.
+func (c *Character) SettingsData() map[string]string {
 	c.RLock()
 	defer c.RUnlock()
 
@@ -566,21 +636,79 @@ func (c *Character) SettingsJSON() string {
 		}
 	}
 
-	b, err := json.Marshal(obj)
-	if err != nil {
-		log.Fatalf("error marshalling character settings: %s", err)
+	return obj
+}
+
+// Roles returns the names of the roles that have been granted to the Character, not including RoleDefault.
+func (c *Character) Roles() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	roles := make([]string, len(c.UnsafeRoles))
+	copy(roles, c.UnsafeRoles)
+
+	return roles
+}
+
+// HasRole returns true if the Character has been granted the role.
+func (c *Character) HasRole(role string) bool {
+	for _, r := range c.Roles() {
+		if strings.ToLower(r) == strings.ToLower(role) {
+			return true
+		}
 	}
 
-	return string(b)
+	return false
+}
+
+// GrantRole grants a role to the Character.
+func (c *Character) GrantRole(role string) {
+	if c.HasRole(role) {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.UnsafeRoles = append(c.UnsafeRoles, role)
+}
+
+// RevokeRole revokes a role from the Character.
+func (c *Character) RevokeRole(role string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i, r := range c.UnsafeRoles {
+		if strings.ToLower(r) == strings.ToLower(role) {
+			c.UnsafeRoles = append(c.UnsafeRoles[:i], c.UnsafeRoles[i+1:]...)
+			return
+		}
+	}
 }
 
-// HasPermission returns true if the Character has a particular permission.
+// HasPermission returns true if the Character has a particular permission, through its roles or its Account.
 func (c *Character) HasPermission(p string) bool {
-	c.RLock()
-	defer c.RUnlock()
+	if misc.Contains(Armeria.roleManager.ResolvePermissions(append(c.Roles(), RoleDefault)), p) {
+		return true
+	}
+
+	a := c.Account()
+	return a != nil && a.HasPermission(p)
+}
+
+// Permissions returns the Character's permissions, including those granted by its roles and to its Account.
+func (c *Character) Permissions() []string {
+	perms := Armeria.roleManager.ResolvePermissions(append(c.Roles(), RoleDefault))
+
+	if a := c.Account(); a != nil {
+		for _, p := range a.Permissions() {
+			if !misc.Contains(perms, p) {
+				perms = append(perms, p)
+			}
+		}
+	}
 
-	perms := strings.Split(c.UnsafeAttributes[AttributePermissions], " ")
-	return misc.Contains(perms, p)
+	return perms
 }
 
 // Channels returns the Channel objects for the channels this unsafeCharacter is within.
@@ -636,30 +764,22 @@ func (c *Character) LeaveChannel(ch *Channel) {
 	_ = c.SetAttribute(AttributeChannels, strings.Join(chs, ","))
 }
 
-// InventoryJSON returns the JSON used for rendering the inventory on the client.
-func (c *Character) InventoryJSON() string {
-	var inventory []map[string]interface{}
+// InventoryData returns the data used for rendering the inventory on the client.
+func (c *Character) InventoryData() []*InventoryItemData {
+	var inventory []*InventoryItemData
 
 	for _, ii := range c.Inventory().Items() {
-		inventory = append(inventory, map[string]interface{}{
-			"uuid":      ii.ID(),
-			"name":      ii.Name(),
-			"picture":   ii.Attribute(AttributePicture),
-			"slot":      c.Inventory().Slot(ii.ID()),
-			"equipSlot": ii.Attribute(AttributeEquipSlot),
-			"color":     ii.RarityColor(),
+		inventory = append(inventory, &InventoryItemData{
+			UUID:      ii.ID(),
+			Name:      ii.Name(),
+			Picture:   ii.Attribute(AttributePicture),
+			Slot:      c.Inventory().Slot(ii.ID()),
+			EquipSlot: ii.Attribute(AttributeEquipSlot),
+			Color:     ii.RarityColor(),
 		})
 	}
 
-	inventoryJSON, err := json.Marshal(inventory)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal inventory data",
-			zap.String("character", c.UUID),
-			zap.Error(err),
-		)
-	}
-
-	return string(inventoryJSON)
+	return inventory
 }
 
 // Pronoun is used to determine the appropriate pronoun for the character.
@@ -689,3 +809,13 @@ func (c *Character) Pronoun(pt PronounType) string {
 
 	return ""
 }
+
+type characterJSON Character
+
+// MarshalJSON encodes the Character while holding its lock.
+func (c *Character) MarshalJSON() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	return json.Marshal((*characterJSON)(c))
+}
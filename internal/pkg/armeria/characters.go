@@ -1,9 +1,7 @@
 package armeria
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
+	"errors"
 	"strings"
 	"sync"
 	"time"
@@ -13,16 +11,16 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrCharacterNameTaken is an error for when a character with a particular name already exists.
+var ErrCharacterNameTaken = errors.New("a character with that name already exists")
+
 type CharacterManager struct {
 	sync.RWMutex
-	dataFile         string
 	UnsafeCharacters []*Character `json:"characters"`
 }
 
 func NewCharacterManager() *CharacterManager {
-	m := &CharacterManager{
-		dataFile: fmt.Sprintf("%s/characters.json", Armeria.dataPath),
-	}
+	m := &CharacterManager{}
 
 	m.LoadCharacters()
 
@@ -33,21 +31,18 @@ func (m *CharacterManager) LoadCharacters() {
 	m.Lock()
 	defer m.Unlock()
 
-	charactersFile, err := os.Open(m.dataFile)
+	records, err := Armeria.store.Load(CollectionCharacters)
 	if err != nil {
-		Armeria.log.Fatal("failed to load data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionCharacters.Name),
 			zap.Error(err),
 		)
 	}
-	defer charactersFile.Close()
 
-	jsonParser := json.NewDecoder(charactersFile)
-
-	err = jsonParser.Decode(m)
+	err = DecodeStoreRecords(records, &m.UnsafeCharacters)
 	if err != nil {
-		Armeria.log.Fatal("failed to decode data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionCharacters.Name),
 			zap.Error(err),
 		)
 	}
@@ -65,32 +60,33 @@ func (m *CharacterManager) SaveCharacters() {
 	m.RLock()
 	defer m.RUnlock()
 
-	charactersFile, err := os.Create(m.dataFile)
-	defer charactersFile.Close()
-
-	raw, err := json.Marshal(m)
+	records, err := EncodeStoreRecords(CollectionCharacters, m.UnsafeCharacters)
 	if err != nil {
 		Armeria.log.Fatal("failed to marshal data",
 			zap.Error(err),
 		)
 	}
 
-	bytes, err := charactersFile.Write(raw)
+	err = Armeria.store.Save(CollectionCharacters, records)
 	if err != nil {
-		Armeria.log.Fatal("failed to write data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionCharacters.Name),
 			zap.Error(err),
 		)
 	}
 
-	_ = charactersFile.Sync()
-
-	Armeria.log.Info("wrote data to file",
-		zap.String("file", m.dataFile),
-		zap.Int("bytes", bytes),
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionCharacters.Name),
+		zap.Int("records", len(records)),
 	)
 }
 
+// SaveCharacter immediately persists a single Character to the store. The Character is locked while it's encoded, so
+// this must not be called while holding its lock.
+func (m *CharacterManager) SaveCharacter(c *Character) {
+	persistRecord(CollectionCharacters, c)
+}
+
 // CharacterByName returns the matching Character, by name.
 func (m *CharacterManager) CharacterByName(name string) *Character {
 	m.RLock()
@@ -119,20 +115,44 @@ func (m *CharacterManager) CharacterById(uuid string) *Character {
 	return nil
 }
 
-// CreateCharacter creates a new Character, adds it to memory, initializes it and returns the Character.
-func (m *CharacterManager) CreateCharacter(name, password string) *Character {
+// CharactersByAccount returns the Characters belonging to an Account.
+func (m *CharacterManager) CharactersByAccount(a *Account) []*Character {
+	m.RLock()
+	defer m.RUnlock()
+
+	var chars []*Character
+	for _, c := range m.UnsafeCharacters {
+		if c.AccountID() == a.ID() {
+			chars = append(chars, c)
+		}
+	}
+
+	return chars
+}
+
+// CreateCharacter creates a new Character belonging to an Account, adds it to memory, initializes it and
+// returns the Character. The name is checked while the lock is held, so two characters can never be created with
+// the same name.
+func (m *CharacterManager) CreateCharacter(a *Account, name string) (*Character, error) {
 	m.Lock()
 	defer m.Unlock()
+
+	for _, c := range m.UnsafeCharacters {
+		if strings.ToLower(c.Name()) == strings.ToLower(name) {
+			return nil, ErrCharacterNameTaken
+		}
+	}
+
 	c := &Character{
 		UUID:                 uuid.New().String(),
 		UnsafeName:           name,
+		UnsafeAccount:        a.ID(),
 		UnsafeAttributes:     make(map[string]string),
 		UnsafeSettings:       make(map[string]string),
 		UnsafeTempAttributes: make(map[string]string),
 		UnsafeLastSeen:       time.Time{},
 	}
 
-	c.SetPassword(password)
 	_ = c.SetAttribute(AttributeChannels, "General")
 
 	m.UnsafeCharacters = append(m.UnsafeCharacters, c)
@@ -141,9 +161,10 @@ func (m *CharacterManager) CreateCharacter(name, password string) *Character {
 
 	Armeria.log.Info("character created",
 		zap.String("name", name),
+		zap.String("account", a.Name()),
 	)
 
-	return c
+	return c, nil
 }
 
 // OnlineCharacters returns the characters logged in to the game.
@@ -165,5 +186,8 @@ func (m *CharacterManager) Characters() []*Character {
 	m.RLock()
 	defer m.RUnlock()
 
-	return m.UnsafeCharacters
+	chars := make([]*Character, len(m.UnsafeCharacters))
+	copy(chars, m.UnsafeCharacters)
+
+	return chars
 }
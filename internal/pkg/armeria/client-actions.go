@@ -2,11 +2,8 @@ package armeria
 
 import (
 	"armeria/internal/pkg/sfx"
-	"encoding/json"
 	"fmt"
 	"strings"
-
-	"go.uber.org/zap"
 )
 
 // ClientActions is a struct that references a Player and can send data to the client to trigger client-based
@@ -59,36 +56,36 @@ func (ca *ClientActions) ShowColorizedText(text string, color int) {
 
 // ShowText displays text on the parent's main text window.
 func (ca *ClientActions) ShowText(text string) {
-	ca.parent.CallClientAction("showText", "\n"+text)
+	ca.parent.CallClientAction("showText", ParseRichText("\n"+text))
 }
 
-// ShowRawText displays raw text on the parent's main text window.
+// ShowRawText displays text on the parent's main text window, without a blank line before it.
 func (ca *ClientActions) ShowRawText(text string) {
-	ca.parent.CallClientAction("showText", text)
+	ca.parent.CallClientAction("showText", ParseRichText(text))
 }
 
 // SyncMap displays the current area on the minimap.
 func (ca *ClientActions) SyncMap() {
-	minimap := ca.parent.Character().Room().ParentArea.MinimapJSON()
+	minimap := ca.parent.Character().Room().ParentArea.MinimapData()
 	ca.parent.CallClientAction("setMapData", minimap)
 }
 
 // SyncMapLocation sets the unsafeCharacter location on the minimap.
 func (ca *ClientActions) SyncMapLocation() {
-	loc := ca.parent.Character().Room().Coords.JSON()
+	loc := ca.parent.Character().Room().Coords.LocationData()
 	ca.parent.CallClientAction("setCharacterLocation", loc)
 }
 
 // SyncRoomObjects sets the current room objects on the client.
 func (ca *ClientActions) SyncRoomObjects() {
-	obj := ca.parent.Character().Room().RoomTargetJSON(ca.parent.Character())
+	obj := ca.parent.Character().Room().RoomTargetData(ca.parent.Character())
 	ca.parent.CallClientAction("setRoomObjects", obj)
 }
 
 // SyncRoomTitle sets the current room title on the client.
 func (ca *ClientActions) SyncRoomTitle() {
 	r := ca.parent.Character().Room()
-	if ca.parent.Character().HasPermission("CAN_BUILD") {
+	if ca.parent.Character().HasPermission(PermissionBuild) {
 		c := r.Coords
 		ca.parent.CallClientAction("setRoomTitle",
 			fmt.Sprintf("%s (%d,%d,%d)", r.Attribute("title"), c.X(), c.Y(), c.Z()),
@@ -100,18 +97,27 @@ func (ca *ClientActions) SyncRoomTitle() {
 
 // SyncInventory renders the inventory on the client.
 func (ca *ClientActions) SyncInventory() {
-	inv := ca.parent.Character().InventoryJSON()
+	inv := ca.parent.Character().InventoryData()
 	ca.parent.CallClientAction("setInventory", inv)
 }
 
 // SyncPermissions sets the character permissions on the client (to allow/disallow certain client actions / UI tweaks).
 func (ca *ClientActions) SyncPermissions() {
-	ca.parent.CallClientAction("setPermissions", ca.parent.Character().Attribute(AttributePermissions))
+	ca.parent.CallClientAction("setPermissions", strings.Join(ca.parent.Character().Permissions(), " "))
 }
 
 // SyncSettings sends the Character's setting values to the client.
 func (ca *ClientActions) SyncSettings() {
-	ca.parent.CallClientAction("setSettings", ca.parent.Character().SettingsJSON())
+	ca.parent.CallClientAction("setSettings", ca.parent.Character().SettingsData())
+}
+
+// SendHello lets the client know the version of the protocol the server will speak with it.
+func (ca *ClientActions) SendHello(version int) {
+	ca.parent.CallClientAction("hello", &HelloData{
+		Version:    version,
+		MinVersion: MinProtocolVersion,
+		MaxVersion: ProtocolVersion,
+	})
 }
 
 // SendPong responds to a client's ping request as part of the keep alive lifecycle.
@@ -126,29 +132,22 @@ func (ca *ClientActions) SyncMoney() {
 
 // SyncPlayerInfo sets the character/player information on the client.
 func (ca *ClientActions) SyncPlayerInfo() {
-	ca.parent.CallClientAction("setPlayerInfo", ca.parent.Character().Player().PlayerInfoJSON())
+	ca.parent.CallClientAction("setPlayerInfo", ca.parent.Character().Player().PlayerInfo())
 }
 
 // SyncCommands sends all of the valid commands to the client (used for auto-complete).
 func (ca *ClientActions) SyncCommands() {
 	ca.parent.CallClientAction("setCommandDictionary",
-		Armeria.commandManager.CharacterCommandDictionaryJSON(ca.parent.Character().Player()),
+		Armeria.commandManager.CharacterCommandDictionary(ca.parent.Character().Player()),
 	)
 }
 
 // ShowObjectEditor displays the object editor on the client.
 func (ca *ClientActions) ShowObjectEditor(editorData *ObjectEditorData) {
 	// add access key
-	c := ca.parent.Character()
-	editorData.AccessKey = c.Name() + "/" + c.PasswordHash()
-	j, err := json.Marshal(editorData)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal data for client action: ShowObjectEditor",
-			zap.Error(err),
-		)
-	}
+	editorData.AccessKey = ca.parent.EditorToken()
 
-	ca.parent.CallClientAction("setObjectEditorData", string(j))
+	ca.parent.CallClientAction("setObjectEditorData", editorData)
 }
 
 // Disconnect requests that the client disconnects from the server.
@@ -156,48 +155,33 @@ func (ca *ClientActions) Disconnect() {
 	ca.parent.CallClientAction("disconnect", nil)
 }
 
-// ToggleAutologin sets (or disables) auto-login on the client.
-func (ca *ClientActions) ToggleAutologin() {
+// ToggleAutologin sets (or disables) auto-login on the client. The token is prefixed with the Character's
+// name so the client knows who it is logging in as.
+func (ca *ClientActions) ToggleAutologin(token string) {
 	ca.parent.CallClientAction(
 		"toggleAutoLogin",
-		strings.ToLower(ca.parent.Character().Name())+":"+ca.parent.Character().PasswordHash(),
+		strings.ToLower(ca.parent.Character().Name())+":"+token,
 	)
 }
 
 // SetItemTooltipHTML sets the item's tooltip HTML on the client and stores it in the client-side cache.
 func (ca *ClientActions) SetItemTooltipHTML(ii *ItemInstance) {
-	ca.parent.CallClientAction("setItemTooltipHTML", ii.TooltipContentJSON())
+	ca.parent.CallClientAction("setItemTooltipHTML", ii.TooltipContent())
 }
 
 // SetItemTooltipHTMLRaw sets an item's tooltip HTML on the client to some arbitrary value.
 func (ca *ClientActions) SetItemTooltipHTMLRaw(uuid, content string) {
-	tt := map[string]string{
-		"uuid":   uuid,
-		"html":   content,
-		"rarity": "ffffff",
-	}
-
-	ttJSON, err := json.Marshal(tt)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal data for client action: SetItemTooltipHTMLRaw",
-			zap.Error(err),
-		)
-	}
-
-	ca.parent.CallClientAction("setItemTooltipHTML", string(ttJSON))
+	ca.parent.CallClientAction("setItemTooltipHTML", &ItemTooltipData{
+		UUID:   uuid,
+		HTML:   content,
+		Rarity: "ffffff",
+	})
 }
 
 // PlaySFX plays a sound effect on the client.
 func (ca *ClientActions) PlaySFX(id sfx.ClientSoundEffect) {
-	data := map[string]interface{}{
-		"id":     string(id),
-		"volume": 1,
-	}
-	dataJSON, err := json.Marshal(data)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal data for client action: PlaySFX",
-			zap.Error(err),
-		)
-	}
-	ca.parent.CallClientAction("playSFX", string(dataJSON))
+	ca.parent.CallClientAction("playSFX", &SoundEffectData{
+		ID:     string(id),
+		Volume: 1,
+	})
 }
@@ -0,0 +1,417 @@
+package armeria
+
+import (
+	"armeria/internal/pkg/misc"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+	"go.uber.org/zap"
+)
+
+// A Combatant is an object that can take part in combat.
+type Combatant interface {
+	ContainerObject
+	Room() *Room
+}
+
+// CombatState is the in-memory combat state of a single Combatant. Health is only tracked while a Combatant is
+// hurt; once fully healed, the state is discarded.
+type CombatState struct {
+	Health int
+	Target string
+}
+
+// CombatManager tracks the health and targets of everything taking part in combat.
+type CombatManager struct {
+	sync.RWMutex
+	unsafeStates map[string]*CombatState
+}
+
+// NewCombatManager creates a new CombatManager.
+func NewCombatManager() *CombatManager {
+	return &CombatManager{
+		unsafeStates: make(map[string]*CombatState),
+	}
+}
+
+// CombatAttribute returns a numeric combat attribute (health, attack or defense) of a Combatant.
+func CombatAttribute(o Combatant, attr string) int {
+	i, err := strconv.Atoi(o.Attribute(attr))
+	if err != nil {
+		return 0
+	}
+
+	return i
+}
+
+// combatantByID returns the Combatant matching the uuid, if it is still in the game.
+func combatantByID(uuid string) Combatant {
+	o, rt := Armeria.registry.Get(uuid)
+	switch rt {
+	case RegistryTypeCharacter:
+		return o.(*Character)
+	case RegistryTypeMobInstance:
+		return o.(*MobInstance)
+	}
+
+	return nil
+}
+
+// state returns the CombatState of a Combatant, creating it at full health if necessary. This DOES NOT request a
+// lock and IS NOT thread safe.
+func (m *CombatManager) state(o Combatant) *CombatState {
+	s, ok := m.unsafeStates[o.ID()]
+	if !ok {
+		s = &CombatState{Health: CombatAttribute(o, AttributeHealth)}
+		m.unsafeStates[o.ID()] = s
+	}
+
+	return s
+}
+
+// Health returns the current health of a Combatant.
+func (m *CombatManager) Health(o Combatant) int {
+	m.RLock()
+	defer m.RUnlock()
+
+	if s, ok := m.unsafeStates[o.ID()]; ok {
+		return s.Health
+	}
+
+	return CombatAttribute(o, AttributeHealth)
+}
+
+// HealthString returns the current and maximum health of a Combatant, for display.
+func (m *CombatManager) HealthString(o Combatant) string {
+	return fmt.Sprintf("%d/%d", m.Health(o), CombatAttribute(o, AttributeHealth))
+}
+
+// Target returns the Combatant being attacked, if any.
+func (m *CombatManager) Target(o Combatant) Combatant {
+	m.RLock()
+	target := ""
+	if s, ok := m.unsafeStates[o.ID()]; ok {
+		target = s.Target
+	}
+	m.RUnlock()
+
+	if len(target) == 0 {
+		return nil
+	}
+
+	return combatantByID(target)
+}
+
+// InCombat returns true if the Combatant is attacking, or being attacked by, something.
+func (m *CombatManager) InCombat(o Combatant) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	if s, ok := m.unsafeStates[o.ID()]; ok && len(s.Target) > 0 {
+		return true
+	}
+
+	for _, s := range m.unsafeStates {
+		if s.Target == o.ID() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Engage has the attacker start attacking the target. A target that is not already fighting will fight back.
+func (m *CombatManager) Engage(attacker, target Combatant) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.state(attacker).Target = target.ID()
+
+	ts := m.state(target)
+	if len(ts.Target) == 0 {
+		ts.Target = attacker.ID()
+	}
+}
+
+// Disengage stops the Combatant from attacking, and stops everything from attacking it.
+func (m *CombatManager) Disengage(o Combatant) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.disengage(o.ID())
+}
+
+// disengage clears the targets of, and on, the uuid. This DOES NOT request a lock and IS NOT thread safe.
+func (m *CombatManager) disengage(uuid string) {
+	if s, ok := m.unsafeStates[uuid]; ok {
+		s.Target = ""
+	}
+
+	for _, s := range m.unsafeStates {
+		if s.Target == uuid {
+			s.Target = ""
+		}
+	}
+}
+
+// Remove discards all combat state for the uuid, restoring it to full health.
+func (m *CombatManager) Remove(uuid string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.disengage(uuid)
+	delete(m.unsafeStates, uuid)
+}
+
+// Damage removes health from a Combatant and returns the remaining health.
+func (m *CombatManager) Damage(o Combatant, amount int) int {
+	m.Lock()
+	defer m.Unlock()
+
+	s := m.state(o)
+	s.Health = s.Health - amount
+
+	return s.Health
+}
+
+// Round processes a single round of combat. Every Combatant with a target attacks it once, in order of their
+// uuids so that a round always plays out the same way, and everything that is not fighting regains some health.
+func (m *CombatManager) Round() {
+	m.Lock()
+	attacks := make(map[string]string)
+	attackers := make([]string, 0, len(m.unsafeStates))
+	for uuid, s := range m.unsafeStates {
+		if len(s.Target) > 0 {
+			attacks[uuid] = s.Target
+			attackers = append(attackers, uuid)
+		}
+	}
+	m.Unlock()
+
+	sort.Strings(attackers)
+
+	for _, attackerID := range attackers {
+		targetID := attacks[attackerID]
+		attacker := combatantByID(attackerID)
+		if attacker == nil {
+			m.Remove(attackerID)
+			continue
+		}
+
+		target := combatantByID(targetID)
+		if target == nil || !combatantsCanFight(attacker, target) {
+			m.Lock()
+			if s, ok := m.unsafeStates[attackerID]; ok && s.Target == targetID {
+				s.Target = ""
+			}
+			m.Unlock()
+			continue
+		}
+
+		// The target may have died earlier in the round.
+		if m.Health(target) <= 0 {
+			continue
+		}
+
+		m.attack(attacker, target)
+	}
+
+	m.regenerate()
+}
+
+// combatantsCanFight returns true if both Combatants are present within the same room.
+func combatantsCanFight(a, b Combatant) bool {
+	for _, o := range []Combatant{a, b} {
+		if c, ok := o.(*Character); ok && !c.Online() {
+			return false
+		}
+	}
+
+	ar := a.Room()
+	br := b.Room()
+
+	return ar != nil && br != nil && ar.ID() == br.ID()
+}
+
+// combatDamage returns the damage dealt by a single attack.
+func combatDamage(attacker, target Combatant) int {
+	attack := CombatAttribute(attacker, AttributeAttack)
+	defense := CombatAttribute(target, AttributeDefense)
+
+	dmg := attack + misc.RandomInt(attack/2+1) - defense/2
+	if dmg < 1 {
+		dmg = 1
+	}
+
+	return dmg
+}
+
+// attack has the attacker hit the target once.
+func (m *CombatManager) attack(attacker, target Combatant) {
+	dmg := combatDamage(attacker, target)
+	remaining := m.Damage(target, dmg)
+	room := target.Room()
+
+	for _, c := range room.Here().Characters(true) {
+		switch c.ID() {
+		case attacker.ID():
+			c.Player().client.ShowText(
+				fmt.Sprintf("You hit %s for %d damage.", target.FormattedName(), dmg),
+			)
+		case target.ID():
+			c.Player().client.ShowColorizedText(
+				fmt.Sprintf(
+					"%s hits you for %d damage. [%s]",
+					attacker.FormattedName(),
+					dmg,
+					m.HealthString(target),
+				),
+				ColorError,
+			)
+		default:
+			c.Player().client.ShowText(
+				fmt.Sprintf("%s hits %s.", attacker.FormattedName(), target.FormattedName()),
+			)
+		}
+	}
+
+	if remaining <= 0 {
+		m.kill(target, attacker)
+		return
+	}
+
+	if mi, ok := target.(*MobInstance); ok {
+		if c, ok := attacker.(*Character); ok && misc.Contains(mi.Parent.ScriptFuncs(), "on_attacked") {
+			QueueScriptFunc(c, mi, "on_attacked", lua.LNumber(dmg))
+		}
+	}
+}
+
+// kill handles the death of a Combatant. Mob instances drop their inventory and are removed from the game, while
+// characters are sent back to their home room at full health.
+func (m *CombatManager) kill(o Combatant, killer Combatant) {
+	room := o.Room()
+	m.Remove(o.ID())
+
+	for _, c := range room.Here().Characters(true) {
+		if c.ID() == o.ID() {
+			continue
+		}
+		c.Player().client.ShowColorizedText(
+			fmt.Sprintf("%s has been slain by %s!", o.FormattedName(), killer.FormattedName()),
+			ColorSuccess,
+		)
+	}
+
+	Armeria.log.Info("combatant died",
+		zap.String("name", o.Name()),
+		zap.String("killer", killer.Name()),
+	)
+
+	switch o := o.(type) {
+	case *MobInstance:
+		killMobInstance(o, killer, room)
+	case *Character:
+		killCharacter(o, killer)
+	}
+}
+
+// killMobInstance drops the mob's inventory into the room and removes it from the game.
+func killMobInstance(mi *MobInstance, killer Combatant, room *Room) {
+	if c, ok := killer.(*Character); ok && misc.Contains(mi.Parent.ScriptFuncs(), "on_death") {
+		// Called synchronously, since the mob is removed once the script is complete.
+		CallMobFunc(c, mi, "on_death")
+	}
+
+	// The formatted name refers to the mob's room, so it must be resolved before the mob is removed.
+	name := mi.FormattedName()
+
+	var dropped []string
+	for _, ii := range mi.Inventory().Items() {
+		if err := mi.Inventory().Transfer(ii.ID(), room.Here()); err != nil {
+			if mi.Inventory().Remove(ii.ID()) {
+				ii.Delete()
+			}
+			continue
+		}
+		dropped = append(dropped, ii.FormattedName())
+	}
+
+	room.Here().Remove(mi.ID())
+	mi.Delete()
+
+	for _, c := range room.Here().Characters(true) {
+		for _, item := range dropped {
+			c.Player().client.ShowText(fmt.Sprintf("%s dropped a %s.", name, item))
+		}
+		c.Player().client.SyncRoomObjects()
+	}
+}
+
+// killCharacter sends the character back to their home room, or the starting room when no home is set.
+func killCharacter(c *Character, killer Combatant) {
+	home := Armeria.worldManager.RoomFromLocationString(c.Attribute(AttributeHome))
+	if home == nil {
+		home = Armeria.worldManager.RoomFromLocationString(Armeria.startingRoom)
+	}
+	if home == nil {
+		Armeria.log.Error("no room available to respawn character",
+			zap.String("character", c.Name()),
+		)
+		return
+	}
+
+	c.Move(
+		home,
+		TextStyle(
+			fmt.Sprintf("You have been slain by %s! You awaken somewhere familiar.", killer.FormattedName()),
+			WithUserColor(c, ColorError),
+		),
+		TextStyle(fmt.Sprintf("The body of %s fades away.", c.FormattedName()), WithUserColor(c, ColorMovement)),
+		TextStyle(fmt.Sprintf("%s appears, looking a little worse for wear.", c.FormattedName()), WithUserColor(c, ColorMovement)),
+		"",
+	)
+
+	if c.Online() {
+		Armeria.commandManager.ProcessCommand(c.Player(), "look", false)
+	}
+}
+
+// regenerate restores some health to everything that is hurt but no longer fighting.
+func (m *CombatManager) regenerate() {
+	m.Lock()
+	defer m.Unlock()
+
+	targeted := make(map[string]bool)
+	for _, s := range m.unsafeStates {
+		if len(s.Target) > 0 {
+			targeted[s.Target] = true
+		}
+	}
+
+	for uuid, s := range m.unsafeStates {
+		if len(s.Target) > 0 || targeted[uuid] {
+			continue
+		}
+
+		o := combatantByID(uuid)
+		if o == nil {
+			delete(m.unsafeStates, uuid)
+			continue
+		}
+
+		max := CombatAttribute(o, AttributeHealth)
+		regen := max / 10
+		if regen < 1 {
+			regen = 1
+		}
+
+		s.Health = s.Health + regen
+		if s.Health >= max {
+			delete(m.unsafeStates, uuid)
+		}
+	}
+}
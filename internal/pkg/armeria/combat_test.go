@@ -0,0 +1,158 @@
+package armeria
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// textIndex returns the index of the first text shown to the client containing the substring, or -1.
+func textIndex(hc *HeadlessClient, substr string) int {
+	for i, text := range hc.Texts() {
+		if strings.Contains(text, substr) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func TestCombatKill(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	// The Merchant is carrying the apple, and goes down in a single hit.
+	merchant := Armeria.mobManager.MobByName("Merchant")
+	merchant.SetAttribute(AttributeHealth, "1")
+	mi := merchant.Instances()[0]
+	apple := Armeria.itemManager.ItemByName("Apple").Instances()[0]
+	square := Armeria.worldManager.RoomFromLocationString("Test Area,0,0,0")
+	if err := square.Here().Transfer(apple.ID(), mi.Inventory()); err != nil {
+		t.Fatalf("error giving the Merchant the apple: %s", err)
+	}
+
+	alice := playAs(t, "Alice")
+	alice.Send("/move north")
+	alice.Send("/attack Merchant")
+	expectText(t, alice, "You attack Merchant! [1/1]")
+
+	alice.Clear()
+	runTicker(t, "CombatRound")
+
+	// Combatants attack in order of their uuids, so the Merchant always swings first.
+	hit, hitBy := textIndex(alice, "You hit Merchant for"), textIndex(alice, "Merchant hits you for")
+	if hit < 0 || hitBy < 0 || hitBy > hit {
+		t.Errorf("expected the Merchant to attack before Alice, got %q", alice.Texts())
+	}
+
+	expectText(t, alice, "Merchant has been slain by Alice!")
+	expectText(t, alice, "Merchant dropped a [Apple].")
+	expectRoomObjects(t, alice, "Alice", "Apple")
+
+	if n := len(merchant.Instances()); n != 0 {
+		t.Errorf("expected the Merchant to have been removed, got %d instances", n)
+	}
+	if Armeria.combatManager.InCombat(alice.Player().Character()) {
+		t.Error("expected Alice to have stopped fighting")
+	}
+}
+
+func TestCombatHooks(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	// Alice hits for 9 to 14, so the Merchant survives the first round but not the second.
+	merchant := Armeria.mobManager.MobByName("Merchant")
+	merchant.SetAttribute(AttributeHealth, "15")
+	WriteMobScript(merchant, `
+function on_attacked(damage)
+  say("Ouch!")
+end
+
+function on_death()
+  say("Avenge me!")
+end
+`)
+
+	alice := playAs(t, "Alice")
+	alice.Send("/move north")
+	alice.Send("/attack Merchant")
+
+	alice.Clear()
+	runTicker(t, "CombatRound")
+	expectText(t, alice, "Merchant exclaims, \"Ouch!\"")
+
+	alice.Clear()
+	runTicker(t, "CombatRound")
+	expectText(t, alice, "Merchant exclaims, \"Avenge me!\"")
+	expectText(t, alice, "Merchant has been slain by Alice!")
+	if alice.SawText("Ouch!") {
+		t.Errorf("expected on_attacked() not to be called for the killing blow, got %q", alice.Texts())
+	}
+}
+
+func TestCombatRespawn(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	merchant := Armeria.mobManager.MobByName("Merchant")
+	merchant.SetAttribute(AttributeAttack, "10000")
+	market := merchant.Instances()[0].Room().LocationString()
+
+	bob := playAs(t, "Bob")
+	c := bob.Player().Character()
+	_ = c.SetAttribute(AttributeHealth, "1")
+
+	// Without a home, a character respawns in the starting room.
+	bob.Send("/move north")
+	bob.Send("/attack Merchant")
+	bob.Clear()
+	runTicker(t, "CombatRound")
+	expectText(t, bob, "You have been slain by Merchant! You awaken somewhere familiar.")
+	if loc := c.Room().LocationString(); loc != "Test Area,0,0,0" {
+		t.Errorf("expected Bob to respawn in the starting room, got %s", loc)
+	}
+	if health := Armeria.combatManager.Health(c); health != 1 {
+		t.Errorf("expected Bob to respawn at full health, got %d", health)
+	}
+	if Armeria.combatManager.InCombat(c) {
+		t.Error("expected Bob to have stopped fighting")
+	}
+
+	// With a home, they respawn there instead.
+	_ = c.SetAttribute(AttributeHome, market)
+	bob.Send("/move north")
+	bob.Send("/attack Merchant")
+	bob.Clear()
+	runTicker(t, "CombatRound")
+	expectText(t, bob, "You have been slain by Merchant! You awaken somewhere familiar.")
+	if loc := c.Room().LocationString(); loc != market {
+		t.Errorf("expected Bob to respawn at home in %s, got %s", market, loc)
+	}
+}
+
+func TestCombatFlee(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	c := alice.Player().Character()
+	mi := Armeria.mobManager.MobByName("Merchant").Instances()[0]
+
+	alice.Send("/flee")
+	expectText(t, alice, "You aren't fighting anything.")
+
+	alice.Send("/move north")
+	alice.Send("/attack Merchant")
+	alice.Send("/south")
+	expectText(t, alice, "You can't walk away while fighting!")
+
+	// Fleeing can fail, so keep trying.
+	for i := 0; i < 50 && Armeria.combatManager.InCombat(c); i++ {
+		alice.Send("/flee")
+	}
+
+	expectText(t, alice, "You flee to the south!")
+	if loc := c.Room().LocationString(); loc != "Test Area,0,0,0" {
+		t.Errorf("expected Alice to have fled to the Town Square, got %s", loc)
+	}
+	if Armeria.combatManager.InCombat(c) || Armeria.combatManager.InCombat(mi) {
+		t.Error("expected the fight to be over")
+	}
+}
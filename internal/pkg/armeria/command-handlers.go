@@ -8,6 +8,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/muesli/reflow/wordwrap"
 	"go.uber.org/zap"
@@ -16,8 +17,6 @@ import (
 )
 
 func handleLoginCommand(ctx *CommandContext) {
-	var c *Character
-
 	if len(ctx.Args) == 1 {
 		// token auth
 		sections := strings.Split(ctx.Args["token"], ":")
@@ -27,50 +26,159 @@ func handleLoginCommand(ctx *CommandContext) {
 			return
 		}
 
-		c = Armeria.characterManager.CharacterByName(sections[0])
+		c := Armeria.characterManager.CharacterByName(sections[0])
 		if c == nil {
 			ctx.Player.client.ShowText("Character not found.")
 			return
 		}
 
-		if c.PasswordHash() != sections[1] {
-			ctx.Player.client.ShowColorizedText("Invalid token for that character.", ColorError)
+		s := Armeria.sessionManager.SessionByToken(sections[1])
+		a := c.Account()
+		if s == nil || a == nil || s.AccountID() != a.ID() {
+			ctx.Player.client.ShowColorizedText("Invalid or expired token for that character.", ColorError)
 			return
 		}
-	} else {
-		// basic auth
-		c = Armeria.characterManager.CharacterByName(ctx.Args["character"])
-		if c == nil {
-			ctx.Player.client.ShowText("Character not found.")
+
+		if a.Banned() {
+			ctx.Player.client.ShowColorizedText(a.BanMessage(), ColorError)
 			return
 		}
 
-		if !c.CheckPassword(ctx.Args["password"]) {
-			ctx.Player.client.ShowColorizedText("Password incorrect for that character.", ColorError)
-			return
+		ctx.Player.AttachAccount(a)
+		ctx.Player.AttachSession(s)
+
+		if err := ctx.Player.PlayCharacter(c); err != nil {
+			ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
 		}
+		return
+	}
+
+	// basic auth
+	a := Armeria.accountManager.AccountByName(ctx.Args["account"])
+	if a == nil {
+		ctx.Player.client.ShowText("Account not found.")
+		return
 	}
 
-	if c.Player() != nil {
-		ctx.Player.client.ShowColorizedText("This character is already logged in.", ColorError)
+	if !a.CheckPassword(ctx.Args["password"]) {
+		ctx.Player.client.ShowColorizedText("Password incorrect for that account.", ColorError)
 		return
 	}
 
-	if c.Room() == nil {
-		ctx.Player.client.ShowColorizedText("This character logged out of a room which no longer exists.", ColorError)
+	if a.Banned() {
+		ctx.Player.client.ShowColorizedText(a.BanMessage(), ColorError)
 		return
 	}
 
-	ctx.Player.AttachCharacter(c)
-	c.SetPlayer(ctx.Player)
+	ctx.Player.AttachAccount(a)
+
+	chars := a.Characters()
+	if len(chars) == 0 {
+		ctx.Player.client.ShowText(
+			fmt.Sprintf(
+				"You've logged in to %s, but it doesn't have any characters yet. Use %s to create one.",
+				a.FormattedName(),
+				TextStyle("/create", WithBold()),
+			),
+		)
+		return
+	}
 
-	ctx.Player.client.ShowColorizedText(fmt.Sprintf("You've entered Armeria as %s!", c.FormattedName()), ColorSuccess)
+	var names []string
+	for _, c := range chars {
+		names = append(names, TextStyle(c.Name(), WithLinkCmd("/play "+c.Name())))
+	}
 
-	c.LoggedIn()
+	ctx.Player.client.ShowText(
+		fmt.Sprintf(
+			"You've logged in to %s. Choose a character to play using %s, or %s a new one.\n\n%s",
+			a.FormattedName(),
+			TextStyle("/play [character]", WithBold()),
+			TextStyle("/create", WithBold()),
+			strings.Join(names, ", "),
+		),
+	)
+}
+
+func handlePlayCommand(ctx *CommandContext) {
+	a := ctx.Player.Account()
+	if a == nil {
+		ctx.Player.client.ShowColorizedText("You must /login to your account first.", ColorError)
+		return
+	}
+
+	if a.Banned() {
+		ctx.Player.client.ShowColorizedText(a.BanMessage(), ColorError)
+		return
+	}
+
+	c := Armeria.characterManager.CharacterByName(ctx.Args["character"])
+	if c == nil || c.AccountID() != a.ID() {
+		ctx.Player.client.ShowColorizedText("Your account doesn't have a character with that name.", ColorError)
+		return
+	}
+
+	if err := ctx.Player.PlayCharacter(c); err != nil {
+		ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
+	}
 }
 
 func handleCreateCommand(ctx *CommandContext) {
-	ctx.Player.client.ShowText("You cannot manually create new characters yet. Stay tuned.")
+	// Passwords aren't shown as they are typed, for clients that show what is typed themselves.
+	defer func() {
+		cc := ctx.Player.Creation()
+		ctx.Player.HideInput(cc != nil && cc.AwaitingPassword())
+	}()
+
+	if a := ctx.Player.Account(); a != nil && a.Banned() {
+		ctx.Player.SetCreation(nil)
+		ctx.Player.client.ShowColorizedText(a.BanMessage(), ColorError)
+		return
+	}
+
+	answer := strings.TrimSpace(ctx.Args["answer"])
+	cc := ctx.Player.Creation()
+
+	if cc == nil {
+		cc = NewCharacterCreation(ctx.Player.Account())
+		ctx.Player.SetCreation(cc)
+		ctx.Player.client.ShowText(
+			fmt.Sprintf(
+				"Welcome to character creation! You can stop at any time using %s.",
+				TextStyle("/create cancel", WithBold()),
+			),
+		)
+	}
+
+	if len(answer) == 0 {
+		ctx.Player.client.ShowText(cc.Prompt())
+		return
+	}
+
+	if strings.ToLower(answer) == "cancel" {
+		ctx.Player.SetCreation(nil)
+		ctx.Player.client.ShowText("Character creation has been cancelled.")
+		return
+	}
+
+	c, err := cc.Answer(answer)
+	if err != nil {
+		ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
+		ctx.Player.client.ShowText(cc.Prompt())
+		return
+	}
+
+	if c == nil {
+		ctx.Player.client.ShowText(cc.Prompt())
+		return
+	}
+
+	ctx.Player.SetCreation(nil)
+	ctx.Player.AttachAccount(c.Account())
+
+	if err := ctx.Player.PlayCharacter(c); err != nil {
+		ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
+	}
 }
 
 func handleLookCommand(ctx *CommandContext) {
@@ -271,7 +379,13 @@ func handleSayCommand(ctx *CommandContext) {
 		}
 	}
 
-	normalizedText, textType := TextPunctuation(ctx.Args["text"])
+	text, err := ValidatePlayerText(ctx.Character, ctx.Args["text"])
+	if err != nil {
+		ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
+		return
+	}
+
+	normalizedText, textType := TextPunctuation(text)
 
 	var verbs []string
 	switch textType {
@@ -283,7 +397,7 @@ func handleSayCommand(ctx *CommandContext) {
 		verbs = []string{"say", "says"}
 	}
 
-	normalizedText = TextCapitalization(normalizedText)
+	normalizedText = FormatPlayerText(TextCapitalization(normalizedText))
 
 	ctx.Player.client.ShowText(
 		ctx.Player.Character().Colorize(fmt.Sprintf("You %s, \"%s\"", verbs[0], normalizedText), ColorSay),
@@ -300,13 +414,17 @@ func handleSayCommand(ctx *CommandContext) {
 	}
 
 	for _, mi := range room.Here().Mobs() {
-		go CallMobFunc(
+		QueueScriptFunc(
 			ctx.Character,
 			mi,
 			"character_said",
-			lua.LString(ctx.Args["text"]),
+			lua.LString(text),
 		)
+		Armeria.questManager.Progress(ctx.Character, QuestStepTalk, mi.Name(), "")
 	}
+
+	QueueScriptFunc(ctx.Character, room, "character_said", lua.LString(text))
+	QueueScriptFunc(ctx.Character, room.ParentArea, "character_said", lua.LString(text))
 }
 
 func handleMoveCommand(ctx *CommandContext) {
@@ -333,6 +451,14 @@ func handleMoveCommand(ctx *CommandContext) {
 		return
 	}
 
+	if Armeria.combatManager.InCombat(ctx.Character) {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("You can't walk away while fighting! Use %s to escape.", TextStyle("/flee", WithLinkCmd("/flee"))),
+			ColorError,
+		)
+		return
+	}
+
 	moveAllowed, moveError := ctx.Character.MoveAllowed(newRoom)
 	if !moveAllowed {
 		ctx.Player.client.ShowColorizedText(moveError, ColorError)
@@ -362,6 +488,19 @@ func handleMoveCommand(ctx *CommandContext) {
 	}
 }
 
+// canBuildIn returns whether the Character can build within the area, telling the Player why not if they can't.
+func canBuildIn(ctx *CommandContext, a *Area) bool {
+	if a.CanBuild(ctx.Character) {
+		return true
+	}
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("You don't have permission to build in %s.", TextStyle(a.Name(), WithBold())),
+		ColorError,
+	)
+	return false
+}
+
 func handleRoomEditCommand(ctx *CommandContext) {
 	t := ctx.Args["target"]
 	a := ctx.Character.Room().ParentArea
@@ -402,6 +541,15 @@ func handleRoomSetCommand(ctx *CommandContext) {
 		ctx.Player.client.ShowColorizedText("That's not a valid room attribute.", ColorError)
 		return
 	}
+
+	if len(ctx.Args["value"]) > 0 {
+		valid := AttributeValidate(ObjectTypeRoom, attr, ctx.Args["value"])
+		if !valid.Result {
+			ctx.Player.client.ShowColorizedText(fmt.Sprintf("The attribute value could not be validated: %s.", valid), ColorError)
+			return
+		}
+	}
+
 	ta := ctx.Args["target"]
 	tr := ctx.Character.Room()
 
@@ -426,13 +574,15 @@ func handleRoomSetCommand(ctx *CommandContext) {
 		tr = ctx.Character.Room().ParentArea.RoomAt(NewCoords(x, y, z, 0))
 	}
 
-	if tr != nil {
-		tr.SetAttribute(attr, ctx.Args["value"])
-	} else {
+	if tr == nil {
 		ctx.Player.client.ShowColorizedText("The specified room does not exist.", ColorError)
 		return
+	} else if !canBuildIn(ctx, tr.ParentArea) {
+		return
 	}
 
+	_ = ctx.SetAttribute(ObjectTypeRoom, tr, attr, ctx.Args["value"])
+
 	ctx.Player.client.SyncMap()
 
 	for _, c := range ctx.Character.Room().Here().Characters(true, ctx.Character) {
@@ -466,6 +616,10 @@ func handleRoomMoveCommand(ctx *CommandContext) {
 	}
 
 	rm := ctx.Character.Room()
+	if !canBuildIn(ctx, rm.ParentArea) {
+		return
+	}
+
 	offsets := misc.DirectionOffsets(dir)
 	newCoords := &Coords{
 		UnsafeX: rm.Coords.X() + offsets["x"],
@@ -481,13 +635,15 @@ func handleRoomMoveCommand(ctx *CommandContext) {
 	}
 
 	// Move the room.
-	rm.Coords.SetFrom(newCoords)
+	move := NewRoomMoveEdit(rm, newCoords)
+	move.Apply()
+	ctx.JournalEdit(move)
 
 	// Link the rooms (if applicable).
 	oppositeRm := rm.ConnectedRoom(oppositeDir)
 	if oppositeRm != nil {
-		oppositeRm.SetAttribute(dir, rm.Coords.String())
-		rm.SetAttribute(oppositeDir, oppositeRm.Coords.String())
+		_ = ctx.SetAttribute(ObjectTypeRoom, oppositeRm, dir, rm.Coords.String())
+		_ = ctx.SetAttribute(ObjectTypeRoom, rm, oppositeDir, oppositeRm.Coords.String())
 	}
 
 	// Sync the minimap for anyone in the area.
@@ -510,6 +666,10 @@ func handleRoomCreateCommand(ctx *CommandContext) {
 		return
 	}
 
+	if !canBuildIn(ctx, ctx.Character.Room().ParentArea) {
+		return
+	}
+
 	co := ctx.Character.Room().Coords
 	x := co.X() + o["x"]
 	y := co.Y() + o["y"]
@@ -525,6 +685,7 @@ func handleRoomCreateCommand(ctx *CommandContext) {
 
 	// Match room colors.
 	rm.SetAttribute(AttributeColor, ctx.Character.Room().Attribute(AttributeColor))
+	ctx.JournalEdit(NewRoomEdit(rm, true))
 
 	for _, c := range ctx.Character.Room().ParentArea.Characters() {
 		c.Player().client.SyncMap()
@@ -542,6 +703,10 @@ func handleRoomDestroyCommand(ctx *CommandContext) {
 		return
 	}
 
+	if !canBuildIn(ctx, ctx.Character.Room().ParentArea) {
+		return
+	}
+
 	co := ctx.Character.Room().Coords
 	x := co.X() + o["x"]
 	y := co.Y() + o["y"]
@@ -559,7 +724,10 @@ func handleRoomDestroyCommand(ctx *CommandContext) {
 		return
 	}
 
+	edit := NewRoomEdit(r, false)
 	r.ParentArea.RemoveRoom(r)
+	ctx.AuditDelete(ObjectTypeRoom, r)
+	ctx.JournalEdit(edit)
 
 	for _, c := range ctx.Character.Room().ParentArea.Characters() {
 		c.Player().client.SyncMap()
@@ -599,9 +767,16 @@ func handleWhisperCommand(ctx *CommandContext) {
 		return
 	}
 
+	m, err := ValidatePlayerText(ctx.Character, m)
+	if err != nil {
+		ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
+		return
+	}
+
 	c.SetTempAttribute(TempAttributeReplyTo, ctx.Character.Name())
 
 	normalizedText, _ := TextPunctuation(m)
+	normalizedText = FormatPlayerText(normalizedText)
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("You whisper to %s, \"%s\"", c.FormattedNameWithTitle(), normalizedText),
@@ -703,18 +878,442 @@ func handleCharacterListCommand(ctx *CommandContext) {
 
 func handleCharacterCreateCommand(ctx *CommandContext) {
 	charName := ctx.Args["character"]
-	charPass := ctx.Args["password"]
+	accountName := ctx.Args["account"]
 
 	if char := Armeria.characterManager.CharacterByName(charName); char != nil {
 		ctx.Player.client.ShowColorizedText("A character with that name already exists.", ColorError)
 		return
 	}
 
-	Armeria.characterManager.CreateCharacter(charName, charPass)
+	a := Armeria.accountManager.AccountByName(accountName)
+	if a == nil {
+		ctx.Player.client.ShowColorizedText("That account doesn't exist.", ColorError)
+		return
+	}
+
+	c, err := Armeria.characterManager.CreateCharacter(a, charName)
+	if err != nil {
+		ctx.Player.client.ShowColorizedText("A character with that name already exists.", ColorError)
+		return
+	}
+	Armeria.characterManager.SaveCharacter(c)
 
 	ctx.Player.client.ShowColorizedText("The character has been created!", ColorSuccess)
 }
 
+func handleAccountListCommand(ctx *CommandContext) {
+	f := ctx.Args["filter"]
+
+	rows := []string{TableRow(
+		TableCell{content: "Account", header: true},
+		TableCell{content: "Characters", header: true},
+		TableCell{content: "Permissions", header: true},
+		TableCell{content: "Banned", header: true},
+	)}
+
+	for _, a := range Armeria.accountManager.Accounts() {
+		if len(f) == 0 || strings.Contains(strings.ToLower(a.Name()), strings.ToLower(f)) {
+			var chars []string
+			for _, c := range a.Characters() {
+				chars = append(chars, c.Name())
+			}
+
+			rows = append(rows, TableRow(
+				TableCell{content: a.Name()},
+				TableCell{content: strings.Join(chars, ", ")},
+				TableCell{content: strings.Join(a.Permissions(), " ")},
+				TableCell{content: misc.BoolToWords(a.Banned(), "Yes", "No")},
+			))
+		}
+	}
+
+	if len(f) > 0 && len(rows) == 1 {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("There are no accounts matching \"%s\".", f),
+			ColorError,
+		)
+		return
+	}
+
+	ctx.Player.client.ShowText(TextTable(rows...))
+}
+
+func handleAccountBanCommand(ctx *CommandContext) {
+	a := Armeria.accountManager.AccountByName(ctx.Args["account"])
+	if a == nil {
+		ctx.Player.client.ShowColorizedText("That account doesn't exist.", ColorError)
+		return
+	}
+
+	if a.ID() == ctx.Character.AccountID() {
+		ctx.Player.client.ShowColorizedText("You cannot ban your own account.", ColorError)
+		return
+	}
+
+	a.SetBanned(true, ctx.Args["reason"])
+	Armeria.accountManager.SaveAccount(a)
+
+	// Players logged in to the account are disconnected, even if they haven't chosen a character yet.
+	for _, p := range Armeria.playerManager.PlayersWithAccount(a) {
+		Armeria.playerManager.DisconnectPlayer(p)
+	}
+
+	ctx.Player.client.ShowColorizedText(fmt.Sprintf("%s has been banned.", a.FormattedName()), ColorSuccess)
+}
+
+func handleAccountUnbanCommand(ctx *CommandContext) {
+	a := Armeria.accountManager.AccountByName(ctx.Args["account"])
+	if a == nil {
+		ctx.Player.client.ShowColorizedText("That account doesn't exist.", ColorError)
+		return
+	}
+
+	a.SetBanned(false, "")
+	Armeria.accountManager.SaveAccount(a)
+
+	ctx.Player.client.ShowColorizedText(fmt.Sprintf("%s is no longer banned.", a.FormattedName()), ColorSuccess)
+}
+
+func handleAccountGrantCommand(ctx *CommandContext) {
+	perm := strings.ToUpper(ctx.Args["permission"])
+	if !misc.Contains(ValidPermissions(), perm) {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("That permission doesn't exist. Valid permissions are: %s.", strings.Join(ValidPermissions(), ", ")),
+			ColorError,
+		)
+		return
+	}
+
+	a := Armeria.accountManager.AccountByName(ctx.Args["account"])
+	if a == nil {
+		ctx.Player.client.ShowColorizedText("That account doesn't exist.", ColorError)
+		return
+	}
+
+	a.GrantPermission(perm)
+	Armeria.accountManager.SaveAccount(a)
+
+	for _, c := range a.Characters() {
+		if p := c.Player(); p != nil {
+			p.client.SyncPermissions()
+			p.client.SyncCommands()
+		}
+	}
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("%s has been granted %s.", a.FormattedName(), TextStyle(perm, WithBold())),
+		ColorSuccess,
+	)
+}
+
+func handleAccountRevokeCommand(ctx *CommandContext) {
+	perm := strings.ToUpper(ctx.Args["permission"])
+	if !misc.Contains(ValidPermissions(), perm) {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("That permission doesn't exist. Valid permissions are: %s.", strings.Join(ValidPermissions(), ", ")),
+			ColorError,
+		)
+		return
+	}
+
+	a := Armeria.accountManager.AccountByName(ctx.Args["account"])
+	if a == nil {
+		ctx.Player.client.ShowColorizedText("That account doesn't exist.", ColorError)
+		return
+	}
+
+	if !a.HasPermission(perm) {
+		ctx.Player.client.ShowColorizedText("That account hasn't been granted that permission.", ColorError)
+		return
+	}
+
+	a.RevokePermission(perm)
+	Armeria.accountManager.SaveAccount(a)
+
+	for _, c := range a.Characters() {
+		if p := c.Player(); p != nil {
+			p.client.SyncPermissions()
+			p.client.SyncCommands()
+		}
+	}
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("%s has been revoked from %s.", TextStyle(perm, WithBold()), a.FormattedName()),
+		ColorSuccess,
+	)
+}
+
+func handleRoleListCommand(ctx *CommandContext) {
+	if len(ctx.Args["character"]) > 0 {
+		c := Armeria.characterManager.CharacterByName(ctx.Args["character"])
+		if c == nil {
+			ctx.Player.client.ShowColorizedText("That character doesn't exist.", ColorError)
+			return
+		}
+
+		roles := c.Roles()
+		if len(roles) == 0 {
+			roles = []string{"none"}
+		}
+
+		perms := c.Permissions()
+		if len(perms) == 0 {
+			perms = []string{"none"}
+		}
+
+		ctx.Player.client.ShowText(
+			fmt.Sprintf(
+				"%s has been granted: %s.\nPermissions: %s.",
+				c.FormattedName(),
+				strings.Join(roles, ", "),
+				strings.Join(perms, " "),
+			),
+		)
+		return
+	}
+
+	rows := []string{TableRow(
+		TableCell{content: "Role", header: true},
+		TableCell{content: "Inherits", header: true},
+		TableCell{content: "Permissions", header: true},
+		TableCell{content: "Description", header: true},
+	)}
+
+	for _, r := range Armeria.roleManager.Roles() {
+		rows = append(rows, TableRow(
+			TableCell{content: r.Name()},
+			TableCell{content: strings.Join(r.Inherits(), ", ")},
+			TableCell{content: strings.Join(r.Permissions(), " ")},
+			TableCell{content: r.Description()},
+		))
+	}
+
+	ctx.Player.client.ShowText(TextTable(rows...))
+}
+
+func handleRoleGrantCommand(ctx *CommandContext) {
+	c := Armeria.characterManager.CharacterByName(ctx.Args["character"])
+	if c == nil {
+		ctx.Player.client.ShowColorizedText("That character doesn't exist.", ColorError)
+		return
+	}
+
+	r := Armeria.roleManager.RoleByName(ctx.Args["role"])
+	if r == nil {
+		ctx.Player.client.ShowColorizedText("That role doesn't exist.", ColorError)
+		return
+	}
+
+	if c.HasRole(r.Name()) {
+		ctx.Player.client.ShowColorizedText("That character already has that role.", ColorError)
+		return
+	}
+
+	c.GrantRole(r.Name())
+	Armeria.characterManager.SaveCharacter(c)
+
+	if p := c.Player(); p != nil {
+		p.client.SyncPermissions()
+		p.client.SyncCommands()
+	}
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("%s has been granted the %s role.", c.FormattedName(), TextStyle(r.Name(), WithBold())),
+		ColorSuccess,
+	)
+}
+
+func handleRoleRevokeCommand(ctx *CommandContext) {
+	c := Armeria.characterManager.CharacterByName(ctx.Args["character"])
+	if c == nil {
+		ctx.Player.client.ShowColorizedText("That character doesn't exist.", ColorError)
+		return
+	}
+
+	if !c.HasRole(ctx.Args["role"]) {
+		ctx.Player.client.ShowColorizedText("That character hasn't been granted that role.", ColorError)
+		return
+	}
+
+	c.RevokeRole(ctx.Args["role"])
+	Armeria.characterManager.SaveCharacter(c)
+
+	if p := c.Player(); p != nil {
+		p.client.SyncPermissions()
+		p.client.SyncCommands()
+	}
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("The %s role has been revoked from %s.", TextStyle(ctx.Args["role"], WithBold()), c.FormattedName()),
+		ColorSuccess,
+	)
+}
+
+func handleAuditCommand(ctx *CommandContext) {
+	f, err := ParseAuditFilter(ctx.Args["filters"], time.Now())
+	if err != nil {
+		ctx.Player.client.ShowColorizedText(fmt.Sprintf("The filter could not be understood (%s).", err), ColorError)
+		return
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultAuditQueryLimit
+	}
+
+	entries, err := Armeria.auditLog.Query(f)
+	if err != nil {
+		Armeria.log.Error("error querying audit log",
+			zap.Error(err),
+		)
+		ctx.Player.client.ShowColorizedText("The audit log could not be read.", ColorError)
+		return
+	}
+
+	if len(entries) == 0 {
+		ctx.Player.client.ShowColorizedText("There are no entries in the audit log matching that filter.", ColorError)
+		return
+	}
+
+	rows := []string{TableRow(
+		TableCell{content: "Time", header: true},
+		TableCell{content: "Character", header: true},
+		TableCell{content: "Command", header: true},
+		TableCell{content: "Changes", header: true},
+	)}
+
+	for _, e := range entries {
+		var changes []string
+		for _, c := range e.Changes {
+			changes = append(changes, c.String())
+		}
+
+		rows = append(rows, TableRow(
+			TableCell{content: e.Time.Format("2006-01-02 15:04:05")},
+			TableCell{content: e.Actor},
+			TableCell{content: e.Command},
+			TableCell{content: strings.Join(changes, "; ")},
+		))
+	}
+
+	ctx.Player.client.ShowText(TextTable(rows...))
+}
+
+func handleSnapshotCreateCommand(ctx *CommandContext) {
+	Armeria.Save()
+
+	s, err := Armeria.snapshotManager.Create(Armeria.store, Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Error("error taking snapshot",
+			zap.Error(err),
+		)
+		ctx.Player.client.ShowColorizedText("The snapshot could not be taken.", ColorError)
+		return
+	}
+
+	Armeria.log.Info("snapshot taken",
+		zap.String("name", s.Name),
+		zap.String("character", ctx.Character.Name()),
+	)
+
+	ctx.Player.client.ShowText(fmt.Sprintf("A snapshot of the game data has been taken: %s.", TextStyle(s.Name, WithBold())))
+}
+
+func handleSnapshotListCommand(ctx *CommandContext) {
+	snapshots, err := Armeria.snapshotManager.Snapshots()
+	if err != nil {
+		Armeria.log.Error("error listing snapshots",
+			zap.Error(err),
+		)
+		ctx.Player.client.ShowColorizedText("The snapshots could not be listed.", ColorError)
+		return
+	}
+
+	if len(snapshots) == 0 {
+		ctx.Player.client.ShowColorizedText("There are no snapshots.", ColorError)
+		return
+	}
+
+	rows := []string{TableRow(
+		TableCell{content: "Name", header: true},
+		TableCell{content: "Taken", header: true},
+		TableCell{content: "Size", header: true},
+		TableCell{content: "Schema", header: true},
+	)}
+
+	for _, s := range snapshots {
+		rows = append(rows, TableRow(
+			TableCell{content: TextStyle(s.Name, WithLinkCmd("/snapshot diff "+s.Name))},
+			TableCell{content: s.Time.Format("2006-01-02 15:04:05")},
+			TableCell{content: fmt.Sprintf("%d KB", (s.Size+1023)/1024)},
+			TableCell{content: strconv.Itoa(s.SchemaVersion)},
+		))
+	}
+
+	ctx.Player.client.ShowText(TextTable(rows...))
+}
+
+func handleSnapshotDiffCommand(ctx *CommandContext) {
+	readSnapshot := func(name string) (*SnapshotContents, bool) {
+		s, err := Armeria.snapshotManager.Snapshot(name)
+		if err != nil {
+			ctx.Player.client.ShowColorizedText(fmt.Sprintf("There's no snapshot named %s.", name), ColorError)
+			return nil, false
+		}
+
+		contents, err := s.Contents()
+		if err != nil {
+			Armeria.log.Error("error reading snapshot",
+				zap.String("name", s.Name),
+				zap.Error(err),
+			)
+			ctx.Player.client.ShowColorizedText(fmt.Sprintf("The snapshot %s could not be read.", s.Name), ColorError)
+			return nil, false
+		}
+
+		return contents, true
+	}
+
+	before, ok := readSnapshot(ctx.Args["name"])
+	if !ok {
+		return
+	}
+
+	var after *SnapshotContents
+	if len(ctx.Args["other"]) > 0 {
+		if after, ok = readSnapshot(ctx.Args["other"]); !ok {
+			return
+		}
+	} else {
+		Armeria.Save()
+
+		var err error
+		if after, err = ReadSnapshotContents(Armeria.store, Armeria.dataPath); err != nil {
+			Armeria.log.Error("error reading game data",
+				zap.Error(err),
+			)
+			ctx.Player.client.ShowColorizedText("The game data could not be read.", ColorError)
+			return
+		}
+	}
+
+	var sb strings.Builder
+	if err := WriteSnapshotDiff(&sb, before, after); err != nil {
+		Armeria.log.Error("error comparing snapshots",
+			zap.Error(err),
+		)
+		ctx.Player.client.ShowColorizedText("The snapshots could not be compared.", ColorError)
+		return
+	}
+
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(lines) > MaxSnapshotDiffLines {
+		more := len(lines) - MaxSnapshotDiffLines
+		lines = append(lines[:MaxSnapshotDiffLines], fmt.Sprintf("... and %d more lines", more))
+	}
+
+	ctx.Player.client.ShowText(TextStyle(strings.Join(lines, "\n"), WithMonospace()))
+}
+
 func handleCharacterSetCommand(ctx *CommandContext) {
 	char := ctx.Args["character"]
 	attr := ctx.Args["property"]
@@ -739,7 +1338,10 @@ func handleCharacterSetCommand(ctx *CommandContext) {
 		}
 	}
 
+	before := c.Attribute(attr)
 	_ = c.SetAttribute(attr, val)
+	ctx.AuditAttribute(ObjectTypeCharacter, c, attr, before)
+	ctx.Persist(c)
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("You modified the %s property of the character %s.", TextStyle(attr, WithBold()), c.FormattedName()),
@@ -826,6 +1428,7 @@ func handleMobDeleteCommand(ctx *CommandContext) {
 	}
 
 	Armeria.mobManager.RemoveMob(mob)
+	ctx.AuditDelete(ObjectTypeMob, mob)
 
 	ctx.Player.client.ShowColorizedText("The mob has been removed from the game.", ColorSuccess)
 }
@@ -866,6 +1469,9 @@ func handleMobSetCommand(ctx *CommandContext) {
 	if m == nil {
 		ctx.Player.client.ShowColorizedText("That mob doesn't exist.", ColorError)
 		return
+	} else if !m.CanBuild(ctx.Character) {
+		ctx.Player.client.ShowColorizedText(CommonCannotChangeShared, ColorError)
+		return
 	}
 
 	if !misc.Contains(AttributeList(ObjectTypeMob), attr) {
@@ -881,7 +1487,7 @@ func handleMobSetCommand(ctx *CommandContext) {
 		}
 	}
 
-	m.SetAttribute(attr, val)
+	_ = ctx.SetAttribute(ObjectTypeMob, m, attr, val)
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("You modified the %s property of the mob %s.",
@@ -908,6 +1514,10 @@ func handleMobInstanceSetCommand(ctx *CommandContext) {
 	}
 
 	mi := o.(*MobInstance)
+	if r := mi.Room(); r != nil && !canBuildIn(ctx, r.ParentArea) {
+		return
+	}
+
 	attr := AttributeCasing(ctx.Args["property"])
 	val := ctx.Args["value"]
 
@@ -927,7 +1537,7 @@ func handleMobInstanceSetCommand(ctx *CommandContext) {
 		}
 	}
 
-	_ = mi.SetAttribute(attr, val)
+	_ = ctx.SetAttribute(ObjectTypeMobInstance, mi, attr, val)
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("You modified the %s property of the mob instace %s (%s).",
@@ -951,8 +1561,13 @@ func handleMobSpawnCommand(ctx *CommandContext) {
 		return
 	}
 
+	if !canBuildIn(ctx, ctx.Character.Room().ParentArea) {
+		return
+	}
+
 	mi := m.CreateInstance()
 	_ = ctx.Character.Room().Here().Add(mi.ID())
+	ctx.JournalEdit(NewInstanceEdit(mi, ctx.Character.Room(), true))
 
 	for _, c := range ctx.Character.Room().Here().Characters(true) {
 		c.Player().client.ShowText(
@@ -995,6 +1610,10 @@ func handleWipeCommand(ctx *CommandContext) {
 	filter := ctx.Args["filter"]
 	matches := 0
 
+	if !canBuildIn(ctx, ctx.Character.Room().ParentArea) {
+		return
+	}
+
 	for _, o := range ctx.Character.Room().Here().All() {
 		obj := o.(ContainerObject)
 
@@ -1009,6 +1628,8 @@ func handleWipeCommand(ctx *CommandContext) {
 			ctx.Character.Room().Here().Remove(obj.ID())
 			if m != nil {
 				m.DeleteInstance(obj.(*MobInstance))
+				ctx.AuditDelete(ObjectTypeMobInstance, obj.(*MobInstance))
+				ctx.JournalEdit(NewInstanceEdit(obj, ctx.Character.Room(), false))
 				matches = matches + 1
 			}
 		case ContainerObjectTypeItem:
@@ -1016,6 +1637,8 @@ func handleWipeCommand(ctx *CommandContext) {
 			ctx.Character.Room().Here().Remove(obj.ID())
 			if i != nil {
 				i.DeleteInstance(obj.(*ItemInstance))
+				ctx.AuditDelete(ObjectTypeItemInstance, obj.(*ItemInstance))
+				ctx.JournalEdit(NewInstanceEdit(obj, ctx.Character.Room(), false))
 				matches = matches + 1
 			}
 		}
@@ -1037,6 +1660,64 @@ func handleWipeCommand(ctx *CommandContext) {
 	ctx.Player.client.SyncRoomObjects()
 }
 
+func handleUndoCommand(ctx *CommandContext) {
+	entry, err := ctx.Character.EditJournal().Undo(ctx.Character)
+	if err == ErrNothingToUndo {
+		ctx.Player.client.ShowColorizedText("You haven't changed anything that can be undone.", ColorError)
+		return
+	} else if err != nil {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("%s couldn't be undone, since %s.", TextStyle(entry.Command, WithBold()), err),
+			ColorError,
+		)
+		return
+	}
+
+	ctx.Persist(entry.Objects()...)
+	syncEditedAreas(ctx, entry)
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("You undid %s.", TextStyle(entry.Command, WithBold())),
+		ColorSuccess,
+	)
+}
+
+func handleRedoCommand(ctx *CommandContext) {
+	entry, err := ctx.Character.EditJournal().Redo(ctx.Character)
+	if err == ErrNothingToRedo {
+		ctx.Player.client.ShowColorizedText("You haven't undone anything that can be redone.", ColorError)
+		return
+	} else if err != nil {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("%s couldn't be redone, since %s.", TextStyle(entry.Command, WithBold()), err),
+			ColorError,
+		)
+		return
+	}
+
+	ctx.Persist(entry.Objects()...)
+	syncEditedAreas(ctx, entry)
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("You redid %s.", TextStyle(entry.Command, WithBold())),
+		ColorSuccess,
+	)
+}
+
+// syncEditedAreas refreshes the map and the room for everyone within the areas an undone or redone entry changed.
+func syncEditedAreas(ctx *CommandContext, entry *EditJournalEntry) {
+	for _, a := range entry.Areas() {
+		for _, c := range a.Characters(ctx.Character) {
+			c.Player().client.SyncMap()
+			c.Player().client.SyncRoomTitle()
+			c.Player().client.SyncRoomObjects()
+		}
+	}
+
+	ctx.Player.client.SyncMap()
+	ctx.Player.client.SyncRoomTitle()
+	ctx.Player.client.SyncRoomObjects()
+	ctx.Player.client.SyncInventory()
+}
+
 func handleItemCreateCommand(ctx *CommandContext) {
 	n := ctx.Args["name"]
 
@@ -1069,6 +1750,7 @@ func handleItemDeleteCommand(ctx *CommandContext) {
 	}
 
 	Armeria.itemManager.RemoveItem(item)
+	ctx.AuditDelete(ObjectTypeItem, item)
 
 	ctx.Player.client.ShowColorizedText("The item has been removed from the game.", ColorSuccess)
 }
@@ -1122,8 +1804,13 @@ func handleItemSpawnCommand(ctx *CommandContext) {
 		return
 	}
 
+	if !canBuildIn(ctx, ctx.Character.Room().ParentArea) {
+		return
+	}
+
 	ii := i.CreateInstance()
 	_ = ctx.Character.Room().Here().Add(ii.ID())
+	ctx.JournalEdit(NewInstanceEdit(ii, ctx.Character.Room(), true))
 
 	for _, c := range ctx.Character.Room().Here().Characters(true) {
 		c.Player().client.ShowText(
@@ -1167,6 +1854,9 @@ func handleItemSetCommand(ctx *CommandContext) {
 	if i == nil {
 		ctx.Player.client.ShowColorizedText("That item doesn't exist.", ColorError)
 		return
+	} else if !i.CanBuild(ctx.Character) {
+		ctx.Player.client.ShowColorizedText(CommonCannotChangeShared, ColorError)
+		return
 	}
 
 	if !misc.Contains(AttributeList(ObjectTypeItem), attr) {
@@ -1182,7 +1872,7 @@ func handleItemSetCommand(ctx *CommandContext) {
 		}
 	}
 
-	i.SetAttribute(attr, val)
+	_ = ctx.SetAttribute(ObjectTypeItem, i, attr, val)
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("You modified the %s property of the item %s.",
@@ -1213,6 +1903,10 @@ func handleItemInstanceSetCommand(ctx *CommandContext) {
 	}
 
 	ii := o.(*ItemInstance)
+	if a := ii.Area(); a != nil && !canBuildIn(ctx, a) {
+		return
+	}
+
 	attr := AttributeCasing(ctx.Args["property"])
 	val := ctx.Args["value"]
 
@@ -1232,7 +1926,7 @@ func handleItemInstanceSetCommand(ctx *CommandContext) {
 		}
 	}
 
-	_ = ii.SetAttribute(attr, val)
+	_ = ctx.SetAttribute(ObjectTypeItemInstance, ii, attr, val)
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("You modified the %s property of the item instace %s (%s).",
@@ -1332,6 +2026,8 @@ func handleAreaCreateCommand(ctx *CommandContext) {
 	}
 
 	a := Armeria.worldManager.CreateArea(n)
+	a.SetOwner(ctx.Character.ID())
+	ctx.Persist(a)
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("An area named %s has been created!", TextStyle(a.Name(), WithBold())),
@@ -1345,13 +2041,22 @@ func handleAreaListCommand(ctx *CommandContext) {
 	rows := []string{TableRow(
 		TableCell{content: "Area", header: true},
 		TableCell{content: "Rooms", header: true},
+		TableCell{content: "Owner", header: true},
+		TableCell{content: "Builders", header: true},
 	)}
 
 	for _, a := range Armeria.worldManager.Areas() {
 		if len(f) == 0 || strings.Contains(strings.ToLower(a.Name()), strings.ToLower(f)) {
+			var builders []string
+			for _, b := range a.Builders() {
+				builders = append(builders, areaBuilderName(b))
+			}
+
 			rows = append(rows, TableRow(
 				TableCell{content: a.Name()},
 				TableCell{content: fmt.Sprintf("%d rooms", len(a.Rooms()))},
+				TableCell{content: areaBuilderName(a.Owner())},
+				TableCell{content: strings.Join(builders, ", ")},
 			))
 		}
 	}
@@ -1383,10 +2088,112 @@ func handleAreaEditCommand(ctx *CommandContext) {
 	ctx.Player.client.ShowObjectEditor(a.EditorData())
 }
 
+// areaBuilderName returns the name of the Character with the UUID, for listing who can build in an area.
+func areaBuilderName(uuid string) string {
+	if len(uuid) == 0 {
+		return ""
+	} else if c := Armeria.characterManager.CharacterById(uuid); c != nil {
+		return c.Name()
+	}
+
+	return uuid
+}
+
+// areaBuilderArgs returns the Character and the Area (the Character's current area if no area was given) that
+// one of the area builder commands was run for, or nil if either doesn't exist.
+func areaBuilderArgs(ctx *CommandContext) (*Character, *Area) {
+	c := Armeria.characterManager.CharacterByName(ctx.Args["character"])
+	if c == nil {
+		ctx.Player.client.ShowColorizedText("That character doesn't exist.", ColorError)
+		return nil, nil
+	}
+
+	a := ctx.Character.Room().ParentArea
+	if len(ctx.Args["area"]) > 0 {
+		a = Armeria.worldManager.AreaByName(ctx.Args["area"])
+		if a == nil {
+			ctx.Player.client.ShowColorizedText("That area doesn't exist.", ColorError)
+			return nil, nil
+		}
+	}
+
+	return c, a
+}
+
+func handleAreaGrantCommand(ctx *CommandContext) {
+	c, a := areaBuilderArgs(ctx)
+	if a == nil {
+		return
+	}
+
+	if a.Owner() != ctx.Character.ID() && !ctx.Character.HasPermission(PermissionBuildAnywhere) {
+		ctx.Player.client.ShowColorizedText("Only the owner of an area can allow others to build in it.", ColorError)
+		return
+	}
+
+	a.AddBuilder(c.ID())
+	ctx.Persist(a)
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("%s can now build in %s.", c.FormattedName(), TextStyle(a.Name(), WithBold())),
+		ColorSuccess,
+	)
+}
+
+func handleAreaRevokeCommand(ctx *CommandContext) {
+	c, a := areaBuilderArgs(ctx)
+	if a == nil {
+		return
+	}
+
+	if a.Owner() != ctx.Character.ID() && !ctx.Character.HasPermission(PermissionBuildAnywhere) {
+		ctx.Player.client.ShowColorizedText("Only the owner of an area can stop others from building in it.", ColorError)
+		return
+	}
+
+	if !misc.Contains(a.Builders(), c.ID()) {
+		ctx.Player.client.ShowColorizedText("That character hasn't been allowed to build in that area.", ColorError)
+		return
+	}
+
+	a.RemoveBuilder(c.ID())
+	ctx.Persist(a)
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("%s can no longer build in %s.", c.FormattedName(), TextStyle(a.Name(), WithBold())),
+		ColorSuccess,
+	)
+}
+
+func handleAreaOwnerCommand(ctx *CommandContext) {
+	c, a := areaBuilderArgs(ctx)
+	if a == nil {
+		return
+	}
+
+	a.SetOwner(c.ID())
+	a.RemoveBuilder(c.ID())
+	ctx.Persist(a)
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("%s now owns %s.", c.FormattedName(), TextStyle(a.Name(), WithBold())),
+		ColorSuccess,
+	)
+}
+
 func handlePasswordCommand(ctx *CommandContext) {
 	pw := ctx.Args["password"]
-	ctx.Character.SetPassword(pw)
-	ctx.Player.client.ShowColorizedText("Your character password has been set.", ColorSuccess)
+	a := ctx.Character.Account()
+	a.SetPassword(pw)
+	Armeria.accountManager.SaveAccount(a)
+
+	revoked := Armeria.sessionManager.RevokeAccountSessions(a)
+	ctx.Player.AttachSession(nil)
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("Your account password has been set and %d session(s) have been revoked.", revoked),
+		ColorSuccess,
+	)
 }
 
 func handleTeleportCommand(ctx *CommandContext) {
@@ -1485,7 +2292,7 @@ func handleCommandsCommand(ctx *CommandContext) {
 
 	for _, cmd := range valid {
 		rows = append(rows, TableRow(
-			TableCell{content: TextStyle("/"+cmd.Name, WithBold()), styling: "padding:0px 2px"},
+			TableCell{content: TextStyle("/"+cmd.Name, WithBold())},
 			TableCell{content: cmd.Help},
 		))
 	}
@@ -1556,10 +2363,13 @@ func handleClipboardPasteCommand(ctx *CommandContext) {
 			ctx.Player.client.ShowColorizedText("That room is not valid.", ColorError)
 			return
 		}
+		if !canBuildIn(ctx, r.ParentArea) {
+			return
+		}
 		// paste room attributes
 		for _, attr := range cba {
 			attrValue := ctx.Character.TempAttribute("clipboard_attribute_" + attr)
-			r.SetAttribute(attr, attrValue)
+			_ = ctx.SetAttribute(ObjectTypeRoom, r, attr, attrValue)
 		}
 		ctx.Player.client.ShowColorizedText("Room attributes on the clipboard have been applied.", ColorSuccess)
 		ctx.Player.client.SyncMap()
@@ -1602,15 +2412,17 @@ func handleGetCommand(ctx *CommandContext) {
 		return
 	}
 
-	err := ctx.Character.Inventory().Add(item.ID())
+	err := roomObjects.Transfer(item.ID(), ctx.Character.Inventory())
 	if err == ErrContainerNoRoom {
 		ctx.Player.client.ShowColorizedText("You have no room in your inventory.", ColorError)
 		return
 	} else if err == ErrContainerDuplicate {
 		ctx.Player.client.ShowColorizedText("You already have that item instance in your inventory.", ColorError)
 		return
+	} else if err != nil {
+		ctx.Player.client.ShowColorizedText(CommonTargetNotFoundHere, ColorError)
+		return
 	}
-	roomObjects.Remove(item.ID())
 
 	ctx.Player.client.SyncRoomObjects()
 	ctx.Player.client.SyncInventory()
@@ -1626,6 +2438,8 @@ func handleGetCommand(ctx *CommandContext) {
 			fmt.Sprintf("%s picked up a %s.", ctx.Character.FormattedName(), item.FormattedName()),
 		)
 	}
+
+	QueueScriptFunc(ctx.Character, item, "on_get")
 }
 
 func handleDropCommand(ctx *CommandContext) {
@@ -1639,8 +2453,10 @@ func handleDropCommand(ctx *CommandContext) {
 
 	item := result.Object.(*ItemInstance)
 
-	ctx.Character.Inventory().Remove(item.ID())
-	_ = ctx.Character.Room().Here().Add(item.ID())
+	if err := ctx.Character.Inventory().Transfer(item.ID(), ctx.Character.Room().Here()); err != nil {
+		ctx.Player.client.ShowColorizedText(CommonItemNotFoundOnCharacter, ColorError)
+		return
+	}
 
 	ctx.Player.client.SyncRoomObjects()
 	ctx.Player.client.SyncInventory()
@@ -1655,48 +2471,127 @@ func handleDropCommand(ctx *CommandContext) {
 			fmt.Sprintf("%s dropped a %s.", ctx.Character.FormattedName(), item.FormattedName()),
 		)
 	}
+
+	QueueScriptFunc(ctx.Character, item, "on_drop")
 }
 
 func handleSwapCommand(ctx *CommandContext) {
 	source := ctx.Args["source"]
 	destination := ctx.Args["destination"]
 
-	snum, err := strconv.Atoi(source)
-	if err != nil {
-		ctx.Player.client.ShowColorizedText("You must enter a valid slot number as the source slot.", ColorError)
-		return
+	snum, err := strconv.Atoi(source)
+	if err != nil {
+		ctx.Player.client.ShowColorizedText("You must enter a valid slot number as the source slot.", ColorError)
+		return
+	}
+
+	dnum, err := strconv.Atoi(destination)
+	if err != nil {
+		ctx.Player.client.ShowColorizedText("You must enter a valid slot number as the destination slot.", ColorError)
+		return
+	}
+
+	if snum >= ctx.Character.Inventory().MaxSize() {
+		ctx.Player.client.ShowColorizedText("Source slot does not exist in your inventory.", ColorError)
+		return
+	}
+
+	if dnum >= ctx.Character.Inventory().MaxSize() {
+		ctx.Player.client.ShowColorizedText("Destination slot does not exist in your inventory.", ColorError)
+		return
+	}
+
+	ctx.Character.Inventory().SwapSlots(snum, dnum)
+
+	ctx.Player.client.SyncInventory()
+}
+
+func handleAutoLoginCommand(ctx *CommandContext) {
+	// Disabling auto-login from the device that logged in with it revokes that device's session.
+	if s := ctx.Player.Session(); s != nil {
+		Armeria.sessionManager.RevokeSession(s)
+		ctx.Player.AttachSession(nil)
+		ctx.Player.client.ToggleAutologin("")
+		ctx.Player.client.ShowText("Auto-login has been disabled and the session for this device has been revoked.")
+		return
+	}
+
+	label := ctx.Args["device"]
+	if len(label) == 0 {
+		label = "Unnamed Device"
+	}
+
+	s, token := Armeria.sessionManager.CreateSession(ctx.Character.Account(), label, SessionTTLAutologin)
+	ctx.Player.AttachSession(s)
+	ctx.Player.client.ToggleAutologin(token)
+	ctx.Player.client.ShowText(
+		fmt.Sprintf(
+			"Auto-login has been enabled for %s. It will expire on %s, or you can revoke it using %s.",
+			TextStyle(label, WithBold()),
+			TextStyle(s.Expires().Format("Mon Jan 2 2006"), WithBold()),
+			TextStyle("/sessions", WithBold()),
+		),
+	)
+}
+
+func handleSessionsListCommand(ctx *CommandContext) {
+	current := ctx.Player.Session()
+
+	rows := []string{TableRow(
+		TableCell{content: "ID", header: true},
+		TableCell{content: "Device", header: true},
+		TableCell{content: "Last Used", header: true},
+		TableCell{content: "Expires", header: true},
+	)}
+
+	for _, s := range Armeria.sessionManager.SessionsByAccount(ctx.Character.Account()) {
+		label := s.Label()
+		if s == current {
+			label = label + " " + TextStyle("(current)", WithItalics())
+		}
+
+		rows = append(rows, TableRow(
+			TableCell{content: TextStyle(s.ShortID(), WithLinkCmd("/sessions revoke "+s.ShortID()))},
+			TableCell{content: label},
+			TableCell{content: s.LastUsed().Format("Mon Jan 2 2006 15:04 MST")},
+			TableCell{content: s.Expires().Format("Mon Jan 2 2006 15:04 MST")},
+		))
 	}
 
-	dnum, err := strconv.Atoi(destination)
-	if err != nil {
-		ctx.Player.client.ShowColorizedText("You must enter a valid slot number as the destination slot.", ColorError)
+	if len(rows) == 1 {
+		ctx.Player.client.ShowText("Your account doesn't have any active sessions.")
 		return
 	}
 
-	if snum >= ctx.Character.Inventory().MaxSize() {
-		ctx.Player.client.ShowColorizedText("Source slot does not exist in your inventory.", ColorError)
+	ctx.Player.client.ShowText(TextTable(rows...))
+}
+
+func handleSessionsRevokeCommand(ctx *CommandContext) {
+	id := ctx.Args["session"]
+	a := ctx.Character.Account()
+
+	if strings.ToLower(id) == "all" {
+		revoked := Armeria.sessionManager.RevokeAccountSessions(a)
+		ctx.Player.AttachSession(nil)
+		ctx.Player.client.ShowColorizedText(fmt.Sprintf("%d session(s) have been revoked.", revoked), ColorSuccess)
 		return
 	}
 
-	if dnum >= ctx.Character.Inventory().MaxSize() {
-		ctx.Player.client.ShowColorizedText("Destination slot does not exist in your inventory.", ColorError)
+	s := Armeria.sessionManager.SessionByShortID(a, id)
+	if s == nil {
+		ctx.Player.client.ShowColorizedText("Your account doesn't have a session with that ID.", ColorError)
 		return
 	}
 
-	sourceSlot := ctx.Character.Inventory().AtSlot(snum)
-	destSlot := ctx.Character.Inventory().AtSlot(dnum)
-	if sourceSlot.Type != RegistryTypeUnknown {
-		ctx.Character.Inventory().SetSlot(sourceSlot.Object.(*ItemInstance).ID(), dnum)
-	}
-	if destSlot.Type != RegistryTypeUnknown {
-		ctx.Character.Inventory().SetSlot(destSlot.Object.(*ItemInstance).ID(), snum)
+	Armeria.sessionManager.RevokeSession(s)
+	if s == ctx.Player.Session() {
+		ctx.Player.AttachSession(nil)
 	}
 
-	ctx.Player.client.SyncInventory()
-}
-
-func handleAutoLoginCommand(ctx *CommandContext) {
-	ctx.Player.client.ToggleAutologin()
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("The session for %s has been revoked.", TextStyle(s.Label(), WithBold())),
+		ColorSuccess,
+	)
 }
 
 func handleChannelListCommand(ctx *CommandContext) {
@@ -1789,6 +2684,12 @@ func handleChannelSayCommand(ctx *CommandContext) {
 		return
 	}
 
+	sayText, err := ValidatePlayerText(ctx.Character, sayText)
+	if err != nil {
+		ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
+		return
+	}
+
 	ch.Broadcast(ctx.Character, sayText)
 }
 
@@ -1815,16 +2716,15 @@ func handleSettingsCommand(ctx *CommandContext) {
 		valid := ValidSettings()
 
 		for _, s := range valid {
-			reqPerm := SettingPermission(s)
-			if len(reqPerm) > 0 && !ctx.Character.HasPermission(reqPerm) {
+			if !SettingPermission(s).MetBy(ctx.Character) {
 				continue
 			}
 
 			rows = append(rows, TableRow(
 				TableCell{content: s},
-				TableCell{content: SettingDesc(s), styling: "padding:0px 2px"},
-				TableCell{content: ctx.Character.Setting(s), styling: "padding:0px 2px"},
-				TableCell{content: SettingDefault(s), styling: "padding:0px 2px;color:#666"},
+				TableCell{content: SettingDesc(s)},
+				TableCell{content: ctx.Character.Setting(s)},
+				TableCell{content: TextStyle(SettingDefault(s), WithColor("666"))},
 			))
 		}
 		ctx.Player.client.ShowText(TextTable(rows...))
@@ -1841,8 +2741,7 @@ func handleSettingsCommand(ctx *CommandContext) {
 	}
 
 	// Check if the character has permission to modify the setting.
-	reqPerm := SettingPermission(setting)
-	if len(reqPerm) > 0 && !ctx.Character.HasPermission(reqPerm) {
+	if !SettingPermission(setting).MetBy(ctx.Character) {
 		ctx.Player.client.ShowColorizedText(
 			fmt.Sprintf(
 				"%s is not a valid setting name.",
@@ -1945,7 +2844,10 @@ func handleGiveCommand(ctx *CommandContext) {
 	if targetResult.Type == RegistryTypeItemInstance && targetResult.Object.(*ItemInstance).Attribute(AttributeType) == ItemTypeTrashCan {
 		// Destroy the item.
 		ii := itemResult.Object.(*ItemInstance)
-		ctx.Character.Inventory().Remove(ii.ID())
+		if !ctx.Character.Inventory().Remove(ii.ID()) {
+			ctx.Player.client.ShowColorizedText(CommonItemNotFoundOnCharacter, ColorError)
+			return
+		}
 		ii.Delete()
 		ctx.Player.client.ShowColorizedText(
 			fmt.Sprintf("You put a %s into the %s. Goodbye!",
@@ -1994,27 +2896,49 @@ func handleGiveCommand(ctx *CommandContext) {
 	}
 
 	// check if the mob can handle it?
+	var discard bool
 	if targetResult.Type == RegistryTypeMobInstance {
-		if !misc.Contains(toc.ParentMobInstance().Parent.ScriptFuncs(), "received_item") {
+		mi := toc.ParentMobInstance()
+		itemName := itemResult.Object.(*ItemInstance).Name()
+		scripted := misc.Contains(mi.Parent.ScriptFuncs(), "received_item")
+		if !scripted && !Armeria.questManager.AwaitsAny(ctx.Character, QuestStepDeliver, itemName, mi.Name()) {
 			ctx.Player.client.ShowColorizedText(
 				fmt.Sprintf(
 					"%s does not want that.",
-					toc.ParentMobInstance().FormattedName(),
+					mi.FormattedName(),
 				),
 				ColorError,
 			)
 			return
 		}
+
+		// Items delivered for a quest are discarded, rather than piling up on the mob, unless its script takes them.
+		discard = !scripted
 	}
 
 	ii := itemResult.Object.(*ItemInstance)
 	tco := targetResult.Object
 
-	// remove item from source
-	ctx.Character.Inventory().Remove(ii.ID())
-
-	// add item to target
-	_ = toc.Add(ii.ID())
+	// move the item from the source to the target
+	if discard {
+		if !ctx.Character.Inventory().Remove(ii.ID()) {
+			ctx.Player.client.ShowColorizedText(CommonItemNotFoundOnCharacter, ColorError)
+			return
+		}
+		ii.Delete()
+	} else if err := ctx.Character.Inventory().Transfer(ii.ID(), toc); err == ErrContainerNoRoom {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf(
+				"%s does not have enough room to hold that!",
+				tco.FormattedName(),
+			),
+			ColorError,
+		)
+		return
+	} else if err != nil {
+		ctx.Player.client.ShowColorizedText(CommonItemNotFoundOnCharacter, ColorError)
+		return
+	}
 
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf(
@@ -2036,13 +2960,19 @@ func handleGiveCommand(ctx *CommandContext) {
 		)
 		targetResult.Object.(*Character).Player().client.SyncInventory()
 	} else if targetResult.Type == RegistryTypeMobInstance {
-		go CallMobFunc(
+		QueueScriptFunc(
 			ctx.Character,
 			targetResult.Object.(*MobInstance),
 			"received_item",
 			lua.LString(ctx.Character.ID()),
 			lua.LString(ii.ID()),
 		)
+		Armeria.questManager.Progress(
+			ctx.Character,
+			QuestStepDeliver,
+			ii.Name(),
+			targetResult.Object.(*MobInstance).Name(),
+		)
 	}
 
 	roomExceptions := []*Character{ctx.Character}
@@ -2058,10 +2988,18 @@ func handleGiveCommand(ctx *CommandContext) {
 			),
 		)
 	}
+
+	if !discard {
+		QueueScriptFunc(ctx.Character, ii, "on_give", lua.LString(tco.ID()))
+	}
 }
 
 func handleEmoteCommand(ctx *CommandContext) {
-	emotion := ctx.Args["emote"]
+	emotion, err := ValidatePlayerText(ctx.Character, ctx.Args["emote"])
+	if err != nil {
+		ctx.Player.client.ShowColorizedText(TextCapitalization(err.Error())+".", ColorError)
+		return
+	}
 
 	if emotion[len(emotion)-1:] == "." {
 		emotion = emotion[:len(emotion)-1]
@@ -2069,7 +3007,7 @@ func handleEmoteCommand(ctx *CommandContext) {
 
 	for _, c := range ctx.Character.Room().Here().Characters(true) {
 		c.Player().client.ShowText(
-			fmt.Sprintf("%s %s.", ctx.Character.FormattedName(), emotion),
+			fmt.Sprintf("%s %s.", ctx.Character.FormattedName(), FormatPlayerText(emotion)),
 		)
 	}
 }
@@ -2082,7 +3020,7 @@ func handleLedgerListCommand(ctx *CommandContext) {
 
 	for _, l := range Armeria.ledgerManager.Ledgers() {
 		rows = append(rows, TableRow(
-			TableCell{content: fmt.Sprintf("[cmd=/ledger show %[1]s]%[1]s[/cmd]", l.Name())},
+			TableCell{content: TextStyle(l.Name(), WithLinkCmd("/ledger show "+l.Name()))},
 			TableCell{content: fmt.Sprintf("%d items", len(l.Entries()))},
 		))
 	}
@@ -2125,7 +3063,10 @@ func handleLedgerRenameCommand(ctx *CommandContext) {
 		return
 	}
 
+	// The ledger is stored by its name, so the record under the old name is replaced.
+	removeRecord(CollectionLedgers, ledger.Name())
 	ledger.SetName(newName)
+	ctx.Persist(ledger)
 
 	ctx.Player.client.ShowColorizedText("The ledger has been renamed.", ColorSuccess)
 }
@@ -2156,6 +3097,7 @@ func handleLedgerAddCommand(ctx *CommandContext) {
 		BuyPrice:  0.00,
 		SellPrice: 0.00,
 	})
+	ctx.Persist(ledger)
 
 	ctx.Player.client.ShowColorizedText("Entry has been added to the ledger.", ColorSuccess)
 }
@@ -2177,6 +3119,7 @@ func handleLedgerRemoveCommand(ctx *CommandContext) {
 	}
 
 	ledger.RemoveEntry(entry)
+	ctx.Persist(ledger)
 
 	ctx.Player.client.ShowColorizedText("Entry has been removed from the ledger.", ColorSuccess)
 }
@@ -2233,7 +3176,7 @@ func handleLedgerSearchCommand(ctx *CommandContext) {
 	for ledger, matches := range matches {
 		for _, item := range matches {
 			rows = append(rows, TableRow(
-				TableCell{content: fmt.Sprintf("[cmd=/ledger show %[1]s]%[1]s[/cmd]", ledger)},
+				TableCell{content: TextStyle(ledger, WithLinkCmd("/ledger show "+ledger))},
 				TableCell{content: item},
 			))
 		}
@@ -2276,10 +3219,247 @@ func handleLedgerSetCommand(ctx *CommandContext) {
 	} else {
 		entry.SellPrice = amount
 	}
+	ctx.Persist(ledger)
 
 	ctx.Player.client.ShowColorizedText("The price has been set on the ledger.", ColorSuccess)
 }
 
+func handleQuestListCommand(ctx *CommandContext) {
+	rows := []string{TableRow(
+		TableCell{content: "Quest", header: true},
+		TableCell{content: "Progress", header: true},
+	)}
+
+	for _, name := range ctx.Character.Quests() {
+		q := Armeria.questManager.QuestByName(name)
+		p, _ := ctx.Character.QuestProgress(name)
+		if q == nil {
+			continue
+		}
+
+		progress := fmt.Sprintf("Step %d of %d", p.Step+1, len(q.Steps()))
+		if p.Completed {
+			progress = "Completed"
+		}
+
+		rows = append(rows, TableRow(
+			TableCell{content: TextStyle(q.Name(), WithLinkCmd("/quest show "+q.Name()))},
+			TableCell{content: progress},
+		))
+	}
+
+	if len(rows) == 1 {
+		ctx.Player.client.ShowText("You haven't started any quests.")
+	} else {
+		ctx.Player.client.ShowText(TextTable(rows...))
+	}
+
+	if !ctx.Character.HasPermission(PermissionBuild) {
+		return
+	}
+
+	rows = []string{TableRow(
+		TableCell{content: "All Quests", header: true},
+		TableCell{content: "Steps", header: true},
+		TableCell{content: "In Progress", header: true},
+	)}
+
+	for _, q := range Armeria.questManager.Quests() {
+		rows = append(rows, TableRow(
+			TableCell{content: TextStyle(q.Name(), WithLinkCmd("/quest show "+q.Name()))},
+			TableCell{content: strconv.Itoa(len(q.Steps()))},
+			TableCell{content: fmt.Sprintf("%d characters", len(Armeria.questManager.CharactersOnQuest(q)))},
+		))
+	}
+
+	ctx.Player.client.ShowText(TextTable(rows...))
+}
+
+func handleQuestShowCommand(ctx *CommandContext) {
+	builder := ctx.Character.HasPermission(PermissionBuild)
+
+	q := Armeria.questManager.QuestByName(ctx.Args["quest_name"])
+	p, started := QuestProgress{}, false
+	if q != nil {
+		p, started = ctx.Character.QuestProgress(q.Name())
+	}
+
+	if q == nil || (!started && !builder) {
+		ctx.Player.client.ShowColorizedText("You haven't started a quest by that name.", ColorError)
+		return
+	}
+
+	output := []string{
+		TextStyle(q.Name(), WithBold()),
+		q.Description(),
+	}
+
+	rows := []string{TableRow(
+		TableCell{content: "Step", header: true},
+		TableCell{content: "Objective", header: true},
+		TableCell{content: "Status", header: true},
+	)}
+
+	for i, step := range q.Steps() {
+		status := ""
+		if started && (p.Completed || i < p.Step) {
+			status = "Done"
+		} else if started && i == p.Step {
+			status = "Current"
+		} else if !builder {
+			break
+		}
+
+		objective := step.Description
+		if builder {
+			objective = fmt.Sprintf("%s (%s)", step.Description, step.String())
+		}
+
+		rows = append(rows, TableRow(
+			TableCell{content: strconv.Itoa(i + 1)},
+			TableCell{content: objective},
+			TableCell{content: status},
+		))
+	}
+
+	output = append(output, TextTable(rows...))
+	output = append(output, fmt.Sprintf("%s %s", TextStyle("Reward:", WithBold()), q.RewardString()))
+
+	if builder {
+		var names []string
+		for _, c := range Armeria.questManager.CharactersOnQuest(q) {
+			cp, _ := c.QuestProgress(q.Name())
+			names = append(names, fmt.Sprintf("%s (step %d)", c.Name(), cp.Step+1))
+		}
+		if len(names) == 0 {
+			names = []string{"nobody"}
+		}
+		output = append(output, fmt.Sprintf("%s %s", TextStyle("In progress:", WithBold()), strings.Join(names, ", ")))
+	}
+
+	ctx.Player.client.ShowText(strings.Join(output, "\n"))
+}
+
+func handleQuestAbandonCommand(ctx *CommandContext) {
+	q := Armeria.questManager.QuestByName(ctx.Args["quest_name"])
+	if q == nil || Armeria.questManager.Abandon(ctx.Character, q) != nil {
+		ctx.Player.client.ShowColorizedText("You aren't on a quest by that name.", ColorError)
+		return
+	}
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("You have abandoned the quest %s.", TextStyle(q.Name(), WithBold())),
+		ColorSuccess,
+	)
+}
+
+func handleQuestCreateCommand(ctx *CommandContext) {
+	name := ctx.Args["name"]
+
+	if strings.Contains(name, " ") {
+		ctx.Player.client.ShowColorizedText("The quest name cannot contain a space.", ColorError)
+		return
+	}
+
+	if Armeria.questManager.QuestByName(name) != nil {
+		ctx.Player.client.ShowColorizedText("A quest already exists with that name.", ColorError)
+		return
+	}
+
+	Armeria.questManager.CreateQuest(name)
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf(
+			"The quest has been created. Use %s to add steps.",
+			TextStyle("/quest edit "+name, WithLinkCmd("/quest edit "+name)),
+		),
+		ColorSuccess,
+	)
+}
+
+func handleQuestEditCommand(ctx *CommandContext) {
+	property := strings.ToLower(ctx.Args["property"])
+	value := ctx.Args["value"]
+
+	q := Armeria.questManager.QuestByName(ctx.Args["quest_name"])
+	if q == nil {
+		ctx.Player.client.ShowColorizedText("A quest by that name doesn't exist.", ColorError)
+		return
+	}
+
+	switch property {
+	case "description":
+		q.SetDescription(value)
+	case "money":
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount < 0 {
+			ctx.Player.client.ShowColorizedText("You must set a numerical amount of money.", ColorError)
+			return
+		}
+		q.SetRewardMoney(amount)
+	case "items":
+		items := []string{}
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if len(name) == 0 {
+				continue
+			}
+			i := Armeria.itemManager.ItemByName(name)
+			if i == nil {
+				ctx.Player.client.ShowColorizedText(fmt.Sprintf("An item named %s doesn't exist.", name), ColorError)
+				return
+			}
+			items = append(items, i.Name())
+		}
+		q.SetRewardItems(items)
+	case "add-step":
+		step, errorMsg := ParseQuestStep(ctx.Character, value)
+		if step == nil {
+			ctx.Player.client.ShowColorizedText(errorMsg, ColorError)
+			return
+		}
+		q.AddStep(step)
+		ctx.Persist(q)
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("You added step %d to the quest %s.", len(q.Steps()), q.Name()),
+			ColorSuccess,
+		)
+		return
+	case "remove-step":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			ctx.Player.client.ShowColorizedText("That step doesn't exist on the quest.", ColorError)
+			return
+		}
+		moved, ok := Armeria.questManager.RemoveStep(q, n-1)
+		if !ok {
+			ctx.Player.client.ShowColorizedText("That step doesn't exist on the quest.", ColorError)
+			return
+		}
+		ctx.Persist(q)
+		for _, c := range moved {
+			ctx.Persist(c)
+		}
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("You removed step %d from the quest %s.", n, q.Name()),
+			ColorSuccess,
+		)
+		return
+	default:
+		ctx.Player.client.ShowColorizedText(
+			"You can edit the description, money, items, add-step or remove-step of a quest.",
+			ColorError,
+		)
+		return
+	}
+	ctx.Persist(q)
+
+	ctx.Player.client.ShowColorizedText(
+		fmt.Sprintf("You modified the %s property of the quest %s.", TextStyle(property, WithBold()), q.Name()),
+		ColorSuccess,
+	)
+}
+
 func handleBuyCommand(ctx *CommandContext) {
 	mobName := ctx.Args["npc"]
 	itemName := ctx.Args["item"]
@@ -2324,10 +3504,8 @@ func handleBuyCommand(ctx *CommandContext) {
 	}
 
 	// Transfer the item
-	mobInstance.Inventory().Remove(item.ID())
-	if err := ctx.Character.Inventory().Add(item.ID()); err != nil {
-		// Something went wrong, let's destroy the item instance and return the money
-		item.Parent.DeleteInstance(item)
+	if err := mobInstance.Inventory().Transfer(item.ID(), ctx.Character.Inventory()); err != nil {
+		// Something went wrong, so the mob keeps the item and the money is returned
 		ctx.Character.AddMoney(itemLedger.BuyPrice)
 		ctx.Player.client.ShowColorizedText("Something went wrong with the transaction.", ColorError)
 		return
@@ -2393,13 +3571,16 @@ func handleSellCommand(ctx *CommandContext) {
 		return
 	}
 
+	// Destroy the item, unless it has already left the character's inventory
+	if !ctx.Character.Inventory().Remove(item.ID()) {
+		ctx.Player.client.ShowColorizedText(CommonItemNotFoundOnCharacter, ColorError)
+		return
+	}
+	item.Parent.DeleteInstance(item)
+
 	// Add money to the character
 	ctx.Character.AddMoney(itemLedger.SellPrice)
 
-	// Destroy the item
-	ctx.Character.Inventory().Remove(item.ID())
-	item.Parent.DeleteInstance(item)
-
 	ctx.Player.client.SyncMoney()
 	ctx.Player.client.SyncInventory()
 	ctx.Player.client.PlaySFX(sfx.SellBuyItem)
@@ -2429,19 +3610,28 @@ func handleDestroyCommand(ctx *CommandContext) {
 
 	if result := ctx.Character.Inventory().GetByAny(searchString); result.Type == RegistryTypeItemInstance {
 		item := result.Object.(*ItemInstance)
-		ctx.Character.Inventory().Remove(item.ID())
-		item.Delete()
+		if ctx.Character.Inventory().Remove(item.ID()) {
+			item.Delete()
+		}
 		ctx.Player.client.ShowColorizedText("The item has been destroyed!", ColorSuccess)
 		ctx.Player.client.SyncInventory()
 		return
+	} else if !canBuildIn(ctx, ctx.Character.Room().ParentArea) {
+		return
 	} else if result := ctx.Character.Room().Here().GetByAny(searchString); result.Type == RegistryTypeItemInstance {
 		item := result.Object.(*ItemInstance)
-		ctx.Character.Room().Here().Remove(item.ID())
-		item.Delete()
+		if ctx.Character.Room().Here().Remove(item.ID()) {
+			item.Delete()
+			ctx.AuditDelete(ObjectTypeItemInstance, item)
+			ctx.JournalEdit(NewInstanceEdit(item, ctx.Character.Room(), false))
+		}
 	} else if result := ctx.Character.Room().Here().GetByAny(searchString); result.Type == RegistryTypeMobInstance {
 		mob := result.Object.(*MobInstance)
-		ctx.Character.Room().Here().Remove(mob.ID())
-		mob.Delete()
+		if ctx.Character.Room().Here().Remove(mob.ID()) {
+			mob.Delete()
+			ctx.AuditDelete(ObjectTypeMobInstance, mob)
+			ctx.JournalEdit(NewInstanceEdit(mob, ctx.Character.Room(), false))
+		}
 	} else {
 		ctx.Player.client.ShowColorizedText("There were no matches in the room or your inventory.", ColorError)
 		return
@@ -2462,7 +3652,7 @@ func handleTickersCommand(ctx *CommandContext) {
 		TableCell{content: "Iterations", header: true},
 	)}
 
-	for _, t := range Armeria.tickManager.Tickers {
+	for _, t := range Armeria.tickManager.Tickers() {
 		rows = append(rows, TableRow(
 			TableCell{content: t.Name},
 			TableCell{content: t.Interval.String()},
@@ -2473,6 +3663,14 @@ func handleTickersCommand(ctx *CommandContext) {
 	}
 
 	ctx.Player.client.ShowText(TextTable(rows...))
+
+	if Armeria.eventLoop != nil {
+		ctx.Player.client.ShowText(fmt.Sprintf(
+			"The event loop has processed %d events, with %d waiting.",
+			Armeria.eventLoop.Processed(),
+			Armeria.eventLoop.Pending(),
+		))
+	}
 }
 
 func handleSelectCommand(ctx *CommandContext) {
@@ -2493,7 +3691,7 @@ func handleSelectCommand(ctx *CommandContext) {
 
 	Armeria.commandManager.ProcessCommand(ctx.Player, fmt.Sprintf("say %s", mobInst.ConvoText(optionId)), false)
 
-	go CallMobFunc(
+	QueueScriptFunc(
 		ctx.Character,
 		mobInst,
 		"conversation_select",
@@ -2511,13 +3709,76 @@ func handleInteractCommand(ctx *CommandContext) {
 	}
 	mobInst := result.Object.(*MobInstance)
 
-	go CallMobFunc(
+	QueueScriptFunc(
 		ctx.Character,
 		mobInst,
 		"interact",
 	)
 }
 
+func handleAttackCommand(ctx *CommandContext) {
+	target := ctx.Args["target"]
+
+	result := ctx.Character.Room().Here().GetByAny(target)
+	if result.Type == RegistryTypeCharacter {
+		ctx.Player.client.ShowColorizedText("You can only attack mobs.", ColorError)
+		return
+	} else if result.Type != RegistryTypeMobInstance {
+		ctx.Player.client.ShowColorizedText(CommonTargetNotFoundHere, ColorError)
+		return
+	}
+	mobInst := result.Object.(*MobInstance)
+
+	if current := Armeria.combatManager.Target(ctx.Character); current != nil && current.ID() == mobInst.ID() {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("You are already attacking %s.", mobInst.FormattedName()),
+			ColorError,
+		)
+		return
+	}
+
+	Armeria.combatManager.Engage(ctx.Character, mobInst)
+
+	ctx.Player.client.ShowText(
+		fmt.Sprintf(
+			"You attack %s! [%s]",
+			mobInst.FormattedName(),
+			Armeria.combatManager.HealthString(mobInst),
+		),
+	)
+
+	for _, c := range ctx.Character.Room().Here().Characters(true, ctx.Character) {
+		c.Player().client.ShowText(
+			fmt.Sprintf("%s attacks %s!", ctx.Character.FormattedName(), mobInst.FormattedName()),
+		)
+	}
+}
+
+func handleFleeCommand(ctx *CommandContext) {
+	if !Armeria.combatManager.InCombat(ctx.Character) {
+		ctx.Player.client.ShowColorizedText("You aren't fighting anything.", ColorError)
+		return
+	}
+
+	dir, newRoom := ctx.Character.Room().AdjacentRooms().Random()
+	if moveAllowed, _ := ctx.Character.MoveAllowed(newRoom); !moveAllowed || misc.RandomInt(2) == 0 {
+		ctx.Player.client.ShowColorizedText("You try to flee, but can't get away!", ColorError)
+		return
+	}
+
+	Armeria.combatManager.Disengage(ctx.Character)
+
+	ctx.Character.Move(
+		newRoom,
+		TextStyle(fmt.Sprintf("You flee %s!", misc.MoveToStringFromDir("to the", dir)), WithUserColor(ctx.Character, ColorMovement)),
+		TextStyle(fmt.Sprintf("%s flees %s!", ctx.Character.FormattedName(), misc.MoveToStringFromDir("to the", dir)), WithUserColor(ctx.Character, ColorMovement)),
+		TextStyle(fmt.Sprintf("%s ran in from %s.", ctx.Character.FormattedName(), misc.MoveFromStringFromDir("the", misc.OppositeDirection(dir))), WithUserColor(ctx.Character, ColorMovement)),
+		"",
+	)
+
+	Armeria.commandManager.ProcessCommand(ctx.Player, "look", false)
+}
+
 func handleEquipCommand(ctx *CommandContext) {
 	itemName := ctx.Args["item"]
 
@@ -2569,8 +3830,10 @@ func handleEquipCommand(ctx *CommandContext) {
 		return
 	}
 
-	ctx.Character.Inventory().Remove(item.ID())
-	_ = ctx.Character.Equipment().Add(item.ID())
+	if err := ctx.Character.Inventory().Transfer(item.ID(), ctx.Character.Equipment()); err != nil {
+		ctx.Player.client.ShowColorizedText(CommonItemNotFoundOnCharacter, ColorError)
+		return
+	}
 	ctx.Character.Equipment().SetSlotName(item.ID(), EquipmentSlot(equipSlot))
 
 	ctx.Player.client.SyncInventory()
@@ -2578,6 +3841,8 @@ func handleEquipCommand(ctx *CommandContext) {
 		fmt.Sprintf("You equipped a %s to yourself.", item.FormattedName()),
 		ColorSuccess,
 	)
+
+	QueueScriptFunc(ctx.Character, item, "on_equip")
 }
 
 func handleRemoveCommand(ctx *CommandContext) {
@@ -2590,15 +3855,50 @@ func handleRemoveCommand(ctx *CommandContext) {
 	}
 
 	item := res.Object.(*ItemInstance)
-	if err := ctx.Character.Inventory().Add(item.ID()); err != nil {
+	if err := ctx.Character.Equipment().Transfer(item.ID(), ctx.Character.Inventory()); err == ErrContainerNoRoom {
 		ctx.Player.client.ShowColorizedText(CommonInventoryFilled, ColorError)
 		return
+	} else if err != nil {
+		ctx.Player.client.ShowColorizedText("You don't have an item equipped by that name.", ColorError)
+		return
 	}
-	ctx.Character.Equipment().Remove(item.ID())
 
 	ctx.Player.client.SyncInventory()
 	ctx.Player.client.ShowColorizedText(
 		fmt.Sprintf("You removed a %s from yourself.", item.FormattedName()),
 		ColorSuccess,
 	)
+
+	QueueScriptFunc(ctx.Character, item, "on_remove")
+}
+
+func handleUseCommand(ctx *CommandContext) {
+	itemName := ctx.Args["item"]
+
+	var item *ItemInstance
+	for _, oc := range []*ObjectContainer{
+		ctx.Character.Inventory(),
+		ctx.Character.Equipment(),
+		ctx.Character.Room().Here(),
+	} {
+		if res := oc.GetLoose(itemName); res.Type == RegistryTypeItemInstance {
+			item = res.Object.(*ItemInstance)
+			break
+		}
+	}
+
+	if item == nil {
+		ctx.Player.client.ShowColorizedText(CommonTargetNotFoundHere, ColorError)
+		return
+	}
+
+	if !misc.Contains(item.ScriptFuncs(), "on_use") {
+		ctx.Player.client.ShowColorizedText(
+			fmt.Sprintf("You can't find a way to use the %s.", item.FormattedName()),
+			ColorError,
+		)
+		return
+	}
+
+	QueueScriptFunc(ctx.Character, item, "on_use")
 }
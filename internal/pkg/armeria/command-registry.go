@@ -12,13 +12,13 @@ func RegisterGameCommands() {
 		},
 		{
 			Name: "login",
-			Help: "Log your character into the game world.",
+			Help: "Log in to your account.",
 			Permissions: &CommandPermissions{
 				RequireNoCharacter: true,
 			},
 			Arguments: []*CommandArgument{
 				{
-					Name: "character",
+					Name: "account",
 				},
 				{
 					Name:  "password",
@@ -43,12 +43,33 @@ func RegisterGameCommands() {
 			Handler: handleLoginCommand,
 		},
 		{
-			Name:   "create",
-			Help:   "Create a new character.",
-			Hidden: true,
+			Name: "play",
+			Help: "Enter the game world as one of your account's characters.",
+			Permissions: &CommandPermissions{
+				RequireNoCharacter: true,
+			},
+			Arguments: []*CommandArgument{
+				{
+					Name: "character",
+				},
+			},
+			Handler: handlePlayCommand,
+		},
+		{
+			Name: "create",
+			Help: "Create a new character.",
 			Permissions: &CommandPermissions{
 				RequireNoCharacter: true,
 			},
+			Arguments: []*CommandArgument{
+				{
+					Name:             "answer",
+					Optional:         true,
+					IncludeRemaining: true,
+					NoLog:            true,
+					Help:             "Your answer to the current character creation question.",
+				},
+			},
 			Handler: handleCreateCommand,
 		},
 		{
@@ -109,12 +130,36 @@ func RegisterGameCommands() {
 			},
 			Handler: handleInteractCommand,
 		},
+		{
+			Name:     "attack",
+			Help:     "Attacks a mob in the room.",
+			AltNames: []string{"kill"},
+			Permissions: &CommandPermissions{
+				RequireCharacter: true,
+			},
+			Arguments: []*CommandArgument{
+				{
+					Name:             "target",
+					IncludeRemaining: true,
+					Help:             "The name (or uuid) of the mob.",
+				},
+			},
+			Handler: handleAttackCommand,
+		},
+		{
+			Name: "flee",
+			Help: "Attempts to run away from combat.",
+			Permissions: &CommandPermissions{
+				RequireCharacter: true,
+			},
+			Handler: handleFleeCommand,
+		},
 		{
 			Name: "destroy",
 			Help: "Destroys an item or mob in the room or your inventory.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Arguments: []*CommandArgument{
 				{
@@ -163,7 +208,7 @@ func RegisterGameCommands() {
 			Help: "Manage rooms and their properties.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Subcommands: []*Command{
 				{
@@ -233,7 +278,7 @@ func RegisterGameCommands() {
 			Help: "Manage characters.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_CHAREDIT",
+				RequirePermission: RequireAnyPermission(PermissionCharEdit),
 			},
 			Subcommands: []*Command{
 				{
@@ -285,20 +330,192 @@ func RegisterGameCommands() {
 							Help: "The name of the character to create.",
 						},
 						{
-							Name: "password",
-							Help: "The password of the character.",
+							Name: "account",
+							Help: "The account the character belongs to.",
 						},
 					},
 					Handler: handleCharacterCreateCommand,
 				},
 			},
 		},
+		{
+			Name: "account",
+			Help: "Manage accounts.",
+			Permissions: &CommandPermissions{
+				RequireCharacter:  true,
+				RequirePermission: RequireAnyPermission(PermissionSysop),
+			},
+			Subcommands: []*Command{
+				{
+					Name: "list",
+					Help: "List the accounts in the game, optionally using a filter.",
+					Arguments: []*CommandArgument{
+						{
+							Name:     "filter",
+							Optional: true,
+						},
+					},
+					Handler: handleAccountListCommand,
+				},
+				{
+					Name: "ban",
+					Help: "Ban an account, disconnecting anyone logged in to it.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "account",
+						},
+						{
+							Name:             "reason",
+							IncludeRemaining: true,
+							Optional:         true,
+						},
+					},
+					Handler: handleAccountBanCommand,
+				},
+				{
+					Name: "unban",
+					Help: "Lift the ban on an account.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "account",
+						},
+					},
+					Handler: handleAccountUnbanCommand,
+				},
+				{
+					Name: "grant",
+					Help: "Grant a permission to an account and all of its characters.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "account",
+						},
+						{
+							Name: "permission",
+						},
+					},
+					Handler: handleAccountGrantCommand,
+				},
+				{
+					Name: "revoke",
+					Help: "Revoke an account-level permission.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "account",
+						},
+						{
+							Name: "permission",
+						},
+					},
+					Handler: handleAccountRevokeCommand,
+				},
+			},
+		},
+		{
+			Name: "role",
+			Help: "Manage the roles granted to characters.",
+			Permissions: &CommandPermissions{
+				RequireCharacter:  true,
+				RequirePermission: RequireAnyPermission(PermissionSysop),
+			},
+			Subcommands: []*Command{
+				{
+					Name: "list",
+					Help: "List the roles in the game, or those granted to a character.",
+					Arguments: []*CommandArgument{
+						{
+							Name:     "character",
+							Optional: true,
+						},
+					},
+					Handler: handleRoleListCommand,
+				},
+				{
+					Name: "grant",
+					Help: "Grant a role to a character.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "character",
+						},
+						{
+							Name: "role",
+						},
+					},
+					Handler: handleRoleGrantCommand,
+				},
+				{
+					Name: "revoke",
+					Help: "Revoke a role from a character.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "character",
+						},
+						{
+							Name: "role",
+						},
+					},
+					Handler: handleRoleRevokeCommand,
+				},
+			},
+		},
+		{
+			Name: "audit",
+			Help: "Search the audit log of privileged commands and changes, newest first.",
+			Permissions: &CommandPermissions{
+				RequireCharacter:  true,
+				RequirePermission: RequireAnyPermission(PermissionSysop),
+			},
+			Arguments: []*CommandArgument{
+				{
+					Name:             "filters",
+					Optional:         true,
+					IncludeRemaining: true,
+					Help: "Any of character:<name>, object:<uuid or name>, command:<text>, since:<time>, " +
+						"until:<time> and limit:<count>. A time is a date, a date and time (2006-01-02T15:04) " +
+						"or how long ago (such as 36h or 7d).",
+				},
+			},
+			Handler: handleAuditCommand,
+		},
+		{
+			Name: "snapshot",
+			Help: "Manage compressed snapshots of the whole game data.",
+			Permissions: &CommandPermissions{
+				RequireCharacter:  true,
+				RequirePermission: RequireAnyPermission(PermissionSysop),
+			},
+			Subcommands: []*Command{
+				{
+					Name:    "create",
+					Help:    "Save the game data and take a snapshot of it.",
+					Handler: handleSnapshotCreateCommand,
+				},
+				{
+					Name:    "list",
+					Help:    "List the snapshots, newest first.",
+					Handler: handleSnapshotListCommand,
+				},
+				{
+					Name: "diff",
+					Help: "Show what has changed since a snapshot was taken, or between two snapshots.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "name",
+						},
+						{
+							Name:     "other",
+							Optional: true,
+						},
+					},
+					Handler: handleSnapshotDiffCommand,
+				},
+			},
+		},
 		{
 			Name: "save",
 			Help: "Write the in-memory game data to disk.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_SYSOP",
+				RequirePermission: RequireAnyPermission(PermissionSysop),
 			},
 			Handler: handleSaveCommand,
 		},
@@ -356,7 +573,7 @@ func RegisterGameCommands() {
 			Help: "Manage mobiles (npcs/monsters).",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Subcommands: []*Command{
 				{
@@ -478,7 +695,7 @@ func RegisterGameCommands() {
 			Help: "Manage areas.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Subcommands: []*Command{
 				{
@@ -515,6 +732,55 @@ func RegisterGameCommands() {
 					},
 					Handler: handleAreaEditCommand,
 				},
+				{
+					Name: "grant",
+					Help: "Allow a character to build in an area you own (or your current area).",
+					Arguments: []*CommandArgument{
+						{
+							Name: "character",
+						},
+						{
+							Name:             "area",
+							Optional:         true,
+							IncludeRemaining: true,
+						},
+					},
+					Handler: handleAreaGrantCommand,
+				},
+				{
+					Name: "revoke",
+					Help: "Stop a character from building in an area you own (or your current area).",
+					Arguments: []*CommandArgument{
+						{
+							Name: "character",
+						},
+						{
+							Name:             "area",
+							Optional:         true,
+							IncludeRemaining: true,
+						},
+					},
+					Handler: handleAreaRevokeCommand,
+				},
+				{
+					Name: "owner",
+					Help: "Change the owner of an area (or your current area).",
+					Permissions: &CommandPermissions{
+						RequireCharacter:  true,
+						RequirePermission: RequireAllPermissions(PermissionBuild, PermissionBuildAnywhere),
+					},
+					Arguments: []*CommandArgument{
+						{
+							Name: "character",
+						},
+						{
+							Name:             "area",
+							Optional:         true,
+							IncludeRemaining: true,
+						},
+					},
+					Handler: handleAreaOwnerCommand,
+				},
 			},
 		},
 		{
@@ -522,7 +788,7 @@ func RegisterGameCommands() {
 			Help: "Manage items.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Subcommands: []*Command{
 				{
@@ -644,7 +910,7 @@ func RegisterGameCommands() {
 			Help: "Wipe everything, or a specific thing, in your current room.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Arguments: []*CommandArgument{
 				{
@@ -655,18 +921,36 @@ func RegisterGameCommands() {
 			},
 			Handler: handleWipeCommand,
 		},
+		{
+			Name: "undo",
+			Help: "Undo your most recent change to the world.",
+			Permissions: &CommandPermissions{
+				RequireCharacter:  true,
+				RequirePermission: RequireAnyPermission(PermissionBuild),
+			},
+			Handler: handleUndoCommand,
+		},
+		{
+			Name: "redo",
+			Help: "Make a change to the world that you've undone again.",
+			Permissions: &CommandPermissions{
+				RequireCharacter:  true,
+				RequirePermission: RequireAnyPermission(PermissionBuild),
+			},
+			Handler: handleRedoCommand,
+		},
 		{
 			Name: "ghost",
 			Help: "Bypass movement restrictions while moving.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_GHOST",
+				RequirePermission: RequireAnyPermission(PermissionGhost),
 			},
 			Handler: handleGhostCommand,
 		},
 		{
 			Name: "password",
-			Help: "Set a new password for your character.",
+			Help: "Set a new password for your account.",
 			Permissions: &CommandPermissions{
 				RequireCharacter: true,
 			},
@@ -684,7 +968,7 @@ func RegisterGameCommands() {
 			Help:     "Teleport to the specified character or room.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_TELEPORT",
+				RequirePermission: RequireAnyPermission(PermissionTeleport),
 			},
 			Arguments: []*CommandArgument{
 				{
@@ -700,7 +984,7 @@ func RegisterGameCommands() {
 			Help:     "Copy and paste object attributes.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Subcommands: []*Command{
 				{
@@ -787,8 +1071,41 @@ func RegisterGameCommands() {
 			Permissions: &CommandPermissions{
 				RequireCharacter: true,
 			},
+			Arguments: []*CommandArgument{
+				{
+					Name:             "device",
+					Optional:         true,
+					IncludeRemaining: true,
+					Help:             "A label for this device, shown when listing your sessions.",
+				},
+			},
 			Handler: handleAutoLoginCommand,
 		},
+		{
+			Name: "sessions",
+			Help: "List or revoke the sessions that can access your account.",
+			Permissions: &CommandPermissions{
+				RequireCharacter: true,
+			},
+			Subcommands: []*Command{
+				{
+					Name:    "list",
+					Help:    "List the active sessions for your account.",
+					Handler: handleSessionsListCommand,
+				},
+				{
+					Name: "revoke",
+					Help: "Revoke a session so it can no longer be used.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "session",
+							Help: "The ID of the session, or \"all\" to revoke every session.",
+						},
+					},
+					Handler: handleSessionsRevokeCommand,
+				},
+			},
+		},
 		{
 			Name: "channel",
 			Help: "Join, leave or list talking channels you can participate in.",
@@ -908,7 +1225,7 @@ func RegisterGameCommands() {
 			Help: "Manage item ledgers.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_BUILD",
+				RequirePermission: RequireAnyPermission(PermissionBuild),
 			},
 			Subcommands: []*Command{
 				{
@@ -1020,6 +1337,80 @@ func RegisterGameCommands() {
 				},
 			},
 		},
+		{
+			Name: "quest",
+			Help: "View and manage quests.",
+			Permissions: &CommandPermissions{
+				RequireCharacter: true,
+			},
+			Subcommands: []*Command{
+				{
+					Name:    "list",
+					Help:    "List the quests you have started or completed.",
+					Handler: handleQuestListCommand,
+				},
+				{
+					Name: "show",
+					Help: "Show your progress on a quest.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "quest_name",
+						},
+					},
+					Handler: handleQuestShowCommand,
+				},
+				{
+					Name: "abandon",
+					Help: "Abandon a quest you have started.",
+					Arguments: []*CommandArgument{
+						{
+							Name: "quest_name",
+						},
+					},
+					Handler: handleQuestAbandonCommand,
+				},
+				{
+					Name: "create",
+					Help: "Create a new quest.",
+					Permissions: &CommandPermissions{
+						RequireCharacter:  true,
+						RequirePermission: RequireAnyPermission(PermissionBuild),
+					},
+					Arguments: []*CommandArgument{
+						{
+							Name:             "name",
+							IncludeRemaining: true,
+						},
+					},
+					Handler: handleQuestCreateCommand,
+				},
+				{
+					Name: "edit",
+					Help: "Edit the description, rewards or steps of a quest.",
+					Permissions: &CommandPermissions{
+						RequireCharacter:  true,
+						RequirePermission: RequireAnyPermission(PermissionBuild),
+					},
+					Arguments: []*CommandArgument{
+						{
+							Name: "quest_name",
+						},
+						{
+							Name: "property",
+							Help: "One of: description, money, items, add-step or remove-step.",
+						},
+						{
+							Name:             "value",
+							IncludeRemaining: true,
+							Optional:         true,
+							Help: "For add-step, use 'talk &lt;mob&gt;', 'deliver &lt;item&gt; to &lt;mob&gt;', " +
+								"'visit &lt;location&gt;' (or 'visit .' for your room) or 'script &lt;description&gt;'.",
+						},
+					},
+					Handler: handleQuestEditCommand,
+				},
+			},
+		},
 		{
 			Name: "buy",
 			Help: "Buy an item from an NPC.",
@@ -1063,7 +1454,7 @@ func RegisterGameCommands() {
 			Help: "Displays the status of server-side tickers.",
 			Permissions: &CommandPermissions{
 				RequireCharacter:  true,
-				RequirePermission: "CAN_SYSOP",
+				RequirePermission: RequireAnyPermission(PermissionSysop),
 			},
 			Handler: handleTickersCommand,
 		},
@@ -1100,6 +1491,21 @@ func RegisterGameCommands() {
 			},
 			Handler: handleRemoveCommand,
 		},
+		{
+			Name: "use",
+			Help: "Use an item you are carrying, or one lying nearby.",
+			Permissions: &CommandPermissions{
+				RequireCharacter: true,
+			},
+			Arguments: []*CommandArgument{
+				{
+					Name:             "item",
+					Help:             "The name of the item you wish to use.",
+					IncludeRemaining: true,
+				},
+			},
+			Handler: handleUseCommand,
+		},
 	}
 
 	// Register commands for communicating on channels.
@@ -33,7 +33,7 @@ type CommandArgument struct {
 type CommandPermissions struct {
 	RequireNoCharacter bool
 	RequireCharacter   bool
-	RequirePermission  string
+	RequirePermission  *PermissionRequirement
 }
 
 type CommandContext struct {
@@ -43,6 +43,9 @@ type CommandContext struct {
 	Character       *Character
 	Args            map[string]string
 	HandlerStart    time.Time
+	AuditChanges    []*AuditChange
+	Edits           []Edit
+	Changed         []interface{}
 }
 
 // CheckPermissions returns whether or not a parent can see/use the command.
@@ -63,8 +66,8 @@ func (cmd *Command) CheckPermissions(p *Player) bool {
 		}
 	}
 
-	if len(cmd.Permissions.RequirePermission) > 0 {
-		if !p.Character().HasPermission(cmd.Permissions.RequirePermission) {
+	if cmd.Permissions.RequirePermission != nil {
+		if p.Character() == nil || !cmd.Permissions.RequirePermission.MetBy(p.Character()) {
 			return false
 		}
 	}
@@ -88,7 +91,7 @@ func (cmd *Command) ShowSubcommandHelp(p *Player, commandsEntered []string) stri
 
 	var rows []string
 	for _, scmd := range cmd.Subcommands {
-		if cmd.CheckPermissions(p) {
+		if scmd.CheckPermissions(p) {
 			rows = append(rows, TableRow(
 				TableCell{content: TextStyle(scmd.Name, WithBold())},
 				TableCell{content: scmd.Help},
@@ -148,6 +151,111 @@ func (cmd *Command) ArgumentByName(name string) *CommandArgument {
 	return nil
 }
 
+// AuditAttribute records a change to an attribute of an object, for the audit log. It is called after the
+// attribute has been set, with the value it had before.
+func (ctx *CommandContext) AuditAttribute(ot ObjectType, o AuditedObject, attr string, before string) {
+	change := NewAuditChange(ot, o)
+	change.Attribute = attr
+	change.Before = before
+	change.After = o.Attribute(attr)
+
+	ctx.AuditChanges = append(ctx.AuditChanges, change)
+}
+
+// AuditDelete records that an object was deleted, for the audit log.
+func (ctx *CommandContext) AuditDelete(ot ObjectType, o AuditedObject) {
+	change := NewAuditChange(ot, o)
+	change.Deleted = true
+
+	ctx.AuditChanges = append(ctx.AuditChanges, change)
+}
+
+// SetAttribute sets an attribute of a room, mob, item or instance, recording the change for the audit log and in
+// the character's edit journal.
+func (ctx *CommandContext) SetAttribute(ot ObjectType, o AuditedObject, attr string, value string) error {
+	before := o.Attribute(attr)
+
+	edit := NewAttributeEdit(ot, o, attr, value)
+	if err := edit.Apply(); err != nil {
+		return err
+	}
+
+	ctx.AuditAttribute(ot, o, attr, before)
+	ctx.JournalEdit(edit)
+
+	return nil
+}
+
+// JournalEdit records an edit made to the world, so that the character can undo it.
+func (ctx *CommandContext) JournalEdit(e Edit) {
+	ctx.Edits = append(ctx.Edits, e)
+	ctx.Persist(editedObjects(e)...)
+}
+
+// Persist records that the command changed the objects, so that they're saved to the store once it finishes.
+func (ctx *CommandContext) Persist(objects ...interface{}) {
+	ctx.Changed = append(ctx.Changed, objects...)
+}
+
+// Privileged returns whether the command, or the command it belongs to, needs a permission to be used.
+func (cmd *Command) Privileged() bool {
+	for c := cmd; c != nil; c = c.Parent {
+		if c.Permissions != nil && c.Permissions.RequirePermission != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CommandLine returns the command as it was entered, leaving out any arguments that shouldn't be logged.
+func (cmd *Command) CommandLine(args map[string]string) string {
+	var names []string
+	for c := cmd; c != nil; c = c.Parent {
+		names = append([]string{c.Name}, names...)
+	}
+
+	line := "/" + strings.Join(names, " ")
+	for _, a := range cmd.Arguments {
+		if !a.NoLog && len(args[a.Name]) > 0 {
+			line += " " + args[a.Name]
+		}
+	}
+
+	return line
+}
+
+// AuditCtx writes a privileged command, or one that changed something, to the audit log.
+func (cmd *Command) AuditCtx(ctx *CommandContext) {
+	if !cmd.Privileged() && len(ctx.AuditChanges) == 0 {
+		return
+	}
+
+	Armeria.auditLog.Record(NewAuditEntry(ctx.Character, cmd.CommandLine(ctx.Args), ctx.AuditChanges...))
+}
+
+// JournalCtx records the edits made by the command in the character's edit journal, as a single entry.
+func (cmd *Command) JournalCtx(ctx *CommandContext) {
+	if ctx.Character == nil || len(ctx.Edits) == 0 {
+		return
+	}
+
+	ctx.Character.EditJournal().Record(&EditJournalEntry{
+		Command: cmd.CommandLine(ctx.Args),
+		Edits:   ctx.Edits,
+	})
+}
+
+// PersistCtx saves the objects changed by the command to the store, so that they survive a crash before the next
+// game save.
+func (cmd *Command) PersistCtx(ctx *CommandContext) {
+	if len(ctx.Changed) == 0 {
+		return
+	}
+
+	persistObjects(ctx.Changed...)
+}
+
 // LogCtx logs a parent using a command.
 func (cmd *Command) LogCtx(ctx *CommandContext) {
 	handlerDuration := time.Since(ctx.HandlerStart)
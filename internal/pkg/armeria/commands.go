@@ -2,11 +2,9 @@ package armeria
 
 import (
 	"armeria/internal/pkg/misc"
-	"encoding/json"
 	"strings"
+	"sync"
 	"time"
-
-	"go.uber.org/zap"
 )
 
 const (
@@ -16,6 +14,7 @@ const (
 
 // Manager is the global manager instance for Command objects
 type CommandManager struct {
+	sync.RWMutex
 	commands []*Command
 }
 
@@ -28,7 +27,13 @@ func NewCommandManager() *CommandManager {
 
 // Commands returns all the registered commands in the game.
 func (m *CommandManager) Commands() []*Command {
-	return m.commands
+	m.RLock()
+	defer m.RUnlock()
+
+	commands := make([]*Command, len(m.commands))
+	copy(commands, m.commands)
+
+	return commands
 }
 
 // RegisterCommand will register a Command with the command manager with the arguments
@@ -39,6 +44,9 @@ func (m *CommandManager) RegisterCommand(c *Command) {
 		cmd.Parent = c
 	}
 
+	m.Lock()
+	defer m.Unlock()
+
 	m.commands = append(m.commands, c)
 }
 
@@ -101,7 +109,7 @@ func (m *CommandManager) ProcessCommand(p *Player, command string, playerInitiat
 		return
 	}
 
-	cmd, cmdArgs, errorMsg := m.FindCommand(p, m.commands, strings.Join(sections, " "), []string{})
+	cmd, cmdArgs, errorMsg := m.FindCommand(p, m.Commands(), strings.Join(sections, " "), []string{})
 
 	if cmd == nil {
 		p.client.ShowColorizedText(errorMsg, ColorCmdHelp)
@@ -124,12 +132,25 @@ func (m *CommandManager) ProcessCommand(p *Player, command string, playerInitiat
 		return
 	}
 
+	if playerInitiated {
+		Armeria.AnnotateEvent(func(e *Event) {
+			if ctx.Character != nil {
+				e.Character = ctx.Character.Name()
+			}
+			e.Command = cmd.CommandLine(ctx.Args)
+		})
+	}
+
 	ctx.HandlerStart = time.Now()
 	cmd.Handler(ctx)
 	cmd.LogCtx(ctx)
+	cmd.AuditCtx(ctx)
+	cmd.JournalCtx(ctx)
+	cmd.PersistCtx(ctx)
 }
 
-func (m *CommandManager) CharacterCommandDictionaryJSON(p *Player) string {
+// CharacterCommandDictionary returns the commands the Player can use, for auto-completion on the client.
+func (m *CommandManager) CharacterCommandDictionary(p *Player) []*Command {
 	commandSlice := make([]*Command, 0)
 
 	for _, cmd := range m.Commands() {
@@ -142,13 +163,5 @@ func (m *CommandManager) CharacterCommandDictionaryJSON(p *Player) string {
 		commandSlice = append(commandSlice, cmd)
 	}
 
-	commandMapJSON, err := json.Marshal(commandSlice)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal command dictionary data",
-			zap.String("character", p.Character().UUID),
-			zap.Error(err),
-		)
-	}
-
-	return string(commandMapJSON)
+	return commandSlice
 }
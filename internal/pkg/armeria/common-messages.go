@@ -5,4 +5,5 @@ const (
 	CommonItemNotFoundOnCharacter string = "You don't have an item by that name."
 	CommonInvalidDirection        string = "You cannot go that way."
 	CommonInventoryFilled         string = "You have no room in your inventory for that."
+	CommonCannotChangeShared      string = "That's in areas you don't have permission to build in, so you can't change it."
 )
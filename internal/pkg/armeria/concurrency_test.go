@@ -0,0 +1,371 @@
+package armeria
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// useConcurrencyState boots a headless game from empty data within a temporary directory, which the caller is
+// responsible for removing.
+func useConcurrencyState(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "armeria-concurrency")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+
+	for _, c := range StoreCollections() {
+		contents := []byte(fmt.Sprintf(`{"%s":[]}`, c.Name))
+		if err := ioutil.WriteFile(filepath.Join(dir, c.File), contents, 0644); err != nil {
+			t.Fatalf("error writing collection: %s", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "scripts"), 0755); err != nil {
+		t.Fatalf("error creating scripts dir: %s", err)
+	}
+
+	schemaVersion := []byte(strconv.Itoa(SchemaVersion))
+	if err := ioutil.WriteFile(filepath.Join(dir, "schema-version"), schemaVersion, 0644); err != nil {
+		t.Fatalf("error writing schema version: %s", err)
+	}
+
+	InitHeadless(dir, "", zap.NewNop())
+
+	return dir
+}
+
+// createTestCharacters creates offline characters standing in the room.
+func createTestCharacters(t *testing.T, r *Room, count int) []*Character {
+	a, err := Armeria.accountManager.CreateAccount("tester", "secret")
+	if err != nil {
+		t.Fatalf("error creating account: %s", err)
+	}
+
+	chars := make([]*Character, count)
+	for i := range chars {
+		if chars[i], err = Armeria.characterManager.CreateCharacter(a, fmt.Sprintf("Tester%d", i)); err != nil {
+			t.Fatalf("error creating character: %s", err)
+		}
+		if err := r.Here().Add(chars[i].ID()); err != nil {
+			t.Fatalf("error adding character to room: %s", err)
+		}
+	}
+
+	return chars
+}
+
+// createTestItem creates an item and adds it to memory.
+func createTestItem(name string) *Item {
+	i := Armeria.itemManager.CreateItem(name)
+	Armeria.itemManager.AddItem(i)
+	return i
+}
+
+// waitOrDeadlock waits for the goroutines to finish, failing the test if they appear to be deadlocked.
+func waitOrDeadlock(t *testing.T, wg *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for goroutines; possible deadlock")
+	}
+}
+
+func TestConcurrentMove(t *testing.T) {
+	defer os.RemoveAll(useConcurrencyState(t))
+
+	a := Armeria.worldManager.CreateArea("Test")
+	first := a.RoomAt(NewCoords(0, 0, 0, 0))
+	second := Armeria.worldManager.CreateRoom(a, NewCoords(1, 0, 0, 0))
+	chars := createTestCharacters(t, first, 10)
+
+	var wg sync.WaitGroup
+	for _, c := range chars {
+		wg.Add(1)
+		go func(c *Character) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				to := first
+				if c.Room() == first {
+					to = second
+				}
+				c.Move(to, "", "", "", "")
+			}
+		}(c)
+	}
+	waitOrDeadlock(t, &wg)
+
+	if n := first.Here().Count() + second.Here().Count(); n != len(chars) {
+		t.Errorf("expected %d characters across both rooms, got %d", len(chars), n)
+	}
+	for _, c := range chars {
+		if c.Room() == nil {
+			t.Errorf("character %s is not in a room", c.Name())
+		}
+	}
+}
+
+func TestConcurrentGetDrop(t *testing.T) {
+	defer os.RemoveAll(useConcurrencyState(t))
+
+	a := Armeria.worldManager.CreateArea("Test")
+	r := a.RoomAt(NewCoords(0, 0, 0, 0))
+	chars := createTestCharacters(t, r, 10)
+	ii := createTestItem("Coin").CreateInstance()
+	if err := r.Here().Add(ii.ID()); err != nil {
+		t.Fatalf("error adding item to room: %s", err)
+	}
+
+	// Everyone tries to pick up the same item, and exactly one of them should get it.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for _, c := range chars {
+		wg.Add(1)
+		go func(c *Character) {
+			defer wg.Done()
+			if r.Here().Transfer(ii.ID(), c.Inventory()) == nil {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}(c)
+	}
+	waitOrDeadlock(t, &wg)
+
+	if winners != 1 {
+		t.Fatalf("expected exactly one character to get the item, got %d", winners)
+	}
+
+	// Everyone then races to drop it and pick it back up.
+	for _, c := range chars {
+		wg.Add(1)
+		go func(c *Character) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_ = c.Inventory().Transfer(ii.ID(), r.Here())
+				_ = r.Here().Transfer(ii.ID(), c.Inventory())
+			}
+		}(c)
+	}
+	waitOrDeadlock(t, &wg)
+
+	holders := r.Here().Count() - len(chars)
+	for _, c := range chars {
+		holders += c.Inventory().Count()
+	}
+	if holders != 1 {
+		t.Errorf("expected the item to be in exactly one container, found in %d", holders)
+	}
+	if Armeria.registry.GetObjectContainer(ii.ID()) == nil {
+		t.Error("expected the item to be registered to a container")
+	}
+}
+
+func TestConcurrentGive(t *testing.T) {
+	defer os.RemoveAll(useConcurrencyState(t))
+
+	a := Armeria.worldManager.CreateArea("Test")
+	r := a.RoomAt(NewCoords(0, 0, 0, 0))
+	chars := createTestCharacters(t, r, 2)
+	i := createTestItem("Coin")
+
+	const perCharacter = 10
+	var items [2][]string
+	for idx, c := range chars {
+		for n := 0; n < perCharacter; n++ {
+			ii := i.CreateInstance()
+			if err := c.Inventory().Add(ii.ID()); err != nil {
+				t.Fatalf("error adding item to inventory: %s", err)
+			}
+			items[idx] = append(items[idx], ii.ID())
+		}
+	}
+
+	// Each character repeatedly gives their items to the other, so transfers run in both directions at once.
+	var wg sync.WaitGroup
+	for idx := range chars {
+		from := chars[idx]
+		to := chars[1-idx]
+		for _, id := range items[idx] {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				for n := 0; n < 50; n++ {
+					_ = from.Inventory().Transfer(id, to.Inventory())
+					_ = to.Inventory().Transfer(id, from.Inventory())
+				}
+			}(id)
+		}
+	}
+	waitOrDeadlock(t, &wg)
+
+	total := chars[0].Inventory().Count() + chars[1].Inventory().Count()
+	if total != perCharacter*2 {
+		t.Errorf("expected %d items between both characters, got %d", perCharacter*2, total)
+	}
+}
+
+func TestConcurrentMobSpawner(t *testing.T) {
+	defer os.RemoveAll(useConcurrencyState(t))
+
+	a := Armeria.worldManager.CreateArea("Test")
+	r := a.RoomAt(NewCoords(0, 0, 0, 0))
+	second := Armeria.worldManager.CreateRoom(a, NewCoords(1, 0, 0, 0))
+	chars := createTestCharacters(t, r, 5)
+
+	m := Armeria.mobManager.CreateMob("Rat")
+	Armeria.mobManager.AddMob(m)
+
+	spawner := createTestItem("Rat Hole")
+	spawner.SetAttribute(AttributeType, ItemTypeMobSpawner)
+	spawner.SetAttribute(AttributeSpawnMob, "Rat")
+	spawner.SetAttribute(AttributeSpawnLimit, "3")
+	if err := r.Here().Add(spawner.CreateInstance().ID()); err != nil {
+		t.Fatalf("error adding spawner to room: %s", err)
+	}
+
+	// The spawner ticks while characters move through the room and the mobs are saved.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 50; n++ {
+			MobSpawner()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 10; n++ {
+			Armeria.mobManager.SaveMobs()
+		}
+	}()
+	for _, c := range chars {
+		wg.Add(1)
+		go func(c *Character) {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				to := r
+				if c.Room() == r {
+					to = second
+				}
+				c.Move(to, "", "", "", "")
+			}
+		}(c)
+	}
+	waitOrDeadlock(t, &wg)
+
+	if n := len(m.Instances()); n != 3 {
+		t.Errorf("expected 3 spawned mobs, got %d", n)
+	}
+	if n := len(r.Here().Mobs()); n != 3 {
+		t.Errorf("expected 3 mobs in the room, got %d", n)
+	}
+}
+
+func TestConcurrentSaves(t *testing.T) {
+	defer os.RemoveAll(useConcurrencyState(t))
+
+	a := Armeria.worldManager.CreateArea("Test")
+	r := a.RoomAt(NewCoords(0, 0, 0, 0))
+	second := Armeria.worldManager.CreateRoom(a, NewCoords(1, 0, 0, 0))
+	chars := createTestCharacters(t, r, 5)
+	i := createTestItem("Coin")
+	m := Armeria.mobManager.CreateMob("Rat")
+	Armeria.mobManager.AddMob(m)
+
+	var wg sync.WaitGroup
+	saves := []func(){
+		Armeria.characterManager.SaveCharacters,
+		Armeria.worldManager.SaveWorld,
+		Armeria.mobManager.SaveMobs,
+		Armeria.itemManager.SaveItems,
+	}
+	for _, save := range saves {
+		wg.Add(1)
+		go func(save func()) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				save()
+			}
+		}(save)
+	}
+	for _, c := range chars {
+		wg.Add(1)
+		go func(c *Character) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				ii := i.CreateInstance()
+				_ = c.Inventory().Add(ii.ID())
+				_ = c.Inventory().Transfer(ii.ID(), r.Here())
+				_ = c.SetAttribute(AttributeTitle, fmt.Sprintf("the %d", n))
+
+				mi := m.CreateInstance()
+				_ = second.Here().Add(mi.ID())
+
+				to := r
+				if c.Room() == r {
+					to = second
+				}
+				c.Move(to, "", "", "", "")
+			}
+		}(c)
+	}
+	waitOrDeadlock(t, &wg)
+
+	// A final save should capture everything that was created.
+	for _, save := range saves {
+		save()
+	}
+
+	records, err := Armeria.store.Load(CollectionItems)
+	if err != nil {
+		t.Fatalf("error loading items: %s", err)
+	}
+	var loaded []*Item
+	if err := DecodeStoreRecords(records, &loaded); err != nil {
+		t.Fatalf("error decoding items: %s", err)
+	}
+	if len(loaded) != 1 || len(loaded[0].UnsafeInstances) != len(chars)*20 {
+		t.Errorf("expected %d saved item instances", len(chars)*20)
+	}
+}
+
+func TestConcurrentSaveRecord(t *testing.T) {
+	defer os.RemoveAll(useConcurrencyState(t))
+
+	a := Armeria.worldManager.CreateArea("Test")
+	c := createTestCharacters(t, a.RoomAt(NewCoords(0, 0, 0, 0)), 1)[0]
+	acct := c.Account()
+
+	// Saving a single record while it's being written to must never deadlock.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 200; n++ {
+			Armeria.characterManager.SaveCharacter(c)
+			Armeria.accountManager.SaveAccount(acct)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 200; n++ {
+			_ = c.SetAttribute(AttributeTitle, fmt.Sprintf("the %d", n))
+			acct.SetBanned(n%2 == 0, "")
+		}
+	}()
+	waitOrDeadlock(t, &wg)
+}
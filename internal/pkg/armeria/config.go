@@ -8,10 +8,23 @@ import (
 )
 
 type config struct {
-	HTTPPort   int    `yaml:"httpPort"`
-	PublicPath string `yaml:"publicPath"`
-	Production bool   `yaml:"production"`
-	DataPath   string `yaml:"dataPath"`
+	HTTPPort          int      `yaml:"httpPort"`
+	TelnetPort        int      `yaml:"telnetPort"`
+	PublicPath        string   `yaml:"publicPath"`
+	Production        bool     `yaml:"production"`
+	DataPath          string   `yaml:"dataPath"`
+	Store             string   `yaml:"store"`
+	StartingRoom      string   `yaml:"startingRoom"`
+	ReservedNames     []string `yaml:"reservedNames"`
+	EventLoop         bool     `yaml:"eventLoop"`
+	EventLog          bool     `yaml:"eventLog"`
+	MaxMessageLength  int      `yaml:"maxMessageLength"`
+	FilteredWords     []string `yaml:"filteredWords"`
+	AuditLogMaxSize   int      `yaml:"auditLogMaxSize"`
+	AuditLogMaxFiles  int      `yaml:"auditLogMaxFiles"`
+	EditJournalDepth  int      `yaml:"editJournalDepth"`
+	SnapshotInterval  int      `yaml:"snapshotInterval"`
+	SnapshotRetention int      `yaml:"snapshotRetention"`
 }
 
 func parseConfigFile(filePath string) config {
@@ -118,7 +118,7 @@ func (convo *Conversation) Start() {
 				return
 			case <-convo.ticker.C:
 				convo.IncTickCount()
-				go CallMobFunc(
+				QueueScriptFunc(
 					convo.Character(),
 					convo.MobInstance(),
 					"conversation_tick",
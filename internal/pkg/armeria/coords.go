@@ -6,8 +6,6 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-
-	"go.uber.org/zap"
 )
 
 // Coords store positional information relative to an ParentArea.
@@ -124,22 +122,29 @@ func (c *Coords) SetFrom(co *Coords) {
 	c.Set(co.X(), co.Y(), co.Z(), co.I())
 }
 
-// JSON returns the JSON-encoded string of the coordinates.
-func (c *Coords) JSON() string {
+// LocationData returns the coordinates for the client.
+func (c *Coords) LocationData() *LocationData {
 	c.RLock()
 	defer c.RUnlock()
 
-	j, err := json.Marshal(c)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal coords",
-			zap.Error(err),
-		)
+	return &LocationData{
+		X: c.UnsafeX,
+		Y: c.UnsafeY,
+		Z: c.UnsafeZ,
 	}
-
-	return string(j)
 }
 
 // String returns the coordinates as a string.
 func (c *Coords) String() string {
 	return fmt.Sprintf("%d,%d,%d", c.UnsafeX, c.UnsafeY, c.UnsafeZ)
 }
+
+type coordsJSON Coords
+
+// MarshalJSON encodes the Coords while holding its lock.
+func (c *Coords) MarshalJSON() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	return json.Marshal((*coordsJSON)(c))
+}
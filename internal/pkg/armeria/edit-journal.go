@@ -0,0 +1,600 @@
+package armeria
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// DefaultEditJournalDepth is how many commands can be undone, when it isn't set in the config file.
+const DefaultEditJournalDepth int = 20
+
+var (
+	// ErrNothingToUndo is an error for when the edit journal has nothing left to undo.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is an error for when the edit journal has nothing left to redo.
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrEditConflict is an error for when something else has changed an object since it was edited.
+	ErrEditConflict = errors.New("has been changed by something else")
+	// ErrEditMissing is an error for when an edited object has since been removed from the world.
+	ErrEditMissing = errors.New("no longer exists")
+	// ErrEditMoved is an error for when an instance is no longer in the room it was edited in.
+	ErrEditMoved = errors.New("is no longer where it was")
+	// ErrEditRoomNotEmpty is an error for when a room can't be removed, because there's something in it.
+	ErrEditRoomNotEmpty = errors.New("isn't empty")
+	// ErrEditRoomTaken is an error for when a room can't be put back, because another room has taken its place.
+	ErrEditRoomTaken = errors.New("has been taken by another room")
+	// ErrEditForbidden is an error for when the character can no longer build where the edit was made.
+	ErrEditForbidden = errors.New("you no longer have permission to build in")
+)
+
+// An Edit is a single change made to the world, which knows how to revert itself.
+type Edit interface {
+	// Undo reverts the edit.
+	Undo() error
+	// Redo makes the edit again, after it has been undone.
+	Redo() error
+	// Area returns the Area the edit was made within, or nil when it wasn't made within one.
+	Area() *Area
+}
+
+// Force verify that each edit implements Edit.
+var _ Edit = (*AttributeEdit)(nil)
+var _ Edit = (*RoomEdit)(nil)
+var _ Edit = (*RoomMoveEdit)(nil)
+var _ Edit = (*InstanceEdit)(nil)
+
+// An EditJournalEntry holds the edits made by a single command, which are undone and redone together.
+type EditJournalEntry struct {
+	Command string
+	Edits   []Edit
+}
+
+// undo reverts each edit, last to first. If one can't be reverted, the edits already reverted are made again, so
+// that the entry is either undone completely or not at all.
+func (e *EditJournalEntry) undo(c *Character) error {
+	if err := e.checkAreas(c); err != nil {
+		return err
+	}
+
+	for i := len(e.Edits) - 1; i >= 0; i-- {
+		if err := e.Edits[i].Undo(); err != nil {
+			for j := i + 1; j < len(e.Edits); j++ {
+				_ = e.Edits[j].Redo()
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// redo makes each edit again, first to last. If one can't be made, the edits already made are reverted.
+func (e *EditJournalEntry) redo(c *Character) error {
+	if err := e.checkAreas(c); err != nil {
+		return err
+	}
+
+	for i, edit := range e.Edits {
+		if err := edit.Redo(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				_ = e.Edits[j].Undo()
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAreas returns an error if the Character can no longer build in an area that one of the edits was made in.
+func (e *EditJournalEntry) checkAreas(c *Character) error {
+	for _, edit := range e.Edits {
+		if a := edit.Area(); a != nil && !a.CanBuild(c) {
+			return fmt.Errorf("%w %s", ErrEditForbidden, a.Name())
+		}
+	}
+
+	return nil
+}
+
+// Areas returns each distinct Area that the edits were made within.
+func (e *EditJournalEntry) Areas() []*Area {
+	var areas []*Area
+	for _, edit := range e.Edits {
+		a := edit.Area()
+		if a == nil {
+			continue
+		}
+
+		seen := false
+		for _, existing := range areas {
+			if existing == a {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			areas = append(areas, a)
+		}
+	}
+
+	return areas
+}
+
+// Objects returns the objects that the edits changed, so that they can be persisted.
+func (e *EditJournalEntry) Objects() []interface{} {
+	var objects []interface{}
+	for _, edit := range e.Edits {
+		objects = append(objects, editedObjects(edit)...)
+	}
+
+	return objects
+}
+
+// editedObjects returns the objects changed by an edit. Creating or destroying a room changes its area, and
+// spawning or deleting an instance changes both its room and its mob or item.
+func editedObjects(e Edit) []interface{} {
+	switch edit := e.(type) {
+	case *AttributeEdit:
+		return []interface{}{edit.object}
+	case *RoomEdit:
+		return []interface{}{edit.area}
+	case *RoomMoveEdit:
+		return []interface{}{edit.room}
+	case *InstanceEdit:
+		return []interface{}{edit.room, edit.instance}
+	}
+
+	return nil
+}
+
+// EditJournal holds a builder's recent edits to the world, so that they can be undone and redone.
+type EditJournal struct {
+	sync.Mutex
+	depth      int
+	unsafeUndo []*EditJournalEntry
+	unsafeRedo []*EditJournalEntry
+}
+
+// NewEditJournal returns an EditJournal that keeps the most recent depth entries.
+func NewEditJournal(depth int) *EditJournal {
+	if depth <= 0 {
+		depth = DefaultEditJournalDepth
+	}
+
+	return &EditJournal{
+		depth:      depth,
+		unsafeUndo: make([]*EditJournalEntry, 0),
+		unsafeRedo: make([]*EditJournalEntry, 0),
+	}
+}
+
+// Record adds an entry to the journal, dropping the oldest entry if the journal is full. Anything that had been
+// undone can no longer be redone.
+func (j *EditJournal) Record(e *EditJournalEntry) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.unsafeUndo = j.unsafePush(j.unsafeUndo, e)
+	j.unsafeRedo = j.unsafeRedo[:0]
+}
+
+// unsafePush appends an entry to a stack, keeping it within the journal's depth. This DOES NOT request a lock and
+// IS NOT thread safe.
+func (j *EditJournal) unsafePush(stack []*EditJournalEntry, e *EditJournalEntry) []*EditJournalEntry {
+	stack = append(stack, e)
+	if len(stack) > j.depth {
+		stack = append(stack[:0], stack[len(stack)-j.depth:]...)
+	}
+
+	return stack
+}
+
+// pop removes the most recent entry from a stack, returning nil if it's empty.
+func (j *EditJournal) pop(stack *[]*EditJournalEntry) *EditJournalEntry {
+	j.Lock()
+	defer j.Unlock()
+
+	if len(*stack) == 0 {
+		return nil
+	}
+
+	e := (*stack)[len(*stack)-1]
+	*stack = (*stack)[:len(*stack)-1]
+
+	return e
+}
+
+// push adds an entry back onto a stack.
+func (j *EditJournal) push(stack *[]*EditJournalEntry, e *EditJournalEntry) {
+	j.Lock()
+	defer j.Unlock()
+
+	*stack = j.unsafePush(*stack, e)
+}
+
+// Undo reverts the most recent entry, on behalf of the Character, and returns it. The entry stays in the journal
+// when it can't be undone. The journal isn't locked while the edits are reverted, since that locks the objects
+// being edited.
+func (j *EditJournal) Undo(c *Character) (*EditJournalEntry, error) {
+	e := j.pop(&j.unsafeUndo)
+	if e == nil {
+		return nil, ErrNothingToUndo
+	}
+
+	if err := e.undo(c); err != nil {
+		j.push(&j.unsafeUndo, e)
+		return e, err
+	}
+
+	j.push(&j.unsafeRedo, e)
+	return e, nil
+}
+
+// Redo makes the most recently undone entry again, on behalf of the Character, and returns it.
+func (j *EditJournal) Redo(c *Character) (*EditJournalEntry, error) {
+	e := j.pop(&j.unsafeRedo)
+	if e == nil {
+		return nil, ErrNothingToRedo
+	}
+
+	if err := e.redo(c); err != nil {
+		j.push(&j.unsafeRedo, e)
+		return e, err
+	}
+
+	j.push(&j.unsafeUndo, e)
+	return e, nil
+}
+
+// registered returns whether the object is still registered under the uuid, which is no longer the case once it
+// has been removed from the world.
+func registered(uuid string, o interface{}) bool {
+	ro, _ := Armeria.registry.Get(uuid)
+	return ro == o
+}
+
+// AttributeEdit is a change to an attribute of a room, mob, item or instance.
+type AttributeEdit struct {
+	objectType ObjectType
+	object     AuditedObject
+	attribute  string
+	before     string
+	after      string
+}
+
+// NewAttributeEdit returns an AttributeEdit that sets the attribute of the object to a new value. It must be
+// created before the attribute is set, so that the value it had can be restored.
+func NewAttributeEdit(ot ObjectType, o AuditedObject, attr string, value string) *AttributeEdit {
+	return &AttributeEdit{
+		objectType: ot,
+		object:     o,
+		attribute:  attr,
+		before:     storedAttribute(o, attr),
+		after:      value,
+	}
+}
+
+// storedAttribute returns the value of an attribute as it's stored on the object, which for an instance doesn't
+// fall back to its parent.
+func storedAttribute(o AuditedObject, attr string) string {
+	switch obj := o.(type) {
+	case *MobInstance:
+		return obj.InstanceAttribute(attr)
+	case *ItemInstance:
+		return obj.InstanceAttribute(attr)
+	}
+
+	return o.Attribute(attr)
+}
+
+// Apply sets the attribute to its new value for the first time.
+func (e *AttributeEdit) Apply() error {
+	if err := e.set(e.after); err != nil {
+		return err
+	}
+
+	// Reading the value back picks up any default, such as when the attribute was cleared.
+	e.after = storedAttribute(e.object, e.attribute)
+
+	return nil
+}
+
+// Undo sets the attribute back to the value it had, as long as nothing else has changed it since.
+func (e *AttributeEdit) Undo() error {
+	return e.change(e.after, e.before)
+}
+
+// Redo sets the attribute to its new value again, as long as nothing else has changed it since.
+func (e *AttributeEdit) Redo() error {
+	return e.change(e.before, e.after)
+}
+
+// change sets the attribute to a value, when it currently has the expected value.
+func (e *AttributeEdit) change(expected string, value string) error {
+	if !e.exists() {
+		return fmt.Errorf("%s %w", e.object.Name(), ErrEditMissing)
+	} else if storedAttribute(e.object, e.attribute) != expected {
+		return fmt.Errorf("the %s of %s %w", e.attribute, e.object.Name(), ErrEditConflict)
+	}
+
+	return e.set(value)
+}
+
+// set sets the attribute, regardless of its current value.
+func (e *AttributeEdit) set(value string) error {
+	switch obj := e.object.(type) {
+	case *Room:
+		obj.SetAttribute(e.attribute, value)
+	case *Mob:
+		obj.SetAttribute(e.attribute, value)
+	case *Item:
+		obj.SetAttribute(e.attribute, value)
+	case *MobInstance:
+		return obj.SetAttribute(e.attribute, value)
+	case *ItemInstance:
+		return obj.SetAttribute(e.attribute, value)
+	default:
+		return fmt.Errorf("a %s can't be edited", e.objectType)
+	}
+
+	return nil
+}
+
+// exists returns whether the object is still part of the world.
+func (e *AttributeEdit) exists() bool {
+	switch obj := e.object.(type) {
+	case *Room:
+		return registered(obj.ID(), obj)
+	case *Mob:
+		return Armeria.mobManager.MobByName(obj.Name()) == obj
+	case *Item:
+		return Armeria.itemManager.ItemByName(obj.Name()) == obj
+	case *MobInstance:
+		return registered(obj.ID(), obj)
+	case *ItemInstance:
+		return registered(obj.ID(), obj)
+	}
+
+	return false
+}
+
+// Area returns the Area of the room that was edited, or that the instance is in.
+func (e *AttributeEdit) Area() *Area {
+	var r *Room
+	switch obj := e.object.(type) {
+	case *Room:
+		r = obj
+	case *MobInstance:
+		r = obj.Room()
+	case *ItemInstance:
+		r = obj.Room()
+	}
+
+	if r == nil {
+		return nil
+	}
+
+	return r.ParentArea
+}
+
+// RoomEdit is the creation or destruction of a room, which keeps the room itself, along with its container and its
+// script, so that it can be put back.
+type RoomEdit struct {
+	room    *Room
+	area    *Area
+	script  string
+	created bool
+}
+
+// NewRoomEdit returns a RoomEdit for a room that was just created, or that is about to be destroyed.
+func NewRoomEdit(r *Room, created bool) *RoomEdit {
+	return &RoomEdit{
+		room:    r,
+		area:    r.ParentArea,
+		script:  r.Script(),
+		created: created,
+	}
+}
+
+// Undo destroys the room if it was created, or puts it back if it was destroyed.
+func (e *RoomEdit) Undo() error {
+	if e.created {
+		return e.remove()
+	}
+	return e.restore()
+}
+
+// Redo creates the room again if it was created, or destroys it again if it was destroyed.
+func (e *RoomEdit) Redo() error {
+	if e.created {
+		return e.restore()
+	}
+	return e.remove()
+}
+
+// remove takes the room out of its area, keeping its script so that it can be written again later.
+func (e *RoomEdit) remove() error {
+	if !registered(e.room.ID(), e.room) {
+		return fmt.Errorf("the room at %s %w", e.room.Coords, ErrEditMissing)
+	} else if e.room.Here().Count() > 0 {
+		return fmt.Errorf("the room at %s %w", e.room.Coords, ErrEditRoomNotEmpty)
+	}
+
+	e.script = e.room.Script()
+	e.area.RemoveRoom(e.room)
+
+	return nil
+}
+
+// restore puts the room back where it was, along with everything that was within it and its script.
+func (e *RoomEdit) restore() error {
+	if Armeria.worldManager.AreaByName(e.area.Name()) != e.area {
+		return fmt.Errorf("the area %s %w", e.area.Name(), ErrEditMissing)
+	} else if e.area.RoomAt(e.room.Coords) != nil {
+		return fmt.Errorf("the location %s %w", e.room.Coords, ErrEditRoomTaken)
+	}
+
+	if len(e.script) > 0 {
+		if err := ioutil.WriteFile(e.room.ScriptFile(), []byte(e.script), 0644); err != nil {
+			Armeria.log.Error("error restoring room script",
+				zap.String("uuid", e.room.ID()),
+				zap.Error(err),
+			)
+		}
+	}
+
+	e.area.AddRoom(e.room)
+
+	return nil
+}
+
+// Area returns the Area the room belongs to.
+func (e *RoomEdit) Area() *Area {
+	return e.area
+}
+
+// RoomMoveEdit is the move of a room to other coordinates within its area.
+type RoomMoveEdit struct {
+	room *Room
+	from *Coords
+	to   *Coords
+}
+
+// NewRoomMoveEdit returns a RoomMoveEdit that moves the room to new coordinates. It must be created before the room
+// is moved, so that the coordinates it had can be restored.
+func NewRoomMoveEdit(r *Room, to *Coords) *RoomMoveEdit {
+	return &RoomMoveEdit{
+		room: r,
+		from: NewCoords(r.Coords.X(), r.Coords.Y(), r.Coords.Z(), r.Coords.I()),
+		to:   NewCoords(to.X(), to.Y(), to.Z(), to.I()),
+	}
+}
+
+// Apply moves the room to its new coordinates for the first time.
+func (e *RoomMoveEdit) Apply() {
+	e.room.Coords.SetFrom(e.to)
+}
+
+// Undo moves the room back, as long as it hasn't been moved since and nothing else has taken its old place.
+func (e *RoomMoveEdit) Undo() error {
+	return e.move(e.to, e.from)
+}
+
+// Redo moves the room to its new coordinates again, as long as nothing else has taken its place.
+func (e *RoomMoveEdit) Redo() error {
+	return e.move(e.from, e.to)
+}
+
+// move moves the room to the coordinates, when it is currently at the expected coordinates.
+func (e *RoomMoveEdit) move(expected *Coords, to *Coords) error {
+	if !registered(e.room.ID(), e.room) {
+		return fmt.Errorf("the room at %s %w", e.room.Coords, ErrEditMissing)
+	} else if e.room.ParentArea.RoomAt(expected) != e.room {
+		return fmt.Errorf("the room at %s %w", expected, ErrEditConflict)
+	} else if e.room.ParentArea.RoomAt(to) != nil {
+		return fmt.Errorf("the location %s %w", to, ErrEditRoomTaken)
+	}
+
+	e.room.Coords.SetFrom(to)
+
+	return nil
+}
+
+// Area returns the Area the room belongs to.
+func (e *RoomMoveEdit) Area() *Area {
+	return e.room.ParentArea
+}
+
+// InstanceEdit is the spawning or deletion of a mob or item instance within a room. A deleted instance is kept,
+// along with its attributes and inventory, so that it can be put back.
+type InstanceEdit struct {
+	instance ContainerObject
+	room     *Room
+	created  bool
+}
+
+// NewInstanceEdit returns an InstanceEdit for a mob or item instance that was just spawned in the room, or that
+// is about to be deleted from it.
+func NewInstanceEdit(o ContainerObject, r *Room, created bool) *InstanceEdit {
+	return &InstanceEdit{
+		instance: o,
+		room:     r,
+		created:  created,
+	}
+}
+
+// Undo deletes the instance if it was spawned, or puts it back if it was deleted.
+func (e *InstanceEdit) Undo() error {
+	if e.created {
+		return e.remove()
+	}
+	return e.restore()
+}
+
+// Redo spawns the instance again if it was spawned, or deletes it again if it was deleted.
+func (e *InstanceEdit) Redo() error {
+	if e.created {
+		return e.restore()
+	}
+	return e.remove()
+}
+
+// remove takes the instance out of the room and deletes it.
+func (e *InstanceEdit) remove() error {
+	if !registered(e.instance.ID(), e.instance) {
+		return fmt.Errorf("the %s %w", e.instance.Name(), ErrEditMissing)
+	} else if !e.room.Here().Remove(e.instance.ID()) {
+		return fmt.Errorf("the %s %w", e.instance.Name(), ErrEditMoved)
+	}
+
+	e.delete()
+
+	return nil
+}
+
+// restore puts the deleted instance back into the room.
+func (e *InstanceEdit) restore() error {
+	if !registered(e.room.ID(), e.room) {
+		return fmt.Errorf("the room at %s %w", e.room.Coords, ErrEditMissing)
+	}
+
+	switch obj := e.instance.(type) {
+	case *MobInstance:
+		if Armeria.mobManager.MobByName(obj.Parent.Name()) != obj.Parent {
+			return fmt.Errorf("the mob %s %w", obj.Name(), ErrEditMissing)
+		}
+		obj.Parent.RestoreInstance(obj)
+	case *ItemInstance:
+		if Armeria.itemManager.ItemByName(obj.Parent.Name()) != obj.Parent {
+			return fmt.Errorf("the item %s %w", obj.Name(), ErrEditMissing)
+		}
+		obj.Parent.RestoreInstance(obj)
+	}
+
+	if err := e.room.Here().Add(e.instance.ID()); err != nil {
+		e.delete()
+		return err
+	}
+
+	return nil
+}
+
+// delete deletes the instance, once it has been taken out of its container.
+func (e *InstanceEdit) delete() {
+	switch obj := e.instance.(type) {
+	case *MobInstance:
+		obj.Delete()
+	case *ItemInstance:
+		obj.Delete()
+	}
+}
+
+// Area returns the Area of the room the instance was in.
+func (e *InstanceEdit) Area() *Area {
+	return e.room.ParentArea
+}
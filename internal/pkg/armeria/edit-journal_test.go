@@ -0,0 +1,262 @@
+package armeria
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// testEdit is an Edit that counts how many times it has been applied, and can be made to fail.
+type testEdit struct {
+	applied *int
+	fail    bool
+}
+
+func (e *testEdit) Undo() error {
+	if e.fail {
+		return ErrEditConflict
+	}
+	*e.applied--
+	return nil
+}
+
+func (e *testEdit) Redo() error {
+	if e.fail {
+		return ErrEditConflict
+	}
+	*e.applied++
+	return nil
+}
+
+func (e *testEdit) Area() *Area {
+	return nil
+}
+
+func TestEditJournal(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	c := Armeria.characterManager.CharacterByName("Alice")
+	applied := 0
+	j := NewEditJournal(2)
+	for _, cmd := range []string{"/one", "/two", "/three"} {
+		applied++
+		j.Record(&EditJournalEntry{Command: cmd, Edits: []Edit{&testEdit{applied: &applied}}})
+	}
+
+	// Only the two most recent entries are kept.
+	for _, expected := range []string{"/three", "/two"} {
+		if e, err := j.Undo(c); err != nil || e.Command != expected {
+			t.Fatalf("expected to undo %s, got %v (%v)", expected, e, err)
+		}
+	}
+	if _, err := j.Undo(c); err != ErrNothingToUndo {
+		t.Errorf("expected nothing to undo, got %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("expected 1 edit to still be applied, got %d", applied)
+	}
+
+	if e, err := j.Redo(c); err != nil || e.Command != "/two" {
+		t.Fatalf("expected to redo /two, got %v (%v)", e, err)
+	}
+
+	// A new entry means nothing can be redone.
+	j.Record(&EditJournalEntry{Command: "/four"})
+	if _, err := j.Redo(c); err != ErrNothingToRedo {
+		t.Errorf("expected nothing to redo, got %v", err)
+	}
+
+	// An entry is either undone completely, or not at all.
+	applied = 2
+	j.Record(&EditJournalEntry{Command: "/five", Edits: []Edit{
+		&testEdit{applied: &applied, fail: true},
+		&testEdit{applied: &applied},
+		&testEdit{applied: &applied},
+	}})
+	if _, err := j.Undo(c); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("expected the edits to have been made again, got %d", applied)
+	}
+	if e, _ := j.Undo(c); e == nil || e.Command != "/five" {
+		t.Errorf("expected /five to stay in the journal, got %v", e)
+	}
+}
+
+func TestHeadlessUndoAttributes(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("builder")
+	Armeria.characterManager.CharacterByName("Bob").GrantRole("builder")
+	room := alice.Player().Character().Room()
+
+	alice.Send("/undo")
+	expectText(t, alice, "You haven't changed anything that can be undone.")
+
+	alice.Send("/room set . title Old Square")
+	alice.Send("/room set . description")
+	alice.Send("/undo")
+	expectText(t, alice, "You undid /room set . description.")
+	if room.Attribute(AttributeDescription) != "A quiet town square." {
+		t.Errorf("expected the description to be back, got %q", room.Attribute(AttributeDescription))
+	}
+
+	alice.Send("/redo")
+	expectText(t, alice, "You redid /room set . description.")
+	alice.Send("/undo")
+	alice.Send("/undo")
+	if room.Attribute(AttributeTitle) != "Town Square" {
+		t.Errorf("expected the title to be back, got %q", room.Attribute(AttributeTitle))
+	}
+	alice.Send("/redo")
+
+	// Nothing is undone once someone else has changed the same attribute.
+	bob.Send("/room set . title New Square")
+	alice.Clear()
+	alice.Send("/undo")
+	expectText(t, alice, "/room set . title Old Square couldn't be undone, since the title of New Square has been "+
+		"changed by something else.")
+	if room.Attribute(AttributeTitle) != "New Square" {
+		t.Errorf("expected the title to be left alone, got %q", room.Attribute(AttributeTitle))
+	}
+
+	// Nor once the character can no longer build there.
+	Armeria.characterManager.CharacterByName("Bob").RevokeRole("builder")
+	Armeria.characterManager.CharacterByName("Bob").GrantRole("contributor")
+	bob.Send("/undo")
+	expectText(t, bob, "since you no longer have permission to build in Test Area.")
+}
+
+func TestHeadlessUndoRooms(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("builder")
+	area := alice.Player().Character().Room().ParentArea
+	east := NewCoords(1, 0, 0, 0)
+
+	alice.Send("/room create east")
+	created := area.RoomAt(east)
+	if created == nil {
+		t.Fatal("expected a room to the east")
+	}
+
+	alice.Send("/undo")
+	if area.RoomAt(east) != nil {
+		t.Error("expected the room to be gone")
+	}
+	alice.Send("/redo")
+	if area.RoomAt(east) != created {
+		t.Error("expected the same room to be back")
+	}
+
+	// A destroyed room comes back with its script.
+	WriteRoomScript(created, "function character_entered() end")
+	alice.Send("/room destroy east")
+	if _, err := os.Stat(created.ScriptFile()); !os.IsNotExist(err) {
+		t.Error("expected the script to have been deleted")
+	}
+
+	alice.Send("/undo")
+	expectText(t, alice, "You undid /room destroy east.")
+	if area.RoomAt(east) != created {
+		t.Fatal("expected the destroyed room to be back")
+	}
+	if b, _ := ioutil.ReadFile(created.ScriptFile()); string(b) != "function character_entered() end" {
+		t.Errorf("expected the script to be back, got %q", b)
+	}
+	if o, _ := Armeria.registry.Get(created.ID()); o != created {
+		t.Error("expected the room to be registered again")
+	}
+
+	// A room that something has moved into can't be taken away.
+	alice.Send("/undo")
+	alice.Send("/redo")
+	alice.Send("/move east")
+	alice.Send("/undo")
+	expectText(t, alice, "since the room at 1,0,0 isn't empty.")
+}
+
+func TestHeadlessUndoRoomMove(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("builder")
+	square := alice.Player().Character().Room()
+	area := square.ParentArea
+	market := area.RoomAt(NewCoords(0, 1, 0, 0))
+
+	// The Market leads back to the square, so moving it relinks the square to the Market's new location.
+	alice.Send("/move north")
+	alice.Send("/room set . south 0,0,0")
+	alice.Send("/room move north")
+	expectText(t, alice, "The room has been moved.")
+	if area.RoomAt(NewCoords(0, 2, 0, 0)) != market || square.Attribute(NorthDirection) != "0,2,0" {
+		t.Fatalf("expected the Market to have moved and been linked, got %s", square.Attribute(NorthDirection))
+	}
+
+	// The move and the new exit are undone together.
+	alice.Send("/undo")
+	expectText(t, alice, "You undid /room move north.")
+	if area.RoomAt(NewCoords(0, 1, 0, 0)) != market {
+		t.Error("expected the Market to be back where it was")
+	}
+	if exit := square.Attribute(NorthDirection); exit != "" {
+		t.Errorf("expected the square's exit to be removed, got %q", exit)
+	}
+
+	alice.Send("/redo")
+	if area.RoomAt(NewCoords(0, 2, 0, 0)) != market || square.Attribute(NorthDirection) != "0,2,0" {
+		t.Error("expected the move to be redone")
+	}
+
+	// The room can't be moved back once another room has taken its place.
+	Armeria.worldManager.CreateRoom(area, NewCoords(0, 1, 0, 0))
+	alice.Send("/undo")
+	expectText(t, alice, "since the location 0,1,0 has been taken by another room.")
+}
+
+func TestHeadlessUndoWipe(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("builder")
+	here := alice.Player().Character().Room().Here()
+
+	alice.Send("/item spawn Apple")
+	apple := here.Items()[len(here.Items())-1]
+	_ = apple.SetAttribute(AttributeDescription, "A bruised apple.")
+	before := here.Count()
+
+	alice.Send("/wipe")
+	if here.Count() != 2 {
+		t.Fatalf("expected only the characters to be left, got %d", here.Count())
+	}
+
+	alice.Send("/undo")
+	expectText(t, alice, "You undid /wipe.")
+	if here.Count() != before || here.Get(apple.ID()).Object != apple {
+		t.Fatalf("expected everything to be back, got %d", here.Count())
+	}
+	if apple.Attribute(AttributeDescription) != "A bruised apple." {
+		t.Errorf("expected the apple to keep its description, got %q", apple.Attribute(AttributeDescription))
+	}
+	if o, _ := Armeria.registry.Get(apple.ID()); o != apple {
+		t.Error("expected the apple to be registered again")
+	}
+
+	alice.Send("/redo")
+	if here.Count() != 2 {
+		t.Errorf("expected the wipe to be redone, got %d", here.Count())
+	}
+
+	// A spawned apple that was picked up can't be unspawned.
+	alice.Send("/item spawn Apple")
+	alice.Send("/get Apple")
+	alice.Send("/undo")
+	expectText(t, alice, "since the Apple is no longer where it was.")
+}
@@ -0,0 +1,282 @@
+package armeria
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// EventLogDir is the directory within the data directory where events are recorded. The events run after a
+// snapshot was taken are written to a file named after the snapshot, such as 20261017-150405-v11.log, so that
+// they can be replayed on top of it.
+const EventLogDir string = "events"
+
+// eventLogExtension is the extension of each file within the event log.
+const eventLogExtension string = ".log"
+
+var (
+	// ErrNoRecordedEvents is an error for when no events were recorded after a snapshot was taken.
+	ErrNoRecordedEvents = errors.New("no events were recorded after that snapshot")
+)
+
+// An EventRecord is a single event as it's written to the event log. Only what's needed to replay it is kept, so
+// a command is recorded without any of its arguments that shouldn't be logged, such as passwords.
+type EventRecord struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Character string    `json:"character,omitempty"`
+	Command   string    `json:"command,omitempty"`
+	Snapshot  string    `json:"snapshot,omitempty"`
+}
+
+// EventLog records every event run on the EventLoop, as one JSON object per line, so that what happened after a
+// snapshot was taken can be replayed.
+type EventLog struct {
+	sync.Mutex
+	dir        string
+	unsafeName string
+	unsafeFile *os.File
+}
+
+// NewEventLog returns an EventLog that writes to the directory. Events are written to a file named after the time
+// the server started, until the first snapshot is taken.
+func NewEventLog(dir string) *EventLog {
+	return &EventLog{
+		dir:        dir,
+		unsafeName: "boot-" + time.Now().Format("20060102-150405"),
+	}
+}
+
+// Record appends the event to the log. When a snapshot was taken during the event, the events that follow are
+// written to the snapshot's file, and the files of snapshots that are no longer kept are removed.
+func (l *EventLog) Record(e *Event) {
+	r := &EventRecord{
+		Seq:       e.Seq,
+		Time:      time.Now(),
+		Kind:      e.Kind,
+		Name:      e.Name,
+		Character: e.Character,
+		Command:   e.Command,
+		Snapshot:  e.Snapshot,
+	}
+
+	l.Lock()
+	err := l.unsafeWrite(r)
+	if err == nil && len(r.Snapshot) > 0 {
+		err = l.unsafeSwitch(r.Snapshot)
+	}
+	l.Unlock()
+
+	if err != nil {
+		Armeria.log.Error("error writing to event log",
+			zap.Uint64("seq", r.Seq),
+			zap.Error(err),
+		)
+	}
+
+	if len(r.Snapshot) > 0 {
+		l.prune()
+	}
+}
+
+// unsafeWrite writes the record to the file being written to, opening it first if needed. This DOES NOT request
+// a lock and IS NOT thread safe.
+func (l *EventLog) unsafeWrite(r *EventRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if l.unsafeFile == nil {
+		if err := os.MkdirAll(l.dir, 0755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(l.filePath(l.unsafeName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		l.unsafeFile = f
+	}
+
+	_, err = l.unsafeFile.Write(line)
+	return err
+}
+
+// unsafeSwitch closes the file being written to, so that the next event is written to the file with the name.
+// This DOES NOT request a lock and IS NOT thread safe.
+func (l *EventLog) unsafeSwitch(name string) error {
+	var err error
+	if l.unsafeFile != nil {
+		err = l.unsafeFile.Close()
+		l.unsafeFile = nil
+	}
+	l.unsafeName = name
+
+	return err
+}
+
+// prune removes the files of the snapshots that are no longer kept, along with the file written to before the
+// first snapshot was taken.
+func (l *EventLog) prune() {
+	snapshots, err := Armeria.snapshotManager.Snapshots()
+	if err != nil {
+		return
+	}
+
+	kept := make(map[string]bool)
+	for _, s := range snapshots {
+		kept[s.Name] = true
+	}
+
+	files, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), eventLogExtension)
+		if name != l.unsafeName && !kept[name] {
+			_ = os.Remove(filepath.Join(l.dir, f.Name()))
+		}
+	}
+}
+
+// filePath returns the path to the file with the name.
+func (l *EventLog) filePath(name string) string {
+	return filepath.Join(l.dir, name+eventLogExtension)
+}
+
+// Close closes the file being written to. The next event recorded will open it again.
+func (l *EventLog) Close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.unsafeFile == nil {
+		return nil
+	}
+
+	err := l.unsafeFile.Close()
+	l.unsafeFile = nil
+
+	return err
+}
+
+// ReplayEvents restores a snapshot into a temporary directory, then replays the commands and ticks recorded after
+// it was taken on a headless game, writing what each character was shown to w. The data directory itself is never
+// changed. Anything that wasn't recorded, such as random rolls, the timing of scripts and what was typed before a
+// character was played, can make the replay differ from what really happened.
+func ReplayEvents(name string, w io.Writer) error {
+	m := NewSnapshotManager(filepath.Join(Armeria.dataPath, SnapshotDir), Armeria.snapshotRetention)
+	s, err := m.Snapshot(name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Join(Armeria.dataPath, EventLogDir, s.Name+eventLogExtension))
+	if os.IsNotExist(err) {
+		return ErrNoRecordedEvents
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	contents, err := s.Contents()
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "armeria-replay")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewJSONStore(dir)
+	if err := contents.restore(store, dir); err != nil {
+		return err
+	}
+	_ = store.Close()
+
+	InitHeadless(dir, Armeria.startingRoom, Armeria.log)
+
+	clients := make(map[string]*HeadlessClient)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r EventRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return err
+		}
+
+		switch {
+		case r.Kind == EventMessage && len(r.Character) > 0 && len(r.Command) > 0:
+			hc, err := replayClient(clients, r.Character)
+			if err != nil {
+				_, _ = fmt.Fprintf(w, "#%d %s: %s skipped, since %s\n", r.Seq, r.Character, r.Command, err)
+				continue
+			}
+
+			hc.Send(r.Command)
+			_, _ = fmt.Fprintf(w, "#%d %s: %s\n", r.Seq, r.Character, r.Command)
+			for _, text := range hc.Texts() {
+				_, _ = fmt.Fprintf(w, "  %s\n", strings.ReplaceAll(text, "\n", "\n  "))
+			}
+			hc.Clear()
+		case r.Kind == EventTick:
+			for _, t := range Armeria.tickManager.Tickers() {
+				if t.Name == r.Name {
+					t.Run()
+					SettleEventLoop()
+					_, _ = fmt.Fprintf(w, "#%d tick %s\n", r.Seq, r.Name)
+				}
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// replayClient returns the HeadlessClient playing the character, connecting a new one when the character isn't
+// being played yet.
+func replayClient(clients map[string]*HeadlessClient, name string) (*HeadlessClient, error) {
+	if hc := clients[name]; hc != nil && hc.Player().Character() != nil {
+		return hc, nil
+	}
+
+	c := Armeria.characterManager.CharacterByName(name)
+	if c == nil {
+		return nil, errors.New("the character doesn't exist")
+	}
+
+	hc := NewHeadlessClient()
+	var err error
+	Armeria.RunEvent(EventMessage, "replay", func() {
+		hc.Player().AttachAccount(c.Account())
+		err = hc.Player().PlayCharacter(c)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	SettleEventLoop()
+	hc.Clear()
+	clients[name] = hc
+
+	return hc, nil
+}
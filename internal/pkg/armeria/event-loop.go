@@ -0,0 +1,243 @@
+package armeria
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Event kinds.
+const (
+	EventMessage string = "message"
+	EventTick    string = "tick"
+	EventScript  string = "script"
+	EventHTTP    string = "http"
+)
+
+// An Event is a single unit of work, such as a command or a tick, that is run on the EventLoop. The Seq is the
+// order in which the event was queued, and so the order in which it was run. The Character, Command and Snapshot
+// are filled in while the event runs, for the event log.
+type Event struct {
+	Seq       uint64
+	Kind      string
+	Name      string
+	Character string
+	Command   string
+	Snapshot  string
+	Run       func()
+	done      chan struct{}
+}
+
+// An EventLoop runs queued events one at a time, in the order they were queued, on a single goroutine. When it
+// is enabled, everything that changes the game world goes through the loop, so the outcome no longer depends on
+// how goroutines happen to be scheduled, and only socket I/O runs concurrently.
+type EventLoop struct {
+	sync.Mutex
+	unsafeQueue     []*Event
+	unsafeSeq       uint64
+	unsafeProcessed uint64
+	unsafeCurrent   *Event
+	unsafeGoroutine uint64
+	wake            chan struct{}
+	eventLog        *EventLog
+}
+
+// NewEventLoop creates a new EventLoop and starts processing events. Each event is written to the EventLog once
+// it has run, unless the EventLog is nil.
+func NewEventLoop(el *EventLog) *EventLoop {
+	l := &EventLoop{
+		wake:     make(chan struct{}, 1),
+		eventLog: el,
+	}
+
+	go l.run()
+
+	Armeria.log.Info("event loop started")
+
+	return l
+}
+
+// Queue adds an event to the end of the queue and returns without waiting for it to run. The queue is unbounded,
+// so events may safely be queued from within another event.
+func (l *EventLoop) Queue(kind, name string, fn func()) *Event {
+	l.Lock()
+	l.unsafeSeq = l.unsafeSeq + 1
+	e := &Event{
+		Seq:  l.unsafeSeq,
+		Kind: kind,
+		Name: name,
+		Run:  fn,
+		done: make(chan struct{}),
+	}
+	l.unsafeQueue = append(l.unsafeQueue, e)
+	l.Unlock()
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+
+	return e
+}
+
+// QueueAndWait adds an event to the end of the queue and waits for it to run. When it's called from within an
+// event, such as a tick that runs a command, fn is run straight away as part of that event instead, since the loop
+// would otherwise be left waiting on itself.
+func (l *EventLoop) QueueAndWait(kind, name string, fn func()) {
+	if l.running() {
+		fn()
+		return
+	}
+
+	<-l.Queue(kind, name, fn).done
+}
+
+// running returns true if it's called from within an event.
+func (l *EventLoop) running() bool {
+	l.Lock()
+	current, goroutine := l.unsafeCurrent, l.unsafeGoroutine
+	l.Unlock()
+
+	return current != nil && goroutine == goroutineID()
+}
+
+// goroutineID returns the id of the calling goroutine, which is read from the top of its stack trace.
+func goroutineID() uint64 {
+	b := make([]byte, 64)
+	b = bytes.TrimPrefix(b[:runtime.Stack(b, false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// Pending returns the number of events waiting to run.
+func (l *EventLoop) Pending() int {
+	l.Lock()
+	defer l.Unlock()
+	return len(l.unsafeQueue)
+}
+
+// Processed returns the number of events that have been run.
+func (l *EventLoop) Processed() uint64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.unsafeProcessed
+}
+
+// run processes events until the server exits.
+func (l *EventLoop) run() {
+	l.Lock()
+	l.unsafeGoroutine = goroutineID()
+	l.Unlock()
+
+	for range l.wake {
+		for {
+			l.Lock()
+			if len(l.unsafeQueue) == 0 {
+				l.Unlock()
+				break
+			}
+			e := l.unsafeQueue[0]
+			l.unsafeQueue[0] = nil
+			l.unsafeQueue = l.unsafeQueue[1:]
+			l.Unlock()
+
+			l.process(e)
+		}
+	}
+}
+
+// process runs a single event and lets anything waiting on it know that it is complete.
+func (l *EventLoop) process(e *Event) {
+	l.Lock()
+	l.unsafeCurrent = e
+	l.Unlock()
+
+	start := time.Now()
+	l.execute(e)
+
+	Armeria.log.Debug("event processed",
+		zap.Uint64("seq", e.Seq),
+		zap.String("kind", e.Kind),
+		zap.String("name", e.Name),
+		zap.Duration("duration", time.Since(start)),
+	)
+
+	l.Lock()
+	l.unsafeCurrent = nil
+	l.unsafeProcessed = l.unsafeProcessed + 1
+	l.Unlock()
+
+	if l.eventLog != nil {
+		l.eventLog.Record(e)
+	}
+
+	close(e.done)
+}
+
+// execute runs the event. A panic is recovered and logged, so that a single bad command, tick or script callback
+// can't stop the loop, and leave everything waiting on it forever.
+func (l *EventLoop) execute(e *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			Armeria.log.Error("event panicked",
+				zap.Uint64("seq", e.Seq),
+				zap.String("kind", e.Kind),
+				zap.String("name", e.Name),
+				zap.Any("panic", r),
+				zap.Stack("stack"),
+			)
+		}
+	}()
+
+	e.Run()
+}
+
+// annotate lets fn fill in the details of the event that is running, if there is one.
+func (l *EventLoop) annotate(fn func(e *Event)) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.unsafeCurrent != nil {
+		fn(l.unsafeCurrent)
+	}
+}
+
+// RunEvent runs fn on the event loop and waits for it to complete. When the event loop is disabled, or RunEvent is
+// called from within an event, fn is run immediately on the calling goroutine.
+func (gs *GameState) RunEvent(kind, name string, fn func()) {
+	if gs.eventLoop == nil {
+		fn()
+		return
+	}
+
+	gs.eventLoop.QueueAndWait(kind, name, fn)
+}
+
+// AnnotateEvent lets fn fill in the details of the event that is running, such as the command it ran, for the event
+// log. Nothing happens when the event loop is disabled or no event is running.
+func (gs *GameState) AnnotateEvent(fn func(e *Event)) {
+	if gs.eventLoop == nil {
+		return
+	}
+
+	gs.eventLoop.annotate(fn)
+}
+
+// GoEvent runs fn on the event loop once the events ahead of it are complete, without waiting. When the event
+// loop is disabled, fn is run on a new goroutine.
+func (gs *GameState) GoEvent(kind, name string, fn func()) {
+	if gs.eventLoop == nil {
+		go fn()
+		return
+	}
+
+	gs.eventLoop.Queue(kind, name, fn)
+}
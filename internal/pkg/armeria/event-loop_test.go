@@ -0,0 +1,235 @@
+package armeria
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"go.uber.org/zap"
+)
+
+func TestEventLoopOrder(t *testing.T) {
+	Armeria = &GameState{log: zap.NewNop()}
+	Armeria.eventLoop = NewEventLoop(nil)
+
+	// Events queued from within an event run after everything already queued.
+	var order []string
+	Armeria.RunEvent(EventMessage, "first", func() {
+		order = append(order, "first")
+		Armeria.GoEvent(EventScript, "nested", func() {
+			order = append(order, "nested")
+		})
+	})
+	Armeria.RunEvent(EventMessage, "second", func() {
+		order = append(order, "second")
+	})
+
+	expected := []string{"first", "nested", "second"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestEventLoopPanic(t *testing.T) {
+	Armeria = &GameState{log: zap.NewNop()}
+	Armeria.eventLoop = NewEventLoop(nil)
+
+	// A panicking event still completes, and the loop carries on with the next one.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Armeria.RunEvent(EventMessage, "panic", func() {
+			panic("something went wrong")
+		})
+	}()
+	waitOrDeadlock(t, &wg)
+
+	ran := false
+	Armeria.RunEvent(EventMessage, "after", func() {
+		ran = true
+	})
+	if !ran {
+		t.Error("expected the event after the panic to run")
+	}
+	if n := Armeria.eventLoop.Processed(); n != 2 {
+		t.Errorf("expected 2 processed events, got %d", n)
+	}
+}
+
+func TestEventLoopNested(t *testing.T) {
+	Armeria = &GameState{log: zap.NewNop()}
+	Armeria.eventLoop = NewEventLoop(nil)
+
+	// Waiting on an event from within an event runs it straight away, rather than waiting on itself.
+	var order []string
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Armeria.RunEvent(EventTick, "outer", func() {
+			order = append(order, "outer")
+			Armeria.RunEvent(EventMessage, "inner", func() {
+				order = append(order, "inner")
+			})
+			order = append(order, "outer again")
+		})
+	}()
+	waitOrDeadlock(t, &wg)
+
+	expected := []string{"outer", "inner", "outer again"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+	if n := Armeria.eventLoop.Processed(); n != 1 {
+		t.Errorf("expected the inner event to run as part of the outer one, got %d processed events", n)
+	}
+
+	// Other goroutines still wait their turn while an event is running.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	Armeria.GoEvent(EventTick, "slow", func() {
+		close(started)
+		<-release
+		order = append(order, "slow")
+	})
+	<-started
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Armeria.RunEvent(EventMessage, "waiting", func() {
+			order = append(order, "waiting")
+		})
+	}()
+	close(release)
+	waitOrDeadlock(t, &wg)
+
+	if last := order[len(order)-2:]; last[0] != "slow" || last[1] != "waiting" {
+		t.Errorf("expected the waiting event to run after the slow one, got %v", order)
+	}
+}
+
+func TestEventLoopSequence(t *testing.T) {
+	Armeria = &GameState{log: zap.NewNop()}
+	Armeria.eventLoop = NewEventLoop(nil)
+
+	// Events run one at a time, so the unsynchronized slices are safe, and in the order they were queued.
+	var wg sync.WaitGroup
+	runs := make([][]int, 10)
+	for i := range runs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				n := n
+				Armeria.eventLoop.Queue(EventTick, "count", func() {
+					runs[i] = append(runs[i], n)
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	Armeria.RunEvent(EventTick, "wait", func() {})
+
+	for i, run := range runs {
+		if len(run) != 100 {
+			t.Fatalf("expected 100 events from producer %d to run, got %d", i, len(run))
+		}
+		for n := range run {
+			if run[n] != n {
+				t.Fatalf("producer %d's events ran out of order: %v", i, run)
+			}
+		}
+	}
+	if n := Armeria.eventLoop.Processed(); n != 1001 {
+		t.Errorf("expected 1001 processed events, got %d", n)
+	}
+}
+
+func TestEventLogReplay(t *testing.T) {
+	dir := useHeadlessState(t)
+	defer os.RemoveAll(dir)
+
+	Armeria.eventLog = NewEventLog(filepath.Join(dir, EventLogDir))
+	Armeria.eventLoop = NewEventLoop(Armeria.eventLog)
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+	alice.Send("/snapshot create")
+	snapshots, _ := Armeria.snapshotManager.Snapshots()
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(snapshots))
+	}
+	name := snapshots[0].Name
+
+	alice.Send("/room set . title Old Square")
+	alice.Send("/look")
+	SettleEventLoop()
+
+	// Passwords are never recorded, even before the snapshot was taken.
+	files, _ := ioutil.ReadDir(filepath.Join(dir, EventLogDir))
+	for _, f := range files {
+		b, _ := ioutil.ReadFile(filepath.Join(dir, EventLogDir, f.Name()))
+		if strings.Contains(string(b), "secret") {
+			t.Errorf("expected %s not to contain the password, got:\n%s", f.Name(), b)
+		}
+	}
+
+	var sb strings.Builder
+	if err := ReplayEvents(name, &sb); err != nil {
+		t.Fatalf("error replaying events: %s", err)
+	}
+
+	out := sb.String()
+	for _, expected := range []string{"Alice: /room set . title Old Square", "Alice: /look\n  Old Square"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected the replay to contain %q, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "/snapshot create") {
+		t.Errorf("expected only the events after the snapshot to be replayed, got:\n%s", out)
+	}
+}
+
+func TestScriptWriteEvent(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	c := Armeria.characterManager.CharacterByName("Alice")
+	c.GrantRole("admin")
+	_, token := Armeria.sessionManager.CreateSession(c.Account(), "test", time.Hour)
+
+	before := Armeria.eventLoop.Processed()
+	req := mux.SetURLVars(
+		httptest.NewRequest("POST", "/script/mob/Merchant", strings.NewReader("-- changed")),
+		map[string]string{"objectType": "mob", "objectName": "Merchant"},
+	)
+	req.Header.Set(AccessTokenHeader, token)
+	rec := httptest.NewRecorder()
+	HandleScriptWrite(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if Armeria.eventLoop.Processed() == before {
+		t.Error("expected the script to be written within an event")
+	}
+	if s := ReadMobScript(Armeria.mobManager.MobByName("Merchant")); s != "-- changed" {
+		t.Errorf("expected the script to have changed, got %q", s)
+	}
+	expectText(t, alice, "The script has been saved to Merchant.")
+}
@@ -0,0 +1,197 @@
+package armeria
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// InitHeadless loads the game state from the data directory without serving any traffic, so that the game can be
+// driven in-process by HeadlessClients, such as within tests. The tickers are not started, and the event loop is
+// always enabled, so that a command and the script callbacks it causes have all finished once Send returns.
+func InitHeadless(dataPath string, startingRoom string, logger *zap.Logger) {
+	Armeria = &GameState{
+		dataPath:         dataPath,
+		storeType:        StoreTypeJSON,
+		objectImagesPath: dataPath + "/object-images",
+		startingRoom:     startingRoom,
+		log:              logger,
+	}
+
+	verifySchemaVersion()
+
+	loadGameState(true)
+}
+
+// Force verify that headlessTransport implements Transport.
+var _ Transport = (*headlessTransport)(nil)
+
+// A headlessTransport is a Transport that records the messages written to it. Nothing is ever read from it, since
+// a HeadlessClient sends its commands straight to the game.
+type headlessTransport struct {
+	sync.Mutex
+	unsafeMessages []*OutgoingDataStructure
+	closed         chan struct{}
+	closeOnce      sync.Once
+}
+
+// ReadMessage blocks until the transport is closed.
+func (t *headlessTransport) ReadMessage() (*IncomingDataStructure, error) {
+	<-t.closed
+	return nil, io.EOF
+}
+
+// WriteMessage records the message.
+func (t *headlessTransport) WriteMessage(m *OutgoingDataStructure) error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.unsafeMessages = append(t.unsafeMessages, m)
+	return nil
+}
+
+// Close closes the transport.
+func (t *headlessTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+	return nil
+}
+
+// RemoteAddr returns a placeholder address, since the client is within the same process.
+func (t *headlessTransport) RemoteAddr() string {
+	return "headless"
+}
+
+// A HeadlessClient is a fake connection to the game, driven by code rather than a websocket, which records every
+// message sent to its Player.
+type HeadlessClient struct {
+	player    *Player
+	transport *headlessTransport
+}
+
+// NewHeadlessClient connects a new HeadlessClient to the game. The Player's pumps are never started; messages
+// sent to it are written to the transport whenever the client's messages are read.
+func NewHeadlessClient() *HeadlessClient {
+	t := &headlessTransport{
+		closed: make(chan struct{}),
+	}
+
+	return &HeadlessClient{
+		player:    Armeria.playerManager.NewPlayer(t),
+		transport: t,
+	}
+}
+
+// Player returns the Player connected by the client.
+func (hc *HeadlessClient) Player() *Player {
+	return hc.player
+}
+
+// Send processes a command, such as "/look", as if the player had typed it, and waits for it to finish along with
+// anything it queued on the event loop.
+func (hc *HeadlessClient) Send(command string) {
+	Armeria.RunEvent(EventMessage, "command", func() {
+		Armeria.commandManager.ProcessCommand(hc.player, strings.TrimPrefix(command, "/"), true)
+	})
+
+	SettleEventLoop()
+}
+
+// Disconnect disconnects the client from the game, as if the socket had been closed.
+func (hc *HeadlessClient) Disconnect() {
+	Armeria.RunEvent(EventMessage, "disconnect", func() {
+		Armeria.playerManager.DisconnectPlayer(hc.player)
+	})
+
+	SettleEventLoop()
+}
+
+// Messages returns every message sent to the client since it connected, or since it was last cleared.
+func (hc *HeadlessClient) Messages() []*OutgoingDataStructure {
+	hc.player.FlushWrites()
+
+	hc.transport.Lock()
+	defer hc.transport.Unlock()
+
+	messages := make([]*OutgoingDataStructure, len(hc.transport.unsafeMessages))
+	copy(messages, hc.transport.unsafeMessages)
+
+	return messages
+}
+
+// Clear forgets the messages sent to the client so far.
+func (hc *HeadlessClient) Clear() {
+	hc.player.FlushWrites()
+
+	hc.transport.Lock()
+	defer hc.transport.Unlock()
+
+	hc.transport.unsafeMessages = nil
+}
+
+// Payloads returns the payloads of the messages sent to the client for a client action, such as "showText".
+func (hc *HeadlessClient) Payloads(action string) []interface{} {
+	var payloads []interface{}
+	for _, m := range hc.Messages() {
+		if m.Action == action {
+			payloads = append(payloads, m.Payload)
+		}
+	}
+
+	return payloads
+}
+
+// Texts returns the text shown to the client, as plain text.
+func (hc *HeadlessClient) Texts() []string {
+	var texts []string
+	for _, p := range hc.Payloads("showText") {
+		texts = append(texts, strings.TrimSpace(p.(*RichText).Plain()))
+	}
+
+	return texts
+}
+
+// SawText returns whether any of the text shown to the client contains the substring.
+func (hc *HeadlessClient) SawText(substr string) bool {
+	for _, text := range hc.Texts() {
+		if strings.Contains(text, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoomObjects returns the names of the objects from the most recent room objects sent to the client.
+func (hc *HeadlessClient) RoomObjects() []string {
+	payloads := hc.Payloads("setRoomObjects")
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	objects := payloads[len(payloads)-1].([]*RoomObjectData)
+	names := make([]string, len(objects))
+	for i, o := range objects {
+		names[i] = o.Name
+	}
+
+	return names
+}
+
+// SettleEventLoop waits until the event loop has no events left to run, including any queued by the events it
+// was waiting on. It must never be called from within an event.
+func SettleEventLoop() {
+	if Armeria.eventLoop == nil {
+		return
+	}
+
+	for {
+		Armeria.RunEvent(EventMessage, "settle", func() {})
+		if Armeria.eventLoop.Pending() == 0 {
+			return
+		}
+	}
+}
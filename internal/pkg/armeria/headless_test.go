@@ -0,0 +1,438 @@
+package armeria
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const headlessFixturePath string = "testdata/headless"
+
+// copyDir copies the files within a directory, and its sub-directories, into another directory.
+func copyDir(t *testing.T, from, to string) {
+	files, err := ioutil.ReadDir(from)
+	if err != nil {
+		t.Fatalf("error reading fixture: %s", err)
+	}
+
+	for _, f := range files {
+		src := filepath.Join(from, f.Name())
+		dst := filepath.Join(to, f.Name())
+		if f.IsDir() {
+			if err := os.Mkdir(dst, 0755); err != nil {
+				t.Fatalf("error writing fixture: %s", err)
+			}
+			copyDir(t, src, dst)
+			continue
+		}
+
+		b, err := ioutil.ReadFile(src)
+		if err != nil {
+			t.Fatalf("error reading fixture: %s", err)
+		}
+		if err := ioutil.WriteFile(dst, b, 0644); err != nil {
+			t.Fatalf("error writing fixture: %s", err)
+		}
+	}
+}
+
+// useHeadlessState boots a headless game from a copy of the fixture data within a temporary directory, which the
+// caller is responsible for removing. The fixture has the account "tester" (password "secret") with the characters
+// Alice and Bob, who start in the Town Square with an Apple on the floor. The Market, to the north, has a Merchant
+// who opens the General shop when someone says "shop".
+func useHeadlessState(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "armeria-headless")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+
+	copyDir(t, headlessFixturePath, dir)
+
+	InitHeadless(dir, "Test Area,0,0,0", zap.NewNop())
+
+	return dir
+}
+
+// playAs connects a new client, logs in to the fixture account and plays the character.
+func playAs(t *testing.T, name string) *HeadlessClient {
+	hc := NewHeadlessClient()
+	hc.Send("/login tester secret")
+	hc.Send("/play " + name)
+
+	if !hc.SawText("You've entered Armeria as " + name + "!") {
+		t.Fatalf("expected to play as %s, got %q", name, hc.Texts())
+	}
+
+	hc.Clear()
+	return hc
+}
+
+// expectText fails the test unless the client was shown text containing the substring.
+func expectText(t *testing.T, hc *HeadlessClient, substr string) {
+	t.Helper()
+	if !hc.SawText(substr) {
+		t.Errorf("expected text containing %q, got %q", substr, hc.Texts())
+	}
+}
+
+// expectRoomObjects fails the test unless the client's most recent room objects match the names, in any order.
+func expectRoomObjects(t *testing.T, hc *HeadlessClient, names ...string) {
+	t.Helper()
+	objects := hc.RoomObjects()
+
+	matches := len(objects) == len(names)
+	for _, name := range names {
+		found := false
+		for _, o := range objects {
+			if o == name {
+				found = true
+			}
+		}
+		matches = matches && found
+	}
+
+	if !matches {
+		t.Errorf("expected room objects %v, got %v", names, objects)
+	}
+}
+
+// runTicker runs a single iteration of the named ticker, and waits for the events it queued.
+func runTicker(t *testing.T, name string) {
+	t.Helper()
+	for _, tk := range Armeria.tickManager.Tickers() {
+		if tk.Name == name {
+			tk.Run()
+			SettleEventLoop()
+			return
+		}
+	}
+
+	t.Fatalf("no ticker named %s", name)
+}
+
+func TestHeadlessLogin(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	hc := NewHeadlessClient()
+	hc.Send("/login tester wrong")
+	expectText(t, hc, "Password incorrect for that account.")
+
+	hc.Send("/login nobody secret")
+	expectText(t, hc, "Account not found.")
+
+	hc.Clear()
+	hc.Send("/login tester secret")
+	expectText(t, hc, "You've logged in to tester.")
+
+	hc.Send("/play Alice")
+	expectText(t, hc, "You've entered Armeria as Alice!")
+	expectText(t, hc, "Town Square")
+	expectRoomObjects(t, hc, "Alice", "Apple")
+
+	// A second client can't play the same character.
+	other := NewHeadlessClient()
+	other.Send("/login tester secret")
+	other.Send("/play Alice")
+	expectText(t, other, "This character is already logged in.")
+}
+
+func TestHeadlessBan(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	a := Armeria.accountManager.AccountByName("tester")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+	other, _ := Armeria.accountManager.CreateAccount("other", "secret")
+	if _, err := Armeria.characterManager.CreateCharacter(other, "Carol"); err != nil {
+		t.Fatalf("error creating character: %s", err)
+	}
+
+	// A banned account can't play or create characters from character select.
+	hc := NewHeadlessClient()
+	hc.Send("/login tester secret")
+	a.SetBanned(true, "griefing")
+	hc.Send("/play Bob")
+	expectText(t, hc, "This account has been banned: griefing")
+	hc.Send("/create")
+	if hc.SawText("Welcome to character creation!") {
+		t.Error("expected character creation to be refused")
+	}
+	if hc.Player().Character() != nil || hc.Player().Creation() != nil {
+		t.Error("expected the player to still be at character select")
+	}
+	a.SetBanned(false, "")
+
+	// Banning disconnects everyone logged in to the account, with or without a character.
+	alice := playAs(t, "Alice")
+	selecting := NewHeadlessClient()
+	selecting.Send("/login other secret")
+	playing := NewHeadlessClient()
+	playing.Send("/login other secret")
+	playing.Send("/play Carol")
+
+	alice.Send("/account ban other")
+	expectText(t, alice, "has been banned.")
+	if players := Armeria.playerManager.PlayersWithAccount(other); len(players) != 0 {
+		t.Errorf("expected everyone on the account to be disconnected, got %d", len(players))
+	}
+	if Armeria.characterManager.CharacterByName("Carol").Online() {
+		t.Error("expected Carol to be offline")
+	}
+}
+
+func TestHeadlessMove(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+	expectText(t, alice, "Bob connected and appeared here with you.")
+
+	alice.Clear()
+	alice.Send("/move north")
+	expectText(t, alice, "You walk to the north.")
+	expectText(t, alice, "Market")
+	expectRoomObjects(t, alice, "Alice", "Merchant")
+	expectText(t, bob, "Alice walks to the north.")
+	expectRoomObjects(t, bob, "Bob", "Apple")
+
+	alice.Send("/move west")
+	expectText(t, alice, "You cannot go that way.")
+
+	bob.Clear()
+	alice.Send("/south")
+	expectText(t, bob, "Alice walked in from the north.")
+	expectRoomObjects(t, bob, "Alice", "Bob", "Apple")
+}
+
+func TestHeadlessGetDrop(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+
+	alice.Send("/get apple")
+	expectText(t, alice, "You picked up a [Apple].")
+	expectRoomObjects(t, bob, "Alice", "Bob")
+
+	bob.Send("/get apple")
+	expectText(t, bob, "You don't see anyone here by that name.")
+
+	alice.Send("/give Bob apple")
+	expectText(t, alice, "You gave Bob a [Apple].")
+	if bob.Player().Character().Inventory().GetByName("Apple").Object == nil {
+		t.Error("expected Bob to have the apple")
+	}
+
+	bob.Send("/drop apple")
+	expectText(t, bob, "You dropped a [Apple].")
+	expectRoomObjects(t, alice, "Alice", "Bob", "Apple")
+}
+
+func TestHeadlessBuy(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	alice.Send("/move north")
+
+	alice.Send("/buy Merchant Apple")
+	expectText(t, alice, "Merchant does not have that to sell.")
+
+	// The Merchant learns about the shop's ledger when the shop is opened.
+	alice.Send("/say shop")
+	expectText(t, alice, "Apple")
+
+	for i := 0; i < 4; i++ {
+		alice.Send("/buy Merchant Apple")
+	}
+	expectText(t, alice, "You bought a [Apple] from Merchant for $5.00.")
+	if money := alice.Player().Character().Money(); money != 0 {
+		t.Errorf("expected Alice to have spent all of her money, got %.2f", money)
+	}
+	if n := alice.Player().Character().Inventory().Count(); n != 4 {
+		t.Errorf("expected Alice to have 4 apples, got %d", n)
+	}
+
+	alice.Clear()
+	alice.Send("/buy Merchant Apple")
+	expectText(t, alice, "You can't afford that.")
+}
+
+func TestHeadlessDisconnect(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+
+	alice.Disconnect()
+	expectText(t, bob, "Alice disconnected and is no longer here with you.")
+	expectRoomObjects(t, bob, "Bob", "Apple")
+
+	if c := Armeria.characterManager.CharacterByName("Alice"); c.Online() {
+		t.Error("expected Alice to be offline")
+	}
+
+	// Alice's character can be played again once she has left.
+	playAs(t, "Alice")
+	expectRoomObjects(t, bob, "Alice", "Bob", "Apple")
+}
+
+// storedRecord returns the data of the record with the key within the collection, as it is in the store.
+func storedRecord(t *testing.T, c StoreCollection, key string) (string, bool) {
+	t.Helper()
+	records, err := Armeria.store.Load(c)
+	if err != nil {
+		t.Fatalf("error loading %s: %s", c.Name, err)
+	}
+
+	for _, r := range records {
+		if r.Key == key {
+			return string(r.Data), true
+		}
+	}
+
+	return "", false
+}
+
+func TestHeadlessPersistEdits(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+	area := Armeria.worldManager.AreaByName("Test Area").ID()
+
+	// Nothing here calls Armeria.Save, so each change must have been persisted by the command that made it.
+	alice.Send("/mob create Goblin")
+	alice.Send("/ledger create Stock")
+	alice.Send("/ledger rename Stock Supplies")
+	alice.Send("/quest create Errand")
+	alice.Send("/quest edit Errand money 5")
+	alice.Send("/room set . title Old Square")
+
+	if data, _ := storedRecord(t, CollectionWorld, area); !strings.Contains(data, "Old Square") {
+		t.Errorf("expected the room title to be stored, got %s", data)
+	}
+	if _, ok := storedRecord(t, CollectionMobs, "Goblin"); !ok {
+		t.Error("expected the new mob to be stored")
+	}
+	if _, ok := storedRecord(t, CollectionLedgers, "Stock"); ok {
+		t.Error("expected the ledger to no longer be stored under its old name")
+	}
+	if _, ok := storedRecord(t, CollectionLedgers, "Supplies"); !ok {
+		t.Error("expected the ledger to be stored under its new name")
+	}
+	if data, _ := storedRecord(t, CollectionQuests, "Errand"); !strings.Contains(data, `"rewardMoney":5`) {
+		t.Errorf("expected the quest reward to be stored, got %s", data)
+	}
+
+	alice.Send("/undo")
+	if data, _ := storedRecord(t, CollectionWorld, area); !strings.Contains(data, "Town Square") {
+		t.Errorf("expected the undone room title to be stored, got %s", data)
+	}
+
+	alice.Send("/mob delete Goblin")
+	if _, ok := storedRecord(t, CollectionMobs, "Goblin"); ok {
+		t.Error("expected the deleted mob to be removed from the store")
+	}
+}
+
+func TestHeadlessQuestRemoveStep(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+	alice.Send("/quest create Errand")
+	alice.Send("/quest edit Errand add-step talk Merchant")
+	alice.Send("/quest edit Errand add-step visit .")
+	alice.Send("/quest edit Errand add-step script Find the lost key")
+
+	q := Armeria.questManager.QuestByName("Errand")
+	a := Armeria.characterManager.CharacterByName("Alice")
+	b := Armeria.characterManager.CharacterByName("Bob")
+	_ = Armeria.questManager.Start(a, q)
+	_ = Armeria.questManager.Advance(a, q)
+	_ = Armeria.questManager.Start(b, q)
+	_ = Armeria.questManager.Advance(b, q)
+	_ = Armeria.questManager.Advance(b, q)
+
+	// Alice and Bob stay on the same objective once an earlier step is removed.
+	alice.Send("/quest edit Errand remove-step 1")
+	if p, _ := a.QuestProgress("Errand"); p.Step != 0 || q.Step(p.Step).Type != QuestStepVisit {
+		t.Errorf("expected Alice to still be on the visit step, got step %d", p.Step)
+	}
+	if p, _ := b.QuestProgress("Errand"); p.Step != 1 || q.Step(p.Step).Type != QuestStepScript {
+		t.Errorf("expected Bob to still be on the script step, got step %d", p.Step)
+	}
+
+	// Bob's step was the last, so removing it completes the quest for him.
+	alice.Send("/quest edit Errand remove-step 2")
+	if p, _ := b.QuestProgress("Errand"); !p.Completed || p.Step != len(q.Steps()) {
+		t.Errorf("expected Bob to have completed the quest, got %+v", p)
+	}
+	if p, _ := a.QuestProgress("Errand"); p.Completed || p.Step != 0 {
+		t.Errorf("expected Alice to still be on the visit step, got %+v", p)
+	}
+}
+
+func TestHeadlessQuestDeliver(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+	alice.Send("/quest create Errand")
+	alice.Send("/quest edit Errand add-step deliver Apple to Merchant")
+
+	a := Armeria.characterManager.CharacterByName("Alice")
+	_ = Armeria.questManager.Start(a, Armeria.questManager.QuestByName("Errand"))
+
+	alice.Send("/get apple")
+	alice.Send("/move north")
+	alice.Send("/give Merchant apple")
+	expectText(t, alice, "You gave Merchant a [Apple].")
+	expectText(t, alice, "You have completed the quest Errand!")
+
+	// The Merchant has no script for receiving items, so the delivered apple is discarded.
+	merchant := Armeria.mobManager.MobByName("Merchant").Instances()[0]
+	if n := merchant.Inventory().Count(); n != 0 {
+		t.Errorf("expected the Merchant's inventory to be empty, got %d items", n)
+	}
+	if n := len(Armeria.itemManager.ItemByName("Apple").Instances()); n != 0 {
+		t.Errorf("expected the apple to have been deleted, got %d instances", n)
+	}
+}
+
+func TestHeadlessCreateNameTaken(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	first, second := NewHeadlessClient(), NewHeadlessClient()
+	for _, hc := range []*HeadlessClient{first, second} {
+		hc.Send("/create Dave")
+		hc.Send("/create secret")
+		hc.Send("/create secret")
+	}
+
+	first.Send("/create male")
+	expectText(t, first, "You've entered Armeria as Dave!")
+
+	// The name is checked again once the wizard is complete, so the second player has to choose another.
+	second.Send("/create male")
+	expectText(t, second, "That name was taken while you were deciding.")
+	expectText(t, second, "What would you like to name your character?")
+
+	// The new account isn't kept when its character can't be created.
+	second.Send("/create Erin")
+	second.Send("/create secret")
+	second.Send("/create secret")
+	a := Armeria.accountManager.AccountByName("tester")
+	if _, err := Armeria.characterManager.CreateCharacter(a, "Erin"); err != nil {
+		t.Fatalf("error creating character: %s", err)
+	}
+	second.Clear()
+	second.Send("/create female")
+	expectText(t, second, "That name was taken while you were deciding.")
+	if Armeria.accountManager.AccountByName("Erin") != nil {
+		t.Error("expected the account to be removed")
+	}
+}
@@ -5,15 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
 	"sync"
 
-	"go.uber.org/zap"
+	lua "github.com/yuin/gopher-lua"
 )
 
-// Force verify that ItemInstance implements ContainerObject.
+// Force verify that ItemInstance implements ContainerObject and ScriptOwner.
 var _ ContainerObject = (*ItemInstance)(nil)
+var _ ScriptOwner = (*ItemInstance)(nil)
 
 // ItemInstance is an instance of an Item.
 type ItemInstance struct {
@@ -21,16 +23,20 @@ type ItemInstance struct {
 	UUID             string            `json:"uuid"`
 	UnsafeAttributes map[string]string `json:"attributes"`
 	Parent           *Item             `json:"-"`
+	UnsafeScriptVM   *ScriptVM         `json:"-"`
 }
 
 // Init is called when the ItemInstance is created or loaded from disk.
 func (ii *ItemInstance) Init() {
 	Armeria.registry.Register(ii, ii.ID(), RegistryTypeItemInstance)
+	ii.UnsafeScriptVM = NewScriptVM(SetupItemLState)
 }
 
 // Deinit is called when the ItemInstance is deleted.
 func (ii *ItemInstance) Deinit() {
 	Armeria.registry.Unregister(ii.ID())
+	// Closed in the background, since a script may still be running.
+	go ii.ScriptVM().Close()
 }
 
 // ID returns the UUID of the instance.
@@ -86,13 +92,15 @@ func (ii *ItemInstance) SetAttribute(name string, value string) error {
 // Attribute returns an attribute on the ItemInstance, and falls back to the parent Item.
 func (ii *ItemInstance) Attribute(name string) string {
 	ii.RLock()
-	defer ii.RUnlock()
+	value := ii.UnsafeAttributes[name]
+	ii.RUnlock()
 
-	if len(ii.UnsafeAttributes[name]) == 0 {
+	// The parent is read without holding the instance lock, to keep to the lock order.
+	if len(value) == 0 {
 		return ii.Parent.Attribute(name)
 	}
 
-	return ii.UnsafeAttributes[name]
+	return value
 }
 
 // AttributeBool returns an attribute on the ItemInstance as a bool.
@@ -151,6 +159,66 @@ func (ii *ItemInstance) MobInstance() *MobInstance {
 	return oc.ParentMobInstance()
 }
 
+// Area returns the Area the ItemInstance is in, either within a room or held by a mob in one. Items held by
+// characters aren't in any Area.
+func (ii *ItemInstance) Area() *Area {
+	r := ii.Room()
+	if mi := ii.MobInstance(); mi != nil {
+		r = mi.Room()
+	}
+
+	if r == nil {
+		return nil
+	}
+	return r.ParentArea
+}
+
+// ScriptVM returns the persistent Lua environment used to run the item's script.
+func (ii *ItemInstance) ScriptVM() *ScriptVM {
+	ii.RLock()
+	defer ii.RUnlock()
+	return ii.UnsafeScriptVM
+}
+
+// ScriptKind returns the kind of script the ItemInstance runs, which prefixes its Lua globals.
+func (ii *ItemInstance) ScriptKind() string {
+	return "item"
+}
+
+// ScriptFile returns the full path to the parent Item's Lua script file.
+func (ii *ItemInstance) ScriptFile() string {
+	return ii.Parent.ScriptFile()
+}
+
+// ScriptFuncs returns the functions within the parent Item's script file.
+func (ii *ItemInstance) ScriptFuncs() []string {
+	return ii.Parent.ScriptFuncs()
+}
+
+// ScriptProto returns the parent Item's compiled script.
+func (ii *ItemInstance) ScriptProto() (*lua.FunctionProto, error) {
+	return ii.Parent.ScriptProto()
+}
+
+// ScriptRoom returns the Room the item's script acts upon: the room the item is lying in, or the room of the
+// character or mob carrying it.
+func (ii *ItemInstance) ScriptRoom() *Room {
+	oc := Armeria.registry.GetObjectContainer(ii.ID())
+	if oc == nil {
+		return nil
+	}
+
+	if r := oc.ParentRoom(); r != nil {
+		return r
+	} else if c := oc.ParentCharacter(); c != nil {
+		return c.Room()
+	} else if mi := oc.ParentMobInstance(); mi != nil {
+		return mi.Room()
+	}
+
+	return nil
+}
+
 // RarityColor returns the HTML color code that represents the rarity of the item.
 func (ii *ItemInstance) RarityColor() string {
 	switch ii.Attribute(AttributeRarity) {
@@ -200,8 +268,9 @@ func (ii *ItemInstance) EditorData() *ObjectEditorData {
 	}
 }
 
-// TooltipContentJSON generates the HTML string to be sent to the game client in JSON format.
-func (ii *ItemInstance) TooltipContentJSON() string {
+// TooltipContent generates the tooltip HTML to be sent to the game client. The item's name is escaped, since it's
+// the only part of the tooltip that a builder chooses.
+func (ii *ItemInstance) TooltipContent() *ItemTooltipData {
 	qualitiesSlice := make([]string, 0)
 
 	if ii.Attribute(AttributeHoldable) == "false" {
@@ -216,32 +285,22 @@ func (ii *ItemInstance) TooltipContentJSON() string {
 		qualitiesSlice = append(qualitiesSlice, "Equippable")
 	}
 
-	tt := map[string]string{
-		"uuid": ii.ID(),
-		"html": fmt.Sprintf(
+	return &ItemTooltipData{
+		UUID: ii.ID(),
+		HTML: fmt.Sprintf(
 			`
 			<div class="name" style="color:%s">%s</div>
 			<div class="type">%s</div>
 			<div class="qualities">%s</div>
 			`,
 			ii.RarityColor(),
-			ii.Name(),
+			html.EscapeString(ii.Name()),
 			ii.RarityName(),
 			strings.Join(qualitiesSlice, "<br />"),
 		),
-		"rarity":  ii.RarityColor(),
-		"picture": ii.Attribute(AttributePicture),
+		Rarity:  ii.RarityColor(),
+		Picture: ii.Attribute(AttributePicture),
 	}
-
-	ttJSON, err := json.Marshal(tt)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal item tooltip content",
-			zap.String("uuid", ii.ID()),
-			zap.Error(err),
-		)
-	}
-
-	return string(ttJSON)
 }
 
 // Delete removes the item instance from the game. It should be manually removed from containers
@@ -249,3 +308,13 @@ func (ii *ItemInstance) TooltipContentJSON() string {
 func (ii *ItemInstance) Delete() {
 	ii.Parent.DeleteInstance(ii)
 }
+
+type itemInstanceJSON ItemInstance
+
+// MarshalJSON encodes the ItemInstance while holding its lock.
+func (ii *ItemInstance) MarshalJSON() ([]byte, error) {
+	ii.RLock()
+	defer ii.RUnlock()
+
+	return json.Marshal((*itemInstanceJSON)(ii))
+}
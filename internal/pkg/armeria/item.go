@@ -2,9 +2,13 @@ package armeria
 
 import (
 	"armeria/internal/pkg/misc"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
+	lua "github.com/yuin/gopher-lua"
 
 	"go.uber.org/zap"
 )
@@ -14,6 +18,7 @@ type Item struct {
 	UnsafeName       string            `json:"name"`
 	UnsafeAttributes map[string]string `json:"attributes"`
 	UnsafeInstances  []*ItemInstance   `json:"instances"`
+	UnsafeScript     *CachedScript     `json:"-"`
 }
 
 const (
@@ -39,7 +44,9 @@ func ItemTypes() []string {
 }
 
 // Init is called when the Item is created or loaded from disk.
-func (i *Item) Init() {}
+func (i *Item) Init() {
+	i.CacheScript()
+}
 
 // Name returns the name of the Item.
 func (i *Item) Name() string {
@@ -54,7 +61,26 @@ func (i *Item) Instances() []*ItemInstance {
 	i.RLock()
 	defer i.RUnlock()
 
-	return i.UnsafeInstances
+	instances := make([]*ItemInstance, len(i.UnsafeInstances))
+	copy(instances, i.UnsafeInstances)
+
+	return instances
+}
+
+// CanBuild returns whether the Character may change the Item. Items are shared by every area, so the Character
+// must be able to build in every area with an instance of the Item in it.
+func (i *Item) CanBuild(c *Character) bool {
+	if !c.HasPermission(PermissionBuild) {
+		return false
+	}
+
+	for _, ii := range i.Instances() {
+		if a := ii.Area(); a != nil && !a.CanBuild(c) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // CreateInstance creates a new ItemInstance and adds it in-memory.
@@ -103,6 +129,68 @@ func (i *Item) DeleteInstance(ii *ItemInstance) bool {
 	return false
 }
 
+// RestoreInstance adds a deleted ItemInstance back to the Item, such as when its deletion is undone.
+func (i *Item) RestoreInstance(ii *ItemInstance) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.UnsafeInstances = append(i.UnsafeInstances, ii)
+
+	ii.Init()
+
+	Armeria.log.Info("instance restored",
+		zap.String("uuid", ii.ID()),
+		zap.String("name", i.UnsafeName),
+	)
+}
+
+// scriptFile returns the full path to the associated Lua script file. This DOES NOT request a lock and IS NOT
+// thread safe.
+func (i *Item) scriptFile() string {
+	return fmt.Sprintf(
+		"%s/scripts/item-%s.lua",
+		Armeria.dataPath,
+		strings.ToLower(strings.ReplaceAll(i.UnsafeName, " ", "-")),
+	)
+}
+
+// ScriptFile returns the full path to the associated Lua script file.
+func (i *Item) ScriptFile() string {
+	i.RLock()
+	defer i.RUnlock()
+	return i.scriptFile()
+}
+
+// CacheScript reads the script contents and caches the contents, individual functions and compiled script.
+func (i *Item) CacheScript() {
+	i.Lock()
+	defer i.Unlock()
+
+	i.UnsafeScript = LoadScript(i.scriptFile())
+}
+
+// Script returns the cached script contents.
+func (i *Item) Script() string {
+	i.RLock()
+	defer i.RUnlock()
+	return i.UnsafeScript.Source
+}
+
+// ScriptFuncs returns the cached functions within the Item's script file.
+func (i *Item) ScriptFuncs() []string {
+	i.RLock()
+	defer i.RUnlock()
+	return i.UnsafeScript.Funcs
+}
+
+// ScriptProto returns the compiled script, or the error encountered while compiling it. The proto is nil when
+// the Item has no script.
+func (i *Item) ScriptProto() (*lua.FunctionProto, error) {
+	i.RLock()
+	defer i.RUnlock()
+	return i.UnsafeScript.Proto, i.UnsafeScript.Err
+}
+
 // Attribute returns a permanent attribute.
 func (i *Item) Attribute(name string) string {
 	i.RLock()
@@ -148,3 +236,13 @@ func (i *Item) EditorData() *ObjectEditorData {
 		Properties: props,
 	}
 }
+
+type itemJSON Item
+
+// MarshalJSON encodes the Item while holding its lock.
+func (i *Item) MarshalJSON() ([]byte, error) {
+	i.RLock()
+	defer i.RUnlock()
+
+	return json.Marshal((*itemJSON)(i))
+}
@@ -1,8 +1,6 @@
 package armeria
 
 import (
-	"encoding/json"
-	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -13,15 +11,12 @@ import (
 // ItemManager holds all items found in the server data.
 type ItemManager struct {
 	sync.RWMutex
-	dataFile    string
 	UnsafeItems []*Item `json:"items"`
 }
 
 // NewItemManager creates a new ItemManager.
 func NewItemManager() *ItemManager {
-	m := &ItemManager{
-		dataFile: fmt.Sprintf("%s/items.json", Armeria.dataPath),
-	}
+	m := &ItemManager{}
 
 	m.LoadItems()
 	m.AttachParents()
@@ -34,22 +29,18 @@ func (m *ItemManager) LoadItems() {
 	m.Lock()
 	defer m.Unlock()
 
-	itemsFile, err := os.Open(m.dataFile)
-	defer itemsFile.Close()
-
+	records, err := Armeria.store.Load(CollectionItems)
 	if err != nil {
-		Armeria.log.Fatal("failed to load data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionItems.Name),
 			zap.Error(err),
 		)
 	}
 
-	jsonParser := json.NewDecoder(itemsFile)
-
-	err = jsonParser.Decode(m)
+	err = DecodeStoreRecords(records, &m.UnsafeItems)
 	if err != nil {
-		Armeria.log.Fatal("failed to decode data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionItems.Name),
 			zap.Error(err),
 		)
 	}
@@ -72,32 +63,32 @@ func (m *ItemManager) SaveItems() {
 	m.RLock()
 	defer m.RUnlock()
 
-	itemsFile, err := os.Create(m.dataFile)
-	defer itemsFile.Close()
-
-	raw, err := json.Marshal(m)
+	records, err := EncodeStoreRecords(CollectionItems, m.UnsafeItems)
 	if err != nil {
 		Armeria.log.Fatal("failed to marshal data",
 			zap.Error(err),
 		)
 	}
 
-	bytes, err := itemsFile.Write(raw)
+	err = Armeria.store.Save(CollectionItems, records)
 	if err != nil {
-		Armeria.log.Fatal("failed to write data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionItems.Name),
 			zap.Error(err),
 		)
 	}
 
-	_ = itemsFile.Sync()
-
-	Armeria.log.Info("wrote data to file",
-		zap.String("file", m.dataFile),
-		zap.Int("bytes", bytes),
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionItems.Name),
+		zap.Int("records", len(records)),
 	)
 }
 
+// SaveItem immediately persists a single Item, along with its instances, to the store.
+func (m *ItemManager) SaveItem(i *Item) {
+	persistRecord(CollectionItems, i)
+}
+
 // AttachParents attaches a pointer to ItemInstance that references the parent Item.
 func (m *ItemManager) AttachParents() {
 	m.RLock()
@@ -145,7 +136,10 @@ func (m *ItemManager) Items() []*Item {
 	m.RLock()
 	defer m.RUnlock()
 
-	return m.UnsafeItems
+	items := make([]*Item, len(m.UnsafeItems))
+	copy(items, m.UnsafeItems)
+
+	return items
 }
 
 // ItemsByAttribute returns all of the in-memory Items that have a particular attribute + value defined.
@@ -165,18 +159,22 @@ func (m *ItemManager) ItemsByAttribute(a, t string) []*Item {
 
 // CreateItem creates a new Item instance, but doesn't add it to memory.
 func (m *ItemManager) CreateItem(name string) *Item {
-	return &Item{
+	i := &Item{
 		UnsafeName:       name,
 		UnsafeAttributes: make(map[string]string),
 	}
+	i.CacheScript()
+
+	return i
 }
 
-// AddItem adds a new Item reference to memory.
+// AddItem adds a new Item reference to memory, and persists it.
 func (m *ItemManager) AddItem(i *Item) {
 	m.Lock()
-	defer m.Unlock()
-
 	m.UnsafeItems = append(m.UnsafeItems, i)
+	m.Unlock()
+
+	m.SaveItem(i)
 }
 
 // RemoveItem removes an existing Item reference from memory.
@@ -198,6 +196,11 @@ func (m *ItemManager) RemoveItem(item *Item) {
 		return
 	}
 
+	removeRecord(CollectionItems, item.Name())
+
+	// Delete the script file, if it has one.
+	_ = os.Remove(item.ScriptFile())
+
 	// Delete the picture file.
 	picture := item.Attribute(AttributePicture)
 	if len(picture) > 0 {
@@ -1,6 +1,7 @@
 package armeria
 
 import (
+	"encoding/json"
 	"strings"
 	"sync"
 )
@@ -73,5 +74,18 @@ func (l *Ledger) Entries() []*LedgerEntry {
 	l.RLock()
 	defer l.RUnlock()
 
-	return l.UnsafeEntries
+	entries := make([]*LedgerEntry, len(l.UnsafeEntries))
+	copy(entries, l.UnsafeEntries)
+
+	return entries
+}
+
+type ledgerJSON Ledger
+
+// MarshalJSON encodes the Ledger while holding its lock.
+func (l *Ledger) MarshalJSON() ([]byte, error) {
+	l.RLock()
+	defer l.RUnlock()
+
+	return json.Marshal((*ledgerJSON)(l))
 }
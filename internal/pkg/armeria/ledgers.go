@@ -1,9 +1,6 @@
 package armeria
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
 	"strings"
 	"sync"
 
@@ -12,15 +9,12 @@ import (
 
 type LedgerManager struct {
 	sync.RWMutex
-	dataFile      string
 	UnsafeLedgers []*Ledger `json:"ledgers"`
 }
 
 // NewLedgerManager creates a new LedgerManager.
 func NewLedgerManager() *LedgerManager {
-	m := &LedgerManager{
-		dataFile: fmt.Sprintf("%s/ledgers.json", Armeria.dataPath),
-	}
+	m := &LedgerManager{}
 
 	m.LoadLedgers()
 
@@ -32,22 +26,18 @@ func (m *LedgerManager) LoadLedgers() {
 	m.Lock()
 	defer m.Unlock()
 
-	ledgersFile, err := os.Open(m.dataFile)
-	defer ledgersFile.Close()
-
+	records, err := Armeria.store.Load(CollectionLedgers)
 	if err != nil {
-		Armeria.log.Fatal("failed to load data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionLedgers.Name),
 			zap.Error(err),
 		)
 	}
 
-	jsonParser := json.NewDecoder(ledgersFile)
-
-	err = jsonParser.Decode(m)
+	err = DecodeStoreRecords(records, &m.UnsafeLedgers)
 	if err != nil {
-		Armeria.log.Fatal("failed to decode data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionLedgers.Name),
 			zap.Error(err),
 		)
 	}
@@ -62,38 +52,41 @@ func (m *LedgerManager) SaveLedgers() {
 	m.RLock()
 	defer m.RUnlock()
 
-	ledgersFile, err := os.Create(m.dataFile)
-	defer ledgersFile.Close()
-
-	raw, err := json.Marshal(m)
+	records, err := EncodeStoreRecords(CollectionLedgers, m.UnsafeLedgers)
 	if err != nil {
 		Armeria.log.Fatal("failed to marshal data",
 			zap.Error(err),
 		)
 	}
 
-	bytes, err := ledgersFile.Write(raw)
+	err = Armeria.store.Save(CollectionLedgers, records)
 	if err != nil {
-		Armeria.log.Fatal("failed to write data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionLedgers.Name),
 			zap.Error(err),
 		)
 	}
 
-	_ = ledgersFile.Sync()
-
-	Armeria.log.Info("wrote data to file",
-		zap.String("file", m.dataFile),
-		zap.Int("bytes", bytes),
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionLedgers.Name),
+		zap.Int("records", len(records)),
 	)
 }
 
+// SaveLedger immediately persists a single Ledger to the store.
+func (m *LedgerManager) SaveLedger(l *Ledger) {
+	persistRecord(CollectionLedgers, l)
+}
+
 // Ledgers returns all of the in-memory Ledgers.
 func (m *LedgerManager) Ledgers() []*Ledger {
 	m.RLock()
 	defer m.RUnlock()
 
-	return m.UnsafeLedgers
+	ledgers := make([]*Ledger, len(m.UnsafeLedgers))
+	copy(ledgers, m.UnsafeLedgers)
+
+	return ledgers
 }
 
 // LedgerByName returns the matching Ledger, by name.
@@ -117,10 +110,11 @@ func (m *LedgerManager) CreateLedger(name string) *Ledger {
 	}
 }
 
-// AddLedger adds a new Item reference to memory.
+// AddLedger adds a new Ledger reference to memory, and persists it.
 func (m *LedgerManager) AddLedger(l *Ledger) {
 	m.Lock()
-	defer m.Unlock()
-
 	m.UnsafeLedgers = append(m.UnsafeLedgers, l)
+	m.Unlock()
+
+	m.SaveLedger(l)
 }
@@ -0,0 +1,536 @@
+package armeria
+
+import (
+	"armeria/internal/pkg/misc"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Migrations returns every registered schema migration, in version order. Version 1 is the original schema and
+// has no migration.
+func Migrations() []*Migration {
+	return []*Migration{
+		{
+			Version:     2,
+			Description: "track when characters were last seen",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionCharacters.File,
+					Up:   setMigrationField("lastSeen", func() interface{} { return time.Now().Format(time.RFC3339Nano) }),
+					Down: removeMigrationField("lastSeen"),
+				},
+			},
+		},
+		{
+			Version:     3,
+			Description: "add character settings",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionCharacters.File,
+					Up:   setMigrationField("settings", func() interface{} { return map[string]interface{}{} }),
+					Down: removeMigrationField("settings"),
+				},
+			},
+		},
+		{
+			Version:     4,
+			Description: "add mob instance inventories",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionMobs.File,
+					Up:   migrateMobInventoriesUp,
+					Down: migrateMobInventoriesDown,
+				},
+			},
+		},
+		{
+			Version:     5,
+			Description: "add ledgers",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionLedgers.File,
+					Up:   createMigrationFile,
+					Down: removeMigrationFile,
+				},
+			},
+		},
+		{
+			Version:     6,
+			Description: "reset item rarity to common",
+			Steps: []*MigrationStep{
+				{
+					// The previous rarity is not kept, so this cannot be undone.
+					File: CollectionItems.File,
+					Up:   migrateItemRarityUp,
+				},
+			},
+		},
+		{
+			Version:     7,
+			Description: "move character passwords into accounts",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionAccounts.File,
+					Up:   migrateAccountsUp,
+					Down: removeMigrationFile,
+				},
+				{
+					File: CollectionCharacters.File,
+					Up:   migrateAccountCharactersUp,
+					Down: migrateAccountCharactersDown,
+				},
+			},
+		},
+		{
+			Version:     8,
+			Description: "add sessions",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionSessions.File,
+					Up:   createMigrationFile,
+					Down: removeMigrationFile,
+				},
+			},
+		},
+		{
+			Version:     9,
+			Description: "add quests and character quest progress",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionQuests.File,
+					Up:   createMigrationFile,
+					Down: removeMigrationFile,
+				},
+				{
+					File: CollectionCharacters.File,
+					Up:   setMigrationField("quests", func() interface{} { return map[string]interface{}{} }),
+					Down: removeMigrationField("quests"),
+				},
+			},
+		},
+		{
+			Version:     10,
+			Description: "replace character permissions with roles",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionRoles.File,
+					Up:   migrateRolesUp,
+					Down: removeMigrationFile,
+				},
+				{
+					File: CollectionCharacters.File,
+					Up:   migrateCharacterRolesUp,
+					Down: migrateCharacterRolesDown,
+				},
+			},
+		},
+		{
+			Version:     11,
+			Description: "add area owners and builders",
+			Steps: []*MigrationStep{
+				{
+					File: CollectionWorld.File,
+					Up:   setMigrationField("owner", func() interface{} { return "" }),
+					Down: removeMigrationField("owner"),
+				},
+				{
+					File: CollectionWorld.File,
+					Up:   setMigrationField("builders", func() interface{} { return []interface{}{} }),
+					Down: removeMigrationField("builders"),
+				},
+				{
+					File: CollectionRoles.File,
+					Up:   grantMigrationPermission("CAN_BUILD", "CAN_BUILD_ANYWHERE"),
+					Down: revokeMigrationPermission("CAN_BUILD_ANYWHERE"),
+				},
+				{
+					File: CollectionRoles.File,
+					Up: addMigrationRole(func() map[string]interface{} {
+						return map[string]interface{}{
+							"name":        "contributor",
+							"description": "Builds within the areas they own or have been allowed to build in.",
+							"inherits":    []interface{}{"player"},
+							"permissions": []interface{}{"CAN_BUILD"},
+						}
+					}),
+					Down: removeMigrationRole("contributor"),
+				},
+				{
+					File: CollectionAccounts.File,
+					Up:   grantMigrationPermission("CAN_BUILD", "CAN_BUILD_ANYWHERE"),
+					Down: revokeMigrationPermission("CAN_BUILD_ANYWHERE"),
+				},
+			},
+		},
+	}
+}
+
+// setMigrationField returns a MigrationFunc that sets a field on every record.
+func setMigrationField(field string, value func() interface{}) MigrationFunc {
+	return func(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+		for _, r := range records {
+			r[field] = value()
+		}
+		return records, nil
+	}
+}
+
+// removeMigrationField returns a MigrationFunc that removes a field from every record.
+func removeMigrationField(field string) MigrationFunc {
+	return func(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+		for _, r := range records {
+			delete(r, field)
+		}
+		return records, nil
+	}
+}
+
+// createMigrationFile is a MigrationFunc that creates an empty data file.
+func createMigrationFile(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+	if records != nil {
+		return records, nil
+	}
+	return []map[string]interface{}{}, nil
+}
+
+// removeMigrationFile is a MigrationFunc that removes a data file.
+func removeMigrationFile(_ []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+
+// migrationObjects returns a field containing an array of objects, such as the instances of a mob or item.
+func migrationObjects(r map[string]interface{}, field string) []map[string]interface{} {
+	list, _ := r[field].([]interface{})
+
+	var objects []map[string]interface{}
+	for _, o := range list {
+		if m, ok := o.(map[string]interface{}); ok {
+			objects = append(objects, m)
+		}
+	}
+
+	return objects
+}
+
+func migrateMobInventoriesUp(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+	for _, m := range records {
+		for _, mi := range migrationObjects(m, "instances") {
+			mi["inventory"] = map[string]interface{}{
+				"objects": []interface{}{},
+				"maxSize": 0,
+			}
+		}
+	}
+	return records, nil
+}
+
+func migrateMobInventoriesDown(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+	for _, m := range records {
+		for _, mi := range migrationObjects(m, "instances") {
+			delete(mi, "inventory")
+		}
+	}
+	return records, nil
+}
+
+func migrateItemRarityUp(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+	reset := func(o map[string]interface{}) {
+		attrs, _ := o["attributes"].(map[string]interface{})
+		if _, exists := attrs["rarity"]; exists {
+			attrs["rarity"] = "common"
+		}
+	}
+
+	for _, i := range records {
+		reset(i)
+		for _, ii := range migrationObjects(i, "instances") {
+			reset(ii)
+		}
+	}
+	return records, nil
+}
+
+// migrateAccountsUp creates an account for each character, sharing the character's name and password.
+func migrateAccountsUp(records []map[string]interface{}, data MigrationData) ([]map[string]interface{}, error) {
+	if records == nil {
+		records = []map[string]interface{}{}
+	}
+
+	for _, c := range data[CollectionCharacters.File] {
+		name, _ := c["name"].(string)
+		password, _ := c["password"].(string)
+
+		records = append(records, map[string]interface{}{
+			"uuid":        uuid.New().String(),
+			"name":        name,
+			"password":    password,
+			"permissions": []interface{}{},
+			"banned":      false,
+			"banReason":   "",
+		})
+	}
+
+	return records, nil
+}
+
+// migrateAccountCharactersUp links each character to the account created for it, and drops the password.
+func migrateAccountCharactersUp(records []map[string]interface{}, data MigrationData) ([]map[string]interface{}, error) {
+	accounts := make(map[string]string)
+	for _, a := range data[CollectionAccounts.File] {
+		name, _ := a["name"].(string)
+		id, _ := a["uuid"].(string)
+		accounts[name] = id
+	}
+
+	for _, c := range records {
+		name, _ := c["name"].(string)
+		id, ok := accounts[name]
+		if !ok {
+			return nil, fmt.Errorf("no account was created for character %s", name)
+		}
+
+		c["account"] = id
+		delete(c, "password")
+	}
+
+	return records, nil
+}
+
+// migrateAccountCharactersDown moves each account's password back onto its characters.
+func migrateAccountCharactersDown(records []map[string]interface{}, data MigrationData) ([]map[string]interface{}, error) {
+	passwords := make(map[string]interface{})
+	for _, a := range data[CollectionAccounts.File] {
+		id, _ := a["uuid"].(string)
+		passwords[id] = a["password"]
+	}
+
+	for _, c := range records {
+		id, _ := c["account"].(string)
+		password, ok := passwords[id]
+		if !ok {
+			name, _ := c["name"].(string)
+			return nil, fmt.Errorf("character %s has no account", name)
+		}
+
+		c["password"] = password
+		delete(c, "account")
+	}
+
+	return records, nil
+}
+
+// defaultMigrationRoles are the roles created along with the roles file.
+func defaultMigrationRoles() []map[string]interface{} {
+	role := func(name, description string, inherits []interface{}, perms ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name":        name,
+			"description": description,
+			"inherits":    inherits,
+			"permissions": append([]interface{}{}, perms...),
+		}
+	}
+
+	return []map[string]interface{}{
+		role("player", "Every character has this role.", []interface{}{}),
+		role("builder", "Builds areas, mobs and items.", []interface{}{"player"},
+			"CAN_BUILD", "CAN_GHOST", "CAN_TELEPORT"),
+		role("admin", "Runs the game.", []interface{}{"builder"},
+			"CAN_SYSOP", "CAN_CHAREDIT"),
+	}
+}
+
+// migrationRolePermissions returns every permission granted by a role within the roles file, including those it
+// inherits, in the order they are granted.
+func migrationRolePermissions(roles []map[string]interface{}, name string) []string {
+	var perms []string
+	visited := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		for _, r := range roles {
+			if r["name"] != name {
+				continue
+			}
+			list, _ := r["permissions"].([]interface{})
+			for _, p := range list {
+				if s, ok := p.(string); ok && !misc.Contains(perms, s) {
+					perms = append(perms, s)
+				}
+			}
+			inherits, _ := r["inherits"].([]interface{})
+			for _, i := range inherits {
+				if s, ok := i.(string); ok {
+					visit(s)
+				}
+			}
+		}
+	}
+	visit(name)
+
+	return perms
+}
+
+// samePermissions returns whether two lists hold the same permissions, in any order.
+func samePermissions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, p := range a {
+		if !misc.Contains(b, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func migrateRolesUp(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+	if records != nil {
+		return records, nil
+	}
+	return defaultMigrationRoles(), nil
+}
+
+// migrateCharacterRolesUp replaces each character's permissions attribute with the role granting exactly the same
+// permissions. A character whose permissions don't match any role is given a role of its own, so that nothing is
+// lost.
+func migrateCharacterRolesUp(records []map[string]interface{}, data MigrationData) ([]map[string]interface{}, error) {
+	for _, c := range records {
+		roles := []interface{}{}
+
+		attrs, _ := c["attributes"].(map[string]interface{})
+		value, _ := attrs["permissions"].(string)
+		perms := strings.Fields(value)
+
+		if len(perms) > 0 {
+			var matched string
+			for _, r := range data[CollectionRoles.File] {
+				name, _ := r["name"].(string)
+				if samePermissions(perms, migrationRolePermissions(data[CollectionRoles.File], name)) {
+					matched = name
+					break
+				}
+			}
+
+			if len(matched) == 0 {
+				name, _ := c["name"].(string)
+				matched = "legacy-" + strings.ToLower(name)
+				granted := make([]interface{}, len(perms))
+				for i, p := range perms {
+					granted[i] = p
+				}
+				data[CollectionRoles.File] = append(data[CollectionRoles.File], map[string]interface{}{
+					"name":        matched,
+					"description": fmt.Sprintf("The permissions %s had before roles were added.", name),
+					"inherits":    []interface{}{},
+					"permissions": granted,
+				})
+			}
+
+			roles = append(roles, matched)
+		}
+
+		if attrs != nil {
+			delete(attrs, "permissions")
+		}
+		c["roles"] = roles
+	}
+
+	return records, nil
+}
+
+// migrateCharacterRolesDown sets each character's permissions attribute to the permissions granted by its roles.
+func migrateCharacterRolesDown(records []map[string]interface{}, data MigrationData) ([]map[string]interface{}, error) {
+	for _, c := range records {
+		roles, _ := c["roles"].([]interface{})
+
+		var perms []string
+		for _, r := range roles {
+			name, _ := r.(string)
+			for _, p := range migrationRolePermissions(data[CollectionRoles.File], name) {
+				if !misc.Contains(perms, p) {
+					perms = append(perms, p)
+				}
+			}
+		}
+
+		if len(perms) > 0 {
+			attrs, ok := c["attributes"].(map[string]interface{})
+			if !ok {
+				attrs = make(map[string]interface{})
+				c["attributes"] = attrs
+			}
+			attrs["permissions"] = strings.Join(perms, " ")
+		}
+		delete(c, "roles")
+	}
+
+	return records, nil
+}
+
+// grantMigrationPermission returns a MigrationFunc that grants a permission to every record (a role or an account)
+// that has already been granted another.
+func grantMigrationPermission(has, grant string) MigrationFunc {
+	return func(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+		for _, r := range records {
+			perms, _ := r["permissions"].([]interface{})
+			for _, p := range perms {
+				if p == has {
+					r["permissions"] = append(perms, grant)
+					break
+				}
+			}
+		}
+		return records, nil
+	}
+}
+
+// revokeMigrationPermission returns a MigrationFunc that revokes a permission from every record (a role or an
+// account).
+func revokeMigrationPermission(perm string) MigrationFunc {
+	return func(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+		for _, r := range records {
+			perms, _ := r["permissions"].([]interface{})
+			for i, p := range perms {
+				if p == perm {
+					r["permissions"] = append(perms[:i], perms[i+1:]...)
+					break
+				}
+			}
+		}
+		return records, nil
+	}
+}
+
+// addMigrationRole returns a MigrationFunc that adds a role, unless one already exists by that name.
+func addMigrationRole(role func() map[string]interface{}) MigrationFunc {
+	return func(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+		r := role()
+		for _, existing := range records {
+			if existing["name"] == r["name"] {
+				return records, nil
+			}
+		}
+		return append(records, r), nil
+	}
+}
+
+// removeMigrationRole returns a MigrationFunc that removes a role by name.
+func removeMigrationRole(name string) MigrationFunc {
+	return func(records []map[string]interface{}, _ MigrationData) ([]map[string]interface{}, error) {
+		kept := records[:0]
+		for _, r := range records {
+			if r["name"] != name {
+				kept = append(kept, r)
+			}
+		}
+		return kept, nil
+	}
+}
@@ -1,10 +1,17 @@
 package armeria
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,7 +19,34 @@ import (
 
 // SchemaVersion defines the current version of the schema. If the file system is using an older version, a
 // migration will be performed.
-const SchemaVersion int = 6
+const SchemaVersion int = 11
+
+// MigrationSnapshotDir is the directory within the data directory where snapshots are written before migrating.
+const MigrationSnapshotDir string = "migration-snapshots"
+
+// MigrationData holds the decoded records of each data file being migrated, keyed by file name. A file that
+// does not exist on disk has no entry.
+type MigrationData map[string][]map[string]interface{}
+
+// A MigrationFunc transforms the records of a single data file. The other data files are available for reference,
+// but only the returned records are written back. Returning nil removes the data file entirely.
+type MigrationFunc func(records []map[string]interface{}, data MigrationData) ([]map[string]interface{}, error)
+
+// A MigrationStep is the change made to a single data file by a Migration. A step without a Down function cannot
+// be undone, and is skipped during a rollback.
+type MigrationStep struct {
+	File string
+	Up   MigrationFunc
+	Down MigrationFunc
+}
+
+// A Migration moves the data files from the previous schema version to Version. Steps are applied in order when
+// migrating up, and in reverse order when rolling back.
+type Migration struct {
+	Version     int
+	Description string
+	Steps       []*MigrationStep
+}
 
 // schemaVersionOnDisk reads the schema version from disk and returns it as an int.
 func schemaVersionOnDisk() int {
@@ -21,7 +55,7 @@ func schemaVersionOnDisk() int {
 		Armeria.log.Fatal("error reading schema version from disk", zap.Error(err))
 	}
 
-	sv, err := strconv.Atoi(string(b))
+	sv, err := strconv.Atoi(strings.TrimSpace(string(b)))
 	if err != nil {
 		Armeria.log.Fatal("error parsing schema version from disk", zap.Error(err))
 	}
@@ -34,7 +68,7 @@ func writeSchemaVersionToDisk(version int) {
 	v := strconv.Itoa(version)
 	b := []byte(v)
 
-	err := ioutil.WriteFile(Armeria.dataPath+"/schema-version", b, 0644)
+	err := writeFileAtomic(Armeria.dataPath+"/schema-version", b)
 	if err != nil {
 		Armeria.log.Fatal("error writing schema version to disk", zap.Error(err))
 	}
@@ -45,7 +79,7 @@ func verifySchemaVersion() {
 	sv := schemaVersionOnDisk()
 
 	if SchemaVersion < sv {
-		Armeria.log.Fatal("cannot downgrade schema version",
+		Armeria.log.Fatal("schema is newer than this build; roll it back with -rollback using a newer build first",
 			zap.Int("installed", sv),
 			zap.Int("desired", SchemaVersion),
 		)
@@ -61,168 +95,450 @@ func verifySchemaVersion() {
 	}
 }
 
-// migrateCharacters handles migrations for characters.
-func migrateCharacters(to int) {
-	s := struct {
-		Characters []*Character `json:"characters"`
-	}{}
-
-	b, err := ioutil.ReadFile(Armeria.dataPath + "/characters.json")
-	if err != nil {
-		Armeria.log.Fatal("error reading characters.json", zap.Error(err))
+// MigrationByVersion returns the registered Migration for a schema version.
+func MigrationByVersion(version int) *Migration {
+	for _, m := range Migrations() {
+		if m.Version == version {
+			return m
+		}
 	}
 
-	err = json.Unmarshal(b, &s)
-	if err != nil {
-		Armeria.log.Fatal("error unmarshalling characters.json", zap.Error(err))
+	return nil
+}
+
+// collectionByFile returns the StoreCollection persisted to a data file.
+func collectionByFile(file string) (StoreCollection, bool) {
+	for _, c := range StoreCollections() {
+		if c.File == file {
+			return c, true
+		}
 	}
 
-	for _, c := range s.Characters {
-		switch to {
-		case 2:
-			// set UnsafeLastSeen to now
-			c.UnsafeLastSeen = time.Now()
-		case 3:
-			// set UnsafeSettings to an initialized map
-			c.UnsafeSettings = map[string]string{}
+	return StoreCollection{}, false
+}
+
+// loadMigrationData reads every data file that exists within the data directory.
+func loadMigrationData(dataPath string) (MigrationData, error) {
+	data := make(MigrationData)
+
+	for _, c := range StoreCollections() {
+		b, err := ioutil.ReadFile(filepath.Join(dataPath, c.File))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
 		}
 
-		Armeria.log.Info("character migration successful",
-			zap.String("name", c.UnsafeName),
-		)
+		records, err := decodeMigrationFile(c, b)
+		if err != nil {
+			return nil, err
+		}
+		data[c.File] = records
 	}
 
-	b, err = json.Marshal(s)
-	if err != nil {
-		Armeria.log.Fatal("error marshalling characters.json", zap.Error(err))
+	return data, nil
+}
+
+// decodeMigrationFile decodes the records within the contents of a data file.
+func decodeMigrationFile(c StoreCollection, b []byte) ([]map[string]interface{}, error) {
+	contents := make(map[string][]map[string]interface{})
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&contents); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %s", c.File, err)
 	}
 
-	err = ioutil.WriteFile(Armeria.dataPath+"/characters.json", b, 0644)
-	if err != nil {
-		Armeria.log.Fatal("error writing characters.json", zap.Error(err))
+	records := contents[c.Name]
+	if records == nil {
+		records = []map[string]interface{}{}
 	}
+
+	return records, nil
 }
 
-// migrateMobs handles migrations for mobs.
-func migrateMobs(to int) {
-	s := struct {
-		Mobs []*Mob `json:"mobs"`
-	}{}
+// writeMigrationData writes the data files that changed during a migration, and removes those that no longer exist.
+func writeMigrationData(dataPath string, before, after MigrationData) error {
+	for _, c := range StoreCollections() {
+		old, existed := before[c.File]
+		records, exists := after[c.File]
 
-	b, err := ioutil.ReadFile(Armeria.dataPath + "/mobs.json")
-	if err != nil {
-		Armeria.log.Fatal("error reading mobs.json", zap.Error(err))
+		if !exists {
+			if existed {
+				if err := os.Remove(filepath.Join(dataPath, c.File)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if existed && reflect.DeepEqual(old, records) {
+			continue
+		}
+
+		b, err := json.Marshal(map[string][]map[string]interface{}{c.Name: records})
+		if err != nil {
+			return err
+		}
+
+		if err := writeFileAtomic(filepath.Join(dataPath, c.File), b); err != nil {
+			return err
+		}
 	}
 
-	err = json.Unmarshal(b, &s)
+	return nil
+}
+
+// copyMigrationData returns a deep copy of the data, so that a migration can be compared against the original.
+func copyMigrationData(data MigrationData) (MigrationData, error) {
+	b, err := json.Marshal(data)
 	if err != nil {
-		Armeria.log.Fatal("error unmarshalling mobs.json", zap.Error(err))
+		return nil, err
 	}
 
-	for _, m := range s.Mobs {
-		for _, mi := range m.Instances() {
-			switch to {
-			case 4:
-				// set UnsafeInventory to an initialized inventory
-				mi.UnsafeInventory = NewObjectContainer(0)
+	cp := make(MigrationData)
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&cp); err != nil {
+		return nil, err
+	}
+
+	for file, records := range data {
+		if records != nil && cp[file] == nil {
+			cp[file] = []map[string]interface{}{}
+		}
+	}
+
+	return cp, nil
+}
+
+// runMigrations applies the registered migrations to the data, moving it from one schema version to another.
+// Migrating to an older version runs the Down function of each step, in reverse.
+func runMigrations(data MigrationData, from, to int) error {
+	apply := func(m *Migration, up bool) error {
+		steps := make([]*MigrationStep, len(m.Steps))
+		copy(steps, m.Steps)
+		if !up {
+			for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+				steps[i], steps[j] = steps[j], steps[i]
 			}
 		}
 
-		Armeria.log.Info("mob migration successful",
-			zap.String("name", m.UnsafeName),
-		)
+		for _, s := range steps {
+			fn := s.Up
+			if !up {
+				fn = s.Down
+			}
+			if fn == nil {
+				continue
+			}
+
+			records, err := fn(data[s.File], data)
+			if err != nil {
+				return fmt.Errorf("version %d (%s): %s", m.Version, s.File, err)
+			}
+
+			if records == nil {
+				delete(data, s.File)
+			} else {
+				data[s.File] = records
+			}
+		}
+
+		return nil
 	}
 
-	b, err = json.Marshal(s)
-	if err != nil {
-		Armeria.log.Fatal("error marshalling mobs.json", zap.Error(err))
+	for v := from + 1; v <= to; v++ {
+		m := MigrationByVersion(v)
+		if m == nil {
+			return fmt.Errorf("no migration registered for version %d", v)
+		}
+		if err := apply(m, true); err != nil {
+			return err
+		}
 	}
 
-	err = ioutil.WriteFile(Armeria.dataPath+"/mobs.json", b, 0644)
-	if err != nil {
-		Armeria.log.Fatal("error writing mobs.json", zap.Error(err))
+	for v := from; v > to; v-- {
+		m := MigrationByVersion(v)
+		if m == nil {
+			return fmt.Errorf("no migration registered for version %d", v)
+		}
+		if err := apply(m, false); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
-// migrateMobs handles migrations for items.
-func migrateItems(to int) {
-	s := struct {
-		Items []*Item `json:"items"`
-	}{}
+// snapshotData copies the data directory, other than the audit log and world snapshots, into a timestamped
+// snapshot directory and returns its path.
+func snapshotData(version int) (string, error) {
+	root := filepath.Join(Armeria.dataPath, MigrationSnapshotDir)
+	dest := filepath.Join(root, fmt.Sprintf("%s-v%d", time.Now().Format("20060102-150405"), version))
 
-	b, err := ioutil.ReadFile(Armeria.dataPath + "/items.json")
-	if err != nil {
-		Armeria.log.Fatal("error reading items.json", zap.Error(err))
+	err := filepath.Walk(Armeria.dataPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == root || path == filepath.Join(Armeria.dataPath, AuditLogDir) ||
+			path == filepath.Join(Armeria.dataPath, SnapshotDir) {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(Armeria.dataPath, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(target, b, info.Mode().Perm())
+	})
+
+	return dest, err
+}
+
+// writeMigrationDiff writes a human-readable list of the changes between two versions of the data.
+func writeMigrationDiff(w io.Writer, before, after MigrationData) {
+	if !writeDataDiff(w, before, after) {
+		_, _ = fmt.Fprintln(w, "no changes")
 	}
+}
 
-	err = json.Unmarshal(b, &s)
-	if err != nil {
-		Armeria.log.Fatal("error unmarshalling items.json", zap.Error(err))
+// writeDataDiff writes the changes between two versions of the data, returning whether there were any.
+func writeDataDiff(w io.Writer, before, after MigrationData) bool {
+	changed := false
+
+	for _, c := range StoreCollections() {
+		old, existed := before[c.File]
+		records, exists := after[c.File]
+
+		switch {
+		case !existed && !exists:
+			continue
+		case !existed:
+			_, _ = fmt.Fprintf(w, "+++ %s (created)\n", c.File)
+		case !exists:
+			_, _ = fmt.Fprintf(w, "--- %s (removed)\n", c.File)
+			changed = true
+			continue
+		case reflect.DeepEqual(old, records):
+			continue
+		default:
+			_, _ = fmt.Fprintf(w, "~~~ %s\n", c.File)
+		}
+		changed = true
+
+		oldByKey := make(map[string]map[string]interface{})
+		for _, r := range old {
+			oldByKey[migrationRecordKey(c, r)] = r
+		}
+
+		newByKey := make(map[string]bool)
+		for _, r := range records {
+			key := migrationRecordKey(c, r)
+			newByKey[key] = true
+
+			o, ok := oldByKey[key]
+			if !ok {
+				_, _ = fmt.Fprintf(w, "  + %s\n", migrationRecordLabel(c, r))
+				continue
+			}
+
+			var lines []string
+			diffMigrationValues(&lines, "", o, r)
+			if len(lines) > 0 {
+				_, _ = fmt.Fprintf(w, "  ~ %s\n", migrationRecordLabel(c, r))
+				for _, l := range lines {
+					_, _ = fmt.Fprintf(w, "      %s\n", l)
+				}
+			}
+		}
+
+		for _, r := range old {
+			if !newByKey[migrationRecordKey(c, r)] {
+				_, _ = fmt.Fprintf(w, "  - %s\n", migrationRecordLabel(c, r))
+			}
+		}
 	}
 
-	for _, i := range s.Items {
-		switch to {
-		case 6:
-			if _, exists := i.UnsafeAttributes["rarity"]; exists {
-				i.UnsafeAttributes["rarity"] = "common"
+	return changed
+}
+
+// migrationRecordKey returns the value of a record's key field.
+func migrationRecordKey(c StoreCollection, r map[string]interface{}) string {
+	return fmt.Sprint(r[c.KeyField])
+}
+
+// migrationRecordLabel returns the record's key, along with its name when the name is not already the key.
+func migrationRecordLabel(c StoreCollection, r map[string]interface{}) string {
+	key := migrationRecordKey(c, r)
+	if name, ok := r["name"].(string); ok && c.KeyField != "name" {
+		return fmt.Sprintf("%s (%s)", key, name)
+	}
+
+	return key
+}
+
+// diffMigrationValues appends a line for each difference between two decoded JSON values.
+func diffMigrationValues(lines *[]string, path string, a, b interface{}) {
+	am, aIsMap := a.(map[string]interface{})
+	bm, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := make([]string, 0, len(am)+len(bm))
+		for k := range am {
+			keys = append(keys, k)
+		}
+		for k := range bm {
+			if _, ok := am[k]; !ok {
+				keys = append(keys, k)
 			}
 		}
+		sort.Strings(keys)
 
-		for _, ii := range i.Instances() {
-			switch to {
-			case 6:
-				if _, exists := ii.UnsafeAttributes["rarity"]; exists {
-					ii.UnsafeAttributes["rarity"] = "common"
-				}
+		for _, k := range keys {
+			p := k
+			if len(path) > 0 {
+				p = path + "." + k
+			}
+
+			av, inA := am[k]
+			bv, inB := bm[k]
+			switch {
+			case !inA:
+				*lines = append(*lines, fmt.Sprintf("+ %s: %s", p, migrationValueString(bv)))
+			case !inB:
+				*lines = append(*lines, fmt.Sprintf("- %s: %s", p, migrationValueString(av)))
+			default:
+				diffMigrationValues(lines, p, av, bv)
 			}
 		}
+		return
+	}
 
-		Armeria.log.Info("item migration successful",
-			zap.String("name", i.UnsafeName),
-		)
+	as, aIsSlice := a.([]interface{})
+	bs, bIsSlice := b.([]interface{})
+	if aIsSlice && bIsSlice && len(as) == len(bs) {
+		for i := range as {
+			diffMigrationValues(lines, fmt.Sprintf("%s[%d]", path, i), as[i], bs[i])
+		}
+		return
 	}
 
-	b, err = json.Marshal(s)
-	if err != nil {
-		Armeria.log.Fatal("error marshalling items.json", zap.Error(err))
+	if !reflect.DeepEqual(a, b) {
+		*lines = append(*lines, fmt.Sprintf(
+			"~ %s: %s -> %s",
+			path,
+			migrationValueString(a),
+			migrationValueString(b),
+		))
 	}
+}
 
-	err = ioutil.WriteFile(Armeria.dataPath+"/items.json", b, 0644)
+// migrationValueString returns a decoded JSON value as a short string, suitable for a diff.
+func migrationValueString(v interface{}) string {
+	b, err := json.Marshal(v)
 	if err != nil {
-		Armeria.log.Fatal("error writing items.json", zap.Error(err))
+		return fmt.Sprint(v)
 	}
-}
 
-// migrateLedgers handles migrations for ledgers.
-func migrateLedgers(to int) {
-	if to == 5 {
-		lm := &LedgerManager{
-			dataFile:      fmt.Sprintf("%s/ledgers.json", Armeria.dataPath),
-			UnsafeLedgers: []*Ledger{},
-		}
-		lm.SaveLedgers()
-		Armeria.log.Info("initial ledger created successfully")
+	s := string(b)
+	if len(s) > 72 {
+		s = s[:69] + "..."
 	}
+
+	return s
 }
 
-// Migrate performs a sequential data migration.
-func Migrate() {
+// migrateTo moves the data directory to another schema version. During a dry run, the changes are printed
+// instead of being written.
+func migrateTo(to int, dryRun bool) {
+	if Armeria.storeType != StoreTypeJSON {
+		Armeria.log.Fatal("migrations can only be performed on the json store; convert the data first",
+			zap.String("store", Armeria.storeType),
+		)
+	}
+
 	sv := schemaVersionOnDisk()
 
 	Armeria.log.Info("migration starting",
 		zap.Int("installed", sv),
-		zap.Int("desired", SchemaVersion),
+		zap.Int("desired", to),
+		zap.Bool("dryRun", dryRun),
 	)
 
-	for i := sv + 1; i <= SchemaVersion; i++ {
-		Armeria.log.Info("migrating to next schema version", zap.Int("version", i))
-		migrateCharacters(i)
-		migrateMobs(i)
-		migrateLedgers(i)
-		migrateItems(i)
+	if sv == to {
+		Armeria.log.Info("schema is already at the desired version")
+		return
+	}
+
+	before, err := loadMigrationData(Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Fatal("error loading data files", zap.Error(err))
+	}
+
+	after, err := copyMigrationData(before)
+	if err != nil {
+		Armeria.log.Fatal("error copying data files", zap.Error(err))
+	}
+
+	if err := runMigrations(after, sv, to); err != nil {
+		Armeria.log.Fatal("error running migrations", zap.Error(err))
 	}
 
-	writeSchemaVersionToDisk(SchemaVersion)
+	if dryRun {
+		writeMigrationDiff(os.Stdout, before, after)
+		Armeria.log.Info("dry run complete; no changes were written")
+		return
+	}
+
+	snapshot, err := snapshotData(sv)
+	if err != nil {
+		Armeria.log.Fatal("error snapshotting data directory", zap.Error(err))
+	}
+
+	Armeria.log.Info("data directory snapshotted",
+		zap.String("path", snapshot),
+	)
+
+	if err := writeMigrationData(Armeria.dataPath, before, after); err != nil {
+		Armeria.log.Fatal("error writing data files; restore from the snapshot",
+			zap.String("snapshot", snapshot),
+			zap.Error(err),
+		)
+	}
+
+	writeSchemaVersionToDisk(to)
+
+	Armeria.log.Info("migration complete",
+		zap.Int("version", to),
+	)
+}
+
+// Migrate performs a sequential data migration up to the current schema version.
+func Migrate(dryRun bool) {
+	migrateTo(SchemaVersion, dryRun)
+}
+
+// Rollback reverts the data to an older schema version.
+func Rollback(version int, dryRun bool) {
+	sv := schemaVersionOnDisk()
+
+	if version < 1 || version > sv {
+		Armeria.log.Fatal("cannot roll back to that schema version",
+			zap.Int("installed", sv),
+			zap.Int("requested", version),
+		)
+	}
 
-	Armeria.log.Info("migration complete")
+	migrateTo(version, dryRun)
 }
@@ -0,0 +1,282 @@
+package armeria
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const migrationFixturePath string = "testdata/migrations/v1"
+
+// loadMigrationFixture loads the version 1 fixture data.
+func loadMigrationFixture(t *testing.T) MigrationData {
+	data, err := loadMigrationData(migrationFixturePath)
+	if err != nil {
+		t.Fatalf("error loading fixture: %s", err)
+	}
+	return data
+}
+
+// normalizeMigrationData round-trips the data through JSON so that values created by a migration compare equal
+// to values decoded from disk.
+func normalizeMigrationData(t *testing.T, data MigrationData) MigrationData {
+	cp, err := copyMigrationData(data)
+	if err != nil {
+		t.Fatalf("error copying data: %s", err)
+	}
+	return cp
+}
+
+// useMigrationDataPath points the game state at a copy of the fixture data within a temporary directory, which
+// the caller is responsible for removing.
+func useMigrationDataPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "armeria-migration")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+
+	files, err := ioutil.ReadDir(migrationFixturePath)
+	if err != nil {
+		t.Fatalf("error reading fixture: %s", err)
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(filepath.Join(migrationFixturePath, f.Name()))
+		if err != nil {
+			t.Fatalf("error reading fixture: %s", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, f.Name()), b, 0644); err != nil {
+			t.Fatalf("error writing fixture: %s", err)
+		}
+	}
+
+	Armeria = &GameState{
+		dataPath:  dir,
+		storeType: StoreTypeJSON,
+		log:       zap.NewNop(),
+	}
+
+	return dir
+}
+
+func TestMigrationRegistry(t *testing.T) {
+	migrations := Migrations()
+
+	if len(migrations) != SchemaVersion-1 {
+		t.Fatalf("expected %d migrations, got %d", SchemaVersion-1, len(migrations))
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+2 {
+			t.Errorf("migration %d has version %d", i, m.Version)
+		}
+		if len(m.Steps) == 0 {
+			t.Errorf("migration %d has no steps", m.Version)
+		}
+		for _, s := range m.Steps {
+			if _, ok := collectionByFile(s.File); !ok {
+				t.Errorf("migration %d has a step for an unknown file: %s", m.Version, s.File)
+			}
+		}
+	}
+}
+
+func TestMigrateFixture(t *testing.T) {
+	data := loadMigrationFixture(t)
+	if err := runMigrations(data, 1, SchemaVersion); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	accounts := data[CollectionAccounts.File]
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(accounts))
+	}
+
+	for _, c := range data[CollectionCharacters.File] {
+		if _, ok := c["lastSeen"]; !ok {
+			t.Errorf("character %s is missing lastSeen", c["name"])
+		}
+		if _, ok := c["settings"]; !ok {
+			t.Errorf("character %s is missing settings", c["name"])
+		}
+		if _, ok := c["password"]; ok {
+			t.Errorf("character %s still has a password", c["name"])
+		}
+
+		var account map[string]interface{}
+		for _, a := range accounts {
+			if a["uuid"] == c["account"] {
+				account = a
+			}
+		}
+		if account == nil || account["name"] != c["name"] {
+			t.Errorf("character %s is not linked to its account", c["name"])
+		}
+	}
+
+	for _, m := range data[CollectionMobs.File] {
+		for _, mi := range migrationObjects(m, "instances") {
+			if _, ok := mi["inventory"]; !ok {
+				t.Errorf("mob instance %s is missing an inventory", mi["uuid"])
+			}
+		}
+	}
+
+	sword := data[CollectionItems.File][0]
+	if sword["attributes"].(map[string]interface{})["rarity"] != "common" {
+		t.Errorf("item rarity was not reset")
+	}
+	if migrationObjects(sword, "instances")[0]["attributes"].(map[string]interface{})["rarity"] != "common" {
+		t.Errorf("item instance rarity was not reset")
+	}
+	if _, ok := data[CollectionItems.File][1]["attributes"].(map[string]interface{})["rarity"]; ok {
+		t.Errorf("item without a rarity was given one")
+	}
+
+	for _, c := range data[CollectionCharacters.File] {
+		roles, _ := c["roles"].([]interface{})
+		if attrs, _ := c["attributes"].(map[string]interface{}); attrs["permissions"] != nil {
+			t.Errorf("character %s still has a permissions attribute", c["name"])
+		}
+		if c["name"] == "Admin" && (len(roles) != 1 || roles[0] != "legacy-admin") {
+			t.Errorf("expected Admin to have the legacy-admin role, got %v", c["roles"])
+		} else if c["name"] == "Bob" && len(roles) != 0 {
+			t.Errorf("expected Bob to have no roles, got %v", c["roles"])
+		}
+	}
+	legacy := migrationRolePermissions(data[CollectionRoles.File], "legacy-admin")
+	if !samePermissions(legacy, []string{PermissionSysop, PermissionBuild, PermissionBuildAnywhere}) {
+		t.Errorf("expected the legacy-admin role to keep Admin's permissions, got %v", legacy)
+	}
+	if contributor := migrationRolePermissions(data[CollectionRoles.File], "contributor"); !samePermissions(contributor, []string{PermissionBuild}) {
+		t.Errorf("expected the contributor role to only grant CAN_BUILD, got %v", contributor)
+	}
+
+	for _, c := range []StoreCollection{CollectionLedgers, CollectionSessions} {
+		if records, ok := data[c.File]; !ok || len(records) != 0 {
+			t.Errorf("expected an empty %s", c.File)
+		}
+	}
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	data := loadMigrationFixture(t)
+
+	for _, m := range Migrations() {
+		reversible := true
+		for _, s := range m.Steps {
+			if s.Down == nil {
+				reversible = false
+			}
+		}
+
+		before := normalizeMigrationData(t, data)
+		if err := runMigrations(data, m.Version-1, m.Version); err != nil {
+			t.Fatalf("error migrating to %d: %s", m.Version, err)
+		}
+
+		if reversible {
+			after := normalizeMigrationData(t, data)
+			if err := runMigrations(after, m.Version, m.Version-1); err != nil {
+				t.Fatalf("error rolling back %d: %s", m.Version, err)
+			}
+			if !reflect.DeepEqual(before, normalizeMigrationData(t, after)) {
+				t.Errorf("rolling back %d did not restore the data", m.Version)
+			}
+		}
+	}
+}
+
+func TestMigrationDiff(t *testing.T) {
+	before := loadMigrationFixture(t)
+	after := normalizeMigrationData(t, before)
+	if err := runMigrations(after, 1, SchemaVersion); err != nil {
+		t.Fatalf("error migrating: %s", err)
+	}
+
+	var b bytes.Buffer
+	writeMigrationDiff(&b, before, after)
+	diff := b.String()
+
+	expected := []string{
+		"+++ accounts.json (created)",
+		"~~~ characters.json",
+		"  ~ 4ae0203b-1907-4bfa-afa8-23951681bd22 (Admin)",
+		"- password: ",
+		"+ settings: {}",
+		"~ attributes.rarity: \"legendary\" -> \"common\"",
+		"+ instances[1].inventory: ",
+		"+++ sessions.json (created)",
+		"+++ quests.json (created)",
+		"+ quests: {}",
+		"+++ roles.json (created)",
+		"+ roles: [\"legacy-admin\"]",
+	}
+	for _, e := range expected {
+		if !strings.Contains(diff, e) {
+			t.Errorf("expected diff to contain %q:\n%s", e, diff)
+		}
+	}
+
+	b.Reset()
+	writeMigrationDiff(&b, before, before)
+	if b.String() != "no changes\n" {
+		t.Errorf("expected no changes, got:\n%s", b.String())
+	}
+}
+
+func TestMigrateAndRollback(t *testing.T) {
+	dir := useMigrationDataPath(t)
+	defer os.RemoveAll(dir)
+	original := loadMigrationFixture(t)
+
+	Migrate(true)
+	if schemaVersionOnDisk() != 1 {
+		t.Fatalf("dry run changed the schema version")
+	}
+	if _, err := os.Stat(filepath.Join(dir, MigrationSnapshotDir)); !os.IsNotExist(err) {
+		t.Fatalf("dry run took a snapshot")
+	}
+
+	Migrate(false)
+	if schemaVersionOnDisk() != SchemaVersion {
+		t.Fatalf("expected schema version %d, got %d", SchemaVersion, schemaVersionOnDisk())
+	}
+
+	snapshots, err := ioutil.ReadDir(filepath.Join(dir, MigrationSnapshotDir))
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("expected a single snapshot")
+	}
+	snapshot, err := loadMigrationData(filepath.Join(dir, MigrationSnapshotDir, snapshots[0].Name()))
+	if err != nil {
+		t.Fatalf("error loading snapshot: %s", err)
+	}
+	if !reflect.DeepEqual(snapshot, original) {
+		t.Errorf("snapshot does not match the original data")
+	}
+
+	Rollback(6, false)
+	if schemaVersionOnDisk() != 6 {
+		t.Fatalf("expected schema version 6, got %d", schemaVersionOnDisk())
+	}
+
+	rolledBack, err := loadMigrationData(dir)
+	if err != nil {
+		t.Fatalf("error loading data: %s", err)
+	}
+	for _, c := range []StoreCollection{CollectionAccounts, CollectionSessions} {
+		if _, ok := rolledBack[c.File]; ok {
+			t.Errorf("expected %s to be removed", c.File)
+		}
+	}
+	for _, c := range rolledBack[CollectionCharacters.File] {
+		if _, ok := c["password"]; !ok {
+			t.Errorf("character %s did not get its password back", c["name"])
+		}
+	}
+}
@@ -2,15 +2,19 @@ package armeria
 
 import (
 	"armeria/internal/pkg/misc"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+
+	lua "github.com/yuin/gopher-lua"
 )
 
-// Force verify that MobInstance implements ContainerObject.
+// Force verify that MobInstance implements ContainerObject and ScriptOwner.
 var _ ContainerObject = (*MobInstance)(nil)
+var _ ScriptOwner = (*MobInstance)(nil)
 
 type MobInstance struct {
 	sync.RWMutex
@@ -22,6 +26,7 @@ type MobInstance struct {
 	UnsafeMobSpawnerUUID string            `json:"spawnerUUID"`
 	UnsafeMoveTicks      int               `json:"moveTicks"`
 	UnsafeConvoText      map[string]string `json:"-"`
+	UnsafeScriptVM       *ScriptVM         `json:"-"`
 }
 
 // Init is called when the MobInstance is created or loaded from disk.
@@ -34,11 +39,14 @@ func (mi *MobInstance) Init() {
 	mi.UnsafeInventory.Sync()
 	// Initialize some properties.
 	mi.UnsafeConvoText = make(map[string]string)
+	mi.UnsafeScriptVM = NewScriptVM(SetupMobLState)
 }
 
 // Deinit is called when the MobInstance is deleted.
 func (mi *MobInstance) Deinit() {
 	Armeria.registry.Unregister(mi.ID())
+	// Closed in the background, since a script may still be running.
+	go mi.ScriptVM().Close()
 }
 
 // ID returns the UUID of the instance.
@@ -67,9 +75,9 @@ func (mi *MobInstance) FormattedName() string {
 			[]string{
 				fmt.Sprintf("Look @|/look %s", mi.ID()),
 				fmt.Sprintf("Interact @|/interact %s", mi.ID()),
-				fmt.Sprintf("Jump @|/tp %s||CAN_BUILD", mi.Room().LocationString()),
-				fmt.Sprintf("Edit @|/mob iedit %s||CAN_BUILD", mi.ID()),
-				fmt.Sprintf("Edit-Parent @|/mob edit %s||CAN_BUILD", mi.Name()),
+				fmt.Sprintf("Jump @|/tp %s||%s", mi.Room().LocationString(), PermissionBuild),
+				fmt.Sprintf("Edit @|/mob iedit %s||%s", mi.ID(), PermissionBuild),
+				fmt.Sprintf("Edit-Parent @|/mob edit %s||%s", mi.Name(), PermissionBuild),
 			},
 		),
 		WithBold(),
@@ -77,6 +85,38 @@ func (mi *MobInstance) FormattedName() string {
 	)
 }
 
+// ScriptVM returns the persistent Lua environment used to run the mob's script.
+func (mi *MobInstance) ScriptVM() *ScriptVM {
+	mi.RLock()
+	defer mi.RUnlock()
+	return mi.UnsafeScriptVM
+}
+
+// ScriptKind returns the kind of script the MobInstance runs, which prefixes its Lua globals.
+func (mi *MobInstance) ScriptKind() string {
+	return "mob"
+}
+
+// ScriptFile returns the full path to the parent Mob's Lua script file.
+func (mi *MobInstance) ScriptFile() string {
+	return mi.Parent.ScriptFile()
+}
+
+// ScriptFuncs returns the functions within the parent Mob's script file.
+func (mi *MobInstance) ScriptFuncs() []string {
+	return mi.Parent.ScriptFuncs()
+}
+
+// ScriptProto returns the parent Mob's compiled script.
+func (mi *MobInstance) ScriptProto() (*lua.FunctionProto, error) {
+	return mi.Parent.ScriptProto()
+}
+
+// ScriptRoom returns the Room the mob's script acts upon.
+func (mi *MobInstance) ScriptRoom() *Room {
+	return mi.Room()
+}
+
 // MoveTicks returns the number of mob movement ticks that have passed.
 func (mi *MobInstance) MoveTicks() int {
 	mi.RLock()
@@ -151,13 +191,15 @@ func (mi *MobInstance) SetAttribute(name string, value string) error {
 // Attribute returns an attribute on the MobInstance, and falls back to the parent Mob.
 func (mi *MobInstance) Attribute(name string) string {
 	mi.RLock()
-	defer mi.RUnlock()
+	value := mi.UnsafeAttributes[name]
+	mi.RUnlock()
 
-	if len(mi.UnsafeAttributes[name]) == 0 {
+	// The parent is read without holding the instance lock, to keep to the lock order.
+	if len(value) == 0 {
 		return mi.Parent.Attribute(name)
 	}
 
-	return mi.UnsafeAttributes[name]
+	return value
 }
 
 // AttributeBool returns an attribute on the MobInstance as a bool.
@@ -202,7 +244,10 @@ func (mi *MobInstance) ItemLedgers() []*Ledger {
 	mi.RLock()
 	defer mi.RUnlock()
 
-	return mi.UnsafeItemLedgers
+	ledgers := make([]*Ledger, len(mi.UnsafeItemLedgers))
+	copy(ledgers, mi.UnsafeItemLedgers)
+
+	return ledgers
 }
 
 // MobInstance returns the MobInstance's Room based on the object container it is within.
@@ -214,6 +259,43 @@ func (mi *MobInstance) Room() *Room {
 	return oc.ParentRoom()
 }
 
+// Move moves the MobInstance to an adjacent Room, in the given direction, and lets both rooms know.
+func (mi *MobInstance) Move(to *Room, dir string) {
+	oldRoom := mi.Room()
+	if oldRoom == nil || oldRoom.Here().Transfer(mi.ID(), to.Here()) != nil {
+		return
+	}
+
+	mobNameString := fmt.Sprintf("A %s", mi.FormattedName())
+	if mi.Attribute(AttributeGender) != "thing" {
+		mobNameString = mi.FormattedName()
+	}
+
+	for _, c := range oldRoom.Here().Characters(true) {
+		c.Player().client.ShowText(
+			TextStyle(
+				fmt.Sprintf("%s travels %s.", mobNameString, misc.MoveToStringFromDir("to the", dir)),
+				WithUserColor(c, ColorMovement),
+			),
+		)
+		c.Player().client.SyncRoomObjects()
+	}
+
+	for _, c := range to.Here().Characters(true) {
+		c.Player().client.ShowText(
+			TextStyle(
+				fmt.Sprintf(
+					"%s entered from %s.",
+					mobNameString,
+					misc.MoveToStringFromDir("the", misc.OppositeDirection(dir)),
+				),
+				WithUserColor(c, ColorMovement),
+			),
+		)
+		c.Player().client.SyncRoomObjects()
+	}
+}
+
 // Inventory returns the unsafeCharacter's inventory.
 func (mi *MobInstance) Inventory() *ObjectContainer {
 	mi.RLock()
@@ -289,3 +371,13 @@ func (mi *MobInstance) Pronoun(pt PronounType) string {
 func (mi *MobInstance) Delete() {
 	mi.Parent.DeleteInstance(mi)
 }
+
+type mobInstanceJSON MobInstance
+
+// MarshalJSON encodes the MobInstance while holding its lock.
+func (mi *MobInstance) MarshalJSON() ([]byte, error) {
+	mi.RLock()
+	defer mi.RUnlock()
+
+	return json.Marshal((*mobInstanceJSON)(mi))
+}
@@ -2,25 +2,23 @@ package armeria
 
 import (
 	"armeria/internal/pkg/misc"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"regexp"
 	"strings"
 	"sync"
 
 	"go.uber.org/zap"
 
 	"github.com/google/uuid"
+	lua "github.com/yuin/gopher-lua"
 )
 
 type Mob struct {
 	sync.RWMutex
-	UnsafeName        string            `json:"name"`
-	UnsafeAttributes  map[string]string `json:"attributes"`
-	UnsafeInstances   []*MobInstance    `json:"instances"`
-	UnsafeScript      string            `json:"-"`
-	UnsafeScriptFuncs []string          `json:"-"`
+	UnsafeName       string            `json:"name"`
+	UnsafeAttributes map[string]string `json:"attributes"`
+	UnsafeInstances  []*MobInstance    `json:"instances"`
+	UnsafeScript     *CachedScript     `json:"-"`
 }
 
 // Init is called when the Mob is created or loaded from disk.
@@ -128,6 +126,21 @@ func (m *Mob) DeleteInstance(mi *MobInstance) bool {
 	return false
 }
 
+// RestoreInstance adds a deleted MobInstance back to the Mob, such as when its deletion is undone.
+func (m *Mob) RestoreInstance(mi *MobInstance) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.UnsafeInstances = append(m.UnsafeInstances, mi)
+
+	mi.Init()
+
+	Armeria.log.Info("instance restored",
+		zap.String("uuid", mi.ID()),
+		zap.String("name", m.UnsafeName),
+	)
+}
+
 // Instance returns a MobInstance by the instance identifier.
 func (m *Mob) Instance(uuid string) *MobInstance {
 	m.RLock()
@@ -146,7 +159,27 @@ func (m *Mob) Instance(uuid string) *MobInstance {
 func (m *Mob) Instances() []*MobInstance {
 	m.RLock()
 	defer m.RUnlock()
-	return m.UnsafeInstances
+
+	instances := make([]*MobInstance, len(m.UnsafeInstances))
+	copy(instances, m.UnsafeInstances)
+
+	return instances
+}
+
+// CanBuild returns whether the Character may change the Mob. Mobs are shared by every area, so the Character
+// must be able to build in every area with an instance of the Mob in it.
+func (m *Mob) CanBuild(c *Character) bool {
+	if !c.HasPermission(PermissionBuild) {
+		return false
+	}
+
+	for _, mi := range m.Instances() {
+		if r := mi.Room(); r != nil && !r.ParentArea.CanBuild(c) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // InstancesFromSpawner returns all MobInstance's from a mob spawner ItemInstance.
@@ -181,43 +214,42 @@ func (m *Mob) ScriptFile() string {
 	return m.scriptFile()
 }
 
-// CacheScript reads the script contents and caches the contents and individual functions.
+// CacheScript reads the script contents and caches the contents, individual functions and compiled script.
 func (m *Mob) CacheScript() {
 	m.Lock()
 	defer m.Unlock()
 
-	if _, err := os.Stat(m.scriptFile()); err != nil {
-		return
-	}
-
-	b, err := ioutil.ReadFile(m.scriptFile())
-	if err != nil {
-		return
-	}
-
-	m.UnsafeScriptFuncs = []string{}
-
-	re := regexp.MustCompile("function ([a-zA-Z_]+)")
-	matches := re.FindAllSubmatch(b, -1)
-	if matches != nil {
-		for _, match := range matches {
-			m.UnsafeScriptFuncs = append(m.UnsafeScriptFuncs, string(match[1]))
-		}
-	}
-
-	m.UnsafeScript = string(b)
+	m.UnsafeScript = LoadScript(m.scriptFile())
 }
 
 // Script returns the cached script contents.
 func (m *Mob) Script() string {
 	m.RLock()
 	defer m.RUnlock()
-	return m.UnsafeScript
+	return m.UnsafeScript.Source
 }
 
 // ScriptFuncs returns the cached functions within the Mob's script file.
 func (m *Mob) ScriptFuncs() []string {
 	m.RLock()
 	defer m.RUnlock()
-	return m.UnsafeScriptFuncs
+	return m.UnsafeScript.Funcs
+}
+
+// ScriptProto returns the compiled script, or the error encountered while compiling it. The proto is nil when
+// the Mob has no script.
+func (m *Mob) ScriptProto() (*lua.FunctionProto, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.UnsafeScript.Proto, m.UnsafeScript.Err
+}
+
+type mobJSON Mob
+
+// MarshalJSON encodes the Mob while holding its lock.
+func (m *Mob) MarshalJSON() ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return json.Marshal((*mobJSON)(m))
 }
@@ -1,7 +1,6 @@
 package armeria
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -13,14 +12,11 @@ import (
 
 type MobManager struct {
 	sync.RWMutex
-	dataFile   string
 	UnsafeMobs []*Mob `json:"mobs"`
 }
 
 func NewMobManager() *MobManager {
-	m := &MobManager{
-		dataFile: fmt.Sprintf("%s/mobs.json", Armeria.dataPath),
-	}
+	m := &MobManager{}
 
 	m.LoadMobs()
 	m.AttachParents()
@@ -33,22 +29,18 @@ func (m *MobManager) LoadMobs() {
 	m.Lock()
 	defer m.Unlock()
 
-	mobsFile, err := os.Open(m.dataFile)
-	defer mobsFile.Close()
-
+	records, err := Armeria.store.Load(CollectionMobs)
 	if err != nil {
-		Armeria.log.Fatal("failed to load data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionMobs.Name),
 			zap.Error(err),
 		)
 	}
 
-	jsonParser := json.NewDecoder(mobsFile)
-
-	err = jsonParser.Decode(m)
+	err = DecodeStoreRecords(records, &m.UnsafeMobs)
 	if err != nil {
-		Armeria.log.Fatal("failed to decode data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionMobs.Name),
 			zap.Error(err),
 		)
 	}
@@ -71,32 +63,32 @@ func (m *MobManager) SaveMobs() {
 	m.RLock()
 	defer m.RUnlock()
 
-	mobsFile, err := os.Create(m.dataFile)
-	defer mobsFile.Close()
-
-	raw, err := json.Marshal(m)
+	records, err := EncodeStoreRecords(CollectionMobs, m.UnsafeMobs)
 	if err != nil {
 		Armeria.log.Fatal("failed to marshal data",
 			zap.Error(err),
 		)
 	}
 
-	bytes, err := mobsFile.Write(raw)
+	err = Armeria.store.Save(CollectionMobs, records)
 	if err != nil {
-		Armeria.log.Fatal("failed to write data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionMobs.Name),
 			zap.Error(err),
 		)
 	}
 
-	_ = mobsFile.Sync()
-
-	Armeria.log.Info("wrote data to file",
-		zap.String("file", m.dataFile),
-		zap.Int("bytes", bytes),
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionMobs.Name),
+		zap.Int("records", len(records)),
 	)
 }
 
+// SaveMob immediately persists a single Mob, along with its instances, to the store.
+func (m *MobManager) SaveMob(mob *Mob) {
+	persistRecord(CollectionMobs, mob)
+}
+
 // AttachParents attaches a pointer to MobInstance that references the parent Mob.
 func (m *MobManager) AttachParents() {
 	m.RLock()
@@ -128,7 +120,10 @@ func (m *MobManager) Mobs() []*Mob {
 	m.RLock()
 	defer m.RUnlock()
 
-	return m.UnsafeMobs
+	mobs := make([]*Mob, len(m.UnsafeMobs))
+	copy(mobs, m.UnsafeMobs)
+
+	return mobs
 }
 
 // CreateMob creates a new Mob instance, but doesn't add it to memory.
@@ -141,16 +136,18 @@ func (m *MobManager) CreateMob(name string) *Mob {
 	// Create the script file.
 	content := fmt.Sprintf("-- %s Script", name)
 	_ = ioutil.WriteFile(mob.ScriptFile(), []byte(content), 0644)
+	mob.CacheScript()
 
 	return mob
 }
 
-// AddMob adds a new Mob reference to memory.
+// AddMob adds a new Mob reference to memory, and persists it.
 func (m *MobManager) AddMob(mob *Mob) {
 	m.Lock()
-	defer m.Unlock()
-
 	m.UnsafeMobs = append(m.UnsafeMobs, mob)
+	m.Unlock()
+
+	m.SaveMob(mob)
 }
 
 // RemoveMob removes an existing Mob reference from memory.
@@ -172,6 +169,8 @@ func (m *MobManager) RemoveMob(mob *Mob) {
 		return
 	}
 
+	removeRecord(CollectionMobs, mob.Name())
+
 	// Delete the script file.
 	_ = os.Remove(mob.ScriptFile())
 
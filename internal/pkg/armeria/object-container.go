@@ -2,6 +2,7 @@ package armeria
 
 import (
 	"armeria/internal/pkg/misc"
+	"encoding/json"
 	"errors"
 	"strings"
 	"sync"
@@ -56,8 +57,17 @@ var (
 	ErrContainerNoRoom = errors.New("no space in container")
 	// ErrContainerDuplicate is an error for when the container already contains a specific uuid.
 	ErrContainerDuplicate = errors.New("object already in container")
+	// ErrContainerMissing is an error for when the container no longer contains a specific uuid.
+	ErrContainerMissing = errors.New("object not in container")
 )
 
+// containerTransfer is held while an object moves between two containers.
+var containerTransfer sync.Mutex
+
+// objectContainerJSON has the fields of an ObjectContainer but none of its methods, so that MarshalJSON can encode it
+// without calling itself.
+type objectContainerJSON ObjectContainer
+
 // ContainerParentType is an int representing the container's parent type.
 type ContainerParentType int
 
@@ -144,6 +154,7 @@ func (oc *ObjectContainer) ParentType() ContainerParentType {
 	return oc.UnsafeParentType
 }
 
+// MaxSize returns the maximum number of objects the container can hold, or 0 when it is unbounded.
 func (oc *ObjectContainer) MaxSize() int {
 	oc.RLock()
 	defer oc.RUnlock()
@@ -154,39 +165,74 @@ func (oc *ObjectContainer) MaxSize() int {
 // Contains returns a bool indicating whether the object container contains something with the
 // specified uuid.
 func (oc *ObjectContainer) Contains(uuid string) bool {
-	return oc.Get(uuid).Type != RegistryTypeUnknown
-}
-
-// Get retrieves an object from the object container, based on the uuid.
-func (oc *ObjectContainer) Get(uuid string) *ObjectContainerResult {
 	oc.RLock()
 	defer oc.RUnlock()
 
-	for _, ocd := range oc.UnsafeObjects {
+	return oc.indexOf(uuid) >= 0
+}
+
+// indexOf returns the position of the uuid within the container, or -1 when it isn't in the container. This
+// DOES NOT request a lock and IS NOT thread safe.
+func (oc *ObjectContainer) indexOf(uuid string) int {
+	for i, ocd := range oc.UnsafeObjects {
 		if ocd.UUID == uuid {
-			o, ot := Armeria.registry.Get(ocd.UUID)
-			return &ObjectContainerResult{Object: o.(ContainerObject), Definition: ocd, Type: ot}
+			return i
 		}
 	}
 
-	return &ObjectContainerResult{Type: RegistryTypeUnknown}
+	return -1
 }
 
-// GetByName retrieves an object from the object container, based on the name of the object.
-func (oc *ObjectContainer) GetByName(name string) *ObjectContainerResult {
+// definitions returns a copy of each definition within the container, so that the objects can be looked up
+// without holding the container's lock.
+func (oc *ObjectContainer) definitions() []*ObjectContainerDefinition {
 	oc.RLock()
 	defer oc.RUnlock()
 
-	for _, ocd := range oc.UnsafeObjects {
-		o, ot := Armeria.registry.Get(ocd.UUID)
-		if strings.ToLower(o.(ContainerObject).Name()) == strings.ToLower(name) {
-			return &ObjectContainerResult{Object: o.(ContainerObject), Definition: ocd, Type: ot}
+	defs := make([]*ObjectContainerDefinition, len(oc.UnsafeObjects))
+	for i, ocd := range oc.UnsafeObjects {
+		def := *ocd
+		defs[i] = &def
+	}
+
+	return defs
+}
+
+// result looks up the object for a definition, returning nil when the object no longer exists.
+func (oc *ObjectContainer) result(ocd *ObjectContainerDefinition) *ObjectContainerResult {
+	o, ot := Armeria.registry.Get(ocd.UUID)
+	if o == nil {
+		return nil
+	}
+
+	return &ObjectContainerResult{Object: o.(ContainerObject), Definition: ocd, Type: ot}
+}
+
+// find returns the first object matching the function, or a RegistryTypeUnknown result if nothing matched.
+func (oc *ObjectContainer) find(match func(r *ObjectContainerResult) bool) *ObjectContainerResult {
+	for _, ocd := range oc.definitions() {
+		if r := oc.result(ocd); r != nil && match(r) {
+			return r
 		}
 	}
 
 	return &ObjectContainerResult{Type: RegistryTypeUnknown}
 }
 
+// Get retrieves an object from the object container, based on the uuid.
+func (oc *ObjectContainer) Get(uuid string) *ObjectContainerResult {
+	return oc.find(func(r *ObjectContainerResult) bool {
+		return r.Definition.UUID == uuid
+	})
+}
+
+// GetByName retrieves an object from the object container, based on the name of the object.
+func (oc *ObjectContainer) GetByName(name string) *ObjectContainerResult {
+	return oc.find(func(r *ObjectContainerResult) bool {
+		return strings.ToLower(r.Object.Name()) == strings.ToLower(name)
+	})
+}
+
 // GetByAny attempts to retrieve an object by it's uuid, and then by it's name.
 func (oc *ObjectContainer) GetByAny(uuidOrName string) *ObjectContainerResult {
 	if misc.IsUUID(uuidOrName) {
@@ -203,90 +249,95 @@ func (oc *ObjectContainer) GetLoose(id string) *ObjectContainerResult {
 		return oc.Get(id)
 	}
 
-	oc.RLock()
-	defer oc.RUnlock()
-
 	match := strings.ToLower(id)
-	for _, ocd := range oc.UnsafeObjects {
-		o, ot := Armeria.registry.Get(ocd.UUID)
-		iname := strings.ToLower(o.(ContainerObject).Name())
-		if len(match) <= len(iname) && iname[0:len(match)] == match {
-			return &ObjectContainerResult{Object: o.(ContainerObject), Definition: ocd, Type: ot}
-		}
-	}
-
-	return &ObjectContainerResult{Type: RegistryTypeUnknown}
+	return oc.find(func(r *ObjectContainerResult) bool {
+		return strings.HasPrefix(strings.ToLower(r.Object.Name()), match)
+	})
 }
 
 // Slot returns the slot that the uuid is within. If the uuid does not exist, slot 0 will be returned,
 // which could result in a false positive. Check existence of the uuid before using this function.
 func (oc *ObjectContainer) Slot(uuid string) int {
-	result := oc.Get(uuid)
-	if result.Type == RegistryTypeUnknown {
-		return 0
+	oc.RLock()
+	defer oc.RUnlock()
+
+	if i := oc.indexOf(uuid); i >= 0 {
+		return oc.UnsafeObjects[i].Slot
 	}
 
-	return result.Definition.Slot
+	return 0
 }
 
 // SlotName returns the slot name that the uuid is using. If the uuid does not exist, an empty string is returned.
 func (oc *ObjectContainer) SlotName(uuid string) string {
-	result := oc.Get(uuid)
-	if result.Type == RegistryTypeUnknown {
-		return ""
+	oc.RLock()
+	defer oc.RUnlock()
+
+	if i := oc.indexOf(uuid); i >= 0 {
+		return oc.UnsafeObjects[i].SlotName
 	}
 
-	return result.Definition.SlotName
+	return ""
 }
 
 // SetSlot explicitly sets an item slot without checking if another item already exists in that slot. Use this
 // function carefully and as-needed (ie: swapping items).
 func (oc *ObjectContainer) SetSlot(uuid string, slot int) {
-	oc.Get(uuid).Definition.Slot = slot
+	oc.Lock()
+	defer oc.Unlock()
+
+	if i := oc.indexOf(uuid); i >= 0 {
+		oc.UnsafeObjects[i].Slot = slot
+	}
 }
 
 // SetSlotName explicitly sets an item slot name.
 func (oc *ObjectContainer) SetSlotName(uuid string, slotName EquipmentSlot) {
-	oc.Get(uuid).Definition.SlotName = string(slotName)
-}
-
-// AtSlot retrieves an object from a specific slot, or nil if the container is unbounded.
-func (oc *ObjectContainer) AtSlot(slot int) *ObjectContainerResult {
-	oc.RLock()
-	defer oc.RUnlock()
+	oc.Lock()
+	defer oc.Unlock()
 
-	if oc.UnsafeMaxSize == 0 {
-		return &ObjectContainerResult{Type: RegistryTypeUnknown}
+	if i := oc.indexOf(uuid); i >= 0 {
+		oc.UnsafeObjects[i].SlotName = string(slotName)
 	}
+}
+
+// SwapSlots exchanges the objects within two slots. Either slot may be empty.
+func (oc *ObjectContainer) SwapSlots(a int, b int) {
+	oc.Lock()
+	defer oc.Unlock()
 
 	for _, ocd := range oc.UnsafeObjects {
-		if ocd.Slot == slot {
-			o, ot := Armeria.registry.Get(ocd.UUID)
-			return &ObjectContainerResult{Object: o.(ContainerObject), Definition: ocd, Type: ot}
+		if ocd.Slot == a {
+			ocd.Slot = b
+		} else if ocd.Slot == b {
+			ocd.Slot = a
 		}
 	}
+}
 
-	return &ObjectContainerResult{Type: RegistryTypeUnknown}
+// AtSlot retrieves an object from a specific slot, or nil if the container is unbounded.
+func (oc *ObjectContainer) AtSlot(slot int) *ObjectContainerResult {
+	if oc.MaxSize() == 0 {
+		return &ObjectContainerResult{Type: RegistryTypeUnknown}
+	}
+
+	return oc.find(func(r *ObjectContainerResult) bool {
+		return r.Definition.Slot == slot
+	})
 }
 
 // AtSlotName retrieves object(s) from a specific slot name (eg: equipment). Can be multiple if the slot allows.
 func (oc *ObjectContainer) AtSlotName(slot EquipmentSlot) []*ObjectContainerResult {
-	oc.RLock()
-	defer oc.RUnlock()
-
 	matches := make([]*ObjectContainerResult, 0)
-	for _, ocd := range oc.UnsafeObjects {
+	for _, ocd := range oc.definitions() {
 		if ocd.SlotName == string(slot) {
-			o, ot := Armeria.registry.Get(ocd.UUID)
-			matches = append(matches, &ObjectContainerResult{Object: o.(ContainerObject), Definition: ocd, Type: ot})
+			if r := oc.result(ocd); r != nil {
+				matches = append(matches, r)
+			}
 		}
 	}
 
-	if len(matches) > 0 {
-		return matches
-	}
-
-	return []*ObjectContainerResult{}
+	return matches
 }
 
 // Count returns the number of objects within the container.
@@ -299,13 +350,11 @@ func (oc *ObjectContainer) Count() int {
 
 // All returns all the objects within the container.
 func (oc *ObjectContainer) All() []interface{} {
-	oc.RLock()
-	defer oc.RUnlock()
-
 	var everything []interface{}
-	for _, ocd := range oc.UnsafeObjects {
-		o, _ := Armeria.registry.Get(ocd.UUID)
-		everything = append(everything, o)
+	for _, ocd := range oc.definitions() {
+		if o, _ := Armeria.registry.Get(ocd.UUID); o != nil {
+			everything = append(everything, o)
+		}
 	}
 
 	return everything
@@ -325,9 +374,6 @@ func (oc *ObjectContainer) Sync() {
 
 // Characters returns all Character objects from the container.
 func (oc *ObjectContainer) Characters(onlineOnly bool, exceptions ...*Character) []*Character {
-	oc.RLock()
-	defer oc.RUnlock()
-
 	var chars []*Character
 	var exceptionIds []string
 
@@ -335,7 +381,7 @@ func (oc *ObjectContainer) Characters(onlineOnly bool, exceptions ...*Character)
 		exceptionIds = append(exceptionIds, c.ID())
 	}
 
-	for _, ocd := range oc.UnsafeObjects {
+	for _, ocd := range oc.definitions() {
 		c, ot := Armeria.registry.Get(ocd.UUID)
 		if ot == RegistryTypeCharacter {
 			char := c.(*Character)
@@ -352,11 +398,8 @@ func (oc *ObjectContainer) Characters(onlineOnly bool, exceptions ...*Character)
 
 // Mobs returns all MobInstance objects from the container.
 func (oc *ObjectContainer) Mobs() []*MobInstance {
-	oc.RLock()
-	defer oc.RUnlock()
-
 	var mobs []*MobInstance
-	for _, ocd := range oc.UnsafeObjects {
+	for _, ocd := range oc.definitions() {
 		m, ot := Armeria.registry.Get(ocd.UUID)
 		if ot == RegistryTypeMobInstance {
 			mobs = append(mobs, m.(*MobInstance))
@@ -368,11 +411,8 @@ func (oc *ObjectContainer) Mobs() []*MobInstance {
 
 // Items returns all ItemInstance objects from the container.
 func (oc *ObjectContainer) Items() []*ItemInstance {
-	oc.RLock()
-	defer oc.RUnlock()
-
 	var items []*ItemInstance
-	for _, ocd := range oc.UnsafeObjects {
+	for _, ocd := range oc.definitions() {
 		i, ot := Armeria.registry.Get(ocd.UUID)
 		if ot == RegistryTypeItemInstance {
 			items = append(items, i.(*ItemInstance))
@@ -384,12 +424,26 @@ func (oc *ObjectContainer) Items() []*ItemInstance {
 
 // NextAvailableSlot returns the next unused slot within the container.
 func (oc *ObjectContainer) NextAvailableSlot() (int, error) {
+	oc.RLock()
+	defer oc.RUnlock()
+
+	return oc.nextAvailableSlot()
+}
+
+// nextAvailableSlot returns the next unused slot within the container. This DOES NOT request a lock and IS NOT
+// thread safe.
+func (oc *ObjectContainer) nextAvailableSlot() (int, error) {
 	if oc.UnsafeMaxSize == 0 {
 		return 0, nil
 	}
 
+	used := make(map[int]bool)
+	for _, ocd := range oc.UnsafeObjects {
+		used[ocd.Slot] = true
+	}
+
 	for s := 0; s < oc.UnsafeMaxSize; s++ {
-		if oc.AtSlot(s).Type == RegistryTypeUnknown {
+		if !used[s] {
 			return s, nil
 		}
 	}
@@ -397,25 +451,45 @@ func (oc *ObjectContainer) NextAvailableSlot() (int, error) {
 	return 0, ErrContainerNoRoom
 }
 
-// Remove removes an object from the container.
-func (oc *ObjectContainer) Remove(uuid string) {
+// Remove removes an object from the container, returning false if it was no longer in the container.
+func (oc *ObjectContainer) Remove(uuid string) bool {
 	oc.Lock()
 	defer oc.Unlock()
 
-	for i, ocd := range oc.UnsafeObjects {
-		if ocd.UUID == uuid {
-			oc.UnsafeObjects[i] = oc.UnsafeObjects[len(oc.UnsafeObjects)-1]
-			oc.UnsafeObjects = oc.UnsafeObjects[:len(oc.UnsafeObjects)-1]
-		}
+	return oc.remove(uuid)
+}
+
+// remove removes an object from the container, returning false if it wasn't in the container. This DOES NOT
+// request a lock and IS NOT thread safe.
+func (oc *ObjectContainer) remove(uuid string) bool {
+	i := oc.indexOf(uuid)
+	if i < 0 {
+		return false
+	}
+
+	oc.UnsafeObjects[i] = oc.UnsafeObjects[len(oc.UnsafeObjects)-1]
+	oc.UnsafeObjects = oc.UnsafeObjects[:len(oc.UnsafeObjects)-1]
+
+	// The object may have already been added to another container.
+	if Armeria.registry.GetObjectContainer(uuid) == oc {
+		Armeria.registry.UnregisterContainerObject(uuid)
 	}
 
-	Armeria.registry.UnregisterContainerObject(uuid)
+	return true
 }
 
 // Add attempts to add an object to the container. This can fail if the object already exists within the container
 // or if the container is already at the maximum size.
 func (oc *ObjectContainer) Add(uuid string) error {
-	if oc.Contains(uuid) {
+	oc.Lock()
+	defer oc.Unlock()
+
+	return oc.add(uuid)
+}
+
+// add adds an object to the next available slot. This DOES NOT request a lock and IS NOT thread safe.
+func (oc *ObjectContainer) add(uuid string) error {
+	if oc.indexOf(uuid) >= 0 {
 		return ErrContainerDuplicate
 	}
 
@@ -423,25 +497,64 @@ func (oc *ObjectContainer) Add(uuid string) error {
 		return ErrContainerNoRoom
 	}
 
-	slot, err := oc.NextAvailableSlot()
+	slot, err := oc.nextAvailableSlot()
 	if err != nil {
 		return err
 	}
 
-	ocd := &ObjectContainerDefinition{
+	oc.UnsafeObjects = append(oc.UnsafeObjects, &ObjectContainerDefinition{
 		UUID: uuid,
 		Slot: slot,
+	})
+
+	Armeria.registry.RegisterContainerObject(uuid, oc)
+
+	return nil
+}
+
+// Transfer moves an object from this container into another in a single step, so that it can never end up in
+// both containers, or in neither. It returns ErrContainerMissing when the object has already left this container,
+// such as when two characters pick up the same item at once, and leaves the object where it was when the
+// destination can't hold it.
+func (oc *ObjectContainer) Transfer(uuid string, to *ObjectContainer) error {
+	if oc == to {
+		if !oc.Contains(uuid) {
+			return ErrContainerMissing
+		}
+		return nil
 	}
 
+	// Holding two container locks at once is only allowed here, and transfers take turns, so two transfers in
+	// opposite directions can't each hold the lock the other is waiting on.
+	containerTransfer.Lock()
+	defer containerTransfer.Unlock()
+
 	oc.Lock()
 	defer oc.Unlock()
-	oc.UnsafeObjects = append(oc.UnsafeObjects, ocd)
+	to.Lock()
+	defer to.Unlock()
 
-	Armeria.registry.RegisterContainerObject(uuid, oc)
+	if oc.indexOf(uuid) < 0 {
+		return ErrContainerMissing
+	}
+
+	if err := to.add(uuid); err != nil {
+		return err
+	}
+
+	oc.remove(uuid)
 
 	return nil
 }
 
+// MarshalJSON encodes the container while holding its lock, so that it can be saved while in use.
+func (oc *ObjectContainer) MarshalJSON() ([]byte, error) {
+	oc.RLock()
+	defer oc.RUnlock()
+
+	return json.Marshal((*objectContainerJSON)(oc))
+}
+
 // PopulateFromLedger ensures at least one entry from the ledger, with a buy price, exists within the
 // object container.
 func (oc *ObjectContainer) PopulateFromLedger(ledger *Ledger) {
@@ -0,0 +1,194 @@
+package armeria
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Permissions that can be granted to a Character through its roles, or to an Account.
+const (
+	PermissionBuild         string = "CAN_BUILD"
+	PermissionBuildAnywhere string = "CAN_BUILD_ANYWHERE"
+	PermissionCharEdit      string = "CAN_CHAREDIT"
+	PermissionGhost         string = "CAN_GHOST"
+	PermissionSysop         string = "CAN_SYSOP"
+	PermissionTeleport      string = "CAN_TELEPORT"
+)
+
+// ValidPermissions returns all of the permissions the game checks for.
+func ValidPermissions() []string {
+	return []string{
+		PermissionBuild,
+		PermissionBuildAnywhere,
+		PermissionCharEdit,
+		PermissionGhost,
+		PermissionSysop,
+		PermissionTeleport,
+	}
+}
+
+// A PermissionRequirement is the set of permissions needed to do something, such as use a command. It is met by
+// a Character with at least one of AnyOf (if set) and all of AllOf (if set).
+type PermissionRequirement struct {
+	AnyOf []string `json:"anyOf,omitempty"`
+	AllOf []string `json:"allOf,omitempty"`
+}
+
+// RequireAnyPermission returns a PermissionRequirement met by a Character with any one of the permissions.
+func RequireAnyPermission(perms ...string) *PermissionRequirement {
+	return &PermissionRequirement{AnyOf: perms}
+}
+
+// RequireAllPermissions returns a PermissionRequirement met by a Character with every one of the permissions.
+func RequireAllPermissions(perms ...string) *PermissionRequirement {
+	return &PermissionRequirement{AllOf: perms}
+}
+
+// MetBy returns whether the Character has the permissions. A nil PermissionRequirement is met by everyone.
+func (pr *PermissionRequirement) MetBy(c *Character) bool {
+	if pr == nil {
+		return true
+	}
+
+	if len(pr.AnyOf) > 0 {
+		found := false
+		for _, p := range pr.AnyOf {
+			if c.HasPermission(p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, p := range pr.AllOf {
+		if !c.HasPermission(p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Mentions returns whether the permission is one of those required.
+func (pr *PermissionRequirement) Mentions(perm string) bool {
+	if pr == nil {
+		return false
+	}
+
+	for _, p := range append(append([]string{}, pr.AnyOf...), pr.AllOf...) {
+		if p == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the requirement in words, such as "CAN_BUILD or CAN_SYSOP".
+func (pr *PermissionRequirement) String() string {
+	if pr == nil {
+		return ""
+	}
+
+	var parts []string
+	if len(pr.AnyOf) > 0 {
+		parts = append(parts, strings.Join(pr.AnyOf, " or "))
+	}
+	if len(pr.AllOf) > 0 {
+		parts = append(parts, strings.Join(pr.AllOf, " and "))
+	}
+
+	if len(parts) > 1 {
+		return fmt.Sprintf("(%s) and %s", parts[0], parts[1])
+	}
+	return strings.Join(parts, "")
+}
+
+// PermissionTable returns a Markdown table of the commands unlocked by each permission, generated from the
+// registered commands.
+func PermissionTable() string {
+	unlocks := make(map[string][]string)
+
+	var walk func(cmds []*Command, path string)
+	walk = func(cmds []*Command, path string) {
+		for _, cmd := range cmds {
+			name := strings.TrimSpace(path + " " + cmd.Name)
+			if cmd.Permissions != nil {
+				req := cmd.Permissions.RequirePermission
+				for _, perm := range ValidPermissions() {
+					if !req.Mentions(perm) {
+						continue
+					}
+					entry := fmt.Sprintf("`/%s`", name)
+					if len(req.AnyOf)+len(req.AllOf) > 1 {
+						entry += fmt.Sprintf(" (needs %s)", req.String())
+					}
+					unlocks[perm] = append(unlocks[perm], entry)
+				}
+			}
+			walk(cmd.Subcommands, name)
+		}
+	}
+	walk(Armeria.commandManager.Commands(), "")
+
+	var sb strings.Builder
+	sb.WriteString("| Permission | Commands |\n")
+	sb.WriteString("|------------|----------|\n")
+	for _, perm := range ValidPermissions() {
+		cmds := unlocks[perm]
+		sort.Strings(cmds)
+		if len(cmds) == 0 {
+			cmds = []string{"None"}
+		}
+		sb.WriteString(fmt.Sprintf("| `%s` | %s |\n", perm, strings.Join(cmds, ", ")))
+	}
+
+	return sb.String()
+}
+
+// WritePermissionTable writes the table from PermissionTable to a file, between the markers within it. The
+// commands are registered without loading any game data.
+func WritePermissionTable(path string) error {
+	Armeria = &GameState{
+		log:            zap.NewNop(),
+		commandManager: NewCommandManager(),
+		channels:       NewChannels(),
+	}
+	RegisterGameCommands()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	doc, err := replacePermissionTable(string(b), PermissionTable())
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, []byte(doc), 0644)
+}
+
+// Markers around the generated table within the roles documentation.
+const (
+	permissionTableStart string = "<!-- permission-table:start -->\n"
+	permissionTableEnd   string = "<!-- permission-table:end -->"
+)
+
+// replacePermissionTable replaces the text between the markers within the document with the table.
+func replacePermissionTable(doc, table string) (string, error) {
+	start := strings.Index(doc, permissionTableStart)
+	end := strings.Index(doc, permissionTableEnd)
+	if start < 0 || end < start {
+		return "", fmt.Errorf("the document has no %q and %q markers", permissionTableStart, permissionTableEnd)
+	}
+
+	return doc[:start+len(permissionTableStart)] + table + doc[end:], nil
+}
@@ -0,0 +1,212 @@
+package armeria
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolvePermissions(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	for _, r := range []*Role{
+		{UnsafeName: "left", UnsafeInherits: []string{"right"}, UnsafePermissions: []string{PermissionGhost}},
+		{UnsafeName: "right", UnsafeInherits: []string{"left", "missing"}, UnsafePermissions: []string{PermissionSysop}},
+	} {
+		r.Init()
+		Armeria.roleManager.UnsafeRoles = append(Armeria.roleManager.UnsafeRoles, r)
+	}
+
+	tests := []struct {
+		roles    []string
+		expected []string
+	}{
+		{[]string{RoleDefault}, nil},
+		{[]string{"contributor"}, []string{PermissionBuild}},
+		{[]string{"builder"}, []string{PermissionBuild, PermissionGhost, PermissionTeleport, PermissionBuildAnywhere}},
+		{[]string{"Admin"}, []string{PermissionSysop, PermissionCharEdit, PermissionBuild, PermissionGhost, PermissionTeleport, PermissionBuildAnywhere}},
+		{[]string{"builder", "admin"}, []string{PermissionBuild, PermissionGhost, PermissionTeleport, PermissionBuildAnywhere, PermissionSysop, PermissionCharEdit}},
+		{[]string{"left"}, []string{PermissionGhost, PermissionSysop}},
+		{[]string{"missing"}, nil},
+	}
+
+	for _, tt := range tests {
+		perms := Armeria.roleManager.ResolvePermissions(tt.roles)
+		if !reflect.DeepEqual(perms, tt.expected) {
+			t.Errorf("%v: expected %v, got %v", tt.roles, tt.expected, perms)
+		}
+	}
+}
+
+func TestPermissionRequirement(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	c := Armeria.characterManager.CharacterByName("Alice")
+	c.GrantRole("builder")
+
+	tests := []struct {
+		requirement *PermissionRequirement
+		met         bool
+	}{
+		{nil, true},
+		{RequireAnyPermission(PermissionBuild), true},
+		{RequireAnyPermission(PermissionSysop), false},
+		{RequireAnyPermission(PermissionSysop, PermissionBuild), true},
+		{RequireAllPermissions(PermissionBuild, PermissionGhost), true},
+		{RequireAllPermissions(PermissionBuild, PermissionSysop), false},
+		{&PermissionRequirement{AnyOf: []string{PermissionSysop, PermissionGhost}, AllOf: []string{PermissionBuild}}, true},
+		{&PermissionRequirement{AnyOf: []string{PermissionSysop}, AllOf: []string{PermissionBuild}}, false},
+	}
+
+	for _, tt := range tests {
+		if met := tt.requirement.MetBy(c); met != tt.met {
+			t.Errorf("%q: expected %t, got %t", tt.requirement.String(), tt.met, met)
+		}
+	}
+}
+
+func TestHeadlessRoles(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	alice.Send("/role list")
+	expectText(t, alice, "You cannot use that command.")
+
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+
+	alice.Send("/role grant Bob wizard")
+	expectText(t, alice, "That role doesn't exist.")
+
+	alice.Send("/role grant Bob builder")
+	expectText(t, alice, "Bob has been granted the builder role.")
+	bob := Armeria.characterManager.CharacterByName("Bob")
+	if !bob.HasPermission(PermissionBuild) || bob.HasPermission(PermissionSysop) {
+		t.Errorf("unexpected permissions for Bob: %v", bob.Permissions())
+	}
+
+	alice.Send("/role grant Bob builder")
+	expectText(t, alice, "That character already has that role.")
+
+	alice.Clear()
+	alice.Send("/role list Bob")
+	expectText(t, alice, "Bob has been granted: builder.")
+	expectText(t, alice, "Permissions: CAN_BUILD CAN_GHOST CAN_TELEPORT CAN_BUILD_ANYWHERE.")
+
+	alice.Send("/role revoke Bob builder")
+	expectText(t, alice, "The builder role has been revoked from Bob.")
+	if bob.HasPermission(PermissionBuild) {
+		t.Error("expected Bob to have lost CAN_BUILD")
+	}
+
+	alice.Send("/role revoke Bob builder")
+	expectText(t, alice, "That character hasn't been granted that role.")
+}
+
+func TestHeadlessAccountPermissions(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+	a := Armeria.accountManager.AccountByName("tester")
+
+	alice.Send("/account grant tester can_fly")
+	expectText(t, alice, "That permission doesn't exist. Valid permissions are: CAN_BUILD,")
+	if a.HasPermission("CAN_FLY") {
+		t.Error("expected the unknown permission not to be granted")
+	}
+
+	alice.Send("/account grant tester can_ghost")
+	expectText(t, alice, "has been granted CAN_GHOST.")
+	if !a.HasPermission(PermissionGhost) {
+		t.Error("expected the account to have CAN_GHOST")
+	}
+
+	alice.Clear()
+	alice.Send("/account revoke tester can_fly")
+	expectText(t, alice, "That permission doesn't exist.")
+
+	alice.Send("/account revoke tester can_ghost")
+	expectText(t, alice, "CAN_GHOST has been revoked from")
+	if a.HasPermission(PermissionGhost) {
+		t.Error("expected the account to have lost CAN_GHOST")
+	}
+}
+
+func TestPermissionTableUpToDate(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	b, err := ioutil.ReadFile("../../../docs/permissions.md")
+	if err != nil {
+		t.Fatalf("error reading docs: %s", err)
+	}
+
+	if !strings.Contains(string(b), permissionTableStart+PermissionTable()+permissionTableEnd) {
+		t.Error("docs/permissions.md is out of date; regenerate it with: " +
+			"go run ./cmd/armeria -permission-table docs/permissions.md")
+	}
+}
+
+func TestHeadlessAreaBuilders(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("contributor")
+	Armeria.characterManager.CharacterByName("Bob").GrantRole("builder")
+
+	// Nobody owns the Test Area, so only Bob can build in it.
+	alice.Send("/room create east")
+	expectText(t, alice, "You don't have permission to build in Test Area.")
+	alice.Send("/item spawn Apple")
+	expectText(t, alice, "You don't have permission to build in Test Area.")
+	alice.Send("/area grant Alice")
+	expectText(t, alice, "Only the owner of an area can allow others to build in it.")
+	alice.Send("/area owner Alice")
+	expectText(t, alice, "You cannot use that command.")
+
+	alice.Send("/area create Wonderland")
+	expectText(t, alice, "An area named Wonderland has been created!")
+	wonderland := Armeria.worldManager.AreaByName("Wonderland")
+	if wonderland.Owner() != alice.Player().Character().ID() || !wonderland.CanBuild(alice.Player().Character()) {
+		t.Error("expected Alice to own and be able to build in her own area")
+	}
+
+	bob.Send("/area grant Alice")
+	expectText(t, bob, "Alice can now build in Test Area.")
+
+	alice.Clear()
+	alice.Send("/room create east")
+	expectText(t, alice, "A new room has been created.")
+
+	bob.Send("/area revoke Alice Test Area")
+	expectText(t, bob, "Alice can no longer build in Test Area.")
+
+	alice.Send("/wipe")
+	expectText(t, alice, "You don't have permission to build in Test Area.")
+	apple := alice.Player().Character().Room().Here().GetByName("Apple").Object
+	if apple == nil {
+		t.Fatal("expected the apple to survive the wipe")
+	}
+
+	alice.Clear()
+	alice.Send("/item iset " + apple.ID() + " description A bruised apple.")
+	expectText(t, alice, "You don't have permission to build in Test Area.")
+	if apple.Attribute(AttributeDescription) == "A bruised apple." {
+		t.Error("expected the apple's description to be left alone")
+	}
+
+	// Mobs and items are shared, so they can only be changed by characters that can build wherever they are.
+	alice.Send("/item set Apple description A bruised apple.")
+	expectText(t, alice, CommonCannotChangeShared)
+	alice.Clear()
+	alice.Send("/mob set Merchant title the grocer")
+	expectText(t, alice, CommonCannotChangeShared)
+	if Armeria.itemManager.ItemByName("Apple").CanBuild(alice.Player().Character()) {
+		t.Error("expected Alice to be unable to change the apple's script")
+	}
+	if !Armeria.mobManager.MobByName("Merchant").CanBuild(bob.Player().Character()) {
+		t.Error("expected Bob to be able to change the merchant")
+	}
+}
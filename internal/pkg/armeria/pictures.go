@@ -11,8 +11,11 @@ import (
 )
 
 // StoreObjectPicture handles the client-initiated process of storing an object picture.
-func StoreObjectPicture(p *Player, o map[string]interface{}) {
-	// TODO: check permissions
+func StoreObjectPicture(p *Player, o *ObjectPictureUploadMessage) {
+	if c := p.Character(); c == nil || !CanChangeObjectPicture(c, o.ObjectType, o.Name) {
+		p.client.ShowColorizedText("You don't have permission to change that picture.", ColorError)
+		return
+	}
 
 	k := SaveObjectPictureToDisk(o)
 	if len(k) == 0 {
@@ -20,17 +23,21 @@ func StoreObjectPicture(p *Player, o map[string]interface{}) {
 		return
 	}
 
-	objectType := o["objectType"].(string)
-	name := o["name"].(string)
+	objectType := o.ObjectType
+	name := o.Name
 
 	var oldKey string
 	var editorData *ObjectEditorData
+	var change *AuditChange
+	var changed interface{}
 	switch objectType {
 	case "character":
 		c := Armeria.characterManager.CharacterByName(name)
 		oldKey = c.Attribute(AttributePicture)
 		_ = c.SetAttribute(AttributePicture, k)
 		editorData = c.EditorData()
+		change = NewAuditChange(ObjectTypeCharacter, c)
+		changed = c
 		p.client.ShowColorizedText(
 			fmt.Sprintf("A picture has been uploaded and set for character %s.", c.FormattedName()),
 			ColorSuccess,
@@ -45,6 +52,8 @@ func StoreObjectPicture(p *Player, o map[string]interface{}) {
 		oldKey = m.Attribute(AttributePicture)
 		m.SetAttribute(AttributePicture, k)
 		editorData = m.EditorData()
+		change = NewAuditChange(ObjectTypeMob, m)
+		changed = m
 		p.client.ShowColorizedText(
 			fmt.Sprintf("A picture has been uploaded and set for mob %s.", TextStyle(m.Name(), WithBold())),
 			ColorSuccess,
@@ -59,6 +68,8 @@ func StoreObjectPicture(p *Player, o map[string]interface{}) {
 		oldKey = i.Attribute(AttributePicture)
 		i.SetAttribute(AttributePicture, k)
 		editorData = i.EditorData()
+		change = NewAuditChange(ObjectTypeItem, i)
+		changed = i
 		p.client.ShowColorizedText(
 			fmt.Sprintf("A picture has been uploaded and set for item %s.", TextStyle(i.Name(), WithBold())),
 			ColorSuccess,
@@ -81,6 +92,12 @@ func StoreObjectPicture(p *Player, o map[string]interface{}) {
 		return
 	}
 
+	change.Attribute = AttributePicture
+	change.Before = oldKey
+	change.After = k
+	Armeria.auditLog.Record(NewAuditEntry(p.Character(), "objectPictureUpload", change))
+	persistObjects(changed)
+
 	if oldKey != k && len(oldKey) > 0 {
 		DeleteObjectPictureFromDisk(oldKey)
 	}
@@ -91,12 +108,30 @@ func StoreObjectPicture(p *Player, o map[string]interface{}) {
 	}
 }
 
+// CanChangeObjectPicture returns whether the Character can change the picture of an object. Mobs and items are
+// shared by every area, so their pictures can only be changed by a Character that can build in every area with
+// one of their instances in it.
+func CanChangeObjectPicture(c *Character, objectType string, name string) bool {
+	switch objectType {
+	case "character":
+		return c.HasPermission(PermissionCharEdit) && Armeria.characterManager.CharacterByName(name) != nil
+	case "mob":
+		m := Armeria.mobManager.MobByName(name)
+		return m != nil && m.CanBuild(c)
+	case "item":
+		i := Armeria.itemManager.ItemByName(name)
+		return i != nil && i.CanBuild(c)
+	}
+
+	return false
+}
+
 // SaveObjectPictureToDisk stores an object picture on the disk and returns the key.
-func SaveObjectPictureToDisk(o map[string]interface{}) string {
-	objectType := o["objectType"].(string)
-	name := o["name"].(string)
-	pictureType := o["pictureType"].(string)
-	pictureData := o["pictureData"].(string)
+func SaveObjectPictureToDisk(o *ObjectPictureUploadMessage) string {
+	objectType := o.ObjectType
+	name := o.Name
+	pictureType := o.PictureType
+	pictureData := o.PictureData
 
 	hash := md5.Sum([]byte(pictureData))
 	normalizedName := strings.ReplaceAll(strings.ToLower(name), " ", "-")
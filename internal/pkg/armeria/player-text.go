@@ -0,0 +1,146 @@
+package armeria
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxMessageLength is the longest message a player can send to others when the config doesn't set one.
+const DefaultMaxMessageLength int = 500
+
+var (
+	// ErrPlayerTextEmpty is an error for when a player sends a message with nothing in it.
+	ErrPlayerTextEmpty = errors.New("you must say something")
+	// ErrPlayerTextTooLong is an error for when a player sends a message longer than the max message length.
+	ErrPlayerTextTooLong = errors.New("your message is too long")
+)
+
+// A ProfanityFilter is a hook that sees every message a player sends to others before it is shown. It returns the
+// text to show in its place, or an error (shown to the player) to refuse the message altogether.
+type ProfanityFilter func(c *Character, text string) (string, error)
+
+// playerTextMarkup is the markup players can use within their messages, mapped to the style it applies.
+var playerTextMarkup = map[rune]TextOperation{
+	'*': WithBold(),
+	'_': WithItalics(),
+}
+
+// AddProfanityFilter adds a filter that every message a player sends to others is passed through, in the order they
+// were added. Filters must be added before the game starts serving traffic.
+func (gs *GameState) AddProfanityFilter(f ProfanityFilter) {
+	gs.profanityFilters = append(gs.profanityFilters, f)
+}
+
+// NewWordFilter returns a ProfanityFilter that masks each of the words, ignoring case, wherever they appear as a
+// whole word.
+func NewWordFilter(words []string) ProfanityFilter {
+	var quoted []string
+	for _, w := range words {
+		if len(w) > 0 {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return func(c *Character, text string) (string, error) {
+			return text, nil
+		}
+	}
+
+	pattern := regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	return func(c *Character, text string) (string, error) {
+		return pattern.ReplaceAllStringFunc(text, func(w string) string {
+			return strings.Repeat("#", utf8.RuneCountInString(w))
+		}), nil
+	}
+}
+
+// ValidatePlayerText checks a message the Character wants to send to others, such as with /say or on a channel,
+// returning the message with anything that could be mistaken for styling or control codes removed and with the
+// profanity filters applied. The result is still raw text; pass it through FormatPlayerText before showing it.
+func ValidatePlayerText(c *Character, text string) (string, error) {
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text))
+
+	if len(text) == 0 {
+		return "", ErrPlayerTextEmpty
+	}
+
+	max := Armeria.maxMessageLength
+	if max <= 0 {
+		max = DefaultMaxMessageLength
+	}
+	if utf8.RuneCountInString(text) > max {
+		return "", ErrPlayerTextTooLong
+	}
+
+	for _, f := range Armeria.profanityFilters {
+		var err error
+		text, err = f(c, text)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return text, nil
+}
+
+// FormatPlayerText converts a message validated by ValidatePlayerText into text that is safe to show to any
+// client. Everything the player typed is shown literally, apart from the markup players are allowed to use: text
+// between asterisks is bold, and text between underscores is italic.
+func FormatPlayerText(text string) string {
+	var sb strings.Builder
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		opt, isMarkup := playerTextMarkup[r]
+		if isMarkup && opensMarkup(runes, i) {
+			if end := closesMarkup(runes, i); end > 0 {
+				sb.WriteString(TextStyle(escapePlayerText(string(runes[i+1:end])), opt))
+				i = end
+				continue
+			}
+		}
+
+		sb.WriteString(escapePlayerText(string(r)))
+	}
+
+	return sb.String()
+}
+
+// opensMarkup returns whether the markup character at i starts a styled run: it must be at the start of a word and
+// be followed by something other than a space.
+func opensMarkup(runes []rune, i int) bool {
+	if i > 0 && (unicode.IsLetter(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+		return false
+	}
+	return i+1 < len(runes) && !unicode.IsSpace(runes[i+1])
+}
+
+// closesMarkup returns the index of the markup character that ends the styled run opened at i, or 0 if there isn't
+// one.
+func closesMarkup(runes []rune, i int) int {
+	for j := i + 2; j < len(runes); j++ {
+		if runes[j] != runes[i] || unicode.IsSpace(runes[j-1]) {
+			continue
+		}
+		if j+1 < len(runes) && (unicode.IsLetter(runes[j+1]) || unicode.IsDigit(runes[j+1])) {
+			continue
+		}
+		return j
+	}
+	return 0
+}
+
+// escapePlayerText escapes the characters that ParseRichText would otherwise decode as HTML entities, so that the
+// text is shown exactly as it was typed.
+func escapePlayerText(text string) string {
+	return strings.Replace(text, "&", "&amp;", -1)
+}
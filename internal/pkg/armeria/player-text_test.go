@@ -0,0 +1,122 @@
+package armeria
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// lastText returns the last text shown to the client.
+func lastText(t *testing.T, hc *HeadlessClient, command string) *RichText {
+	t.Helper()
+	payloads := hc.Payloads("showText")
+	if len(payloads) == 0 {
+		t.Fatalf("%q: expected text to be shown", command)
+	}
+	return payloads[len(payloads)-1].(*RichText)
+}
+
+func TestPlayerTextInjection(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+	alice.Send("/channel join general")
+	bob.Send("/channel join general")
+
+	commands := []string{
+		"/say %s",
+		"/whisper Bob %s",
+		"/me %s",
+		"/channel say general %s",
+	}
+	payloads := []string{
+		"<script>alert(1)</script>",
+		"<img src=x onerror=alert(1)>",
+		"<a href='javascript:alert(1)'>click</a>",
+		`[cmd=",x:alert(1)})//]click[/cmd]`,
+		"[b]bold[/b]",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"\x02{\"type\":\"span\",\"url\":\"javascript:alert(1)\"}\x1fclick\x03",
+		"clear \x1b[2J\x1b[31mred",
+		"&#27;[2J",
+	}
+
+	for _, cmd := range commands {
+		for _, payload := range payloads {
+			bob.Clear()
+			alice.Send(fmt.Sprintf(cmd, payload))
+
+			rt := lastText(t, bob, fmt.Sprintf(cmd, payload))
+			html := rt.HTML()
+			for _, bad := range []string{"<script", "<img", "<a ", "[cmd=", "[b]"} {
+				if strings.Contains(html, bad) {
+					t.Errorf("%q: expected %q to be escaped, got %q", fmt.Sprintf(cmd, payload), bad, html)
+				}
+			}
+			if ansi := rt.ANSI(ColorDepth256); strings.Contains(ansi, "\x1b[2J") || strings.Contains(ansi, "\x1b[31m") {
+				t.Errorf("%q: expected no escape codes from the player, got %q", fmt.Sprintf(cmd, payload), ansi)
+			}
+		}
+	}
+}
+
+func TestItemTooltipInjection(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	ii := createTestItem("<img src=x onerror=alert(1)>").CreateInstance()
+	if tooltip := ii.TooltipContent().HTML; strings.Contains(tooltip, "<img") {
+		t.Errorf("expected the item name to be escaped, got %q", tooltip)
+	}
+}
+
+func TestPlayerTextLiteral(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+
+	alice.Send("/say 1 < 2 & <b>3</b> &amp; [x]")
+	expectText(t, bob, `Alice says, "1 < 2 & <b>3</b> &amp; [x]."`)
+}
+
+func TestPlayerTextMarkup(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"*bold*", "<span style='font-weight:600'>bold</span>"},
+		{"an _italic_ word", "an <span style='font-style:italic'>italic</span> word"},
+		{"*<b>*", "<span style='font-weight:600'>&lt;b&gt;</span>"},
+		{"snake_case_name", "snake_case_name"},
+		{"2*3*4 and * loose *", "2*3*4 and * loose *"},
+		{"*unclosed", "*unclosed"},
+	}
+
+	for _, tt := range tests {
+		if actual := ParseRichText(FormatPlayerText(tt.text)).HTML(); actual != tt.expected {
+			t.Errorf("FormatPlayerText(%q) = %q, expected %q", tt.text, actual, tt.expected)
+		}
+	}
+}
+
+func TestPlayerTextLimits(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	Armeria.maxMessageLength = 10
+	Armeria.AddProfanityFilter(NewWordFilter([]string{"darn"}))
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+
+	bob.Clear()
+	alice.Send("/say this is far too long")
+	expectText(t, alice, "Your message is too long.")
+	if len(bob.Texts()) > 0 {
+		t.Errorf("expected Bob to hear nothing, got %q", bob.Texts())
+	}
+
+	alice.Send("/say Darn it")
+	expectText(t, bob, `Alice says, "#### it."`)
+}
@@ -1,43 +1,34 @@
 package armeria
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
-	"time"
 
-	"code.cloudfoundry.org/bytefmt"
-	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
 type Player struct {
 	sync.RWMutex
 	client           ClientActions
-	socket           *websocket.Conn
+	transport        Transport
 	pumpsInitialized bool
 	sendData         chan *OutgoingDataStructure
+	account          *Account
+	session          *Session
+	editorSession    *Session
+	editorToken      string
 	character        *Character
-}
-
-type IncomingDataStructure struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
-}
-
-type OutgoingDataStructure struct {
-	Action  string      `json:"action"`
-	Payload interface{} `json:"data"`
+	creation         *CharacterCreation
 }
 
 func (p *Player) readPump() {
-	defer Armeria.playerManager.DisconnectPlayer(p)
-
-	// Set max size of a single message to 512KB
-	p.socket.SetReadLimit(512 * bytefmt.KILOBYTE)
+	defer Armeria.RunEvent(EventMessage, "disconnect", func() {
+		Armeria.playerManager.DisconnectPlayer(p)
+	})
 
 	for {
-		messageRead := &IncomingDataStructure{}
-		err := p.socket.ReadJSON(messageRead)
+		messageRead, err := p.transport.ReadMessage()
 		if err != nil {
 			Armeria.log.Debug("socket read error",
 				zap.Error(err),
@@ -45,38 +36,107 @@ func (p *Player) readPump() {
 			break
 		}
 
-		switch messageRead.Type {
-		case "command":
-			cmd := messageRead.Payload.(string)
-			Armeria.commandManager.ProcessCommand(p, cmd[1:], true)
-		case "objectEditorOpen":
-			open := messageRead.Payload.(bool)
-			if open {
-				p.Character().SetTempAttribute("editorOpen", "true")
-			} else {
-				p.Character().SetTempAttribute("editorOpen", "false")
-			}
-		case "objectPictureUpload":
-			StoreObjectPicture(p, messageRead.Payload.(map[string]interface{}))
-		case "itemTooltipHTML":
-			uuid := messageRead.Payload.(string)
-			o, rt := Armeria.registry.Get(uuid)
-			if rt != RegistryTypeItemInstance {
-				p.client.SetItemTooltipHTMLRaw(uuid, "There is no additional information available.")
-				break
-			}
-			ii := o.(*ItemInstance)
-			p.client.SetItemTooltipHTML(ii)
-		case "ping":
+		msg, err := DecodeIncomingMessage(messageRead)
+		if err != nil {
+			Armeria.log.Debug("invalid message from client",
+				zap.String("type", messageRead.Type),
+				zap.Error(err),
+			)
+			p.client.ShowColorizedText("Your client sent invalid data.", ColorError)
+			continue
+		}
+
+		// Pings don't touch the game world, so they are answered straight away.
+		if _, ok := msg.(*PingMessage); ok {
 			p.client.SendPong()
-		default:
-			p.client.ShowText("Your client sent invalid data.")
+			continue
 		}
+
+		Armeria.RunEvent(EventMessage, messageRead.Type, func() {
+			p.handleMessage(msg)
+		})
 	}
 }
 
+// handleMessage processes a single message read from the socket, once its payload has been decoded.
+func (p *Player) handleMessage(msg IncomingMessage) {
+	switch m := msg.(type) {
+	case *HelloMessage:
+		p.negotiateProtocol(m.Version)
+	case *CommandMessage:
+		cmd := StripRichText(string(*m))
+		Armeria.commandManager.ProcessCommand(p, cmd[1:], true)
+	case *TextFormatMessage:
+		s, ok := p.transport.(TextFormatSelector)
+		if !ok {
+			p.client.ShowColorizedText("Your client sent invalid data.", ColorError)
+			break
+		}
+		s.SetTextFormat(TextFormat(*m))
+	case *ObjectEditorOpenMessage:
+		if p.Character() == nil {
+			break
+		}
+		if *m {
+			p.Character().SetTempAttribute("editorOpen", "true")
+		} else {
+			p.Character().SetTempAttribute("editorOpen", "false")
+		}
+	case *ObjectPictureUploadMessage:
+		StoreObjectPicture(p, m)
+	case *ItemTooltipMessage:
+		uuid := string(*m)
+		o, rt := Armeria.registry.Get(uuid)
+		if rt != RegistryTypeItemInstance {
+			p.client.SetItemTooltipHTMLRaw(uuid, "There is no additional information available.")
+			break
+		}
+		ii := o.(*ItemInstance)
+		p.client.SetItemTooltipHTML(ii)
+	default:
+		p.client.ShowColorizedText("Your client sent invalid data.", ColorError)
+	}
+}
+
+// HideInput asks the client not to show what the player types, such as a password, or to start showing it again.
+// Clients that always hide passwords themselves, such as the web client, are left alone.
+func (p *Player) HideInput(hidden bool) {
+	if h, ok := p.transport.(InputHider); ok {
+		h.HideInput(hidden)
+	}
+}
+
+// negotiateProtocol agrees on the version of the protocol to speak with a client that speaks up to the version, and
+// lets the client know. Clients that only speak versions older than MinProtocolVersion are disconnected.
+func (p *Player) negotiateProtocol(clientVersion int) {
+	n, ok := p.transport.(ProtocolNegotiator)
+	if !ok {
+		p.client.ShowColorizedText("Your client sent invalid data.", ColorError)
+		return
+	}
+
+	v, err := NegotiateProtocolVersion(clientVersion)
+	if err != nil {
+		p.client.ShowColorizedText(
+			fmt.Sprintf(
+				"Your client is too old to connect to Armeria. It speaks version %d of the protocol, but version %d or newer is required.",
+				clientVersion,
+				MinProtocolVersion,
+			),
+			ColorError,
+		)
+		p.client.Disconnect()
+		return
+	}
+
+	n.SetProtocolVersion(v)
+	p.client.SendHello(v)
+}
+
 func (p *Player) writePump() {
-	defer Armeria.playerManager.DisconnectPlayer(p)
+	defer Armeria.RunEvent(EventMessage, "disconnect", func() {
+		Armeria.playerManager.DisconnectPlayer(p)
+	})
 
 	for {
 		select {
@@ -86,15 +146,7 @@ func (p *Player) writePump() {
 				return
 			}
 
-			err := p.socket.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err != nil {
-				Armeria.log.Error("error setting write deadline",
-					zap.Error(err),
-				)
-				return
-			}
-
-			if err := p.socket.WriteJSON(message); err != nil {
+			if err := p.transport.WriteMessage(message); err != nil {
 				Armeria.log.Error("error writing to socket",
 					zap.Error(err),
 				)
@@ -104,10 +156,11 @@ func (p *Player) writePump() {
 	}
 }
 
+// FlushWrites writes any data waiting to be sent straight to the transport.
 func (p *Player) FlushWrites() {
 	for len(p.sendData) > 0 {
 		data := <-p.sendData
-		err := p.socket.WriteJSON(data)
+		err := p.transport.WriteMessage(data)
 		if err != nil {
 			Armeria.log.Error("error flushing writes",
 				zap.Error(err),
@@ -116,7 +169,7 @@ func (p *Player) FlushWrites() {
 	}
 }
 
-// SetupPumps will create two go routines for reading to and writing from the underlying socket connection.
+// SetupPumps will create two go routines for reading to and writing from the underlying transport.
 func (p *Player) SetupPumps() {
 	if p.pumpsInitialized {
 		return
@@ -129,7 +182,21 @@ func (p *Player) SetupPumps() {
 
 // CallClientAction sends a socket event to call a Vuex action on the webapp.
 func (p *Player) CallClientAction(actionName string, payload interface{}) {
-	p.sendData <- &OutgoingDataStructure{Action: actionName, Payload: payload}
+	data := &OutgoingDataStructure{Action: actionName, Payload: payload}
+	if Armeria.eventLoop == nil {
+		p.sendData <- data
+		return
+	}
+
+	// A slow client must never hold up the event loop, so it is disconnected rather than waited on.
+	select {
+	case p.sendData <- data:
+	default:
+		Armeria.log.Error("player send buffer is full",
+			zap.Int("dataSize", len(p.sendData)),
+		)
+		_ = p.transport.Close()
+	}
 }
 
 // Connected is called when the parent successfully connects to the game (pre-login).
@@ -137,6 +204,67 @@ func (p *Player) Connected() {
 
 }
 
+// AttachAccount sets the Account the Player has logged in to.
+func (p *Player) AttachAccount(a *Account) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.account = a
+}
+
+// Account returns the Account the Player has logged in to.
+func (p *Player) Account() *Account {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.account
+}
+
+// AttachSession sets the Session the Player used to log in, if any.
+func (p *Player) AttachSession(s *Session) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.session = s
+}
+
+// Session returns the Session the Player used to log in, if any.
+func (p *Player) Session() *Session {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.session
+}
+
+// EditorToken returns the token used by the object editor to access the HTTP routes, creating a new
+// Session when the previous one has expired or been revoked.
+func (p *Player) EditorToken() string {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.editorSession == nil || Armeria.sessionManager.SessionByToken(p.editorToken) == nil {
+		p.editorSession, p.editorToken = Armeria.sessionManager.CreateSession(
+			p.account,
+			SessionLabelObjectEditor,
+			SessionTTLObjectEditor,
+		)
+	}
+
+	return p.editorToken
+}
+
+// RevokeEditorSession revokes the Session used by the object editor, if one exists.
+func (p *Player) RevokeEditorSession() {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.editorSession != nil {
+		Armeria.sessionManager.RevokeSession(p.editorSession)
+		p.editorSession = nil
+		p.editorToken = ""
+	}
+}
+
 func (p *Player) AttachCharacter(c *Character) {
 	p.Lock()
 	defer p.Unlock()
@@ -151,19 +279,46 @@ func (p *Player) Character() *Character {
 	return p.character
 }
 
-func (p *Player) PlayerInfoJSON() string {
-	pi := map[string]string{
-		"uuid": p.Character().ID(),
-		"name": p.Character().Name(),
+// Creation returns the character creation wizard the Player is progressing through, if any.
+func (p *Player) Creation() *CharacterCreation {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.creation
+}
+
+// SetCreation sets (or clears, with nil) the character creation wizard for the Player.
+func (p *Player) SetCreation(cc *CharacterCreation) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.creation = cc
+}
+
+// PlayCharacter enters the game world as the Character.
+func (p *Player) PlayCharacter(c *Character) error {
+	if c.Player() != nil {
+		return errors.New("this character is already logged in")
 	}
 
-	piJSON, err := json.Marshal(pi)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal player info data",
-			zap.String("unsafeCharacter", p.Character().UUID),
-			zap.Error(err),
-		)
+	if c.Room() == nil {
+		return errors.New("this character logged out of a room which no longer exists")
 	}
 
-	return string(piJSON)
+	p.AttachCharacter(c)
+	c.SetPlayer(p)
+
+	p.client.ShowColorizedText(fmt.Sprintf("You've entered Armeria as %s!", c.FormattedName()), ColorSuccess)
+
+	c.LoggedIn()
+
+	return nil
+}
+
+// PlayerInfo returns the information the client uses to identify the Character being played.
+func (p *Player) PlayerInfo() *PlayerInfoData {
+	return &PlayerInfoData{
+		UUID: p.Character().ID(),
+		Name: p.Character().Name(),
+	}
 }
@@ -4,8 +4,6 @@ import (
 	"sync"
 
 	"go.uber.org/zap"
-
-	"github.com/gorilla/websocket"
 )
 
 type PlayerManager struct {
@@ -20,13 +18,13 @@ func NewPlayerManager() *PlayerManager {
 	}
 }
 
-// NewPlayer creates a new Player instance, adds it to memory, and returns Player.
-func (m *PlayerManager) NewPlayer(conn *websocket.Conn) *Player {
+// NewPlayer creates a new Player instance connected over the transport, adds it to memory, and returns Player.
+func (m *PlayerManager) NewPlayer(t Transport) *Player {
 	m.Lock()
 	defer m.Unlock()
 
 	p := &Player{
-		socket:           conn,
+		transport:        t,
 		pumpsInitialized: false,
 		sendData:         make(chan *OutgoingDataStructure, 256),
 	}
@@ -36,13 +34,28 @@ func (m *PlayerManager) NewPlayer(conn *websocket.Conn) *Player {
 	m.players[p] = true
 
 	Armeria.log.Info("player connected",
-		zap.String("ip", conn.RemoteAddr().String()),
+		zap.String("ip", t.RemoteAddr()),
 		zap.Int("players", len(m.players)),
 	)
 
 	return p
 }
 
+// PlayersWithAccount returns the players logged in to the Account, whether or not they're playing a character.
+func (m *PlayerManager) PlayersWithAccount(a *Account) []*Player {
+	m.RLock()
+	defer m.RUnlock()
+
+	var players []*Player
+	for p := range m.players {
+		if p.Account() == a {
+			players = append(players, p)
+		}
+	}
+
+	return players
+}
+
 // DisconnectPlayer will gracefully remove the parent from the game and terminate the socket connection
 func (m *PlayerManager) DisconnectPlayer(p *Player) {
 	m.Lock()
@@ -52,15 +65,18 @@ func (m *PlayerManager) DisconnectPlayer(p *Player) {
 		return
 	}
 
-	if p.character != nil {
-		// Notify unsafeCharacter of logout
-		p.character.LoggedOut()
-		// Unset parent from unsafeCharacter
-		p.character.SetPlayer(nil)
+	if c := p.character; c != nil {
+		// Unset parent from unsafeCharacter first, so the unsafeCharacter is no longer shown to others in the room
+		c.SetPlayer(nil)
 		// Unset unsafeCharacter from parent
 		p.AttachCharacter(nil)
+		// Notify unsafeCharacter of logout
+		c.LoggedOut()
 	}
 
+	// Revoke the object editor's session
+	p.RevokeEditorSession()
+
 	// Fatal if data should of been sent but wasn't
 	if len(p.sendData) > 0 {
 		Armeria.log.Error("player disconnected with unsent data",
@@ -68,8 +84,8 @@ func (m *PlayerManager) DisconnectPlayer(p *Player) {
 		)
 	}
 
-	// Close the socket connection
-	err := p.socket.Close()
+	// Close the transport
+	err := p.transport.Close()
 	if err != nil {
 		Armeria.log.Error("error closing socket",
 			zap.Error(err),
@@ -0,0 +1,194 @@
+package armeria
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// protocolSchemaBuilder builds a JSON Schema from Go types, collecting each named struct into the definitions.
+type protocolSchemaBuilder struct {
+	definitions map[string]interface{}
+}
+
+// ProtocolSchema returns a JSON Schema (draft-07) describing the messages a client can send and receive with the
+// newest version of the protocol. Clients speaking version 1 receive some of the data as JSON-encoded strings
+// instead; see the description of each action.
+func ProtocolSchema() map[string]interface{} {
+	b := &protocolSchemaBuilder{definitions: make(map[string]interface{})}
+
+	var incoming []interface{}
+	for _, mt := range incomingMessageTypes {
+		payloadType := reflect.TypeOf(mt.New()).Elem()
+		properties := map[string]interface{}{
+			"type": map[string]interface{}{"const": mt.Type},
+		}
+		required := []string{"type"}
+		if payloadType.Size() > 0 {
+			properties["payload"] = b.schemaFor(payloadType)
+			required = append(required, "payload")
+		}
+
+		incoming = append(incoming, map[string]interface{}{
+			"title":       mt.Type,
+			"description": mt.Description,
+			"type":        "object",
+			"properties":  properties,
+			"required":    required,
+		})
+	}
+
+	var outgoing []interface{}
+	for _, mt := range outgoingMessageTypes {
+		var payloads []interface{}
+		for _, p := range mt.Payload {
+			if p == nil {
+				payloads = append(payloads, map[string]interface{}{"type": "null"})
+				continue
+			}
+			payloads = append(payloads, b.schemaFor(reflect.TypeOf(p)))
+		}
+
+		data := payloads[0]
+		if len(payloads) > 1 {
+			data = map[string]interface{}{"oneOf": payloads}
+		}
+
+		description := mt.Description
+		if mt.Legacy {
+			description += " Sent as a JSON-encoded string to clients speaking version 1."
+		}
+
+		outgoing = append(outgoing, map[string]interface{}{
+			"title":       mt.Action,
+			"description": description,
+			"type":        "object",
+			"properties": map[string]interface{}{
+				"action": map[string]interface{}{"const": mt.Action},
+				"data":   data,
+			},
+			"required": []string{"action", "data"},
+		})
+	}
+
+	b.definitions["IncomingMessage"] = map[string]interface{}{
+		"description": "A message sent by a client to the server.",
+		"oneOf":       incoming,
+	}
+	b.definitions["OutgoingMessage"] = map[string]interface{}{
+		"description": "A message sent by the server to a client, calling one of its actions.",
+		"oneOf":       outgoing,
+	}
+
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       fmt.Sprintf("Armeria client protocol, version %d", ProtocolVersion),
+		"description": "The JSON messages exchanged with the game over the websocket.",
+		"oneOf": []interface{}{
+			map[string]interface{}{"$ref": "#/definitions/IncomingMessage"},
+			map[string]interface{}{"$ref": "#/definitions/OutgoingMessage"},
+		},
+		"definitions": b.definitions,
+	}
+}
+
+// ProtocolSchemaJSON returns the schema from ProtocolSchema as indented JSON.
+func ProtocolSchemaJSON() []byte {
+	j, err := json.MarshalIndent(ProtocolSchema(), "", "  ")
+	if err != nil {
+		Armeria.log.Fatal("failed to marshal protocol schema",
+			zap.Error(err),
+		)
+	}
+
+	return append(j, '\n')
+}
+
+// WriteProtocolSchema writes the schema from ProtocolSchema to a file, so that it can be published for client
+// authors.
+func WriteProtocolSchema(path string) error {
+	return ioutil.WriteFile(path, ProtocolSchemaJSON(), 0644)
+}
+
+// schemaFor returns the schema of values of the type, as they are encoded by encoding/json.
+func (b *protocolSchemaBuilder) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": b.schemaFor(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": b.schemaFor(t.Elem()),
+		}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return b.structSchema(t)
+		}
+		if _, exists := b.definitions[t.Name()]; !exists {
+			// Reserve the name first, since a struct can refer to itself.
+			b.definitions[t.Name()] = nil
+			b.definitions[t.Name()] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	}
+
+	return map[string]interface{}{}
+}
+
+// structSchema returns the schema of a struct, with a property for each field that is encoded.
+func (b *protocolSchemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 || f.Anonymous {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		} else if len(name) == 0 {
+			name = f.Name
+		}
+
+		omitEmpty := len(tag) > 1 && tag[1] == "omitempty"
+		properties[name] = b.schemaFor(f.Type)
+		if !omitEmpty {
+			required = append(required, name)
+			if f.Type.Kind() == reflect.Ptr {
+				properties[name] = map[string]interface{}{
+					"anyOf": []interface{}{properties[name], map[string]interface{}{"type": "null"}},
+				}
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
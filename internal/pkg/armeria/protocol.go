@@ -0,0 +1,439 @@
+package armeria
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ProtocolVersion is the newest version of the client protocol spoken by the server.
+	ProtocolVersion int = 2
+	// MinProtocolVersion is the oldest version of the client protocol still spoken by the server. Clients that
+	// never send a hello message are assumed to speak it.
+	MinProtocolVersion int = 1
+)
+
+var (
+	// ErrUnknownMessageType is an error for when a client sends a message with a type the server doesn't handle.
+	ErrUnknownMessageType = errors.New("unknown message type")
+	// ErrInvalidPayload is an error for when a client sends a message whose payload doesn't match its type.
+	ErrInvalidPayload = errors.New("invalid payload")
+	// ErrUnsupportedProtocolVersion is an error for when a client only speaks versions of the protocol older than
+	// MinProtocolVersion.
+	ErrUnsupportedProtocolVersion = errors.New("unsupported protocol version")
+)
+
+// IncomingDataStructure is a message sent by a client, before its payload has been decoded.
+type IncomingDataStructure struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// OutgoingDataStructure is a message sent to a client, calling one of its actions.
+type OutgoingDataStructure struct {
+	Action  string      `json:"action"`
+	Payload interface{} `json:"data"`
+}
+
+// NewIncomingMessage returns an IncomingDataStructure for a message of the type, as if a client had sent it. It is
+// used by transports that don't speak JSON, such as telnet.
+func NewIncomingMessage(messageType string, payload interface{}) *IncomingDataStructure {
+	m := &IncomingDataStructure{Type: messageType}
+	if payload != nil {
+		j, err := json.Marshal(payload)
+		if err != nil {
+			Armeria.log.Fatal("failed to marshal payload for incoming message",
+				zap.String("type", messageType),
+				zap.Error(err),
+			)
+		}
+		m.Payload = j
+	}
+
+	return m
+}
+
+// An IncomingMessage is the decoded payload of a message sent by a client.
+type IncomingMessage interface {
+	// Validate returns an error if the payload can't be acted on.
+	Validate() error
+}
+
+// CommandMessage is a slash command typed by the player, such as "/look".
+type CommandMessage string
+
+// HelloMessage is the first message sent by a client, with the newest version of the protocol it speaks.
+type HelloMessage struct {
+	Version int `json:"version"`
+}
+
+// PingMessage is sent by a client to keep the connection alive. It has no payload.
+type PingMessage struct{}
+
+// TextFormatMessage is the format a client wants text sent in: html, plain or rich.
+type TextFormatMessage TextFormat
+
+// ObjectEditorOpenMessage is whether the object editor is open on the client.
+type ObjectEditorOpenMessage bool
+
+// ObjectPictureUploadMessage is a picture uploaded through the object editor.
+type ObjectPictureUploadMessage struct {
+	ObjectType  string `json:"objectType"`
+	Name        string `json:"name"`
+	PictureType string `json:"pictureType"`
+	PictureData string `json:"pictureData"`
+}
+
+// ItemTooltipMessage is the uuid of an item instance the client wants the tooltip for.
+type ItemTooltipMessage string
+
+// Validate returns an error unless the command starts with a slash.
+func (m *CommandMessage) Validate() error {
+	if len(*m) < 2 || !strings.HasPrefix(string(*m), "/") {
+		return errors.New("command must start with a slash")
+	}
+	return nil
+}
+
+// Validate returns an error unless the version is positive.
+func (m *HelloMessage) Validate() error {
+	if m.Version < 1 {
+		return errors.New("version must be at least 1")
+	}
+	return nil
+}
+
+// Validate never returns an error.
+func (m *PingMessage) Validate() error {
+	return nil
+}
+
+// Validate returns an error unless the format is one the server can render text in.
+func (m *TextFormatMessage) Validate() error {
+	switch TextFormat(*m) {
+	case TextFormatHTML, TextFormatPlain, TextFormatRich:
+		return nil
+	}
+	return fmt.Errorf("text format must be %s, %s or %s", TextFormatHTML, TextFormatPlain, TextFormatRich)
+}
+
+// Validate never returns an error.
+func (m *ObjectEditorOpenMessage) Validate() error {
+	return nil
+}
+
+// Validate returns an error unless the upload names the object and carries a picture.
+func (m *ObjectPictureUploadMessage) Validate() error {
+	if len(m.ObjectType) == 0 || len(m.Name) == 0 {
+		return errors.New("objectType and name are required")
+	} else if len(m.PictureData) == 0 {
+		return errors.New("pictureData is required")
+	}
+	return nil
+}
+
+// Validate returns an error if the uuid is empty.
+func (m *ItemTooltipMessage) Validate() error {
+	if len(*m) == 0 {
+		return errors.New("uuid is required")
+	}
+	return nil
+}
+
+// incomingMessageType describes a type of message that clients can send.
+type incomingMessageType struct {
+	Type        string
+	Description string
+	New         func() IncomingMessage
+}
+
+// incomingMessageTypes are the messages clients can send, in the order they are documented in.
+var incomingMessageTypes = []incomingMessageType{
+	{
+		Type:        "hello",
+		Description: "The first message sent by a client, with the newest version of the protocol it speaks.",
+		New:         func() IncomingMessage { return &HelloMessage{} },
+	},
+	{
+		Type:        "ping",
+		Description: "Keeps the connection alive. The server replies with pong.",
+		New:         func() IncomingMessage { return &PingMessage{} },
+	},
+	{
+		Type:        "command",
+		Description: "A slash command typed by the player, such as \"/look\".",
+		New:         func() IncomingMessage { return new(CommandMessage) },
+	},
+	{
+		Type:        "textFormat",
+		Description: "The format text is sent to the client in: \"html\" (the default), \"plain\" or \"rich\".",
+		New:         func() IncomingMessage { return new(TextFormatMessage) },
+	},
+	{
+		Type:        "objectEditorOpen",
+		Description: "Whether the object editor is open.",
+		New:         func() IncomingMessage { return new(ObjectEditorOpenMessage) },
+	},
+	{
+		Type:        "objectPictureUpload",
+		Description: "A picture uploaded through the object editor, base64 encoded.",
+		New:         func() IncomingMessage { return &ObjectPictureUploadMessage{} },
+	},
+	{
+		Type:        "itemTooltipHTML",
+		Description: "Asks for the tooltip of an item instance, by its uuid. The server replies with setItemTooltipHTML.",
+		New:         func() IncomingMessage { return new(ItemTooltipMessage) },
+	},
+}
+
+// DecodeIncomingMessage decodes and validates the payload of a message sent by a client.
+func DecodeIncomingMessage(m *IncomingDataStructure) (IncomingMessage, error) {
+	for _, mt := range incomingMessageTypes {
+		if mt.Type != m.Type {
+			continue
+		}
+
+		msg := mt.New()
+		if len(m.Payload) > 0 {
+			if err := json.Unmarshal(m.Payload, msg); err != nil {
+				return nil, fmt.Errorf("%w for %s: %s", ErrInvalidPayload, m.Type, err)
+			}
+		}
+		if err := msg.Validate(); err != nil {
+			return nil, fmt.Errorf("%w for %s: %s", ErrInvalidPayload, m.Type, err)
+		}
+
+		return msg, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownMessageType, m.Type)
+}
+
+// NegotiateProtocolVersion returns the version of the protocol to speak with a client that speaks up to the
+// version.
+func NegotiateProtocolVersion(clientVersion int) (int, error) {
+	if clientVersion < MinProtocolVersion {
+		return 0, ErrUnsupportedProtocolVersion
+	} else if clientVersion > ProtocolVersion {
+		return ProtocolVersion, nil
+	}
+
+	return clientVersion, nil
+}
+
+// HelloData is the server's reply to a hello message, with the version of the protocol it will speak.
+type HelloData struct {
+	Version    int `json:"version"`
+	MinVersion int `json:"minVersion"`
+	MaxVersion int `json:"maxVersion"`
+}
+
+// MinimapData is the current area, for drawing the minimap.
+type MinimapData struct {
+	Name  string             `json:"name"`
+	Rooms []*MinimapRoomData `json:"rooms"`
+}
+
+// MinimapRoomData is a room on the minimap. Each direction is the location of the connected room, if any.
+type MinimapRoomData struct {
+	Title string `json:"title"`
+	Color string `json:"color"`
+	Type  string `json:"type"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Z     int    `json:"z"`
+	North string `json:"north"`
+	South string `json:"south"`
+	East  string `json:"east"`
+	West  string `json:"west"`
+	Up    string `json:"up"`
+	Down  string `json:"down"`
+}
+
+// LocationData is the location of the character's room within its area.
+type LocationData struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+// RoomObjectData is a character, mob or item within the character's room.
+type RoomObjectData struct {
+	UUID    string              `json:"uuid"`
+	Name    string              `json:"name"`
+	Type    ContainerObjectType `json:"type"`
+	Sort    int                 `json:"sort"`
+	Picture string              `json:"picture"`
+	Color   string              `json:"color"`
+	Title   string              `json:"title"`
+	Visible bool                `json:"visible"`
+}
+
+// InventoryItemData is an item within the character's inventory.
+type InventoryItemData struct {
+	UUID      string `json:"uuid"`
+	Name      string `json:"name"`
+	Picture   string `json:"picture"`
+	Slot      int    `json:"slot"`
+	EquipSlot string `json:"equipSlot"`
+	Color     string `json:"color"`
+}
+
+// PlayerInfoData identifies the character being played.
+type PlayerInfoData struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// ItemTooltipData is the tooltip of an item instance.
+type ItemTooltipData struct {
+	UUID    string `json:"uuid"`
+	HTML    string `json:"html"`
+	Rarity  string `json:"rarity"`
+	Picture string `json:"picture,omitempty"`
+}
+
+// SoundEffectData is a sound effect for the client to play.
+type SoundEffectData struct {
+	ID     string `json:"id"`
+	Volume int    `json:"volume"`
+}
+
+// outgoingMessageType describes a client action the server can call. A payload with more than one sample means
+// the data can be any one of them.
+type outgoingMessageType struct {
+	Action      string
+	Description string
+	Payload     []interface{}
+	// Legacy is set for actions whose data is sent as a JSON-encoded string to clients speaking version 1.
+	Legacy bool
+}
+
+// outgoingMessageTypes are the client actions the server can call, in the order they are documented in.
+var outgoingMessageTypes = []outgoingMessageType{
+	{
+		Action:      "hello",
+		Description: "The reply to a hello message, with the version of the protocol the server will speak.",
+		Payload:     []interface{}{&HelloData{}},
+	},
+	{
+		Action:      "pong",
+		Description: "The reply to a ping message.",
+		Payload:     []interface{}{nil},
+	},
+	{
+		Action:      "disconnect",
+		Description: "Asks the client to close the connection.",
+		Payload:     []interface{}{nil},
+	},
+	{
+		Action:      "showText",
+		Description: "Text for the main window: HTML or plain text, or a RichText document for the rich format.",
+		Payload:     []interface{}{"", &RichText{}},
+	},
+	{
+		Action:      "setRoomTitle",
+		Description: "The title of the current room.",
+		Payload:     []interface{}{""},
+	},
+	{
+		Action:      "setRoomObjects",
+		Description: "The characters, mobs and items within the current room.",
+		Payload:     []interface{}{[]*RoomObjectData{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setMapData",
+		Description: "The rooms of the current area, for drawing the minimap.",
+		Payload:     []interface{}{&MinimapData{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setCharacterLocation",
+		Description: "The location of the current room on the minimap.",
+		Payload:     []interface{}{&LocationData{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setInventory",
+		Description: "The items within the character's inventory.",
+		Payload:     []interface{}{[]*InventoryItemData{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setMoney",
+		Description: "How much money the character has.",
+		Payload:     []interface{}{""},
+	},
+	{
+		Action:      "setPlayerInfo",
+		Description: "The character being played.",
+		Payload:     []interface{}{&PlayerInfoData{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setPermissions",
+		Description: "The character's permissions, separated by spaces.",
+		Payload:     []interface{}{""},
+	},
+	{
+		Action:      "setSettings",
+		Description: "The character's settings, by name.",
+		Payload:     []interface{}{map[string]string{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setCommandDictionary",
+		Description: "The commands available to the character, for auto-completion.",
+		Payload:     []interface{}{[]*Command{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setObjectEditorData",
+		Description: "Opens the object editor for an object.",
+		Payload:     []interface{}{&ObjectEditorData{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "setItemTooltipHTML",
+		Description: "The tooltip of an item instance.",
+		Payload:     []interface{}{&ItemTooltipData{}},
+		Legacy:      true,
+	},
+	{
+		Action:      "toggleAutoLogin",
+		Description: "The name and token to log in with automatically, prefixed with the character's name.",
+		Payload:     []interface{}{""},
+	},
+	{
+		Action:      "playSFX",
+		Description: "A sound effect to play.",
+		Payload:     []interface{}{&SoundEffectData{}},
+		Legacy:      true,
+	},
+}
+
+// EncodeForProtocolVersion returns the message as it is sent to a client speaking the version of the protocol.
+func EncodeForProtocolVersion(m *OutgoingDataStructure, version int) (*OutgoingDataStructure, error) {
+	if version >= 2 || m.Payload == nil {
+		return m, nil
+	}
+
+	for _, mt := range outgoingMessageTypes {
+		if mt.Action != m.Action || !mt.Legacy {
+			continue
+		}
+
+		j, err := json.Marshal(m.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return &OutgoingDataStructure{Action: m.Action, Payload: string(j)}, nil
+	}
+
+	return m, nil
+}
@@ -0,0 +1,157 @@
+package armeria
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A negotiatingTransport is a chanTransport that records the protocol version negotiated with it.
+type negotiatingTransport struct {
+	*chanTransport
+	version int
+}
+
+func (t *negotiatingTransport) SetProtocolVersion(v int) {
+	t.version = v
+}
+
+func TestDecodeIncomingMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *IncomingDataStructure
+		err     error
+	}{
+		{"command", &IncomingDataStructure{Type: "command", Payload: json.RawMessage(`"/look"`)}, nil},
+		{"ping", &IncomingDataStructure{Type: "ping"}, nil},
+		{"hello", &IncomingDataStructure{Type: "hello", Payload: json.RawMessage(`{"version":2}`)}, nil},
+		{"text format", &IncomingDataStructure{Type: "textFormat", Payload: json.RawMessage(`"rich"`)}, nil},
+		{"editor open", &IncomingDataStructure{Type: "objectEditorOpen", Payload: json.RawMessage(`true`)}, nil},
+		{"unknown type", &IncomingDataStructure{Type: "launchMissiles"}, ErrUnknownMessageType},
+		{"command without payload", &IncomingDataStructure{Type: "command"}, ErrInvalidPayload},
+		{"command as number", &IncomingDataStructure{Type: "command", Payload: json.RawMessage(`123`)}, ErrInvalidPayload},
+		{"command without slash", &IncomingDataStructure{Type: "command", Payload: json.RawMessage(`"look"`)}, ErrInvalidPayload},
+		{"editor open as string", &IncomingDataStructure{Type: "objectEditorOpen", Payload: json.RawMessage(`"yes"`)}, ErrInvalidPayload},
+		{"bad text format", &IncomingDataStructure{Type: "textFormat", Payload: json.RawMessage(`"pdf"`)}, ErrInvalidPayload},
+		{"hello without version", &IncomingDataStructure{Type: "hello", Payload: json.RawMessage(`{}`)}, ErrInvalidPayload},
+		{"upload as array", &IncomingDataStructure{Type: "objectPictureUpload", Payload: json.RawMessage(`[]`)}, ErrInvalidPayload},
+		{"upload without data", &IncomingDataStructure{
+			Type:    "objectPictureUpload",
+			Payload: json.RawMessage(`{"objectType":"item","name":"Sword"}`),
+		}, ErrInvalidPayload},
+	}
+
+	for _, tt := range tests {
+		msg, err := DecodeIncomingMessage(tt.message)
+		if tt.err == nil && (err != nil || msg == nil) {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		} else if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	tests := []struct {
+		client   int
+		expected int
+		err      error
+	}{
+		{MinProtocolVersion, MinProtocolVersion, nil},
+		{ProtocolVersion, ProtocolVersion, nil},
+		{ProtocolVersion + 1, ProtocolVersion, nil},
+		{MinProtocolVersion - 1, 0, ErrUnsupportedProtocolVersion},
+	}
+
+	for _, tt := range tests {
+		v, err := NegotiateProtocolVersion(tt.client)
+		if v != tt.expected || err != tt.err {
+			t.Errorf("version %d: expected %d (%v), got %d (%v)", tt.client, tt.expected, tt.err, v, err)
+		}
+	}
+}
+
+func TestEncodeForProtocolVersion(t *testing.T) {
+	inv := &OutgoingDataStructure{Action: "setInventory", Payload: []*InventoryItemData{{UUID: "abc", Name: "Sword"}}}
+
+	m, err := EncodeForProtocolVersion(inv, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s, ok := m.Payload.(string); !ok || !strings.Contains(s, `"name":"Sword"`) {
+		t.Errorf("expected a JSON-encoded string for version 1, got %#v", m.Payload)
+	}
+
+	if m, _ := EncodeForProtocolVersion(inv, 2); m != inv {
+		t.Errorf("expected the message to be unchanged for version 2, got %#v", m.Payload)
+	}
+
+	title := &OutgoingDataStructure{Action: "setRoomTitle", Payload: "Town Square"}
+	if m, _ := EncodeForProtocolVersion(title, 1); m.Payload != "Town Square" {
+		t.Errorf("expected the title to be unchanged for version 1, got %#v", m.Payload)
+	}
+}
+
+func TestProtocolSchemaUpToDate(t *testing.T) {
+	b, err := ioutil.ReadFile("../../../docs/protocol.schema.json")
+	if err != nil {
+		t.Fatalf("error reading schema: %s", err)
+	}
+
+	if !bytes.Equal(b, ProtocolSchemaJSON()) {
+		t.Error("docs/protocol.schema.json is out of date; regenerate it with: " +
+			"go run ./cmd/armeria -protocol-schema docs/protocol.schema.json")
+	}
+}
+
+func TestReadPumpInvalidMessages(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	nt := &negotiatingTransport{chanTransport: newChanTransport()}
+	p := Armeria.playerManager.NewPlayer(nt)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.readPump()
+	}()
+	go func() {
+		defer wg.Done()
+		p.writePump()
+	}()
+
+	// Malformed messages are refused without taking down the read pump.
+	for _, m := range []*IncomingDataStructure{
+		{Type: "command", Payload: json.RawMessage(`{"cmd":"/look"}`)},
+		{Type: "objectEditorOpen", Payload: json.RawMessage(`"true"`)},
+		{Type: "itemTooltipHTML", Payload: json.RawMessage(`42`)},
+		{Type: "mystery"},
+	} {
+		nt.in <- m
+		out := expectWrite(t, nt.chanTransport, "showText")
+		if text := out.Payload.(*RichText).Plain(); !strings.Contains(text, "Your client sent invalid data.") {
+			t.Errorf("%s: unexpected text %q", m.Type, text)
+		}
+	}
+
+	nt.in <- NewIncomingMessage("hello", &HelloMessage{Version: ProtocolVersion + 1})
+	out := expectWrite(t, nt.chanTransport, "hello")
+	if hello := out.Payload.(*HelloData); hello.Version != ProtocolVersion || nt.version != ProtocolVersion {
+		t.Errorf("expected version %d to be negotiated, got %d", ProtocolVersion, hello.Version)
+	}
+
+	nt.in <- NewIncomingMessage("command", "/login tester secret")
+	out = expectWrite(t, nt.chanTransport, "showText")
+	if text := out.Payload.(*RichText).Plain(); !strings.Contains(text, "You've logged in to tester.") {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	_ = nt.Close()
+	waitOrDeadlock(t, &wg)
+}
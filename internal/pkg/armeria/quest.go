@@ -0,0 +1,270 @@
+package armeria
+
+import (
+	"armeria/internal/pkg/misc"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quest step types.
+const (
+	QuestStepTalk    string = "talk"
+	QuestStepDeliver string = "deliver"
+	QuestStepVisit   string = "visit"
+	QuestStepScript  string = "script"
+)
+
+var (
+	// ErrQuestActive is an error for when a character has already started a quest.
+	ErrQuestActive = errors.New("quest already started")
+	// ErrQuestCompleted is an error for when a character has already completed a quest.
+	ErrQuestCompleted = errors.New("quest already completed")
+	// ErrQuestNotActive is an error for when a character is not on a quest.
+	ErrQuestNotActive = errors.New("quest not started")
+)
+
+// QuestStep is a single objective within a Quest. The Target is the mob to talk to, the item to deliver or the
+// location of the room to visit, depending on the step type. Deliver steps also name the Mob to deliver to.
+type QuestStep struct {
+	Type        string `json:"type"`
+	Target      string `json:"target"`
+	Mob         string `json:"mob,omitempty"`
+	Description string `json:"description"`
+}
+
+// QuestProgress is a Character's progress through a single Quest.
+type QuestProgress struct {
+	Step      int       `json:"step"`
+	Completed bool      `json:"completed"`
+	Started   time.Time `json:"started"`
+}
+
+// Quest is a series of steps that a Character works through for a reward.
+type Quest struct {
+	sync.RWMutex
+	UnsafeName        string       `json:"name"`
+	UnsafeDescription string       `json:"description"`
+	UnsafeSteps       []*QuestStep `json:"steps"`
+	UnsafeRewardMoney float64      `json:"rewardMoney"`
+	UnsafeRewardItems []string     `json:"rewardItems"`
+}
+
+// QuestStepTypes returns the valid quest step types.
+func QuestStepTypes() []string {
+	return []string{
+		QuestStepTalk,
+		QuestStepDeliver,
+		QuestStepVisit,
+		QuestStepScript,
+	}
+}
+
+// Matches returns whether the step is completed by an action on a target, such as talking to a mob. Deliver steps
+// also require the mob the item was given to.
+func (qs *QuestStep) Matches(stepType, target, mob string) bool {
+	if qs.Type != stepType || !strings.EqualFold(qs.Target, target) {
+		return false
+	}
+
+	return qs.Type != QuestStepDeliver || strings.EqualFold(qs.Mob, mob)
+}
+
+// String returns the step in the form it is added to a quest, such as "deliver Rat Tail to Astro".
+func (qs *QuestStep) String() string {
+	switch qs.Type {
+	case QuestStepDeliver:
+		return fmt.Sprintf("%s %s to %s", qs.Type, qs.Target, qs.Mob)
+	case QuestStepScript:
+		return qs.Type
+	}
+
+	return fmt.Sprintf("%s %s", qs.Type, qs.Target)
+}
+
+// Name returns the name of the quest.
+func (q *Quest) Name() string {
+	q.RLock()
+	defer q.RUnlock()
+	return q.UnsafeName
+}
+
+// Description returns the description of the quest.
+func (q *Quest) Description() string {
+	q.RLock()
+	defer q.RUnlock()
+	return q.UnsafeDescription
+}
+
+// SetDescription sets the description of the quest.
+func (q *Quest) SetDescription(description string) {
+	q.Lock()
+	defer q.Unlock()
+	q.UnsafeDescription = description
+}
+
+// Steps returns the steps of the quest, in order.
+func (q *Quest) Steps() []*QuestStep {
+	q.RLock()
+	defer q.RUnlock()
+
+	steps := make([]*QuestStep, len(q.UnsafeSteps))
+	copy(steps, q.UnsafeSteps)
+
+	return steps
+}
+
+// Step returns the step at the (zero-based) index, or nil if there is no such step.
+func (q *Quest) Step(idx int) *QuestStep {
+	q.RLock()
+	defer q.RUnlock()
+
+	if idx < 0 || idx >= len(q.UnsafeSteps) {
+		return nil
+	}
+
+	return q.UnsafeSteps[idx]
+}
+
+// AddStep adds a step to the end of the quest.
+func (q *Quest) AddStep(qs *QuestStep) {
+	q.Lock()
+	defer q.Unlock()
+	q.UnsafeSteps = append(q.UnsafeSteps, qs)
+}
+
+// RemoveStep removes the step at the (zero-based) index, returning false if there is no such step.
+func (q *Quest) RemoveStep(idx int) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	if idx < 0 || idx >= len(q.UnsafeSteps) {
+		return false
+	}
+
+	q.UnsafeSteps = append(q.UnsafeSteps[:idx], q.UnsafeSteps[idx+1:]...)
+	return true
+}
+
+// RewardMoney returns the money given to a Character when they complete the quest.
+func (q *Quest) RewardMoney() float64 {
+	q.RLock()
+	defer q.RUnlock()
+	return q.UnsafeRewardMoney
+}
+
+// SetRewardMoney sets the money given to a Character when they complete the quest.
+func (q *Quest) SetRewardMoney(amount float64) {
+	q.Lock()
+	defer q.Unlock()
+	q.UnsafeRewardMoney = amount
+}
+
+// RewardItems returns the names of the items given to a Character when they complete the quest.
+func (q *Quest) RewardItems() []string {
+	q.RLock()
+	defer q.RUnlock()
+
+	items := make([]string, len(q.UnsafeRewardItems))
+	copy(items, q.UnsafeRewardItems)
+
+	return items
+}
+
+// SetRewardItems sets the names of the items given to a Character when they complete the quest.
+func (q *Quest) SetRewardItems(items []string) {
+	q.Lock()
+	defer q.Unlock()
+	q.UnsafeRewardItems = items
+}
+
+// RewardString returns a description of the quest rewards.
+func (q *Quest) RewardString() string {
+	var rewards []string
+	if q.RewardMoney() > 0 {
+		rewards = append(rewards, misc.Money.FormatMoney(q.RewardMoney()))
+	}
+	rewards = append(rewards, q.RewardItems()...)
+
+	if len(rewards) == 0 {
+		return "nothing"
+	}
+
+	return strings.Join(rewards, ", ")
+}
+
+// ParseQuestStep parses a step such as "deliver Rat Tail to Astro" into a QuestStep, returning a message
+// explaining the problem when the step isn't valid. Visit steps use the Character's room when the location is ".".
+func ParseQuestStep(c *Character, value string) (*QuestStep, string) {
+	sections := strings.SplitN(value, " ", 2)
+	stepType := strings.ToLower(sections[0])
+	target := ""
+	if len(sections) > 1 {
+		target = strings.TrimSpace(sections[1])
+	}
+
+	if !misc.Contains(QuestStepTypes(), stepType) || len(target) == 0 {
+		return nil, "Steps must be one of: talk &lt;mob&gt;, deliver &lt;item&gt; to &lt;mob&gt;, " +
+			"visit &lt;location&gt; or script &lt;description&gt;."
+	}
+
+	switch stepType {
+	case QuestStepTalk:
+		m := Armeria.mobManager.MobByName(target)
+		if m == nil {
+			return nil, "A mob by that name doesn't exist."
+		}
+		return &QuestStep{
+			Type:        stepType,
+			Target:      m.Name(),
+			Description: fmt.Sprintf("Talk to %s.", m.Name()),
+		}, ""
+	case QuestStepDeliver:
+		idx := strings.LastIndex(target, " to ")
+		if idx < 0 {
+			return nil, "Deliver steps must be in the form: deliver &lt;item&gt; to &lt;mob&gt;."
+		}
+		i := Armeria.itemManager.ItemByName(target[:idx])
+		m := Armeria.mobManager.MobByName(target[idx+4:])
+		if i == nil || m == nil {
+			return nil, "That item or mob doesn't exist."
+		}
+		return &QuestStep{
+			Type:        stepType,
+			Target:      i.Name(),
+			Mob:         m.Name(),
+			Description: fmt.Sprintf("Deliver a %s to %s.", i.Name(), m.Name()),
+		}, ""
+	case QuestStepVisit:
+		r := c.Room()
+		if target != "." {
+			r = Armeria.worldManager.RoomFromLocationString(target)
+		}
+		if r == nil {
+			return nil, "That room doesn't exist. Use a location like Area,x,y,z."
+		}
+		return &QuestStep{
+			Type:        stepType,
+			Target:      r.LocationString(),
+			Description: fmt.Sprintf("Visit %s.", r.Attribute(AttributeTitle)),
+		}, ""
+	}
+
+	return &QuestStep{
+		Type:        stepType,
+		Description: target,
+	}, ""
+}
+
+type questJSON Quest
+
+// MarshalJSON encodes the Quest while holding its lock.
+func (q *Quest) MarshalJSON() ([]byte, error) {
+	q.RLock()
+	defer q.RUnlock()
+
+	return json.Marshal((*questJSON)(q))
+}
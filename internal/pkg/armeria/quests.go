@@ -0,0 +1,354 @@
+package armeria
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type QuestManager struct {
+	sync.RWMutex
+	UnsafeQuests []*Quest `json:"quests"`
+	progress     sync.Mutex
+}
+
+// NewQuestManager creates a new QuestManager.
+func NewQuestManager() *QuestManager {
+	m := &QuestManager{}
+
+	m.LoadQuests()
+
+	return m
+}
+
+// LoadQuests loads the quests from disk into memory.
+func (m *QuestManager) LoadQuests() {
+	m.Lock()
+	defer m.Unlock()
+
+	records, err := Armeria.store.Load(CollectionQuests)
+	if err != nil {
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionQuests.Name),
+			zap.Error(err),
+		)
+	}
+
+	err = DecodeStoreRecords(records, &m.UnsafeQuests)
+	if err != nil {
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionQuests.Name),
+			zap.Error(err),
+		)
+	}
+
+	Armeria.log.Info("quests loaded",
+		zap.Int("count", len(m.UnsafeQuests)),
+	)
+}
+
+// SaveQuests writes the in-memory quests to disk.
+func (m *QuestManager) SaveQuests() {
+	m.RLock()
+	defer m.RUnlock()
+
+	records, err := EncodeStoreRecords(CollectionQuests, m.UnsafeQuests)
+	if err != nil {
+		Armeria.log.Fatal("failed to marshal data",
+			zap.Error(err),
+		)
+	}
+
+	err = Armeria.store.Save(CollectionQuests, records)
+	if err != nil {
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionQuests.Name),
+			zap.Error(err),
+		)
+	}
+
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionQuests.Name),
+		zap.Int("records", len(records)),
+	)
+}
+
+// SaveQuest immediately persists a single Quest to the store.
+func (m *QuestManager) SaveQuest(q *Quest) {
+	persistRecord(CollectionQuests, q)
+}
+
+// Quests returns all of the in-memory Quests.
+func (m *QuestManager) Quests() []*Quest {
+	m.RLock()
+	defer m.RUnlock()
+
+	quests := make([]*Quest, len(m.UnsafeQuests))
+	copy(quests, m.UnsafeQuests)
+
+	return quests
+}
+
+// QuestByName returns the matching Quest, by name.
+func (m *QuestManager) QuestByName(name string) *Quest {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, q := range m.UnsafeQuests {
+		if strings.ToLower(q.Name()) == strings.ToLower(name) {
+			return q
+		}
+	}
+
+	return nil
+}
+
+// CreateQuest creates a new Quest and adds it to memory.
+func (m *QuestManager) CreateQuest(name string) *Quest {
+	q := &Quest{
+		UnsafeName:        name,
+		UnsafeSteps:       []*QuestStep{},
+		UnsafeRewardItems: []string{},
+	}
+
+	m.Lock()
+	m.UnsafeQuests = append(m.UnsafeQuests, q)
+	m.Unlock()
+
+	m.SaveQuest(q)
+
+	return q
+}
+
+// CharactersOnQuest returns the characters that have started, but not completed, the Quest.
+func (m *QuestManager) CharactersOnQuest(q *Quest) []*Character {
+	var chars []*Character
+	for _, c := range Armeria.characterManager.Characters() {
+		if p, ok := c.QuestProgress(q.Name()); ok && !p.Completed {
+			chars = append(chars, c)
+		}
+	}
+
+	return chars
+}
+
+// Start puts the Character on the first step of the Quest.
+func (m *QuestManager) Start(c *Character, q *Quest) error {
+	m.progress.Lock()
+	defer m.progress.Unlock()
+
+	if p, ok := c.QuestProgress(q.Name()); ok {
+		if p.Completed {
+			return ErrQuestCompleted
+		}
+		return ErrQuestActive
+	}
+
+	c.SetQuestProgress(q.Name(), QuestProgress{Started: time.Now()})
+
+	Armeria.log.Info("character started quest",
+		zap.String("character", c.Name()),
+		zap.String("quest", q.Name()),
+	)
+
+	if c.Online() {
+		c.Player().client.ShowColorizedText(
+			fmt.Sprintf("You have started the quest %s.", TextStyle(q.Name(), WithBold())),
+			ColorSuccess,
+		)
+		if step := q.Step(0); step != nil {
+			c.Player().client.ShowText(fmt.Sprintf("Next: %s", step.Description))
+		}
+	}
+
+	return nil
+}
+
+// Advance moves the Character on to the next step of the Quest, completing it after the last step.
+func (m *QuestManager) Advance(c *Character, q *Quest) error {
+	m.progress.Lock()
+	defer m.progress.Unlock()
+
+	return m.advance(c, q)
+}
+
+// advance moves the Character on to the next step of the Quest. This DOES NOT request a lock and IS NOT thread
+// safe.
+func (m *QuestManager) advance(c *Character, q *Quest) error {
+	p, ok := c.QuestProgress(q.Name())
+	if !ok || p.Completed {
+		return ErrQuestNotActive
+	}
+
+	p.Step = p.Step + 1
+	if p.Step >= len(q.Steps()) {
+		m.complete(c, q, p)
+		return nil
+	}
+
+	c.SetQuestProgress(q.Name(), p)
+
+	if c.Online() {
+		c.Player().client.ShowColorizedText(
+			fmt.Sprintf("Your quest %s has been updated.", TextStyle(q.Name(), WithBold())),
+			ColorSuccess,
+		)
+		c.Player().client.ShowText(fmt.Sprintf("Next: %s", q.Step(p.Step).Description))
+	}
+
+	return nil
+}
+
+// Complete completes the Quest for the Character, regardless of the step they are on, and gives them the rewards.
+func (m *QuestManager) Complete(c *Character, q *Quest) error {
+	m.progress.Lock()
+	defer m.progress.Unlock()
+
+	p, ok := c.QuestProgress(q.Name())
+	if !ok || p.Completed {
+		return ErrQuestNotActive
+	}
+
+	m.complete(c, q, p)
+	return nil
+}
+
+// complete marks the Quest as completed and gives the Character the rewards. This DOES NOT request a lock and IS
+// NOT thread safe.
+func (m *QuestManager) complete(c *Character, q *Quest, p QuestProgress) {
+	p.Step = len(q.Steps())
+	p.Completed = true
+	c.SetQuestProgress(q.Name(), p)
+
+	if q.RewardMoney() > 0 {
+		c.AddMoney(q.RewardMoney())
+	}
+
+	// Reward items go to the floor when the Character's inventory is full.
+	for _, name := range q.RewardItems() {
+		i := Armeria.itemManager.ItemByName(name)
+		if i == nil {
+			continue
+		}
+
+		ii := i.CreateInstance()
+		if err := c.Inventory().Add(ii.ID()); err != nil {
+			if r := c.Room(); r == nil || r.Here().Add(ii.ID()) != nil {
+				ii.Delete()
+			}
+		}
+	}
+
+	Armeria.log.Info("character completed quest",
+		zap.String("character", c.Name()),
+		zap.String("quest", q.Name()),
+	)
+
+	if c.Online() {
+		c.Player().client.ShowColorizedText(
+			fmt.Sprintf(
+				"You have completed the quest %s! You receive %s.",
+				TextStyle(q.Name(), WithBold()),
+				q.RewardString(),
+			),
+			ColorSuccess,
+		)
+		c.Player().client.SyncInventory()
+		c.Player().client.SyncMoney()
+		if r := c.Room(); r != nil {
+			for _, char := range r.Here().Characters(true) {
+				char.Player().client.SyncRoomObjects()
+			}
+		}
+	}
+}
+
+// RemoveStep removes a step from the Quest, returning whether it existed along with the Characters whose progress
+// was moved to make up for it. Anyone past the step moves back one, so they stay on the same objective, and anyone
+// on it moves on to the step that follows, completing the quest if there isn't one.
+func (m *QuestManager) RemoveStep(q *Quest, idx int) ([]*Character, bool) {
+	m.progress.Lock()
+	defer m.progress.Unlock()
+
+	if !q.RemoveStep(idx) {
+		return nil, false
+	}
+
+	var moved []*Character
+	for _, c := range Armeria.characterManager.Characters() {
+		p, ok := c.QuestProgress(q.Name())
+		if !ok {
+			continue
+		}
+
+		switch {
+		case p.Step > idx:
+			p.Step = p.Step - 1
+			c.SetQuestProgress(q.Name(), p)
+			moved = append(moved, c)
+		case !p.Completed && p.Step >= len(q.Steps()):
+			m.complete(c, q, p)
+			moved = append(moved, c)
+		}
+	}
+
+	return moved, true
+}
+
+// Abandon removes the Character's progress on a Quest they have not completed.
+func (m *QuestManager) Abandon(c *Character, q *Quest) error {
+	m.progress.Lock()
+	defer m.progress.Unlock()
+
+	p, ok := c.QuestProgress(q.Name())
+	if !ok || p.Completed {
+		return ErrQuestNotActive
+	}
+
+	c.RemoveQuestProgress(q.Name())
+
+	Armeria.log.Info("character abandoned quest",
+		zap.String("character", c.Name()),
+		zap.String("quest", q.Name()),
+	)
+
+	return nil
+}
+
+// Progress advances each of the Character's quests whose current step is completed by the action, such as
+// talking to a mob or visiting a room.
+func (m *QuestManager) Progress(c *Character, stepType, target, mob string) {
+	m.progress.Lock()
+	defer m.progress.Unlock()
+
+	for _, q := range m.Quests() {
+		if m.Awaits(c, q, stepType, target, mob) {
+			_ = m.advance(c, q)
+		}
+	}
+}
+
+// Awaits returns whether the Character's current step on the Quest is completed by the action.
+func (m *QuestManager) Awaits(c *Character, q *Quest, stepType, target, mob string) bool {
+	p, ok := c.QuestProgress(q.Name())
+	if !ok || p.Completed {
+		return false
+	}
+
+	step := q.Step(p.Step)
+	return step != nil && step.Matches(stepType, target, mob)
+}
+
+// AwaitsAny returns whether any of the Character's current quest steps are completed by the action.
+func (m *QuestManager) AwaitsAny(c *Character, stepType, target, mob string) bool {
+	for _, q := range m.Quests() {
+		if m.Awaits(c, q, stepType, target, mob) {
+			return true
+		}
+	}
+
+	return false
+}
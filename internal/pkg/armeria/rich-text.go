@@ -0,0 +1,403 @@
+package armeria
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Rich text is carried through ordinary strings, so that handlers can keep composing text with fmt.Sprintf, by
+// wrapping each styled node in control characters that are stripped from everything a client sends. A node is
+// written as richTextOpen, the JSON of the node's attributes, richTextBody, the node's contents and richTextClose.
+const (
+	richTextOpen  = '\x02'
+	richTextBody  = '\x1f'
+	richTextClose = '\x03'
+)
+
+// Types of RichTextNode.
+const (
+	RichTextTypeText  = "text"
+	RichTextTypeSpan  = "span"
+	RichTextTypeTable = "table"
+	RichTextTypeRow   = "row"
+	RichTextTypeCell  = "cell"
+)
+
+// TextFormat is the format that text is sent to a client in.
+type TextFormat string
+
+const (
+	TextFormatHTML  TextFormat = "html"
+	TextFormatPlain TextFormat = "plain"
+	TextFormatRich  TextFormat = "rich"
+)
+
+// A RichText is a document of styled text, which is rendered to HTML, ANSI or plain text for each connection.
+type RichText struct {
+	Nodes []*RichTextNode `json:"nodes"`
+}
+
+// A RichTextNode is a run of text, or a span, table, row or cell containing other nodes.
+type RichTextNode struct {
+	Type        string               `json:"type"`
+	Text        string               `json:"text,omitempty"`
+	Bold        bool                 `json:"bold,omitempty"`
+	Italic      bool                 `json:"italic,omitempty"`
+	Monospace   bool                 `json:"monospace,omitempty"`
+	Color       string               `json:"color,omitempty"`
+	Background  string               `json:"background,omitempty"`
+	Label       bool                 `json:"label,omitempty"`
+	Size        int                  `json:"size,omitempty"`
+	Command     string               `json:"command,omitempty"`
+	URL         string               `json:"url,omitempty"`
+	Button      *RichTextButton      `json:"button,omitempty"`
+	ItemTooltip string               `json:"itemTooltip,omitempty"`
+	ContextMenu *RichTextContextMenu `json:"contextMenu,omitempty"`
+	Convo       *RichTextConvo       `json:"convo,omitempty"`
+	Header      bool                 `json:"header,omitempty"`
+	Children    []*RichTextNode      `json:"children,omitempty"`
+}
+
+// A RichTextButton is a clickable button that runs a command, optionally prompting for input first.
+type RichTextButton struct {
+	Command    string `json:"command"`
+	PromptData string `json:"promptData,omitempty"`
+}
+
+// A RichTextContextMenu is the menu shown when right-clicking an object's name.
+type RichTextContextMenu struct {
+	Name       string   `json:"name"`
+	ObjectType string   `json:"objectType"`
+	Color      string   `json:"color"`
+	Items      []string `json:"items"`
+}
+
+// A RichTextConvo is an answer that can be chosen within a conversation with a mob.
+type RichTextConvo struct {
+	ID      string `json:"id"`
+	MobUUID string `json:"mobUuid"`
+	Group   int64  `json:"group"`
+}
+
+// encodeRichText returns the node wrapped around the contents, for use within a string.
+func encodeRichText(n *RichTextNode, contents string) string {
+	attrs := *n
+	attrs.Text = ""
+	attrs.Children = nil
+
+	j, err := json.Marshal(&attrs)
+	if err != nil {
+		return contents
+	}
+
+	return string(richTextOpen) + string(j) + string(richTextBody) + contents + string(richTextClose)
+}
+
+// StripRichText removes the characters used to carry rich text from the string, so that text sent by a client can
+// never be mistaken for styling.
+func StripRichText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == richTextOpen || r == richTextBody || r == richTextClose {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// ParseRichText parses a string built with TextStyle, TextTable and friends into a RichText. Everything outside of
+// the nodes is literal text, so it can never inject markup into a client. Control characters other than newlines
+// and tabs are dropped, and HTML entities (such as &lt;) are decoded for compatibility with text written for the
+// web client.
+func ParseRichText(text string) *RichText {
+	root := &RichTextNode{}
+	stack := []*RichTextNode{root}
+
+	var run strings.Builder
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		top.Children = append(top.Children, &RichTextNode{
+			Type: RichTextTypeText,
+			Text: strings.Map(dropControl, html.UnescapeString(run.String())),
+		})
+		run.Reset()
+	}
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == richTextOpen:
+			flush()
+			end := strings.IndexByte(text[i:], richTextBody)
+			if end < 0 {
+				i = len(text)
+				break
+			}
+
+			n := &RichTextNode{}
+			if err := json.Unmarshal([]byte(text[i+1:i+end]), n); err != nil {
+				n = &RichTextNode{Type: RichTextTypeSpan}
+			}
+			n.Text = ""
+			n.Children = nil
+
+			top := stack[len(stack)-1]
+			top.Children = append(top.Children, n)
+			stack = append(stack, n)
+			i += end
+		case c == richTextClose:
+			flush()
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case c == richTextBody:
+		default:
+			run.WriteByte(c)
+		}
+	}
+	flush()
+
+	return &RichText{Nodes: root.Children}
+}
+
+// dropControl drops control characters other than newlines and tabs, for use with strings.Map.
+func dropControl(r rune) rune {
+	if unicode.IsControl(r) && r != '\n' && r != '\t' {
+		return -1
+	}
+	return r
+}
+
+// Render returns the RichText in the format, as a value ready to be sent to a client.
+func (rt *RichText) Render(f TextFormat) interface{} {
+	switch f {
+	case TextFormatRich:
+		return rt
+	case TextFormatPlain:
+		return rt.Plain()
+	default:
+		return rt.HTML()
+	}
+}
+
+// String returns the RichText as plain text.
+func (rt *RichText) String() string {
+	return rt.Plain()
+}
+
+// Plain returns the RichText as plain text, with tables lined up using spaces.
+func (rt *RichText) Plain() string {
+	return ansiPattern.ReplaceAllString(rt.ANSI(ColorDepth16), "")
+}
+
+// HTML returns the RichText as HTML for the web client, with all text escaped.
+func (rt *RichText) HTML() string {
+	var sb strings.Builder
+	for _, n := range rt.Nodes {
+		n.writeHTML(&sb)
+	}
+	return sb.String()
+}
+
+// writeHTML writes the node as HTML.
+func (n *RichTextNode) writeHTML(sb *strings.Builder) {
+	var inner strings.Builder
+	for _, child := range n.Children {
+		child.writeHTML(&inner)
+	}
+	contents := inner.String()
+
+	attr := html.EscapeString
+	switch n.Type {
+	case RichTextTypeText:
+		// Square brackets are escaped too, since the web client expands [b] and [cmd=...] within text.
+		sb.WriteString(strings.Replace(html.EscapeString(n.Text), "[", "&#91;", -1))
+		return
+	case RichTextTypeTable:
+		sb.WriteString("<table cellspacing=\"0\">" + contents + "</table>")
+		return
+	case RichTextTypeRow:
+		sb.WriteString("<tr>" + contents + "</tr>")
+		return
+	case RichTextTypeCell:
+		if n.Header {
+			sb.WriteString("<th>" + contents + "</th>")
+		} else {
+			sb.WriteString("<td>" + contents + "</td>")
+		}
+		return
+	}
+
+	// Each style wraps the one before it, matching the markup the web client has always received.
+	wrap := func(open, close string) {
+		contents = open + contents + close
+	}
+	if n.Bold {
+		wrap("<span style='font-weight:600'>", "</span>")
+	}
+	if n.Italic {
+		wrap("<span style='font-style:italic'>", "</span>")
+	}
+	if n.Monospace {
+		wrap("<span class='monospace'>", "</span>")
+	}
+	if n.Size > 0 {
+		wrap(fmt.Sprintf("<span style='font-size:%dpx'>", n.Size), "</span>")
+	}
+	if len(n.Color) > 0 && !n.Label {
+		wrap("<span style='color:"+attr(n.Color)+"'>", "</span>")
+	}
+	if n.Label {
+		wrap(
+			"<span style='background-color:"+attr(n.Background)+
+				";padding:3px 8px;color:#fff;border-radius:12px;margin-right:2px'>",
+			"</span>",
+		)
+	} else if len(n.Background) > 0 {
+		wrap("<span style='background-color:"+attr(n.Background)+"'>", "</span>")
+	}
+	if len(n.ItemTooltip) > 0 {
+		wrap("<span class='hover-item-tooltip' data-uuid='"+attr(n.ItemTooltip)+"'>", "</span>")
+	}
+	if cm := n.ContextMenu; cm != nil {
+		enc := base64.StdEncoding.EncodeToString([]byte(strings.Join(cm.Items, ";")))
+		wrap(
+			fmt.Sprintf(
+				"<span class='dynamic-context-menu' data-name='%s' data-type='%s' data-color='%s' data-content='%s'>",
+				attr(cm.Name),
+				attr(cm.ObjectType),
+				attr(cm.Color),
+				enc,
+			),
+			"</span>",
+		)
+	}
+	if cs := n.Convo; cs != nil {
+		wrap(
+			fmt.Sprintf(
+				"<span class='convo-select' data-group='%d' data-convo-option-id='%s' data-mob-uuid='%s'>",
+				cs.Group,
+				attr(cs.ID),
+				attr(cs.MobUUID),
+			),
+			"</span>",
+		)
+	}
+	if b := n.Button; b != nil {
+		wrap(
+			"<span class='inline-button' data-cmd='"+attr(b.Command)+"' data-prompt='"+attr(b.PromptData)+"'>",
+			"</span>",
+		)
+	}
+	if len(n.Command) > 0 {
+		enc := base64.StdEncoding.EncodeToString([]byte(n.Command))
+		wrap(
+			"<a href='#' class='inline-command' data-command='"+enc+"' tooltip='Run: "+attr(n.Command)+"'>",
+			"</a>",
+		)
+	}
+	if len(n.URL) > 0 {
+		wrap("<a href='"+attr(n.URL)+"' class='inline-link' target='_new'>", "</a>")
+	}
+
+	sb.WriteString(contents)
+}
+
+// ANSI returns the RichText as text styled with ANSI escape codes, using no more colors than the depth allows.
+// Styling that has no terminal equivalent, such as tooltips and context menus, is dropped while keeping its text.
+func (rt *RichText) ANSI(depth ColorDepth) string {
+	var sb strings.Builder
+	writeANSI(&sb, rt.Nodes, ansiStyle{}, depth)
+	return sb.String()
+}
+
+// writeANSI writes the nodes to the builder.
+func writeANSI(sb *strings.Builder, nodes []*RichTextNode, style ansiStyle, depth ColorDepth) {
+	for _, n := range nodes {
+		switch n.Type {
+		case RichTextTypeText:
+			writeStyled(sb, n.Text, style, depth)
+		case RichTextTypeTable:
+			ensureNewline(sb)
+			writeANSITable(sb, n, style, depth)
+		default:
+			s := n.ansiStyle(style)
+			if n.Label {
+				writeStyled(sb, " ", s, depth)
+			}
+			writeANSI(sb, n.Children, s, depth)
+			if n.Label {
+				writeStyled(sb, " ", s, depth)
+			}
+			if len(n.URL) > 0 {
+				sb.WriteString(" (" + n.URL + ")")
+			}
+		}
+	}
+}
+
+// ansiStyle returns the style in effect within the node.
+func (n *RichTextNode) ansiStyle(parent ansiStyle) ansiStyle {
+	s := parent
+	s.bold = s.bold || n.Bold || n.Header
+	s.italic = s.italic || n.Italic
+	s.underline = s.underline || len(n.Command) > 0 || len(n.URL) > 0 || n.Button != nil
+	if len(n.Color) > 0 {
+		s.fg = n.Color
+	}
+	if len(n.Background) > 0 {
+		s.bg = n.Background
+	}
+	if n.Label {
+		s.fg = "#fff"
+	}
+	return s
+}
+
+// writeANSITable writes a table with its columns padded to line up.
+func writeANSITable(sb *strings.Builder, table *RichTextNode, style ansiStyle, depth ColorDepth) {
+	var rows [][]string
+	var widths []int
+
+	for _, row := range table.Children {
+		if row.Type != RichTextTypeRow {
+			continue
+		}
+
+		var cells []string
+		for _, cell := range row.Children {
+			if cell.Type != RichTextTypeCell {
+				continue
+			}
+
+			var csb strings.Builder
+			writeANSI(&csb, cell.Children, cell.ansiStyle(style), depth)
+			text := strings.TrimSpace(strings.Replace(csb.String(), "\n", " ", -1))
+
+			col := len(cells)
+			if col == len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := visibleLength(text); w > widths[col] {
+				widths[col] = w
+			}
+			cells = append(cells, text)
+		}
+		rows = append(rows, cells)
+	}
+
+	for _, cells := range rows {
+		for i, text := range cells {
+			sb.WriteString(text)
+			if i < len(cells)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[i]-visibleLength(text)+2))
+			}
+		}
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,134 @@
+package armeria
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRichTextHTML(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"plain", "plain"},
+		{TextStyle("Alice", WithBold()), "<span style='font-weight:600'>Alice</span>"},
+		{
+			TextStyle("Alice", WithBold(), WithColor("ff0000")),
+			"<span style='color:#ff0000'><span style='font-weight:600'>Alice</span></span>",
+		},
+		{
+			TextStyle("look", WithLinkCmd("/look")),
+			"<a href='#' class='inline-command' data-command='L2xvb2s=' tooltip='Run: /look'>look</a>",
+		},
+		{"a &lt;b&gt; & c", "a &lt;b&gt; &amp; c"},
+		{"<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{TextStyle("<b>", WithItalics()), "<span style='font-style:italic'>&lt;b&gt;</span>"},
+		{
+			TextTable(TableRow(TableCell{content: "Name", header: true}), TableRow(TableCell{content: "Apple"})),
+			"<table cellspacing=\"0\"><tr><th>Name</th></tr><tr><td>Apple</td></tr></table>",
+		},
+	}
+
+	for _, tt := range tests {
+		if actual := ParseRichText(tt.text).HTML(); actual != tt.expected {
+			t.Errorf("HTML of %q = %q, expected %q", tt.text, actual, tt.expected)
+		}
+	}
+}
+
+func TestRichTextANSI(t *testing.T) {
+	tests := []struct {
+		text     string
+		depth    ColorDepth
+		expected string
+	}{
+		{TextStyle("Alice", WithBold()), ColorDepth256, "\x1b[1mAlice\x1b[0m"},
+		{TextStyle("red", WithColor("ff0000")), ColorDepthTrueColor, "\x1b[38;2;255;0;0mred\x1b[0m"},
+		{TextStyle("red", WithColor("ff0000")), ColorDepth256, "\x1b[38;5;196mred\x1b[0m"},
+		{TextStyle("red", WithColor("ff0000")), ColorDepth16, "\x1b[91mred\x1b[0m"},
+		{TextStyle("look", WithLinkCmd("/look")), ColorDepth16, "\x1b[4mlook\x1b[0m"},
+		{"a &lt;b&gt; & c\x1b[31m", ColorDepth16, "a <b> & c[31m"},
+		{
+			TextTable(
+				TableRow(TableCell{content: "Name", header: true}, TableCell{content: "Price", header: true}),
+				TableRow(TableCell{content: "Apple"}, TableCell{content: "$5.00"}),
+			),
+			ColorDepth16,
+			"\x1b[1mName\x1b[0m   \x1b[1mPrice\x1b[0m\nApple  $5.00\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if actual := ParseRichText(tt.text).ANSI(tt.depth); actual != tt.expected {
+			t.Errorf("ANSI of %q = %q, expected %q", tt.text, actual, tt.expected)
+		}
+	}
+}
+
+func TestRichTextStructured(t *testing.T) {
+	rt := ParseRichText("You see " + TextStyle("Bob", WithBold(), WithLinkCmd("/look Bob")) + ".")
+
+	if rt.Plain() != "You see Bob." {
+		t.Errorf("unexpected plain text %q", rt.Plain())
+	}
+
+	j, err := json.Marshal(rt.Render(TextFormatRich))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `{"nodes":[{"type":"text","text":"You see "},` +
+		`{"type":"span","bold":true,"command":"/look Bob","children":[{"type":"text","text":"Bob"}]},` +
+		`{"type":"text","text":"."}]}`
+	if string(j) != expected {
+		t.Errorf("expected %s, got %s", expected, j)
+	}
+}
+
+func TestStripRichText(t *testing.T) {
+	forged := TextStyle("fake", WithLink("javascript:alert(1)"))
+
+	if text := StripRichText(forged); strings.ContainsAny(text, "\x02\x03\x1f") {
+		t.Errorf("expected the markers to be stripped, got %q", text)
+	}
+	if html := ParseRichText(StripRichText(forged)).HTML(); strings.Contains(html, "<a") {
+		t.Errorf("expected no link, got %q", html)
+	}
+}
+
+func TestFormatScriptText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"say [b]help[/b].", "say <span style='font-weight:600'>help</span>."},
+		{"[b]a[/b] and [b]b[/b]", "<span style='font-weight:600'>a</span> and <span style='font-weight:600'>b</span>"},
+		{"[b]<i>[/b]", "<span style='font-weight:600'>&lt;i&gt;</span>"},
+		{"[b]unclosed", "&#91;b]unclosed"},
+	}
+
+	for _, tt := range tests {
+		if actual := ParseRichText(FormatScriptText(tt.text)).HTML(); actual != tt.expected {
+			t.Errorf("FormatScriptText(%q) = %q, expected %q", tt.text, actual, tt.expected)
+		}
+	}
+
+	// The markup within the shipped scripts is styled, rather than shown to players.
+	b, err := ioutil.ReadFile("../../../data/scripts/mob-astro.lua")
+	if err != nil {
+		t.Fatalf("error reading script: %s", err)
+	}
+	said := regexp.MustCompile(`say\("([^"]*\[b\][^"]*)"\)`).FindAllStringSubmatch(string(b), -1)
+	if len(said) == 0 {
+		t.Fatal("expected the script to use markup")
+	}
+	for _, m := range said {
+		html := ParseRichText(FormatScriptText(m[1])).HTML()
+		if strings.Contains(html, "[b]") || strings.Contains(html, "&#91;b]") || !strings.Contains(html, "font-weight:600") {
+			t.Errorf("expected %q to be shown in bold, got %q", m[1], html)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package armeria
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// A Role is a named set of permissions, such as "builder", that can be granted to a Character. A Role also has the
+// permissions of each of the roles it inherits from.
+type Role struct {
+	sync.RWMutex
+	UnsafeName        string   `json:"name"`
+	UnsafeDescription string   `json:"description"`
+	UnsafeInherits    []string `json:"inherits"`
+	UnsafePermissions []string `json:"permissions"`
+}
+
+// Init is called when the Role is loaded from disk.
+func (r *Role) Init() {
+	if r.UnsafeInherits == nil {
+		r.UnsafeInherits = []string{}
+	}
+	if r.UnsafePermissions == nil {
+		r.UnsafePermissions = []string{}
+	}
+}
+
+// Name returns the name of the Role.
+func (r *Role) Name() string {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.UnsafeName
+}
+
+// Description returns what the Role is for.
+func (r *Role) Description() string {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.UnsafeDescription
+}
+
+// Inherits returns the names of the roles the Role inherits permissions from.
+func (r *Role) Inherits() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	inherits := make([]string, len(r.UnsafeInherits))
+	copy(inherits, r.UnsafeInherits)
+
+	return inherits
+}
+
+// OwnPermissions returns the permissions granted by the Role itself, without those it inherits.
+func (r *Role) OwnPermissions() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	perms := make([]string, len(r.UnsafePermissions))
+	copy(perms, r.UnsafePermissions)
+
+	return perms
+}
+
+// Permissions returns every permission granted by the Role, including those it inherits.
+func (r *Role) Permissions() []string {
+	return Armeria.roleManager.ResolvePermissions([]string{r.Name()})
+}
+
+type roleJSON Role
+
+// MarshalJSON encodes the Role while holding its lock.
+func (r *Role) MarshalJSON() ([]byte, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return json.Marshal((*roleJSON)(r))
+}
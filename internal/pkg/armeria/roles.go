@@ -0,0 +1,140 @@
+package armeria
+
+import (
+	"armeria/internal/pkg/misc"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// RoleDefault is the role every Character has, whether or not it has been granted.
+const RoleDefault string = "player"
+
+type RoleManager struct {
+	sync.RWMutex
+	UnsafeRoles []*Role `json:"roles"`
+}
+
+// NewRoleManager creates a new RoleManager.
+func NewRoleManager() *RoleManager {
+	m := &RoleManager{}
+
+	m.LoadRoles()
+
+	return m
+}
+
+// LoadRoles loads the roles from disk into memory. Roles are only ever edited on disk.
+func (m *RoleManager) LoadRoles() {
+	m.Lock()
+	defer m.Unlock()
+
+	records, err := Armeria.store.Load(CollectionRoles)
+	if err != nil {
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionRoles.Name),
+			zap.Error(err),
+		)
+	}
+
+	err = DecodeStoreRecords(records, &m.UnsafeRoles)
+	if err != nil {
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionRoles.Name),
+			zap.Error(err),
+		)
+	}
+
+	for _, r := range m.UnsafeRoles {
+		r.Init()
+	}
+
+	for _, r := range m.UnsafeRoles {
+		for _, name := range r.UnsafeInherits {
+			if m.unsafeRoleByName(name) == nil {
+				Armeria.log.Warn("role inherits from a role that doesn't exist",
+					zap.String("role", r.UnsafeName),
+					zap.String("inherits", name),
+				)
+			}
+		}
+		for _, p := range r.UnsafePermissions {
+			if !misc.Contains(ValidPermissions(), p) {
+				Armeria.log.Warn("role grants a permission the game doesn't check for",
+					zap.String("role", r.UnsafeName),
+					zap.String("permission", p),
+				)
+			}
+		}
+	}
+
+	Armeria.log.Info("roles loaded",
+		zap.Int("count", len(m.UnsafeRoles)),
+	)
+}
+
+// Roles returns all of the in-memory Roles.
+func (m *RoleManager) Roles() []*Role {
+	m.RLock()
+	defer m.RUnlock()
+
+	roles := make([]*Role, len(m.UnsafeRoles))
+	copy(roles, m.UnsafeRoles)
+
+	return roles
+}
+
+// RoleByName returns the matching Role, by name.
+func (m *RoleManager) RoleByName(name string) *Role {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.unsafeRoleByName(name)
+}
+
+// unsafeRoleByName returns the matching Role, by name. This DOES NOT request a lock and IS NOT thread safe.
+func (m *RoleManager) unsafeRoleByName(name string) *Role {
+	for _, r := range m.UnsafeRoles {
+		if strings.ToLower(r.UnsafeName) == strings.ToLower(name) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// ResolvePermissions returns every permission granted by the roles, including those they inherit. A role that
+// doesn't exist grants nothing, and a role is only ever visited once, so inheritance loops are harmless.
+func (m *RoleManager) ResolvePermissions(roles []string) []string {
+	var perms []string
+	visited := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[strings.ToLower(name)] {
+			return
+		}
+		visited[strings.ToLower(name)] = true
+
+		r := m.RoleByName(name)
+		if r == nil {
+			return
+		}
+
+		for _, p := range r.OwnPermissions() {
+			if !misc.Contains(perms, p) {
+				perms = append(perms, p)
+			}
+		}
+		for _, inherited := range r.Inherits() {
+			visit(inherited)
+		}
+	}
+
+	for _, name := range roles {
+		visit(name)
+	}
+
+	return perms
+}
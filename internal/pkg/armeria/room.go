@@ -9,10 +9,12 @@ import (
 	"sync"
 
 	"github.com/google/uuid"
-
-	"go.uber.org/zap"
+	lua "github.com/yuin/gopher-lua"
 )
 
+// Force verify that Room implements ScriptOwner.
+var _ ScriptOwner = (*Room)(nil)
+
 // Room is a physical room that exists within an Area.
 type Room struct {
 	sync.RWMutex
@@ -21,6 +23,8 @@ type Room struct {
 	UnsafeHere       *ObjectContainer  `json:"here"`
 	Coords           *Coords           `json:"coords"`
 	ParentArea       *Area             `json:"-"`
+	UnsafeScript     *CachedScript     `json:"-"`
+	UnsafeScriptVM   *ScriptVM         `json:"-"`
 }
 
 // AdjacentRooms holds all of the Room objects that are adjacent to the current room.
@@ -93,6 +97,9 @@ func (r *Room) Init(a *Area) {
 	r.UnsafeHere.AttachParent(r, ContainerParentTypeRoom)
 	// sync container
 	r.UnsafeHere.Sync()
+	// create the lua environment and cache the script
+	r.UnsafeScriptVM = NewScriptVM(SetupRoomLState)
+	r.CacheScript()
 	// register room with registry
 	Armeria.registry.Register(r, r.UUID, RegistryTypeRoom)
 }
@@ -100,6 +107,12 @@ func (r *Room) Init(a *Area) {
 // Deinit is called when the Room is deleted.
 func (r *Room) Deinit() {
 	Armeria.registry.Unregister(r.ID())
+	go r.ScriptVM().Close()
+}
+
+// Name returns the title of the Room.
+func (r *Room) Name() string {
+	return r.Attribute(AttributeTitle)
 }
 
 // SetAttribute sets a persistent attribute for the Room.
@@ -138,12 +151,9 @@ func (r *Room) Here() *ObjectContainer {
 	return r.UnsafeHere
 }
 
-// RoomTargetJSON returns the JSON used for rendering the room objects on the client.
-func (r *Room) RoomTargetJSON(char *Character) string {
-	r.RLock()
-	defer r.RUnlock()
-
-	var roomObjects []map[string]interface{}
+// RoomTargetData returns the data used for rendering the room objects on the client.
+func (r *Room) RoomTargetData(char *Character) []*RoomObjectData {
+	var roomObjects []*RoomObjectData
 
 	for _, obj := range r.Here().All() {
 		o := obj.(ContainerObject)
@@ -155,7 +165,7 @@ func (r *Room) RoomTargetJSON(char *Character) string {
 		rarityColor := ""
 		visible := true
 		if o.Type() == ContainerObjectTypeItem {
-			if !o.(*ItemInstance).AttributeBool(AttributeVisible) && !char.HasPermission("CAN_BUILD") {
+			if !o.(*ItemInstance).AttributeBool(AttributeVisible) && !char.HasPermission(PermissionBuild) {
 				continue
 			}
 			rarityColor = o.(*ItemInstance).RarityColor()
@@ -164,27 +174,19 @@ func (r *Room) RoomTargetJSON(char *Character) string {
 			rarityColor = "d48a3e"
 		}
 
-		roomObjects = append(roomObjects, map[string]interface{}{
-			"uuid":    o.ID(),
-			"name":    o.Name(),
-			"type":    o.Type(),
-			"sort":    ObjectSortOrder(o.Type()),
-			"picture": o.Attribute(AttributePicture),
-			"color":   rarityColor,
-			"title":   o.Attribute(AttributeTitle),
-			"visible": visible,
+		roomObjects = append(roomObjects, &RoomObjectData{
+			UUID:    o.ID(),
+			Name:    o.Name(),
+			Type:    o.Type(),
+			Sort:    ObjectSortOrder(o.Type()),
+			Picture: o.Attribute(AttributePicture),
+			Color:   rarityColor,
+			Title:   o.Attribute(AttributeTitle),
+			Visible: visible,
 		})
 	}
 
-	roomObjectJSON, err := json.Marshal(roomObjects)
-	if err != nil {
-		Armeria.log.Fatal("failed to marshal room object data",
-			zap.String("room", r.UUID),
-			zap.Error(err),
-		)
-	}
-
-	return string(roomObjectJSON)
+	return roomObjects
 }
 
 // EditorData returns the JSON used for the object editor.
@@ -221,12 +223,16 @@ func (r *Room) CharacterEntered(c *Character, causedByLogin bool) {
 	}
 
 	for _, mi := range r.Here().Mobs() {
-		go CallMobFunc(
+		QueueScriptFunc(
 			c,
 			mi,
 			"character_entered",
 		)
 	}
+
+	QueueScriptFunc(c, r, "character_entered")
+
+	Armeria.questManager.Progress(c, QuestStepVisit, r.LocationString(), "")
 }
 
 // CharacterLeft is called when the Character left the room (or logged out).
@@ -236,12 +242,14 @@ func (r *Room) CharacterLeft(c *Character, causedByLogout bool) {
 	}
 
 	for _, mi := range r.Here().Mobs() {
-		go CallMobFunc(
+		QueueScriptFunc(
 			c,
 			mi,
 			"character_left",
 		)
 	}
+
+	QueueScriptFunc(c, r, "character_left")
 }
 
 // AdjacentRooms returns the Room objects that are adjacent to the current room.
@@ -343,3 +351,73 @@ func (r *Room) DistanceBetween(rm *Room) *Coords {
 	}
 	return co
 }
+
+// scriptFile returns the full path to the associated Lua script file. Rooms don't have unique names, so the file
+// is named after the uuid. This DOES NOT request a lock and IS NOT thread safe.
+func (r *Room) scriptFile() string {
+	return fmt.Sprintf("%s/scripts/room-%s.lua", Armeria.dataPath, r.UUID)
+}
+
+// ScriptFile returns the full path to the associated Lua script file.
+func (r *Room) ScriptFile() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.scriptFile()
+}
+
+// CacheScript reads the script contents and caches the contents, individual functions and compiled script.
+func (r *Room) CacheScript() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.UnsafeScript = LoadScript(r.scriptFile())
+}
+
+// Script returns the cached script contents.
+func (r *Room) Script() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.UnsafeScript.Source
+}
+
+// ScriptFuncs returns the cached functions within the Room's script file.
+func (r *Room) ScriptFuncs() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.UnsafeScript.Funcs
+}
+
+// ScriptProto returns the compiled script, or the error encountered while compiling it. The proto is nil when
+// the Room has no script.
+func (r *Room) ScriptProto() (*lua.FunctionProto, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.UnsafeScript.Proto, r.UnsafeScript.Err
+}
+
+// ScriptVM returns the Room's persistent Lua environment.
+func (r *Room) ScriptVM() *ScriptVM {
+	r.RLock()
+	defer r.RUnlock()
+	return r.UnsafeScriptVM
+}
+
+// ScriptKind returns the kind of script the Room runs, which prefixes its Lua globals.
+func (r *Room) ScriptKind() string {
+	return "room"
+}
+
+// ScriptRoom returns the Room the script acts upon, which is the Room itself.
+func (r *Room) ScriptRoom() *Room {
+	return r
+}
+
+type roomJSON Room
+
+// MarshalJSON encodes the Room while holding its lock.
+func (r *Room) MarshalJSON() ([]byte, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	return json.Marshal((*roomJSON)(r))
+}
@@ -0,0 +1,414 @@
+package armeria
+
+import (
+	"armeria/internal/pkg/misc"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// LuaModules returns the Lua modules available to scripts, keyed by the name used with require().
+func LuaModules() map[string]map[string]lua.LGFunction {
+	return map[string]map[string]lua.LGFunction{
+		"mob": {
+			"uuid":       LuaModMobUUID,
+			"name":       LuaModMobName,
+			"attr":       LuaModMobAttribute,
+			"say":        LuaMobSay,
+			"move":       LuaModMobMove,
+			"inventory":  LuaModMobInventory,
+			"spawn_item": LuaModMobSpawnItem,
+		},
+		"room": {
+			"text":       LuaRoomText,
+			"attr":       LuaModRoomAttribute,
+			"characters": LuaModRoomCharacters,
+			"mobs":       LuaModRoomMobs,
+			"items":      LuaModRoomItems,
+			"exits":      LuaModRoomExits,
+			"spawn_item": LuaModRoomSpawnItem,
+		},
+		"character": {
+			"name":      LuaModCharacterName,
+			"attr":      LuaCharacterAttribute,
+			"set_attr":  LuaSetCharacterAttribute,
+			"online":    LuaModCharacterOnline,
+			"here":      LuaModCharacterHere,
+			"text":      LuaModCharacterText,
+			"inventory": LuaModCharacterInventory,
+			"has_item":  LuaModCharacterHasItem,
+		},
+		"item": {
+			"name":           LuaModItemName,
+			"formatted_name": LuaItemName,
+			"attr":           LuaModItemAttribute,
+			"destroy":        LuaModItemDestroy,
+		},
+	}
+}
+
+// PreloadLuaModules makes the named Lua modules available to require() within the LState.
+func PreloadLuaModules(L *lua.LState, names ...string) {
+	modules := LuaModules()
+	for _, name := range names {
+		funcs := modules[name]
+		L.PreloadModule(name, func(state *lua.LState) int {
+			state.Push(state.SetFuncs(state.NewTable(), funcs))
+			return 1
+		})
+	}
+}
+
+// luaStringTable returns a Lua table (array) containing the strings.
+func luaStringTable(L *lua.LState, values []string) *lua.LTable {
+	t := L.NewTable()
+	for _, v := range values {
+		t.Append(lua.LString(v))
+	}
+	return t
+}
+
+// luaItemIDs returns the uuids of the items within an ObjectContainer.
+func luaItemIDs(oc *ObjectContainer) []string {
+	var ids []string
+	for _, ii := range oc.Items() {
+		ids = append(ids, ii.ID())
+	}
+	return ids
+}
+
+// luaCharacter returns the Character matching the uuid passed as the first argument.
+func luaCharacter(L *lua.LState) *Character {
+	if o, rt := Armeria.registry.Get(L.CheckString(1)); rt == RegistryTypeCharacter {
+		return o.(*Character)
+	}
+
+	return nil
+}
+
+// luaItemInstance returns the ItemInstance matching the uuid passed as the first argument.
+func luaItemInstance(L *lua.LState) *ItemInstance {
+	if o, rt := Armeria.registry.Get(L.CheckString(1)); rt == RegistryTypeItemInstance {
+		return o.(*ItemInstance)
+	}
+
+	return nil
+}
+
+// luaSpawnItem creates a new instance of the named item within a container and returns its uuid.
+func luaSpawnItem(L *lua.LState, oc *ObjectContainer) int {
+	i := Armeria.itemManager.ItemByName(L.CheckString(1))
+	if i == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	ii := i.CreateInstance()
+	if err := oc.Add(ii.ID()); err != nil {
+		ii.Delete()
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	L.Push(lua.LString(ii.ID()))
+	return 1
+}
+
+// LuaModMobUUID (mob.uuid) returns the uuid of the mob running the script.
+func LuaModMobUUID(L *lua.LState) int {
+	L.Push(L.GetGlobal("mob_uuid"))
+	return 1
+}
+
+// LuaModMobName (mob.name) returns the name of the mob running the script.
+func LuaModMobName(L *lua.LState) int {
+	L.Push(L.GetGlobal("mob_name"))
+	return 1
+}
+
+// LuaModMobAttribute (mob.attr) returns an attribute of the mob, falling back to the parent mob.
+func LuaModMobAttribute(L *lua.LState) int {
+	mi := LuaMobInstance(L)
+	if mi == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	L.Push(lua.LString(mi.Attribute(L.CheckString(1))))
+	return 1
+}
+
+// LuaModMobMove (mob.move) moves the mob to an adjacent room and returns whether it was able to.
+func LuaModMobMove(L *lua.LState) int {
+	dir := misc.NormalizeDirection(L.CheckString(1))
+
+	mi := LuaMobInstance(L)
+	if mi == nil || mi.Room() == nil || len(dir) == 0 {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	to := mi.Room().ConnectedRoom(dir)
+	if to == nil || Armeria.combatManager.InCombat(mi) {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	mi.Move(to, dir)
+
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// LuaModMobInventory (mob.inventory) returns the uuids of the items the mob is carrying.
+func LuaModMobInventory(L *lua.LState) int {
+	mi := LuaMobInstance(L)
+	if mi == nil {
+		L.Push(L.NewTable())
+		return 1
+	}
+
+	L.Push(luaStringTable(L, luaItemIDs(mi.Inventory())))
+	return 1
+}
+
+// LuaModMobSpawnItem (mob.spawn_item) creates an item within the mob's inventory and returns its uuid.
+func LuaModMobSpawnItem(L *lua.LState) int {
+	mi := LuaMobInstance(L)
+	if mi == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	return luaSpawnItem(L, mi.Inventory())
+}
+
+// LuaModRoomAttribute (room.attr) returns an attribute of the script's room.
+func LuaModRoomAttribute(L *lua.LState) int {
+	room := LuaScriptRoom(L)
+	if room == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	L.Push(lua.LString(room.Attribute(L.CheckString(1))))
+	return 1
+}
+
+// LuaModRoomCharacters (room.characters) returns the uuids of the online characters in the script's room.
+func LuaModRoomCharacters(L *lua.LState) int {
+	var ids []string
+	if room := LuaScriptRoom(L); room != nil {
+		for _, c := range room.Here().Characters(true) {
+			ids = append(ids, c.ID())
+		}
+	}
+
+	L.Push(luaStringTable(L, ids))
+	return 1
+}
+
+// LuaModRoomMobs (room.mobs) returns the uuids of the other mobs in the script's room.
+func LuaModRoomMobs(L *lua.LState) int {
+	var ids []string
+	if o := LuaScriptOwner(L); o != nil && o.ScriptRoom() != nil {
+		for _, m := range o.ScriptRoom().Here().Mobs() {
+			if m.ID() != o.ID() {
+				ids = append(ids, m.ID())
+			}
+		}
+	}
+
+	L.Push(luaStringTable(L, ids))
+	return 1
+}
+
+// LuaModRoomItems (room.items) returns the uuids of the items on the floor of the script's room.
+func LuaModRoomItems(L *lua.LState) int {
+	var ids []string
+	if room := LuaScriptRoom(L); room != nil {
+		ids = luaItemIDs(room.Here())
+	}
+
+	L.Push(luaStringTable(L, ids))
+	return 1
+}
+
+// LuaModRoomExits (room.exits) returns the directions that lead out of the script's room.
+func LuaModRoomExits(L *lua.LState) int {
+	var dirs []string
+	if room := LuaScriptRoom(L); room != nil {
+		for _, dir := range []string{
+			NorthDirection, SouthDirection, EastDirection, WestDirection, UpDirection, DownDirection,
+		} {
+			if room.ConnectedRoom(dir) != nil {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	L.Push(luaStringTable(L, dirs))
+	return 1
+}
+
+// LuaModRoomSpawnItem (room.spawn_item) creates an item on the floor of the script's room and returns its uuid.
+func LuaModRoomSpawnItem(L *lua.LState) int {
+	room := LuaScriptRoom(L)
+	if room == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	ret := luaSpawnItem(L, room.Here())
+
+	for _, c := range room.Here().Characters(true) {
+		c.Player().client.SyncRoomObjects()
+	}
+
+	return ret
+}
+
+// LuaModCharacterName (character.name) returns the name of a character.
+func LuaModCharacterName(L *lua.LState) int {
+	c := luaCharacter(L)
+	if c == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	L.Push(lua.LString(c.Name()))
+	return 1
+}
+
+// LuaModCharacterOnline (character.online) returns whether a character is currently playing.
+func LuaModCharacterOnline(L *lua.LState) int {
+	c := luaCharacter(L)
+	L.Push(lua.LBool(c != nil && c.Online()))
+	return 1
+}
+
+// LuaModCharacterHere (character.here) returns whether a character is in the script's room.
+func LuaModCharacterHere(L *lua.LState) int {
+	c := luaCharacter(L)
+	room := LuaScriptRoom(L)
+	if c == nil || room == nil || c.Room() == nil {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	L.Push(lua.LBool(c.Online() && c.Room().ID() == room.ID()))
+	return 1
+}
+
+// LuaModCharacterText (character.text) sends text to a single character in the script's room.
+func LuaModCharacterText(L *lua.LState) int {
+	c := luaCharacter(L)
+	text := L.CheckString(2)
+
+	room := LuaScriptRoom(L)
+	if c == nil || room == nil || !c.Online() || c.Room() == nil {
+		return 0
+	}
+
+	if c.Room().ID() != room.ID() {
+		return 0
+	}
+
+	c.Player().client.ShowText(FormatScriptText(text))
+	return 0
+}
+
+// LuaModCharacterInventory (character.inventory) returns the uuids of the items a character is carrying.
+func LuaModCharacterInventory(L *lua.LState) int {
+	c := luaCharacter(L)
+	if c == nil {
+		L.Push(L.NewTable())
+		return 1
+	}
+
+	L.Push(luaStringTable(L, luaItemIDs(c.Inventory())))
+	return 1
+}
+
+// LuaModCharacterHasItem (character.has_item) returns the uuid of the first matching item a character is carrying.
+func LuaModCharacterHasItem(L *lua.LState) int {
+	c := luaCharacter(L)
+	name := L.CheckString(2)
+	if c == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	result := c.Inventory().GetByName(name)
+	if result.Type != RegistryTypeItemInstance {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	L.Push(lua.LString(result.Object.ID()))
+	return 1
+}
+
+// LuaModItemName (item.name) returns the raw name of an item.
+func LuaModItemName(L *lua.LState) int {
+	ii := luaItemInstance(L)
+	if ii == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	L.Push(lua.LString(ii.Name()))
+	return 1
+}
+
+// LuaModItemAttribute (item.attr) returns an attribute of an item, falling back to the parent item.
+func LuaModItemAttribute(L *lua.LState) int {
+	ii := luaItemInstance(L)
+	attr := L.CheckString(2)
+	if ii == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	L.Push(lua.LString(ii.Attribute(attr)))
+	return 1
+}
+
+// LuaModItemDestroy (item.destroy) destroys the script's own item, an item carried by the mob running the
+// script, or an item lying in the script's room, and returns whether it was destroyed.
+func LuaModItemDestroy(L *lua.LState) int {
+	ii := luaItemInstance(L)
+	o := LuaScriptOwner(L)
+	if ii == nil || o == nil {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	oc := Armeria.registry.GetObjectContainer(ii.ID())
+	room := o.ScriptRoom()
+
+	allowed := ii.ID() == o.ID()
+	if mi, ok := o.(*MobInstance); ok && mi.Inventory().Contains(ii.ID()) {
+		allowed = true
+	} else if room != nil && room.Here().Contains(ii.ID()) {
+		allowed = true
+	}
+
+	if !allowed || oc == nil {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	oc.Remove(ii.ID())
+	ii.Delete()
+
+	if c := oc.ParentCharacter(); c != nil && c.Online() {
+		c.Player().client.SyncInventory()
+	} else if r := oc.ParentRoom(); r != nil {
+		for _, c := range r.Here().Characters(true) {
+			c.Player().client.SyncRoomObjects()
+		}
+	}
+
+	L.Push(lua.LTrue)
+	return 1
+}
@@ -0,0 +1,83 @@
+package armeria
+
+import (
+	"os"
+	"testing"
+)
+
+func TestScriptModules(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	merchant := Armeria.mobManager.MobByName("Merchant")
+	WriteMobScript(merchant, `
+local mob = require("mob")
+local room = require("room")
+local character = require("character")
+local item = require("item")
+
+function character_said(text)
+  if text == "who" then
+    mob.say(mob.name() .. " sees " .. character.name(room.characters()[1]) .. " in the " .. room.attr("title") .. ".")
+    mob.say("The exits are " .. table.concat(room.exits(), ", ") .. ".")
+  elseif text == "spawn" then
+    local id = mob.spawn_item("Apple")
+    room.spawn_item("Apple")
+    mob.say("I made an " .. item.name(id) .. " and now carry " .. #mob.inventory() .. ".")
+  elseif text == "destroy" then
+    local carried = character.has_item(invoker_uuid, "Apple")
+    local floor = room.items()[1]
+    mob.say("Carried " .. tostring(item.destroy(carried)) .. ", floor " .. tostring(item.destroy(floor)) .. ".")
+  elseif text == "leave" then
+    mob.say("Moved " .. tostring(mob.move("south")) .. ".")
+  end
+end
+`)
+	mi := merchant.Instances()[0]
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+	alice.Send("/get apple")
+	alice.Send("/move north")
+
+	alice.Send("/say who")
+	expectText(t, alice, `Merchant says, "Merchant sees Alice in the Market."`)
+	expectText(t, alice, `Merchant says, "The exits are south."`)
+
+	alice.Send("/say spawn")
+	expectText(t, alice, `Merchant says, "I made an Apple and now carry 1."`)
+	expectRoomObjects(t, alice, "Alice", "Merchant", "Apple")
+	if n := mi.Inventory().Count(); n != 1 {
+		t.Errorf("expected the Merchant to carry 1 item, got %d", n)
+	}
+
+	// Scripts can only destroy items they own, or that are lying in the room.
+	alice.Send("/say destroy")
+	expectText(t, alice, `Merchant says, "Carried false, floor true."`)
+	expectRoomObjects(t, alice, "Alice", "Merchant")
+	if alice.Player().Character().Inventory().GetByName("Apple").Object == nil {
+		t.Error("expected Alice to still have her apple")
+	}
+
+	alice.Send("/say leave")
+	expectText(t, bob, `Merchant says, "Moved true."`)
+	expectRoomObjects(t, bob, "Bob", "Merchant")
+}
+
+func TestScriptModulesAvailable(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	// Only mob scripts can load the mob module.
+	apple := Armeria.itemManager.ItemByName("Apple")
+	WriteItemScript(apple, `
+local room = require("room")
+local loaded = pcall(require, "mob")
+
+function on_get()
+  room.text("The mob module is available: " .. tostring(loaded) .. ".")
+end
+`)
+
+	alice := playAs(t, "Alice")
+	alice.Send("/get apple")
+	expectText(t, alice, "The mob module is available: false.")
+}
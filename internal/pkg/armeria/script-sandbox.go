@@ -0,0 +1,449 @@
+package armeria
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/pm"
+)
+
+// Limits placed on mob scripts by the sandbox.
+const (
+	// ScriptInstructionLimit is the number of Lua instructions a single call may execute, across all of its sleeps.
+	ScriptInstructionLimit int64 = 1000000
+	// ScriptMemoryLimit is the approximate number of bytes a script may hold in its globals and locals.
+	ScriptMemoryLimit int = 4 * 1024 * 1024
+	// scriptInstructionBytes is the number of bytes a single instruction is assumed to allocate, on top of the
+	// strings it may be working with.
+	scriptInstructionBytes int = 64
+	// scriptGsubBatch is the number of matches string.gsub finds at a time.
+	scriptGsubBatch int = 1024
+	// ScriptCallDepthLimit is the maximum depth of nested function calls.
+	ScriptCallDepthLimit int = 64
+	// ScriptStackLimit is the maximum number of values on the Lua data stack.
+	ScriptStackLimit int = 16 * 1024
+	// ScriptConcurrencyLimit is the number of calls a single mob may have running, waiting or sleeping at once.
+	ScriptConcurrencyLimit int32 = 8
+	// ScriptTimeLimit is how long a call may run before it is stopped, not counting time spent asleep.
+	ScriptTimeLimit time.Duration = 5 * time.Second
+	// ScriptSleepLimit is the total time a single call may spend asleep.
+	ScriptSleepLimit time.Duration = time.Minute
+)
+
+var (
+	// ErrScriptInstructions is an error for when a call executes too many instructions.
+	ErrScriptInstructions = errors.New("instruction limit exceeded")
+	// ErrScriptMemory is an error for when a script holds too much data.
+	ErrScriptMemory = errors.New("memory limit exceeded")
+	// ErrScriptCallDepth is an error for when a script nests function calls too deeply.
+	ErrScriptCallDepth = errors.New("call depth limit exceeded")
+	// ErrScriptTime is an error for when a call runs for too long.
+	ErrScriptTime = errors.New("time limit exceeded")
+	// ErrScriptSleep is an error for when a call spends too long asleep.
+	ErrScriptSleep = errors.New("sleep limit exceeded")
+	// ErrScriptConcurrency is an error for when a mob has too many calls running at once.
+	ErrScriptConcurrency = errors.New("too many calls running at once")
+)
+
+// NewSandboxedLState creates an LState with only the safe parts of the standard library. Files can't be read or
+// loaded, and require() only loads the modules registered with PreloadLuaModules.
+func NewSandboxedLState() *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   ScriptCallDepthLimit,
+		RegistrySize:    1024,
+		RegistryMaxSize: ScriptStackLimit,
+	})
+
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.LoadLibName, lua.OpenPackage},
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	for _, name := range []string{"dofile", "loadfile", "collectgarbage", "module"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	// Only keep the preload loader, so that require() can't load files from disk.
+	pkg := L.GetGlobal(lua.LoadLibName).(*lua.LTable)
+	loaders := L.GetField(pkg, "loaders").(*lua.LTable)
+	for i := loaders.Len(); i > 1; i-- {
+		loaders.RawSetInt(i, lua.LNil)
+	}
+	L.SetField(pkg, "path", lua.LString(""))
+
+	// These can create a huge string in a single instruction.
+	strlib := L.GetGlobal(lua.StringLibName)
+	L.SetField(strlib, "rep", L.NewFunction(LuaStringRep))
+	L.SetField(strlib, "gsub", L.NewFunction(LuaStringGsub))
+	L.SetField(L.GetGlobal(lua.TabLibName), "concat", L.NewFunction(LuaTableConcat))
+
+	return L
+}
+
+// raiseScriptMemory stops the run and raises an error, for when a script tries to create a value that would take
+// it past the memory limit.
+func raiseScriptMemory(L *lua.LState) {
+	if sc, ok := L.Context().(*scriptContext); ok {
+		sc.stop(ErrScriptMemory)
+	}
+	L.RaiseError(ErrScriptMemory.Error())
+}
+
+// LuaStringRep (string.rep) repeats a string, within the sandbox's memory limit.
+func LuaStringRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 {
+		L.Push(lua.LString(""))
+		return 1
+	}
+
+	if len(str) > 0 && n > ScriptMemoryLimit/len(str) {
+		raiseScriptMemory(L)
+		return 0
+	}
+
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// LuaTableConcat (table.concat) joins the strings and numbers within a table, within the sandbox's memory limit.
+func LuaTableConcat(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	sep := L.OptString(2, "")
+	i := L.OptInt(3, 1)
+	j := L.OptInt(4, tbl.Len())
+
+	var size int
+	var parts []string
+	for k := i; k <= j; k++ {
+		v := tbl.RawGetInt(k)
+		if !lua.LVCanConvToString(v) {
+			L.RaiseError("invalid value (%s) at index %d in table for concat", v.Type().String(), k)
+			return 0
+		}
+
+		part := lua.LVAsString(v)
+		size = size + len(part) + len(sep)
+		if size > ScriptMemoryLimit {
+			raiseScriptMemory(L)
+			return 0
+		}
+		parts = append(parts, part)
+	}
+
+	L.Push(lua.LString(strings.Join(parts, sep)))
+	return 1
+}
+
+// LuaStringGsub (string.gsub) replaces the matches of a pattern within a string, within the sandbox's memory
+// limit. The new string is built up in a single pass, and the script is stopped as soon as it grows too large.
+func LuaStringGsub(L *lua.LState) int {
+	str := L.CheckString(1)
+	pat := L.CheckString(2)
+	L.CheckTypes(3, lua.LTString, lua.LTTable, lua.LTFunction)
+	repl := L.CheckAny(3)
+	limit := L.OptInt(4, -1)
+
+	var b strings.Builder
+	var replaced, last int
+	for offset := 0; offset <= len(str) && replaced != limit; {
+		// Matches are found a batch at a time, so that a pattern matching every character doesn't hold on to
+		// millions of them at once.
+		batch := scriptGsubBatch
+		if limit >= 0 && limit-replaced < batch {
+			batch = limit - replaced
+		}
+
+		matches, err := pm.Find(pat, []byte(str), offset, batch)
+		if err != nil {
+			L.RaiseError(err.Error())
+			return 0
+		}
+
+		for _, md := range matches {
+			start, end := md.Capture(0), md.Capture(1)
+			b.WriteString(str[last:start])
+			if !luaGsubReplace(L, &b, str, repl, md) {
+				b.WriteString(str[start:end])
+			}
+			last = end
+			replaced++
+
+			if b.Len() > ScriptMemoryLimit {
+				raiseScriptMemory(L)
+				return 0
+			}
+
+			offset = end
+			if end == start {
+				offset = end + 1
+			}
+		}
+
+		if len(matches) < batch || strings.HasPrefix(pat, "^") {
+			break
+		}
+	}
+	b.WriteString(str[last:])
+
+	L.Push(lua.LString(b.String()))
+	L.Push(lua.LNumber(replaced))
+	return 2
+}
+
+// luaGsubReplace writes the replacement for a single match to the builder, returning false when the match should
+// be kept as it is. A replacement string may refer to the captures of the match, such as %1, or to the whole match
+// as %0.
+func luaGsubReplace(L *lua.LState, b *strings.Builder, str string, repl lua.LValue, md *pm.MatchData) bool {
+	capture := func(idx int) lua.LValue {
+		if idx > 2 && idx >= md.CaptureLength() {
+			L.RaiseError("invalid capture index")
+		} else if idx >= md.CaptureLength() {
+			idx = 0
+		}
+
+		if md.IsPosCapture(idx) {
+			return lua.LNumber(md.Capture(idx))
+		}
+		return lua.LString(str[md.Capture(idx):md.Capture(idx+1)])
+	}
+
+	switch r := repl.(type) {
+	case lua.LString:
+		for i := 0; i < len(r); i++ {
+			switch {
+			case r[i] != '%' || i == len(r)-1:
+				b.WriteByte(r[i])
+			case r[i+1] == '%':
+				b.WriteByte('%')
+				i++
+			case r[i+1] >= '0' && r[i+1] <= '9':
+				b.WriteString(lua.LVAsString(capture(2 * int(r[i+1]-'0'))))
+				i++
+			default:
+				b.WriteByte('%')
+			}
+
+			if b.Len() > ScriptMemoryLimit {
+				raiseScriptMemory(L)
+				return true
+			}
+		}
+		return true
+	case *lua.LTable:
+		v := L.GetTable(r, capture(2))
+		if lua.LVIsFalse(v) {
+			return false
+		}
+		b.WriteString(lua.LVAsString(v))
+		return true
+	case *lua.LFunction:
+		L.Push(r)
+		nargs := 1
+		if md.CaptureLength() > 2 {
+			nargs = md.CaptureLength()/2 - 1
+			for i := 1; i <= nargs; i++ {
+				L.Push(capture(2 * i))
+			}
+		} else {
+			L.Push(capture(0))
+		}
+		L.Call(nargs, 1)
+
+		v := L.Get(-1)
+		L.Pop(1)
+		if lua.LVIsFalse(v) {
+			return false
+		}
+		b.WriteString(lua.LVAsString(v))
+		return true
+	}
+
+	return false
+}
+
+// A scriptContext limits a single run of Lua code. The Lua VM checks its context before executing each
+// instruction, which is used to count instructions against the budget and to keep track of memory.
+//
+// Measuring everything the script holds is too slow to do before every instruction, but a single instruction can
+// only create a string as large as the values it's working with, which are in the registers of the running
+// function. So the size of those registers is added up before each instruction, and the whole script is measured
+// again once they could have used up what was left of the limit.
+type scriptContext struct {
+	context.Context
+	L            *lua.LState
+	instructions *int64
+	headroom     int
+	growth       int
+	done         chan struct{}
+	once         sync.Once
+	mu           sync.Mutex
+	err          error
+}
+
+// newScriptContext creates a scriptContext for the LState, which counts instructions against a budget that may
+// be shared between several runs. The returned function must be called once the run is complete.
+func newScriptContext(L *lua.LState, instructions *int64) (*scriptContext, context.CancelFunc) {
+	parent, cancel := context.WithTimeout(context.Background(), ScriptTimeLimit)
+	sc := &scriptContext{
+		Context:      parent,
+		L:            L,
+		instructions: instructions,
+		done:         make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-parent.Done():
+			sc.stop(ErrScriptTime)
+		case <-sc.done:
+		}
+	}()
+
+	return sc, func() {
+		sc.stop(context.Canceled)
+		cancel()
+	}
+}
+
+// Done is called by the Lua VM before each instruction.
+func (sc *scriptContext) Done() <-chan struct{} {
+	*sc.instructions++
+
+	if *sc.instructions > ScriptInstructionLimit {
+		sc.stop(ErrScriptInstructions)
+		return sc.done
+	}
+
+	registers := luaRegistersSize(sc.L)
+	sc.growth = sc.growth + registers + scriptInstructionBytes
+	if sc.growth > sc.headroom {
+		size := luaStateSize(sc.L) + registers
+		if size > ScriptMemoryLimit {
+			sc.stop(ErrScriptMemory)
+		}
+
+		sc.headroom = ScriptMemoryLimit - size
+		sc.growth = 0
+	}
+
+	return sc.done
+}
+
+// Err returns the reason the run was stopped.
+func (sc *scriptContext) Err() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.err
+}
+
+// stop stops the run, unless it has already been stopped.
+func (sc *scriptContext) stop(err error) {
+	sc.once.Do(func() {
+		sc.mu.Lock()
+		sc.err = err
+		sc.mu.Unlock()
+		close(sc.done)
+	})
+}
+
+// Violation returns the sandbox limit that stopped the run, if any. Limits enforced by the LState itself are
+// recognized from the error it raised.
+func (sc *scriptContext) Violation(err error) error {
+	switch serr := sc.Err(); serr {
+	case ErrScriptInstructions, ErrScriptMemory, ErrScriptTime:
+		return serr
+	}
+
+	if err != nil && (strings.Contains(err.Error(), "stack overflow") ||
+		strings.Contains(err.Error(), "registry overflow")) {
+		return ErrScriptCallDepth
+	}
+
+	return nil
+}
+
+// luaStateSize returns the approximate number of bytes held by the globals of an LState, and the locals of the
+// functions it is running.
+func luaStateSize(L *lua.LState) int {
+	seen := make(map[lua.LValue]bool)
+	size := luaValueSize(L.G.Global, seen)
+
+	for level := 0; ; level++ {
+		dbg, ok := L.GetStack(level)
+		if !ok {
+			break
+		}
+		for i := 1; ; i++ {
+			name, lv := L.GetLocal(dbg, i)
+			if len(name) == 0 {
+				break
+			}
+			size = size + luaValueSize(lv, seen)
+		}
+	}
+
+	return size
+}
+
+// luaRegistersSize returns the number of bytes held by the strings in the registers of the function an LState is
+// running.
+func luaRegistersSize(L *lua.LState) int {
+	var size int
+	for i := 1; i <= L.GetTop(); i++ {
+		if s, ok := L.Get(i).(lua.LString); ok {
+			size = size + len(s)
+		}
+	}
+
+	return size
+}
+
+// luaValueSize returns the approximate number of bytes held by a Lua value, skipping tables and functions that
+// have already been counted.
+func luaValueSize(lv lua.LValue, seen map[lua.LValue]bool) int {
+	switch v := lv.(type) {
+	case lua.LString:
+		return len(v) + 16
+	case *lua.LTable:
+		if seen[v] {
+			return 0
+		}
+		seen[v] = true
+
+		size := 64
+		v.ForEach(func(key lua.LValue, value lua.LValue) {
+			size = size + luaValueSize(key, seen) + luaValueSize(value, seen)
+		})
+		return size
+	case *lua.LFunction:
+		if seen[v] {
+			return 0
+		}
+		seen[v] = true
+
+		size := 64
+		for _, uv := range v.Upvalues {
+			size = size + luaValueSize(uv.Value(), seen)
+		}
+		return size
+	}
+
+	return 16
+}
@@ -0,0 +1,150 @@
+package armeria
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runSandboxed runs the Lua code within a new sandboxed LState, the way a script call is run, and returns the
+// sandbox limit that stopped it along with the error it raised. Scripts run by the tests never compute a number of
+// 128 or more, since gopher-lua allocates those in a way that fails the pointer checks made by the race detector.
+func runSandboxed(t *testing.T, code string, setup ...func(L *lua.LState)) (error, error) {
+	t.Helper()
+
+	L := NewSandboxedLState()
+	defer L.Close()
+
+	for _, fn := range setup {
+		fn(L)
+	}
+
+	var instructions int64
+	sc, cancel := newScriptContext(L, &instructions)
+	defer cancel()
+
+	L.SetContext(sc)
+	err := L.DoString(code)
+	L.RemoveContext()
+
+	return sc.Violation(err), err
+}
+
+func TestSandboxLibraries(t *testing.T) {
+	for _, code := range []string{
+		`assert(dofile == nil)`,
+		`assert(loadfile == nil)`,
+		`assert(os == nil)`,
+		`assert(io == nil)`,
+		`assert(collectgarbage == nil)`,
+		`assert(not pcall(require, "os"))`,
+		`assert(not pcall(require, "io"))`,
+		`assert(package.path == "")`,
+	} {
+		if _, err := runSandboxed(t, code); err != nil {
+			t.Errorf("%s: %s", code, err)
+		}
+	}
+}
+
+func TestSandboxAllowed(t *testing.T) {
+	for _, code := range []string{
+		`local s = "" for i = 1, 100 do s = s .. "x" end assert(#s == 100)`,
+		`assert(string.rep("ab", 3) == "ababab")`,
+		`local t = {} for i = 1, 50 do t[i] = "x" end assert(#table.concat(t, ",") == 99)`,
+		`assert(table.concat({1, "b", 3}, "-", 2) == "b-3")`,
+		`assert(string.gsub("hello world", "o", "0") == "hell0 w0rld")`,
+		`assert(string.gsub("hello world", "(%w+)", "<%1>") == "<hello> <world>")`,
+		`assert(string.gsub("abc", "%w", "%0%0", 2) == "aabbc")`,
+		`assert(string.gsub("$name!", "%$(%w+)", {name = "Alice"}) == "Alice!")`,
+		`assert(string.gsub("abc", "%w", function(c) return c:upper() end) == "ABC")`,
+		`assert(select(2, string.gsub("abc", "%w", function() end)) == 3)`,
+		`assert(string.gsub("abc", "", "-") == "-a-b-c-")`,
+		`assert(string.gsub("abc", "()", "%1") == "1a2b3c4")`,
+		`assert(string.gsub("hello", "^h", "H") == "Hello")`,
+		`assert(string.gsub("aaa", "a", "b", 2) == "bba")`,
+		`local s, n = string.gsub(string.rep("a", 3000), "a", "b") assert(s == string.rep("b", 3000) and n == 3000)`,
+	} {
+		if violation, err := runSandboxed(t, code); err != nil {
+			t.Errorf("%s: %s (%v)", code, err, violation)
+		}
+	}
+}
+
+func TestSandboxInstructionLimit(t *testing.T) {
+	violation, _ := runSandboxed(t, `while true do end`)
+	if violation != ErrScriptInstructions {
+		t.Errorf("expected %s, got %v", ErrScriptInstructions, violation)
+	}
+}
+
+func TestSandboxCallDepthLimit(t *testing.T) {
+	violation, _ := runSandboxed(t, `local function f() return 1 + f() end f()`)
+	if violation != ErrScriptCallDepth {
+		t.Errorf("expected %s, got %v", ErrScriptCallDepth, violation)
+	}
+}
+
+func TestSandboxMemoryLimit(t *testing.T) {
+	for _, code := range []string{
+		`local s = "x" for i = 1, 40 do s = s .. s end`,
+		`local s = string.rep("x", 1000000) local t = {} for i = 1, 100 do t[i] = s .. i end`,
+		`local t = {} for i = 1, 100 do t[i] = "x" end table.concat(t, string.rep(",", 100000))`,
+		`local s = string.rep("x", 1000000) string.format("%s%s%s%s%s", s, s, s, s, s)`,
+		`string.rep("x", 100000000)`,
+		`local s = string.rep("x", 100000) string.gsub(s, ".", string.rep("y", 100))`,
+		`local s = string.rep("x", 1000000) string.gsub(s, ".*", "%0%0%0%0%0")`,
+		`local s = string.rep("x", 100000) local r = string.rep("y", 100) string.gsub(s, ".", {x = r})`,
+		`local s = string.rep("x", 100000) local r = string.rep("y", 100) string.gsub(s, ".", function() return r end)`,
+	} {
+		violation, err := runSandboxed(t, code)
+		if violation != ErrScriptMemory {
+			t.Errorf("%s: expected %s, got %v (%v)", code, ErrScriptMemory, violation, err)
+		}
+	}
+}
+
+func TestSandboxTimeLimit(t *testing.T) {
+	wait := func(L *lua.LState) {
+		L.SetGlobal("wait", L.NewFunction(func(L *lua.LState) int {
+			time.Sleep(50 * time.Millisecond)
+			return 0
+		}))
+	}
+
+	start := time.Now()
+	violation, _ := runSandboxed(t, `while true do wait() end`, wait)
+	if violation != ErrScriptTime {
+		t.Errorf("expected %s, got %v", ErrScriptTime, violation)
+	}
+	if elapsed := time.Since(start); elapsed < ScriptTimeLimit || elapsed > 2*ScriptTimeLimit {
+		t.Errorf("expected the script to be stopped after %s, took %s", ScriptTimeLimit, elapsed)
+	}
+}
+
+func TestSandboxConcurrencyLimit(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("builder")
+	Armeria.characterManager.CharacterByName("Alice").JoinChannel(Armeria.channels[ChannelBuilders])
+	hc := playAs(t, "Alice")
+
+	m := Armeria.mobManager.MobByName("Merchant")
+	WriteMobScript(m, `function character_said(text) sleep("1m") end`)
+	mi := m.Instances()[0]
+
+	c := Armeria.characterManager.CharacterByName("Bob")
+	Armeria.RunEvent(EventMessage, "test", func() {
+		for i := int32(0); i <= ScriptConcurrencyLimit; i++ {
+			CallMobFunc(c, mi, "character_said", lua.LString("hello"))
+		}
+	})
+
+	expectText(t, hc, "stopped while running character_said(): "+ErrScriptConcurrency.Error())
+	if len(hc.Texts()) != 1 {
+		t.Errorf("expected only the call past the limit to be stopped, got %q", hc.Texts())
+	}
+
+}
@@ -0,0 +1,328 @@
+package armeria
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"go.uber.org/zap"
+)
+
+// ErrScriptBlockingSleep is an error for when a script sleeps while deciding whether a character may move.
+var ErrScriptBlockingSleep = errors.New("sleep() cannot be used while a character is waiting to move")
+
+// A ScriptVM is the persistent Lua environment of a single MobInstance, ItemInstance, Room or Area. Globals defined by the
+// script are kept between calls, so scripts can hold on to state. Calls into the VM must hold its lock, since an
+// LState cannot be used by more than one goroutine at a time.
+type ScriptVM struct {
+	sync.Mutex
+	unsafeState *lua.LState
+	unsafeProto *lua.FunctionProto
+	unsafeCall  *ScriptCall
+	pending     int32
+	setup       func(L *lua.LState)
+}
+
+// NewScriptVM creates a new, empty ScriptVM. The LState is created when the VM is first used, and prepared with
+// the setup function.
+func NewScriptVM(setup func(L *lua.LState)) *ScriptVM {
+	return &ScriptVM{
+		setup: setup,
+	}
+}
+
+// SetupMobLState sets the global functions and modules available to mob scripts.
+func SetupMobLState(L *lua.LState) {
+	L.SetGlobal("say", L.NewFunction(LuaMobSay))
+	L.SetGlobal("sleep", L.NewFunction(LuaSleep))
+	L.SetGlobal("start_convo", L.NewFunction(LuaStartConvo))
+	L.SetGlobal("end_convo", L.NewFunction(LuaEndConvo))
+	L.SetGlobal("convo_select", L.NewFunction(LuaConvoSelect))
+	L.SetGlobal("c_attr", L.NewFunction(LuaCharacterAttribute))
+	L.SetGlobal("c_set_attr", L.NewFunction(LuaSetCharacterAttribute))
+	L.SetGlobal("i_name", L.NewFunction(LuaItemName))
+	L.SetGlobal("give", L.NewFunction(LuaInventoryGive))
+	L.SetGlobal("room_text", L.NewFunction(LuaRoomText))
+	L.SetGlobal("shop", L.NewFunction(LuaShop))
+	SetQuestLuaGlobals(L)
+
+	PreloadLuaModules(L, "mob", "room", "character", "item")
+}
+
+// SetupItemLState sets the global functions and modules available to item scripts.
+func SetupItemLState(L *lua.LState) {
+	L.SetGlobal("sleep", L.NewFunction(LuaSleep))
+	L.SetGlobal("c_attr", L.NewFunction(LuaCharacterAttribute))
+	L.SetGlobal("c_set_attr", L.NewFunction(LuaSetCharacterAttribute))
+	L.SetGlobal("i_name", L.NewFunction(LuaItemName))
+	L.SetGlobal("room_text", L.NewFunction(LuaRoomText))
+	SetQuestLuaGlobals(L)
+
+	PreloadLuaModules(L, "room", "character", "item")
+}
+
+// SetupRoomLState sets the global functions and modules available to room and area scripts.
+func SetupRoomLState(L *lua.LState) {
+	L.SetGlobal("sleep", L.NewFunction(LuaSleep))
+	L.SetGlobal("c_attr", L.NewFunction(LuaCharacterAttribute))
+	L.SetGlobal("c_set_attr", L.NewFunction(LuaSetCharacterAttribute))
+	L.SetGlobal("i_name", L.NewFunction(LuaItemName))
+	L.SetGlobal("room_text", L.NewFunction(LuaRoomText))
+	L.SetGlobal("block_move", L.NewFunction(LuaBlockMove))
+	SetQuestLuaGlobals(L)
+
+	PreloadLuaModules(L, "room", "character", "item")
+}
+
+// SetQuestLuaGlobals sets the quest functions, which are available to every script.
+func SetQuestLuaGlobals(L *lua.LState) {
+	L.SetGlobal("quest_start", L.NewFunction(LuaQuestStart))
+	L.SetGlobal("quest_advance", L.NewFunction(LuaQuestAdvance))
+	L.SetGlobal("quest_complete", L.NewFunction(LuaQuestComplete))
+	L.SetGlobal("quest_status", L.NewFunction(LuaQuestStatus))
+}
+
+// State returns the LState for the compiled script, replacing the existing LState when the script has changed
+// since it was created. The second return value is true when the LState is new, and the script's main chunk has
+// not been run yet. This DOES NOT request a lock and IS NOT thread safe.
+func (vm *ScriptVM) State(proto *lua.FunctionProto) (*lua.LState, bool) {
+	if vm.unsafeState != nil && vm.unsafeProto == proto {
+		return vm.unsafeState, false
+	}
+
+	vm.Reset()
+
+	L := NewSandboxedLState()
+	vm.setup(L)
+
+	vm.unsafeState = L
+	vm.unsafeProto = proto
+
+	return L, true
+}
+
+// Acquire reserves a slot for a new call, returning false when the VM already has too many calls running,
+// waiting or sleeping. Each successful Acquire must be followed by a Release once the call is complete.
+func (vm *ScriptVM) Acquire() bool {
+	if atomic.AddInt32(&vm.pending, 1) > ScriptConcurrencyLimit {
+		atomic.AddInt32(&vm.pending, -1)
+		return false
+	}
+
+	return true
+}
+
+// Release frees the slot reserved by Acquire.
+func (vm *ScriptVM) Release() {
+	atomic.AddInt32(&vm.pending, -1)
+}
+
+// Reset closes the LState, so that the script is loaded from scratch on the next call. This DOES NOT request a
+// lock and IS NOT thread safe.
+func (vm *ScriptVM) Reset() {
+	if vm.unsafeState != nil {
+		vm.unsafeState.Close()
+	}
+
+	vm.unsafeState = nil
+	vm.unsafeProto = nil
+}
+
+// Close closes the LState once any running script has finished.
+func (vm *ScriptVM) Close() {
+	vm.Lock()
+	defer vm.Unlock()
+
+	vm.Reset()
+}
+
+// A ScriptCall is a single call into a script. The function runs within a coroutine, so that a call to sleep() can
+// put it aside and free up the ScriptVM for other calls until it is woken up. The Invoker is nil for calls that
+// weren't caused by a Character, such as room_tick().
+type ScriptCall struct {
+	Invoker      *Character
+	Owner        ScriptOwner
+	Func         string
+	vm           *ScriptVM
+	state        *lua.LState
+	thread       *lua.LState
+	fn           *lua.LFunction
+	instructions int64
+	slept        time.Duration
+	blocking     bool
+	blocked      string
+}
+
+// NewScriptCall creates a ScriptCall for a function within the owner's script.
+func NewScriptCall(invoker *Character, o ScriptOwner, funcName string) *ScriptCall {
+	return &ScriptCall{
+		Invoker: invoker,
+		Owner:   o,
+		Func:    funcName,
+		vm:      o.ScriptVM(),
+	}
+}
+
+// Start runs the function until it completes or goes to sleep. The VM slot must already be reserved with
+// Acquire, and is released once the call is complete.
+func (call *ScriptCall) Start(proto *lua.FunctionProto, args ...lua.LValue) {
+	call.vm.Lock()
+	defer call.vm.Unlock()
+
+	L, loaded := call.vm.State(proto)
+	call.state = L
+	call.setGlobals()
+
+	// Run the main chunk the first time the script is loaded, which defines the script's functions.
+	if loaded {
+		sc, cancel := newScriptContext(L, &call.instructions)
+		L.SetContext(sc)
+		L.Push(L.NewFunctionFromProto(proto))
+		err := L.PCall(0, lua.MultRet, nil)
+		L.RemoveContext()
+		cancel()
+
+		if err != nil {
+			call.fail(sc.Violation(err), err, "loading the script")
+			return
+		}
+	}
+
+	fn, ok := L.GetGlobal(call.Func).(*lua.LFunction)
+	if !ok {
+		call.vm.Release()
+		return
+	}
+
+	call.thread, _ = L.NewThread()
+	call.fn = fn
+	call.resume(args...)
+}
+
+// setGlobals sets the global variables describing the call. Other calls may run while this one is asleep, so
+// they are set again each time the call is resumed.
+func (call *ScriptCall) setGlobals() {
+	if call.Invoker != nil {
+		call.state.SetGlobal("invoker_uuid", lua.LString(call.Invoker.ID()))
+		call.state.SetGlobal("invoker_name", lua.LString(call.Invoker.Name()))
+	} else {
+		call.state.SetGlobal("invoker_uuid", lua.LNil)
+		call.state.SetGlobal("invoker_name", lua.LNil)
+	}
+	call.state.SetGlobal(call.Owner.ScriptKind()+"_uuid", lua.LString(call.Owner.ID()))
+	call.state.SetGlobal(call.Owner.ScriptKind()+"_name", lua.LString(call.Owner.Name()))
+}
+
+// resume runs the coroutine until it completes or yields. This DOES NOT request a lock and IS NOT thread safe.
+func (call *ScriptCall) resume(args ...lua.LValue) {
+	call.setGlobals()
+
+	sc, cancel := newScriptContext(call.thread, &call.instructions)
+	call.thread.SetContext(sc)
+	call.vm.unsafeCall = call
+	st, err, values := call.state.Resume(call.thread, call.fn, args...)
+	call.vm.unsafeCall = nil
+	call.thread.RemoveContext()
+	cancel()
+
+	switch st {
+	case lua.ResumeError:
+		call.fail(sc.Violation(err), err, fmt.Sprintf("running %s()", call.Func))
+	case lua.ResumeYield:
+		// The Character is waiting on the result, so the call can't be put aside.
+		if call.blocking {
+			call.fail(nil, ErrScriptBlockingSleep, fmt.Sprintf("running %s()", call.Func))
+			return
+		}
+
+		var d time.Duration
+		if len(values) > 0 {
+			d = time.Duration(lua.LVAsNumber(values[0]))
+		}
+
+		call.slept = call.slept + d
+		if call.slept > ScriptSleepLimit {
+			call.fail(ErrScriptSleep, ErrScriptSleep, fmt.Sprintf("running %s()", call.Func))
+			return
+		}
+
+		time.AfterFunc(d, func() {
+			Armeria.GoEvent(EventScript, call.Func, call.wake)
+		})
+	default:
+		if luaStateSize(call.state) > ScriptMemoryLimit {
+			call.fail(ErrScriptMemory, ErrScriptMemory, fmt.Sprintf("running %s()", call.Func))
+			return
+		}
+
+		call.vm.Release()
+	}
+}
+
+// wake resumes the call after it has slept.
+func (call *ScriptCall) wake() {
+	call.vm.Lock()
+	defer call.vm.Unlock()
+
+	// The script was changed, or the owner removed, while the call was asleep.
+	if call.vm.unsafeState != call.state {
+		call.vm.Release()
+		return
+	}
+
+	call.resume()
+}
+
+// fail discards the VM, since the script may have been interrupted part way through and its state can't be
+// trusted, and lets the invoker know if they are a builder. Sandbox violations are also reported to the builders
+// channel. This DOES NOT request a lock and IS NOT thread safe.
+func (call *ScriptCall) fail(violation error, err error, during string) {
+	call.vm.Reset()
+	call.vm.Release()
+
+	Armeria.log.Error("error executing lua script",
+		zap.String("script", call.Owner.ScriptFile()),
+		zap.String("function", call.Func),
+		zap.Error(err),
+	)
+
+	if call.Invoker != nil && call.Invoker.HasPermission(PermissionBuild) && call.Invoker.Online() {
+		call.Invoker.Player().client.ShowColorizedText(
+			fmt.Sprintf(
+				"There was an error %s on %s %s.\n\n%s",
+				during,
+				call.Owner.ScriptKind(),
+				TextStyle(call.Owner.Name(), WithBold()),
+				err.Error(),
+			),
+			ColorError,
+		)
+	}
+
+	if violation != nil {
+		ReportScriptViolation(call.Owner, call.Func, violation)
+	}
+}
+
+// ReportScriptViolation lets builders know that a script was stopped by the sandbox.
+func ReportScriptViolation(o ScriptOwner, funcName string, violation error) {
+	Armeria.log.Error("lua script violated the sandbox",
+		zap.String(o.ScriptKind(), o.Name()),
+		zap.String("function", funcName),
+		zap.Error(violation),
+	)
+
+	Armeria.channels[ChannelBuilders].Broadcast(
+		nil,
+		fmt.Sprintf(
+			"The script on %s %s was stopped while running %s(): %s.",
+			o.ScriptKind(),
+			TextStyle(o.Name(), WithBold()),
+			funcName,
+			violation.Error(),
+		),
+	)
+}
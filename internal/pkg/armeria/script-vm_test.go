@@ -0,0 +1,111 @@
+package armeria
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// waitForText waits for the client to be shown text containing the substring, such as text shown by a script
+// once it wakes up.
+func waitForText(t *testing.T, hc *HeadlessClient, substr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		SettleEventLoop()
+		if hc.SawText(substr) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("expected text containing %q, got %q", substr, hc.Texts())
+}
+
+func TestScriptVMAcquire(t *testing.T) {
+	vm := NewScriptVM(SetupMobLState)
+	for i := int32(0); i < ScriptConcurrencyLimit; i++ {
+		if !vm.Acquire() {
+			t.Fatalf("expected call %d to acquire a slot", i+1)
+		}
+	}
+
+	if vm.Acquire() {
+		t.Fatal("expected the call past the limit not to acquire a slot")
+	}
+
+	vm.Release()
+	if !vm.Acquire() {
+		t.Error("expected a released slot to be acquired again")
+	}
+}
+
+func TestScriptVMPersistent(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	script := `
+heard = 0
+
+function character_said(text)
+  heard = heard + 1
+  say("I have heard " .. heard .. " things.")
+end
+`
+	merchant := Armeria.mobManager.MobByName("Merchant")
+	WriteMobScript(merchant, script)
+
+	alice := playAs(t, "Alice")
+	alice.Send("/move north")
+	alice.Send("/say hello")
+	alice.Send("/say hello again")
+	expectText(t, alice, `Merchant says, "I have heard 2 things."`)
+
+	// Saving the script starts it again from scratch, even when it hasn't changed.
+	WriteMobScript(merchant, script)
+	alice.Clear()
+	alice.Send("/say hello")
+	expectText(t, alice, `Merchant says, "I have heard 1 things."`)
+
+	// So does an error, since the script may have stopped part way through.
+	WriteMobScript(merchant, script+`
+function interact()
+  heard = heard + 10
+  error("something went wrong")
+end
+`)
+	alice.Send("/say hello")
+	Armeria.RunEvent(EventMessage, "test", func() {
+		CallMobFunc(alice.Player().Character(), merchant.Instances()[0], "interact")
+	})
+	alice.Clear()
+	alice.Send("/say hello")
+	expectText(t, alice, `Merchant says, "I have heard 1 things."`)
+}
+
+func TestScriptVMSleep(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	merchant := Armeria.mobManager.MobByName("Merchant")
+	WriteMobScript(merchant, `
+function character_said(text)
+  say("Hold on, " .. text .. ".")
+  sleep("50ms")
+  say("Done with " .. text .. ".")
+end
+`)
+
+	alice := playAs(t, "Alice")
+	alice.Send("/move north")
+	alice.Send("/say first")
+	expectText(t, alice, `Merchant says, "Hold on, first."`)
+	if alice.SawText("Done with first.") {
+		t.Fatal("expected the script to still be asleep")
+	}
+
+	// Other calls run while the first is asleep.
+	alice.Send("/say second")
+	expectText(t, alice, `Merchant says, "Hold on, second."`)
+
+	waitForText(t, alice, `Merchant says, "Done with first."`)
+	waitForText(t, alice, `Merchant says, "Done with second."`)
+}
@@ -2,16 +2,68 @@ package armeria
 
 import (
 	"armeria/internal/pkg/misc"
-	"context"
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"regexp"
 	"time"
 
 	"go.uber.org/zap"
 
 	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
 )
 
+// A ScriptOwner is an object that runs its own Lua script, such as a MobInstance, ItemInstance, Room or Area.
+type ScriptOwner interface {
+	ID() string
+	Name() string
+	ScriptKind() string
+	ScriptFile() string
+	ScriptFuncs() []string
+	ScriptProto() (*lua.FunctionProto, error)
+	ScriptVM() *ScriptVM
+	ScriptRoom() *Room
+}
+
+// A CachedScript is the contents of a Lua script file, along with the functions it defines and its compiled form.
+type CachedScript struct {
+	Source string
+	Funcs  []string
+	Proto  *lua.FunctionProto
+	Err    error
+}
+
+// LoadScript reads and compiles a Lua script file. The CachedScript is empty when the file doesn't exist.
+func LoadScript(filename string) *CachedScript {
+	cs := &CachedScript{Funcs: []string{}}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return cs
+	}
+
+	re := regexp.MustCompile("function ([a-zA-Z_]+)")
+	matches := re.FindAllSubmatch(b, -1)
+	if matches != nil {
+		for _, match := range matches {
+			cs.Funcs = append(cs.Funcs, string(match[1]))
+		}
+	}
+
+	cs.Source = string(b)
+
+	// Compile the script once, so that each instance can load it without parsing it again. A new proto also tells
+	// each instance that its ScriptVM is out of date.
+	chunk, err := parse.Parse(bytes.NewReader(b), filename)
+	if err == nil {
+		cs.Proto, err = lua.Compile(chunk, filename)
+	}
+	cs.Err = err
+
+	return cs
+}
+
 // ReadMobScript returns the script contents for a mob from disk.
 func ReadMobScript(m *Mob) string {
 	return m.Script()
@@ -21,6 +73,51 @@ func ReadMobScript(m *Mob) string {
 func WriteMobScript(m *Mob, script string) {
 	_ = ioutil.WriteFile(m.ScriptFile(), []byte(script), 0644)
 	m.CacheScript()
+
+	// Discard the existing Lua environments, so that each instance picks up the new script.
+	for _, mi := range m.Instances() {
+		mi.ScriptVM().Close()
+	}
+}
+
+// ReadItemScript returns the script contents for an item from disk.
+func ReadItemScript(i *Item) string {
+	return i.Script()
+}
+
+// WriteItemScript writes an item script to disk.
+func WriteItemScript(i *Item, script string) {
+	_ = ioutil.WriteFile(i.ScriptFile(), []byte(script), 0644)
+	i.CacheScript()
+
+	// Discard the existing Lua environments, so that each instance picks up the new script.
+	for _, ii := range i.Instances() {
+		ii.ScriptVM().Close()
+	}
+}
+
+// ReadRoomScript returns the script contents for a room from disk.
+func ReadRoomScript(r *Room) string {
+	return r.Script()
+}
+
+// WriteRoomScript writes a room script to disk.
+func WriteRoomScript(r *Room, script string) {
+	_ = ioutil.WriteFile(r.ScriptFile(), []byte(script), 0644)
+	r.CacheScript()
+	r.ScriptVM().Close()
+}
+
+// ReadAreaScript returns the script contents for an area from disk.
+func ReadAreaScript(a *Area) string {
+	return a.Script()
+}
+
+// WriteAreaScript writes an area script to disk.
+func WriteAreaScript(a *Area, script string) {
+	_ = ioutil.WriteFile(a.ScriptFile(), []byte(script), 0644)
+	a.CacheScript()
+	a.ScriptVM().Close()
 }
 
 // LuaInvoker returns the Character that invoked the lua function.
@@ -43,7 +140,54 @@ func LuaMobInstance(L *lua.LState) *MobInstance {
 	return nil
 }
 
+// LuaScriptOwner returns the MobInstance, ItemInstance, Room or Area that the script belongs to.
+func LuaScriptOwner(L *lua.LState) ScriptOwner {
+	for _, global := range []string{"mob_uuid", "item_uuid", "room_uuid", "area_uuid"} {
+		switch o, rt := Armeria.registry.Get(lua.LVAsString(L.GetGlobal(global))); rt {
+		case RegistryTypeMobInstance:
+			return o.(*MobInstance)
+		case RegistryTypeItemInstance:
+			return o.(*ItemInstance)
+		case RegistryTypeRoom:
+			return o.(*Room)
+		case RegistryTypeArea:
+			return o.(*Area)
+		}
+	}
+
+	return nil
+}
+
+// LuaScriptRoom returns the Room that the script's owner is in. Area scripts act upon the room the invoking
+// Character is in, as long as it is within the area.
+func LuaScriptRoom(L *lua.LState) *Room {
+	o := LuaScriptOwner(L)
+	if o == nil {
+		return nil
+	}
+
+	if a, ok := o.(*Area); ok {
+		if c := LuaInvoker(L); c != nil && c.Room() != nil && c.Room().ParentArea == a {
+			return c.Room()
+		}
+		return nil
+	}
+
+	return o.ScriptRoom()
+}
+
 // LuaMobSay (mob_say) causes the mob to say something to the room.
+// scriptBoldRegex matches the bold markup scripts can use within the text they show, such as [b]help[/b].
+var scriptBoldRegex = regexp.MustCompile(`(?s)\[b\](.*?)\[/b\]`)
+
+// FormatScriptText converts text shown by a script into text that is safe to show to any client. Everything the
+// script wrote is shown literally, apart from [b]bold[/b] markup.
+func FormatScriptText(text string) string {
+	return scriptBoldRegex.ReplaceAllStringFunc(StripRichText(text), func(m string) string {
+		return TextStyle(scriptBoldRegex.FindStringSubmatch(m)[1], WithBold())
+	})
+}
+
 func LuaMobSay(L *lua.LState) int {
 	text := L.ToString(1)
 	mname := lua.LVAsString(L.GetGlobal("mob_name"))
@@ -66,7 +210,7 @@ func LuaMobSay(L *lua.LState) int {
 
 	for _, c := range mi.Room().Here().Characters(true) {
 		c.Player().client.ShowColorizedText(
-			fmt.Sprintf("%s %s, \"%s\"", mi.FormattedName(), verb, normalizedText),
+			fmt.Sprintf("%s %s, \"%s\"", mi.FormattedName(), verb, FormatScriptText(normalizedText)),
 			ColorSay,
 		)
 	}
@@ -74,12 +218,13 @@ func LuaMobSay(L *lua.LState) int {
 	return 0
 }
 
-// LuaSleep (sleep) causes the script to sleep for a particular time.Duration (ie: 30s).
+// LuaSleep (sleep) causes the script to sleep for a particular time.Duration (ie: 30s). The call yields, so other
+// events for the mob can run while it is asleep.
 func LuaSleep(L *lua.LState) int {
 	duration := L.ToString(1)
 
-	if d, err := time.ParseDuration(duration); err == nil {
-		time.Sleep(d)
+	if d, err := time.ParseDuration(duration); err == nil && d > 0 {
+		return L.Yield(lua.LNumber(d))
 	}
 
 	return 0
@@ -177,18 +322,10 @@ func LuaInventoryGive(L *lua.LState) int {
 		return 0
 	}
 
-	if !mi.Inventory().Contains(iuuid) {
+	if mi.Inventory().Transfer(iuuid, c.Inventory()) != nil {
 		return 0
 	}
 
-	if c.Inventory().Count() >= c.Inventory().MaxSize() {
-		return 0
-	}
-
-	mi.Inventory().Remove(iuuid)
-
-	_ = c.Inventory().Add(iuuid)
-
 	if c.Online() {
 		c.Player().client.SyncInventory()
 		c.Player().client.ShowText(
@@ -363,106 +500,196 @@ func LuaShop(L *lua.LState) int {
 // LuaRoomText (room_text) sends arbitrary text to the room.
 func LuaRoomText(L *lua.LState) int {
 	text := L.ToString(1)
-	muuid := lua.LVAsString(L.GetGlobal("mob_uuid"))
 
-	var mi *MobInstance
-	if o, rt := Armeria.registry.Get(muuid); rt == RegistryTypeMobInstance {
-		mi = o.(*MobInstance)
+	room := LuaScriptRoom(L)
+	if room == nil {
+		return 0
+	}
+
+	for _, c := range room.Here().Characters(true) {
+		c.Player().client.ShowText(FormatScriptText(text))
+	}
+
+	return 0
+}
+
+// luaQuestArgs returns the Character and Quest passed as the first two arguments.
+func luaQuestArgs(L *lua.LState) (*Character, *Quest) {
+	c := Armeria.characterManager.CharacterById(L.CheckString(1))
+	q := Armeria.questManager.QuestByName(L.CheckString(2))
+	return c, q
+}
+
+// LuaQuestStart (quest_start) starts a quest for a Character, returning whether it was started.
+func LuaQuestStart(L *lua.LState) int {
+	c, q := luaQuestArgs(L)
+	if c == nil || q == nil {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	L.Push(lua.LBool(Armeria.questManager.Start(c, q) == nil))
+	return 1
+}
+
+// LuaQuestAdvance (quest_advance) moves a Character on to the next step of a quest, returning whether they were
+// on the quest.
+func LuaQuestAdvance(L *lua.LState) int {
+	c, q := luaQuestArgs(L)
+	if c == nil || q == nil {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	L.Push(lua.LBool(Armeria.questManager.Advance(c, q) == nil))
+	return 1
+}
+
+// LuaQuestComplete (quest_complete) completes a quest for a Character, returning whether they were on the quest.
+func LuaQuestComplete(L *lua.LState) int {
+	c, q := luaQuestArgs(L)
+	if c == nil || q == nil {
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	L.Push(lua.LBool(Armeria.questManager.Complete(c, q) == nil))
+	return 1
+}
+
+// LuaQuestStatus (quest_status) returns "active" or "completed" along with the current step number, or nil when
+// the Character hasn't started the quest.
+func LuaQuestStatus(L *lua.LState) int {
+	c, q := luaQuestArgs(L)
+	if c == nil || q == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	p, ok := c.QuestProgress(q.Name())
+	if !ok {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	if p.Completed {
+		L.Push(lua.LString("completed"))
+		L.Push(lua.LNumber(p.Step))
 	} else {
+		L.Push(lua.LString("active"))
+		L.Push(lua.LNumber(p.Step + 1))
+	}
+	return 2
+}
+
+// LuaBlockMove (block_move) stops the invoking Character from moving, with an optional reason shown to them. It
+// can only be used within character_entering() and character_leaving().
+func LuaBlockMove(L *lua.LState) int {
+	reason := L.OptString(1, "Something prevents you from going that way.")
+
+	o := LuaScriptOwner(L)
+	if o == nil {
 		return 0
 	}
 
-	for _, c := range mi.Room().Here().Characters(true) {
-		c.Player().client.ShowText(text)
+	call := o.ScriptVM().unsafeCall
+	if call == nil || !call.blocking {
+		L.RaiseError("block_move() can only be used within character_entering() or character_leaving()")
+		return 0
 	}
 
+	call.blocked = reason
 	return 0
 }
 
-// CallMobFunc handles executing mob scripts within the Lua environment.
+// CallMobFunc handles executing mob scripts within the MobInstance's persistent, sandboxed Lua environment.
 func CallMobFunc(invoker *Character, mi *MobInstance, funcName string, args ...lua.LValue) {
-	L := lua.NewState()
-	defer L.Close()
-
-	// Set a max timeout for script execution.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	L.SetContext(ctx)
-
-	// Set global variables.
-	L.SetGlobal("invoker_uuid", lua.LString(invoker.ID()))
-	L.SetGlobal("invoker_name", lua.LString(invoker.Name()))
-	L.SetGlobal("mob_uuid", lua.LString(mi.ID()))
-	L.SetGlobal("mob_name", lua.LString(mi.Name()))
-
-	// Set global functions.
-	L.SetGlobal("say", L.NewFunction(LuaMobSay))
-	L.SetGlobal("sleep", L.NewFunction(LuaSleep))
-	L.SetGlobal("start_convo", L.NewFunction(LuaStartConvo))
-	L.SetGlobal("end_convo", L.NewFunction(LuaEndConvo))
-	L.SetGlobal("convo_select", L.NewFunction(LuaConvoSelect))
-	L.SetGlobal("c_attr", L.NewFunction(LuaCharacterAttribute))
-	L.SetGlobal("c_set_attr", L.NewFunction(LuaSetCharacterAttribute))
-	L.SetGlobal("i_name", L.NewFunction(LuaItemName))
-	L.SetGlobal("give", L.NewFunction(LuaInventoryGive))
-	L.SetGlobal("room_text", L.NewFunction(LuaRoomText))
-	L.SetGlobal("shop", L.NewFunction(LuaShop))
-
-	// Set "room" module.
-	L.PreloadModule("room", func(state *lua.LState) int {
-		mod := state.SetFuncs(state.NewTable(), map[string]lua.LGFunction{
-			"text": LuaRoomText,
-		})
-		state.Push(mod)
-		return 1
+	CallScriptFunc(invoker, mi, funcName, args...)
+}
+
+// CallItemFunc handles executing item scripts within the ItemInstance's persistent, sandboxed Lua environment.
+func CallItemFunc(invoker *Character, ii *ItemInstance, funcName string, args ...lua.LValue) {
+	CallScriptFunc(invoker, ii, funcName, args...)
+}
+
+// CallRoomFunc handles executing room scripts within the Room's persistent, sandboxed Lua environment.
+func CallRoomFunc(invoker *Character, r *Room, funcName string, args ...lua.LValue) {
+	CallScriptFunc(invoker, r, funcName, args...)
+}
+
+// CallAreaFunc handles executing area scripts within the Area's persistent, sandboxed Lua environment.
+func CallAreaFunc(invoker *Character, a *Area, funcName string, args ...lua.LValue) {
+	CallScriptFunc(invoker, a, funcName, args...)
+}
+
+// CallScriptFunc executes a function within the script of a MobInstance, ItemInstance, Room or Area.
+func CallScriptFunc(invoker *Character, o ScriptOwner, funcName string, args ...lua.LValue) {
+	proto, ok := prepareScriptCall(invoker, o, funcName)
+	if !ok {
+		return
+	}
+
+	NewScriptCall(invoker, o, funcName).Start(proto, args...)
+}
+
+// QueueScriptFunc calls a function within the script of a MobInstance, ItemInstance, Room or Area without waiting
+// for it. The call is queued behind the current event when the event loop is enabled.
+func QueueScriptFunc(invoker *Character, o ScriptOwner, funcName string, args ...lua.LValue) {
+	Armeria.GoEvent(EventScript, funcName, func() {
+		CallScriptFunc(invoker, o, funcName, args...)
 	})
+}
+
+// CallMoveCheck runs a character_entering() or character_leaving() function to completion, and returns the reason
+// given to block_move() when the script blocks the Character from moving. The reason is empty when the move is
+// allowed.
+func CallMoveCheck(invoker *Character, o ScriptOwner, funcName string) string {
+	if !misc.Contains(o.ScriptFuncs(), funcName) {
+		return ""
+	}
+
+	proto, ok := prepareScriptCall(invoker, o, funcName)
+	if !ok {
+		return ""
+	}
 
-	err := L.DoString(mi.Parent.Script())
+	call := NewScriptCall(invoker, o, funcName)
+	call.blocking = true
+	call.Start(proto)
+
+	return call.blocked
+}
+
+// prepareScriptCall returns the owner's compiled script, and reserves a slot on its ScriptVM for the call. The
+// second return value is false when there is nothing to run.
+func prepareScriptCall(invoker *Character, o ScriptOwner, funcName string) (*lua.FunctionProto, bool) {
+	proto, err := o.ScriptProto()
 	if err != nil {
 		Armeria.log.Error("error compiling lua script",
-			zap.String("script", mi.Parent.ScriptFile()),
+			zap.String("script", o.ScriptFile()),
 			zap.Error(err),
 		)
-		if invoker.HasPermission("CAN_BUILD") {
+		if invoker != nil && invoker.HasPermission(PermissionBuild) && invoker.Online() {
 			invoker.Player().client.ShowColorizedText(
 				fmt.Sprintf(
-					"There was an error compiling %s() on mob %s:\n%s",
+					"There was an error compiling %s() on %s %s:\n%s",
 					funcName,
-					mi.Name(),
+					o.ScriptKind(),
+					o.Name(),
 					err.Error(),
 				),
 				ColorError,
 			)
 		}
-		return
+		return nil, false
+	} else if proto == nil {
+		return nil, false
 	}
 
-	lv := L.GetGlobal(funcName)
-	if lv.Type() == lua.LTNil {
-		return
+	if !o.ScriptVM().Acquire() {
+		ReportScriptViolation(o, funcName, ErrScriptConcurrency)
+		return nil, false
 	}
 
-	err = L.CallByParam(lua.P{
-		Fn:      L.GetGlobal(funcName),
-		NRet:    0,
-		Protect: true,
-	}, args...)
-	if err != nil {
-		Armeria.log.Error("error executing function in lua script",
-			zap.String("script", mi.Parent.ScriptFile()),
-			zap.String("function", funcName),
-			zap.Error(err),
-		)
-		if invoker.HasPermission("CAN_BUILD") {
-			invoker.Player().client.ShowColorizedText(
-				fmt.Sprintf(
-					"There was an error running %s on mob %s.\n\n%s",
-					TextStyle(funcName+"()", WithBold()),
-					TextStyle(mi.Name(), WithBold()),
-					err.Error(),
-				),
-				ColorError,
-			)
-		}
-	}
+	return proto, true
 }
@@ -0,0 +1,138 @@
+package armeria
+
+import (
+	"os"
+	"testing"
+)
+
+func TestItemScriptHooks(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	apple := Armeria.itemManager.ItemByName("Apple")
+	apple.SetAttribute(AttributeEquipSlot, string(EquipSlotWalletBank))
+	WriteItemScript(apple, `
+local character = require("character")
+
+function on_get() room_text("The apple was picked up.") end
+function on_drop() room_text("The apple was dropped.") end
+function on_equip() room_text("The apple was equipped.") end
+function on_remove() room_text("The apple was removed.") end
+function on_use() room_text("The apple was eaten by " .. invoker_name .. ".") end
+function on_give(to) room_text("The apple was given to " .. character.name(to) .. ".") end
+`)
+
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+
+	for _, step := range []struct {
+		command  string
+		expected string
+	}{
+		{"/get apple", "The apple was picked up."},
+		{"/equip apple", "The apple was equipped."},
+		{"/remove apple", "The apple was removed."},
+		{"/use apple", "The apple was eaten by Alice."},
+		{"/give Bob apple", "The apple was given to Bob."},
+	} {
+		alice.Send(step.command)
+		expectText(t, bob, step.expected)
+	}
+
+	bob.Send("/drop apple")
+	expectText(t, alice, "The apple was dropped.")
+
+	// Items without an on_use() function can't be used.
+	WriteItemScript(apple, "")
+	alice.Send("/use apple")
+	expectText(t, alice, "You can't find a way to use the [Apple].")
+}
+
+func TestRoomScriptBlockMove(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	market := Armeria.worldManager.RoomFromLocationString("Test Area,0,1,0")
+	WriteRoomScript(market, `
+function character_entering()
+  if invoker_name == "Bob" then
+    block_move("The market is closed to you.")
+  end
+end
+
+function character_entered()
+  room_text("Welcome to the market, " .. invoker_name .. ".")
+end
+
+function character_said(text)
+  block_move()
+end
+`)
+
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("builder")
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+
+	bob.Send("/move north")
+	expectText(t, bob, "The market is closed to you.")
+	if r := bob.Player().Character().Room(); r.Attribute(AttributeTitle) != "Town Square" {
+		t.Errorf("expected Bob to still be in the Town Square, got %s", r.Attribute(AttributeTitle))
+	}
+
+	alice.Send("/move north")
+	expectText(t, alice, "You walk to the north.")
+	expectText(t, alice, "Welcome to the market, Alice.")
+
+	// Moves can only be blocked while the character is waiting to move.
+	alice.Send("/say hello")
+	expectText(t, alice, "block_move() can only be used within character_entering() or character_leaving()")
+
+	// The character can't be kept waiting while the script sleeps, so the move is allowed.
+	square := Armeria.worldManager.RoomFromLocationString("Test Area,0,0,0")
+	WriteRoomScript(square, `
+function character_entering()
+  sleep("1s")
+  block_move()
+end
+`)
+	alice.Clear()
+	alice.Send("/move south")
+	expectText(t, alice, ErrScriptBlockingSleep.Error())
+	expectText(t, alice, "You walk to the south.")
+}
+
+func TestRoomScriptTick(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	square := Armeria.worldManager.RoomFromLocationString("Test Area,0,0,0")
+	WriteRoomScript(square, `
+function room_tick()
+  room_text("The fountain bubbles.")
+end
+`)
+
+	alice := playAs(t, "Alice")
+	runTicker(t, "RoomTick")
+	expectText(t, alice, "The fountain bubbles.")
+}
+
+func TestReportScriptViolation(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	square := Armeria.worldManager.RoomFromLocationString("Test Area,0,0,0")
+	WriteRoomScript(square, `
+function room_tick()
+  while true do end
+end
+`)
+
+	// Only builders listening to the builders channel are told about the violation.
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("builder")
+	Armeria.characterManager.CharacterByName("Alice").JoinChannel(Armeria.channels[ChannelBuilders])
+	alice := playAs(t, "Alice")
+	bob := playAs(t, "Bob")
+
+	runTicker(t, "RoomTick")
+	expectText(t, alice, "stopped while running room_tick(): "+ErrScriptInstructions.Error())
+	if bob.SawText("stopped while running") {
+		t.Errorf("expected Bob not to be told about the violation, got %q", bob.Texts())
+	}
+}
@@ -0,0 +1,117 @@
+package armeria
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Session is a revocable token granting access to an Account without the use of its password.
+type Session struct {
+	sync.RWMutex
+	UUID            string    `json:"uuid"`
+	UnsafeTokenHash string    `json:"tokenHash"`
+	UnsafeAccount   string    `json:"account"`
+	UnsafeLabel     string    `json:"label"`
+	UnsafeCreated   time.Time `json:"created"`
+	UnsafeExpires   time.Time `json:"expires"`
+	UnsafeLastUsed  time.Time `json:"lastUsed"`
+}
+
+// Session constants
+const (
+	SessionLabelObjectEditor string        = "Object Editor"
+	SessionTTLAutologin      time.Duration = 30 * 24 * time.Hour
+	SessionTTLObjectEditor   time.Duration = 12 * time.Hour
+)
+
+// hashSessionToken returns the hash of a token, which is what gets persisted to disk.
+func hashSessionToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// ID returns the uuid of the Session.
+func (s *Session) ID() string {
+	return s.UUID
+}
+
+// ShortID returns the abbreviated uuid of the Session, used when referencing it in commands.
+func (s *Session) ShortID() string {
+	return s.UUID[0:8]
+}
+
+// AccountID returns the uuid of the Account the Session belongs to.
+func (s *Session) AccountID() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.UnsafeAccount
+}
+
+// Label returns the device label of the Session.
+func (s *Session) Label() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.UnsafeLabel
+}
+
+// Created returns the time the Session was created.
+func (s *Session) Created() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.UnsafeCreated
+}
+
+// Expires returns the time the Session expires.
+func (s *Session) Expires() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.UnsafeExpires
+}
+
+// LastUsed returns the time the Session was last used.
+func (s *Session) LastUsed() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.UnsafeLastUsed
+}
+
+// Expired returns true if the Session can no longer be used.
+func (s *Session) Expired() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return time.Now().After(s.UnsafeExpires)
+}
+
+// MatchesToken returns true if the token belongs to the Session.
+func (s *Session) MatchesToken(token string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.UnsafeTokenHash == hashSessionToken(token)
+}
+
+// Touch updates the time the Session was last used.
+func (s *Session) Touch() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.UnsafeLastUsed = time.Now()
+}
+
+type sessionJSON Session
+
+// MarshalJSON encodes the Session while holding its lock.
+func (s *Session) MarshalJSON() ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return json.Marshal((*sessionJSON)(s))
+}
@@ -0,0 +1,217 @@
+package armeria
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go.uber.org/zap"
+)
+
+type SessionManager struct {
+	sync.RWMutex
+	UnsafeSessions []*Session `json:"sessions"`
+}
+
+func NewSessionManager() *SessionManager {
+	m := &SessionManager{}
+
+	m.LoadSessions()
+
+	return m
+}
+
+func (m *SessionManager) LoadSessions() {
+	m.Lock()
+	defer m.Unlock()
+
+	records, err := Armeria.store.Load(CollectionSessions)
+	if err != nil {
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionSessions.Name),
+			zap.Error(err),
+		)
+	}
+
+	err = DecodeStoreRecords(records, &m.UnsafeSessions)
+	if err != nil {
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionSessions.Name),
+			zap.Error(err),
+		)
+	}
+
+	Armeria.log.Info("sessions loaded",
+		zap.Int("count", len(m.UnsafeSessions)),
+	)
+}
+
+func (m *SessionManager) SaveSessions() {
+	m.RLock()
+	defer m.RUnlock()
+
+	records, err := EncodeStoreRecords(CollectionSessions, m.UnsafeSessions)
+	if err != nil {
+		Armeria.log.Fatal("failed to marshal data",
+			zap.Error(err),
+		)
+	}
+
+	err = Armeria.store.Save(CollectionSessions, records)
+	if err != nil {
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionSessions.Name),
+			zap.Error(err),
+		)
+	}
+
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionSessions.Name),
+		zap.Int("records", len(records)),
+	)
+}
+
+// CreateSession creates a new Session for an Account and returns it along with the token. The token itself is
+// never stored, so this is the only opportunity to hand it to the client.
+func (m *SessionManager) CreateSession(a *Account, label string, ttl time.Duration) (*Session, string) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		Armeria.log.Fatal("error generating session token",
+			zap.Error(err),
+		)
+	}
+	token := hex.EncodeToString(b)
+
+	now := time.Now()
+	s := &Session{
+		UUID:            uuid.New().String(),
+		UnsafeTokenHash: hashSessionToken(token),
+		UnsafeAccount:   a.ID(),
+		UnsafeLabel:     label,
+		UnsafeCreated:   now,
+		UnsafeExpires:   now.Add(ttl),
+		UnsafeLastUsed:  now,
+	}
+
+	m.Lock()
+	m.UnsafeSessions = append(m.UnsafeSessions, s)
+	m.Unlock()
+
+	persistRecord(CollectionSessions, s)
+
+	Armeria.log.Info("session created",
+		zap.String("account", a.Name()),
+		zap.String("label", label),
+	)
+
+	return s, token
+}
+
+// SessionByToken returns the Session matching the token, as long as it has not expired. The time the Session was
+// last used is updated and persisted.
+func (m *SessionManager) SessionByToken(token string) *Session {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, s := range m.UnsafeSessions {
+		if s.MatchesToken(token) {
+			if s.Expired() {
+				return nil
+			}
+			s.Touch()
+			persistRecord(CollectionSessions, s)
+			return s
+		}
+	}
+
+	return nil
+}
+
+// SessionsByAccount returns the unexpired Sessions belonging to an Account.
+func (m *SessionManager) SessionsByAccount(a *Account) []*Session {
+	m.RLock()
+	defer m.RUnlock()
+
+	var sessions []*Session
+	for _, s := range m.UnsafeSessions {
+		if s.AccountID() == a.ID() && !s.Expired() {
+			sessions = append(sessions, s)
+		}
+	}
+
+	return sessions
+}
+
+// SessionByShortID returns the Session belonging to an Account that matches the abbreviated uuid.
+func (m *SessionManager) SessionByShortID(a *Account, id string) *Session {
+	for _, s := range m.SessionsByAccount(a) {
+		if strings.ToLower(s.ShortID()) == strings.ToLower(id) {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// RevokeSession removes a Session so that its token can no longer be used.
+func (m *SessionManager) RevokeSession(s *Session) {
+	m.Lock()
+	defer m.Unlock()
+
+	for i, session := range m.UnsafeSessions {
+		if session == s {
+			m.UnsafeSessions = append(m.UnsafeSessions[:i], m.UnsafeSessions[i+1:]...)
+			removeRecord(CollectionSessions, s.ID())
+			return
+		}
+	}
+}
+
+// RevokeAccountSessions removes all Sessions belonging to an Account and returns how many were removed.
+func (m *SessionManager) RevokeAccountSessions(a *Account) int {
+	m.Lock()
+	defer m.Unlock()
+
+	kept := []*Session{}
+	for _, s := range m.UnsafeSessions {
+		if s.AccountID() != a.ID() {
+			kept = append(kept, s)
+		} else {
+			removeRecord(CollectionSessions, s.ID())
+		}
+	}
+
+	revoked := len(m.UnsafeSessions) - len(kept)
+	m.UnsafeSessions = kept
+
+	Armeria.log.Info("account sessions revoked",
+		zap.String("account", a.Name()),
+		zap.Int("count", revoked),
+	)
+
+	return revoked
+}
+
+// PruneExpiredSessions removes all Sessions that have expired.
+func (m *SessionManager) PruneExpiredSessions() int {
+	m.Lock()
+	defer m.Unlock()
+
+	kept := []*Session{}
+	for _, s := range m.UnsafeSessions {
+		if !s.Expired() {
+			kept = append(kept, s)
+		} else {
+			removeRecord(CollectionSessions, s.ID())
+		}
+	}
+
+	pruned := len(m.UnsafeSessions) - len(kept)
+	m.UnsafeSessions = kept
+
+	return pruned
+}
@@ -0,0 +1,43 @@
+package armeria
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSessionPrune(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	a := Armeria.characterManager.CharacterByName("Alice").Account()
+	expired, _ := Armeria.sessionManager.CreateSession(a, "old", -time.Minute)
+	kept, _ := Armeria.sessionManager.CreateSession(a, "new", time.Hour)
+
+	if n := Armeria.sessionManager.PruneExpiredSessions(); n != 1 {
+		t.Errorf("expected 1 session to be pruned, got %d", n)
+	}
+	if _, ok := storedRecord(t, CollectionSessions, expired.ID()); ok {
+		t.Error("expected the expired session to be removed from the store")
+	}
+	if _, ok := storedRecord(t, CollectionSessions, kept.ID()); !ok {
+		t.Error("expected the unexpired session to still be stored")
+	}
+}
+
+func TestSessionTouch(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	a := Armeria.characterManager.CharacterByName("Alice").Account()
+	s, token := Armeria.sessionManager.CreateSession(a, "test", time.Hour)
+	before, _ := storedRecord(t, CollectionSessions, s.ID())
+
+	time.Sleep(10 * time.Millisecond)
+	if Armeria.sessionManager.SessionByToken(token) != s {
+		t.Fatal("expected the token to match the session")
+	}
+
+	after, _ := storedRecord(t, CollectionSessions, s.ID())
+	if after == before {
+		t.Errorf("expected the time the session was last used to be persisted, got %s", after)
+	}
+}
@@ -63,12 +63,12 @@ func SettingValidationString(name string) string {
 	return ""
 }
 
-// SettingPermission returns a specific permission that is required to view/alter a specific setting.
-func SettingPermission(name string) string {
+// SettingPermission returns the permissions required to view/alter a specific setting, if any.
+func SettingPermission(name string) *PermissionRequirement {
 	switch name {
 	case SettingScriptTheme:
-		return "CAN_BUILD"
+		return RequireAnyPermission(PermissionBuild)
 	}
 
-	return ""
+	return nil
 }
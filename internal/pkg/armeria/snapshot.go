@@ -0,0 +1,609 @@
+package armeria
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SnapshotDir is the directory within the data directory where world snapshots are written.
+const SnapshotDir string = "snapshots"
+
+// DefaultSnapshotRetention is how many world snapshots are kept, when it isn't set in the config file.
+const DefaultSnapshotRetention int = 24
+
+// MaxSnapshotDiffLines is the most lines of a snapshot diff shown within the game.
+const MaxSnapshotDiffLines int = 200
+
+// snapshotExtension is the extension of a snapshot's compressed archive.
+const snapshotExtension string = ".tar.gz"
+
+// snapshotSchemaVersionFile is the name of the file within a snapshot holding its schema version.
+const snapshotSchemaVersionFile string = "schema-version"
+
+// snapshotDirs are the directories within the data directory whose files are included in a snapshot.
+var snapshotDirs = []string{"scripts", "object-images"}
+
+// snapshotNameRegex matches the name of a snapshot, such as 20261017-150405-v11, capturing its schema version.
+var snapshotNameRegex = regexp.MustCompile(`^\d{8}-\d{6}-v(\d+)(-\d+)?$`)
+
+var (
+	// ErrSnapshotNotFound is an error for when there's no snapshot with a particular name.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrSnapshotInvalid is an error for when a snapshot's archive holds something it shouldn't.
+	ErrSnapshotInvalid = errors.New("invalid snapshot")
+)
+
+// A Snapshot is a compressed copy of the whole data set, taken at a point in time.
+type Snapshot struct {
+	Name          string
+	Path          string
+	Time          time.Time
+	SchemaVersion int
+	Size          int64
+}
+
+// SnapshotContents is the data set held by a snapshot, or the data set as it is now.
+type SnapshotContents struct {
+	// SchemaVersion is the schema version of the data.
+	SchemaVersion string
+	// Collections holds the contents of each collection's JSON file, keyed by file name.
+	Collections map[string][]byte
+	// Files holds the scripts and object images, keyed by their path within the data directory.
+	Files map[string][]byte
+}
+
+// SnapshotManager takes world snapshots and keeps only the most recent of them.
+type SnapshotManager struct {
+	sync.Mutex
+	dir       string
+	retention int
+}
+
+// NewSnapshotManager returns a SnapshotManager that writes snapshots to the directory, keeping the most recent
+// retention snapshots.
+func NewSnapshotManager(dir string, retention int) *SnapshotManager {
+	if retention <= 0 {
+		retention = DefaultSnapshotRetention
+	}
+
+	return &SnapshotManager{
+		dir:       dir,
+		retention: retention,
+	}
+}
+
+// Create writes a snapshot of the data held by the store and within the data directory, then removes the snapshots
+// beyond the retention. Anything only held in memory should be saved first.
+func (m *SnapshotManager) Create(s Store, dataPath string) (*Snapshot, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	contents, err := ReadSnapshotContents(s, dataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sv, _ := strconv.Atoi(strings.TrimSpace(contents.SchemaVersion))
+	name := fmt.Sprintf("%s-v%d", time.Now().Format("20060102-150405"), sv)
+	for i := 2; m.exists(name); i++ {
+		name = fmt.Sprintf("%s-v%d-%d", time.Now().Format("20060102-150405"), sv, i)
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := writeSnapshotArchive(filepath.Join(m.dir, name+snapshotExtension), contents); err != nil {
+		return nil, err
+	}
+
+	if err := m.unsafePrune(); err != nil {
+		Armeria.log.Error("error removing old snapshots", zap.Error(err))
+	}
+
+	// The events that follow are recorded against the snapshot, so that they can be replayed on top of it.
+	Armeria.AnnotateEvent(func(e *Event) {
+		e.Snapshot = name
+	})
+
+	return m.unsafeSnapshot(name)
+}
+
+// exists returns whether a snapshot with the name has already been written.
+func (m *SnapshotManager) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(m.dir, name+snapshotExtension))
+	return err == nil
+}
+
+// unsafePrune removes the oldest snapshots, keeping the most recent within the retention. This DOES NOT request a
+// lock and IS NOT thread safe.
+func (m *SnapshotManager) unsafePrune() error {
+	snapshots, err := m.unsafeSnapshots()
+	if err != nil {
+		return err
+	}
+
+	for i := m.retention; i < len(snapshots); i++ {
+		if err := os.Remove(snapshots[i].Path); err != nil {
+			return err
+		}
+
+		Armeria.log.Info("snapshot removed",
+			zap.String("name", snapshots[i].Name),
+		)
+	}
+
+	return nil
+}
+
+// Snapshots returns the snapshots, newest first.
+func (m *SnapshotManager) Snapshots() ([]*Snapshot, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.unsafeSnapshots()
+}
+
+// unsafeSnapshots returns the snapshots, newest first. This DOES NOT request a lock and IS NOT thread safe.
+func (m *SnapshotManager) unsafeSnapshots() ([]*Snapshot, error) {
+	files, err := ioutil.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return []*Snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*Snapshot, 0, len(files))
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), snapshotExtension)
+		if f.IsDir() || name == f.Name() || !snapshotNameRegex.MatchString(name) {
+			continue
+		}
+
+		snapshots = append(snapshots, newSnapshot(filepath.Join(m.dir, f.Name()), name, f.Size()))
+	}
+
+	// Names start with the time they were taken, so they sort in order.
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Time.Equal(snapshots[j].Time) {
+			return len(snapshots[i].Name) > len(snapshots[j].Name) ||
+				(len(snapshots[i].Name) == len(snapshots[j].Name) && snapshots[i].Name > snapshots[j].Name)
+		}
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+
+	return snapshots, nil
+}
+
+// newSnapshot returns a Snapshot for an archive, reading the time and schema version from its name.
+func newSnapshot(path string, name string, size int64) *Snapshot {
+	s := &Snapshot{
+		Name: name,
+		Path: path,
+		Size: size,
+	}
+
+	s.Time, _ = time.ParseInLocation("20060102-150405", name[:15], time.Local)
+	if matches := snapshotNameRegex.FindStringSubmatch(name); matches != nil {
+		s.SchemaVersion, _ = strconv.Atoi(matches[1])
+	}
+
+	return s
+}
+
+// Snapshot returns the snapshot with the name.
+func (m *SnapshotManager) Snapshot(name string) (*Snapshot, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.unsafeSnapshot(name)
+}
+
+// unsafeSnapshot returns the snapshot with the name. This DOES NOT request a lock and IS NOT thread safe.
+func (m *SnapshotManager) unsafeSnapshot(name string) (*Snapshot, error) {
+	name = strings.TrimSuffix(name, snapshotExtension)
+	if !snapshotNameRegex.MatchString(name) {
+		return nil, ErrSnapshotNotFound
+	}
+
+	info, err := os.Stat(filepath.Join(m.dir, name+snapshotExtension))
+	if os.IsNotExist(err) {
+		return nil, ErrSnapshotNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return newSnapshot(filepath.Join(m.dir, name+snapshotExtension), name, info.Size()), nil
+}
+
+// Contents reads the data set held by the snapshot.
+func (s *Snapshot) Contents() (*SnapshotContents, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotInvalid, err)
+	}
+	defer gz.Close()
+
+	contents := newSnapshotContents()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrSnapshotInvalid, err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrSnapshotInvalid, err)
+		}
+
+		if err := contents.add(hdr.Name, b); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(contents.SchemaVersion) == 0 {
+		return nil, fmt.Errorf("%w: it has no schema version", ErrSnapshotInvalid)
+	}
+
+	return contents, nil
+}
+
+// newSnapshotContents returns empty SnapshotContents.
+func newSnapshotContents() *SnapshotContents {
+	return &SnapshotContents{
+		Collections: make(map[string][]byte),
+		Files:       make(map[string][]byte),
+	}
+}
+
+// add adds a file from a snapshot's archive to the contents. Anything other than the schema version, a collection,
+// or a file within one of the snapshot directories is refused, so that restoring can't write anywhere else.
+func (sc *SnapshotContents) add(name string, b []byte) error {
+	clean := path.Clean(name)
+
+	if clean == snapshotSchemaVersionFile {
+		sc.SchemaVersion = strings.TrimSpace(string(b))
+		return nil
+	} else if _, ok := collectionByFile(clean); ok {
+		sc.Collections[clean] = b
+		return nil
+	}
+
+	for _, dir := range snapshotDirs {
+		if strings.HasPrefix(clean, dir+"/") && !strings.Contains(clean, "..") {
+			sc.Files[clean] = b
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: unexpected file %s", ErrSnapshotInvalid, name)
+}
+
+// ReadSnapshotContents reads the data set as it is now, from the store and the data directory.
+func ReadSnapshotContents(s Store, dataPath string) (*SnapshotContents, error) {
+	contents := newSnapshotContents()
+
+	sv, err := ioutil.ReadFile(filepath.Join(dataPath, snapshotSchemaVersionFile))
+	if err != nil {
+		return nil, err
+	}
+	contents.SchemaVersion = strings.TrimSpace(string(sv))
+
+	for _, c := range StoreCollections() {
+		records, err := s.Load(c)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("error loading %s: %s", c.Name, err)
+		}
+
+		b, err := encodeJSONStoreFile(c, records)
+		if err != nil {
+			return nil, err
+		}
+		contents.Collections[c.File] = b
+	}
+
+	for _, dir := range snapshotDirs {
+		err := filepath.Walk(filepath.Join(dataPath, dir), func(p string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			} else if err != nil || info.IsDir() {
+				return err
+			}
+
+			rel, err := filepath.Rel(dataPath, p)
+			if err != nil {
+				return err
+			}
+
+			b, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+
+			contents.Files[filepath.ToSlash(rel)] = b
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return contents, nil
+}
+
+// writeSnapshotArchive writes the contents to a compressed archive. It's written to a temporary file first, so that
+// a crash never leaves a partially-written snapshot behind.
+func writeSnapshotArchive(filename string, contents *SnapshotContents) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	write := func(name string, b []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(b)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(b)
+		return err
+	}
+
+	err = write(snapshotSchemaVersionFile, []byte(contents.SchemaVersion))
+	for _, name := range sortedKeys(contents.Collections) {
+		if err == nil {
+			err = write(name, contents.Collections[name])
+		}
+	}
+	for _, name := range sortedKeys(contents.Files) {
+		if err == nil {
+			err = write(name, contents.Files[name])
+		}
+	}
+
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// sortedKeys returns the keys of the map in order.
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// migrationData decodes the collections, so that they can be compared.
+func (sc *SnapshotContents) migrationData() (MigrationData, error) {
+	data := make(MigrationData)
+	for _, c := range StoreCollections() {
+		b, ok := sc.Collections[c.File]
+		if !ok {
+			continue
+		}
+
+		records, err := decodeMigrationFile(c, b)
+		if err != nil {
+			return nil, err
+		}
+		data[c.File] = records
+	}
+
+	return data, nil
+}
+
+// WriteSnapshotDiff writes a human-readable list of the changes between two data sets.
+func WriteSnapshotDiff(w io.Writer, before, after *SnapshotContents) error {
+	changed := false
+
+	if before.SchemaVersion != after.SchemaVersion {
+		_, _ = fmt.Fprintf(w, "schema version %s -> %s\n", before.SchemaVersion, after.SchemaVersion)
+		changed = true
+	}
+
+	beforeData, err := before.migrationData()
+	if err != nil {
+		return err
+	}
+	afterData, err := after.migrationData()
+	if err != nil {
+		return err
+	}
+	if writeDataDiff(w, beforeData, afterData) {
+		changed = true
+	}
+
+	var lines []string
+	for _, name := range sortedKeys(after.Files) {
+		if b, existed := before.Files[name]; !existed {
+			lines = append(lines, "  + "+name)
+		} else if !bytes.Equal(b, after.Files[name]) {
+			lines = append(lines, "  ~ "+name)
+		}
+	}
+	for _, name := range sortedKeys(before.Files) {
+		if _, exists := after.Files[name]; !exists {
+			lines = append(lines, "  - "+name)
+		}
+	}
+	if len(lines) > 0 {
+		_, _ = fmt.Fprintf(w, "~~~ files\n%s\n", strings.Join(lines, "\n"))
+		changed = true
+	}
+
+	if !changed {
+		_, _ = fmt.Fprintln(w, "no changes")
+	}
+
+	return nil
+}
+
+// restore replaces the data set held by the store and within the data directory with the contents. Collections
+// that weren't part of the snapshot are emptied.
+func (sc *SnapshotContents) restore(s Store, dataPath string) error {
+	for _, c := range StoreCollections() {
+		var records []StoreRecord
+		if b, ok := sc.Collections[c.File]; ok {
+			var err error
+			if records, err = decodeJSONStoreFile(c, b); err != nil {
+				return fmt.Errorf("error decoding %s: %s", c.File, err)
+			}
+		}
+
+		if err := s.Save(c, records); err != nil {
+			return fmt.Errorf("error restoring %s: %s", c.Name, err)
+		}
+	}
+
+	for _, dir := range snapshotDirs {
+		if err := os.RemoveAll(filepath.Join(dataPath, dir)); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(dataPath, dir), 0755); err != nil {
+			return err
+		}
+	}
+
+	for name, b := range sc.Files {
+		p := filepath.Join(dataPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, b, 0644); err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(filepath.Join(dataPath, snapshotSchemaVersionFile), []byte(sc.SchemaVersion))
+}
+
+// PeriodicSnapshot saves the game data and takes a snapshot of it.
+func PeriodicSnapshot() {
+	Armeria.Save()
+
+	s, err := Armeria.snapshotManager.Create(Armeria.store, Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Error("error taking snapshot", zap.Error(err))
+		return
+	}
+
+	Armeria.log.Info("snapshot taken",
+		zap.String("name", s.Name),
+		zap.Int64("size", s.Size),
+	)
+}
+
+// RestoreSnapshot replaces the data with a snapshot, before the game server starts. A snapshot of the current data
+// is taken first, so the restore can itself be undone. During a dry run, the changes are printed instead.
+func RestoreSnapshot(name string, dryRun bool) {
+	store, err := NewStore(Armeria.storeType, Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Fatal("error opening store", zap.String("type", Armeria.storeType), zap.Error(err))
+	}
+	defer store.Close()
+
+	m := NewSnapshotManager(filepath.Join(Armeria.dataPath, SnapshotDir), Armeria.snapshotRetention)
+	s, err := m.Snapshot(name)
+	if err != nil {
+		Armeria.log.Fatal("error finding snapshot", zap.String("name", name), zap.Error(err))
+	}
+
+	contents, err := s.Contents()
+	if err != nil {
+		Armeria.log.Fatal("error reading snapshot", zap.String("name", s.Name), zap.Error(err))
+	}
+
+	if dryRun {
+		current, err := ReadSnapshotContents(store, Armeria.dataPath)
+		if err != nil {
+			Armeria.log.Fatal("error reading data", zap.Error(err))
+		}
+		if err := WriteSnapshotDiff(os.Stdout, current, contents); err != nil {
+			Armeria.log.Fatal("error comparing data", zap.Error(err))
+		}
+		Armeria.log.Info("dry run complete; no changes were written")
+		return
+	}
+
+	before, err := m.Create(store, Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Fatal("error taking snapshot of the current data", zap.Error(err))
+	}
+
+	Armeria.log.Info("current data snapshotted",
+		zap.String("name", before.Name),
+	)
+
+	if err := contents.restore(store, Armeria.dataPath); err != nil {
+		Armeria.log.Fatal("error restoring snapshot; restore the snapshot of the current data",
+			zap.String("snapshot", before.Name),
+			zap.Error(err),
+		)
+	}
+
+	Armeria.log.Info("snapshot restored",
+		zap.String("name", s.Name),
+		zap.Int("schemaVersion", s.SchemaVersion),
+	)
+}
@@ -0,0 +1,129 @@
+package armeria
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRetention(t *testing.T) {
+	dir := useHeadlessState(t)
+	defer os.RemoveAll(dir)
+
+	m := NewSnapshotManager(filepath.Join(dir, SnapshotDir), 2)
+	var names []string
+	for i := 0; i < 3; i++ {
+		s, err := m.Create(Armeria.store, dir)
+		if err != nil {
+			t.Fatalf("error taking snapshot: %s", err)
+		}
+		if s.SchemaVersion != SchemaVersion {
+			t.Errorf("expected schema version %d, got %d", SchemaVersion, s.SchemaVersion)
+		}
+		names = append(names, s.Name)
+	}
+
+	// Only the two most recent snapshots are kept, newest first.
+	snapshots, err := m.Snapshots()
+	if err != nil {
+		t.Fatalf("error listing snapshots: %s", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != names[2] || snapshots[1].Name != names[1] {
+		t.Fatalf("expected %s and %s, got %v", names[2], names[1], snapshots)
+	}
+	if _, err := m.Snapshot(names[0]); err != ErrSnapshotNotFound {
+		t.Errorf("expected the oldest snapshot to have been removed, got %v", err)
+	}
+	if _, err := m.Snapshot("../schema-version"); err != ErrSnapshotNotFound {
+		t.Errorf("expected only snapshots to be found, got %v", err)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	dir := useHeadlessState(t)
+	defer os.RemoveAll(dir)
+
+	room := Armeria.worldManager.RoomFromLocationString("Test Area,0,0,0")
+	s, err := Armeria.snapshotManager.Create(Armeria.store, dir)
+	if err != nil {
+		t.Fatalf("error taking snapshot: %s", err)
+	}
+	before, err := s.Contents()
+	if err != nil {
+		t.Fatalf("error reading snapshot: %s", err)
+	}
+
+	room.SetAttribute(AttributeTitle, "Old Square")
+	Armeria.Save()
+	script := filepath.Join(dir, "scripts", "room-new.lua")
+	if err := ioutil.WriteFile(script, []byte("-- new"), 0644); err != nil {
+		t.Fatalf("error writing script: %s", err)
+	}
+
+	after, err := ReadSnapshotContents(Armeria.store, dir)
+	if err != nil {
+		t.Fatalf("error reading data: %s", err)
+	}
+	var sb strings.Builder
+	if err := WriteSnapshotDiff(&sb, before, after); err != nil {
+		t.Fatalf("error comparing data: %s", err)
+	}
+	for _, expected := range []string{"~~~ world.json", `"Town Square" -> "Old Square"`, "  + scripts/room-new.lua"} {
+		if !strings.Contains(sb.String(), expected) {
+			t.Errorf("expected the diff to contain %q, got:\n%s", expected, sb.String())
+		}
+	}
+
+	if err := before.restore(Armeria.store, dir); err != nil {
+		t.Fatalf("error restoring snapshot: %s", err)
+	}
+	if _, err := os.Stat(script); !os.IsNotExist(err) {
+		t.Error("expected the new script to have been removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scripts", "mob-merchant.lua")); err != nil {
+		t.Errorf("expected the existing script to be kept: %s", err)
+	}
+
+	restored, err := ReadSnapshotContents(Armeria.store, dir)
+	if err != nil {
+		t.Fatalf("error reading data: %s", err)
+	}
+	sb.Reset()
+	_ = WriteSnapshotDiff(&sb, before, restored)
+	if sb.String() != "no changes\n" {
+		t.Errorf("expected the data to match the snapshot, got:\n%s", sb.String())
+	}
+}
+
+func TestHeadlessSnapshot(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	alice := playAs(t, "Alice")
+	Armeria.characterManager.CharacterByName("Alice").GrantRole("admin")
+
+	alice.Send("/snapshot list")
+	expectText(t, alice, "There are no snapshots.")
+
+	alice.Send("/snapshot create")
+	expectText(t, alice, "A snapshot of the game data has been taken")
+	snapshots, _ := Armeria.snapshotManager.Snapshots()
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(snapshots))
+	}
+	name := snapshots[0].Name
+
+	alice.Send("/snapshot list")
+	expectText(t, alice, name)
+
+	alice.Send("/snapshot diff " + name)
+	expectText(t, alice, "no changes")
+
+	alice.Send("/room set . title Old Square")
+	alice.Send("/snapshot diff " + name)
+	expectText(t, alice, `"Town Square" -> "Old Square"`)
+
+	alice.Send("/snapshot diff 20000101-000000-v1")
+	expectText(t, alice, "There's no snapshot named 20000101-000000-v1.")
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"time"
@@ -14,24 +15,45 @@ import (
 
 // GameState stores the manager singletons and any other global state.
 type GameState struct {
-	log              *zap.Logger
-	production       bool
-	playerManager    *PlayerManager
-	commandManager   *CommandManager
-	characterManager *CharacterManager
-	worldManager     *WorldManager
-	mobManager       *MobManager
-	itemManager      *ItemManager
-	convoManager     *ConversationManager
-	ledgerManager    *LedgerManager
-	tickManager      *TickManager
-	registry         *Registry
-	channels         map[string]*Channel
-	publicPath       string
-	dataPath         string
-	objectImagesPath string
-	startTime        time.Time
-	github           *github.ArmeriaRepo
+	log               *zap.Logger
+	production        bool
+	playerManager     *PlayerManager
+	commandManager    *CommandManager
+	accountManager    *AccountManager
+	sessionManager    *SessionManager
+	characterManager  *CharacterManager
+	worldManager      *WorldManager
+	mobManager        *MobManager
+	itemManager       *ItemManager
+	convoManager      *ConversationManager
+	ledgerManager     *LedgerManager
+	questManager      *QuestManager
+	roleManager       *RoleManager
+	auditLog          *AuditLog
+	snapshotManager   *SnapshotManager
+	combatManager     *CombatManager
+	tickManager       *TickManager
+	eventLoop         *EventLoop
+	eventLog          *EventLog
+	registry          *Registry
+	store             Store
+	storeType         string
+	channels          map[string]*Channel
+	publicPath        string
+	dataPath          string
+	objectImagesPath  string
+	startingRoom      string
+	reservedNames     []string
+	maxMessageLength  int
+	auditLogMaxSize   int64
+	auditLogMaxFiles  int
+	editJournalDepth  int
+	snapshotInterval  time.Duration
+	snapshotRetention int
+	recordEvents      bool
+	profanityFilters  []ProfanityFilter
+	startTime         time.Time
+	github            *github.ArmeriaRepo
 }
 
 var (
@@ -44,10 +66,28 @@ func Init(configFilePath string, serveTraffic bool) {
 	c := parseConfigFile(configFilePath)
 
 	Armeria = &GameState{
-		production:       c.Production,
-		publicPath:       c.PublicPath,
-		dataPath:         c.DataPath,
-		objectImagesPath: c.DataPath + "/object-images",
+		production:        c.Production,
+		publicPath:        c.PublicPath,
+		dataPath:          c.DataPath,
+		storeType:         c.Store,
+		objectImagesPath:  c.DataPath + "/object-images",
+		startingRoom:      c.StartingRoom,
+		reservedNames:     c.ReservedNames,
+		maxMessageLength:  c.MaxMessageLength,
+		auditLogMaxSize:   int64(c.AuditLogMaxSize) * 1024 * 1024,
+		auditLogMaxFiles:  c.AuditLogMaxFiles,
+		editJournalDepth:  c.EditJournalDepth,
+		snapshotInterval:  time.Duration(c.SnapshotInterval) * time.Minute,
+		snapshotRetention: c.SnapshotRetention,
+		recordEvents:      c.EventLog,
+	}
+
+	if len(c.FilteredWords) > 0 {
+		Armeria.AddProfanityFilter(NewWordFilter(c.FilteredWords))
+	}
+
+	if len(Armeria.storeType) == 0 {
+		Armeria.storeType = StoreTypeJSON
 	}
 
 	logger, err := zap.NewDevelopment()
@@ -62,9 +102,56 @@ func Init(configFilePath string, serveTraffic bool) {
 
 	verifySchemaVersion()
 
+	loadGameState(c.EventLoop)
+
+	Armeria.tickManager.Start()
+
+	Armeria.setupGracefulExit()
+
+	if c.TelnetPort > 0 {
+		go InitTelnet(c.TelnetPort)
+	}
+
+	port := c.HTTPPort
+	// For Heroku, we must listen on a specific port.
+	if len(os.Getenv("PORT")) > 0 {
+		port, err = strconv.Atoi(os.Getenv("PORT"))
+		if err != nil {
+			log.Fatalf("error parsing PORT environment variable: %s", err)
+		}
+	}
+	InitWeb(port)
+}
+
+// loadGameState opens the store and creates the managers, which load the game data from it.
+func loadGameState(eventLoop bool) {
+	var err error
+	Armeria.store, err = NewStore(Armeria.storeType, Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Fatal("error opening store",
+			zap.String("type", Armeria.storeType),
+			zap.Error(err),
+		)
+	}
+
+	// The event loop is started first, since the tickers may run at boot.
+	if eventLoop {
+		if Armeria.recordEvents {
+			Armeria.eventLog = NewEventLog(filepath.Join(Armeria.dataPath, EventLogDir))
+		}
+		Armeria.eventLoop = NewEventLoop(Armeria.eventLog)
+	} else if Armeria.recordEvents {
+		Armeria.log.Warn("events are only recorded when the event loop is enabled")
+	}
+
 	Armeria.registry = NewRegistry()
+	Armeria.auditLog = NewAuditLog(filepath.Join(Armeria.dataPath, AuditLogDir), Armeria.auditLogMaxSize, Armeria.auditLogMaxFiles)
+	Armeria.snapshotManager = NewSnapshotManager(filepath.Join(Armeria.dataPath, SnapshotDir), Armeria.snapshotRetention)
 	Armeria.commandManager = NewCommandManager()
 	Armeria.playerManager = NewPlayerManager()
+	Armeria.roleManager = NewRoleManager()
+	Armeria.accountManager = NewAccountManager()
+	Armeria.sessionManager = NewSessionManager()
 	Armeria.characterManager = NewCharacterManager()
 	Armeria.worldManager = NewWorldManager()
 	Armeria.mobManager = NewMobManager()
@@ -72,25 +159,15 @@ func Init(configFilePath string, serveTraffic bool) {
 	Armeria.channels = NewChannels()
 	Armeria.convoManager = NewConversationManager()
 	Armeria.ledgerManager = NewLedgerManager()
+	Armeria.questManager = NewQuestManager()
+	Armeria.combatManager = NewCombatManager()
 	Armeria.tickManager = NewTickManager()
 
 	Armeria.github = github.New()
 
-	Armeria.setupGracefulExit()
-
 	Armeria.startTime = time.Now()
 
 	RegisterGameCommands()
-
-	port := c.HTTPPort
-	// For Heroku, we must listen on a specific port.
-	if len(os.Getenv("PORT")) > 0 {
-		port, err = strconv.Atoi(os.Getenv("PORT"))
-		if err != nil {
-			log.Fatalf("error parsing PORT environment variable: %s", err)
-		}
-	}
-	InitWeb(port)
 }
 
 func (gs *GameState) setupGracefulExit() {
@@ -99,15 +176,25 @@ func (gs *GameState) setupGracefulExit() {
 	go func() {
 		<-sigs
 		gs.Save()
+		_ = gs.store.Close()
+		_ = gs.auditLog.Close()
+		if gs.eventLog != nil {
+			_ = gs.eventLog.Close()
+		}
 		os.Exit(0)
 	}()
 }
 
-// Save writes the in-memory data to disk.
+// Save writes the in-memory data to disk. Records changed by commands, such as a builder's edits or a new account,
+// are already persisted as the command finishes; this catches up on the changes made during play, like mobs and
+// items moving between rooms, which are otherwise only written by the periodic save and at shutdown.
 func (gs *GameState) Save() {
+	gs.accountManager.SaveAccounts()
+	gs.sessionManager.SaveSessions()
 	gs.characterManager.SaveCharacters()
 	gs.worldManager.SaveWorld()
 	gs.mobManager.SaveMobs()
 	gs.itemManager.SaveItems()
 	gs.ledgerManager.SaveLedgers()
+	gs.questManager.SaveQuests()
 }
@@ -0,0 +1,124 @@
+package armeria
+
+import (
+	"bytes"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Force verify that BoltStore implements Store.
+var _ Store = (*BoltStore)(nil)
+
+// BoltStoreFile is the name of the database file within the data directory.
+const BoltStoreFile string = "armeria.db"
+
+// A BoltStore persists each collection as a bucket within an embedded BoltDB database, with every object stored
+// under its own key.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the BoltDB database within the data directory.
+func NewBoltStore(dataPath string) (*BoltStore, error) {
+	db, err := bolt.Open(filepath.Join(dataPath, BoltStoreFile), 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltStore{
+		db: db,
+	}, nil
+}
+
+// Load returns every record within the collection's bucket.
+func (s *BoltStore) Load(c StoreCollection) ([]StoreRecord, error) {
+	var records []StoreRecord
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(c.Name))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			data := make([]byte, len(v))
+			copy(data, v)
+			records = append(records, StoreRecord{Key: string(k), Data: data})
+			return nil
+		})
+	})
+
+	return records, err
+}
+
+// Save replaces the contents of the collection's bucket within a single transaction. Records that have not
+// changed are left untouched.
+func (s *BoltStore) Save(c StoreCollection, records []StoreRecord) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(c.Name))
+		if err != nil {
+			return err
+		}
+
+		keep := make(map[string]bool, len(records))
+		for _, r := range records {
+			keep[r.Key] = true
+			if bytes.Equal(b.Get([]byte(r.Key)), r.Data) {
+				continue
+			}
+			if err := b.Put([]byte(r.Key), r.Data); err != nil {
+				return err
+			}
+		}
+
+		var stale [][]byte
+		err = b.ForEach(func(k, v []byte) error {
+			if !keep[string(k)] {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SaveRecord creates or updates a single record within the collection's bucket.
+func (s *BoltStore) SaveRecord(c StoreCollection, r StoreRecord) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(c.Name))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(r.Key), r.Data)
+	})
+}
+
+// DeleteRecord removes a single record from the collection's bucket.
+func (s *BoltStore) DeleteRecord(c StoreCollection, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(c.Name))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(key))
+	})
+}
+
+// Close closes the underlying database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
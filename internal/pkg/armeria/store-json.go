@@ -0,0 +1,169 @@
+package armeria
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Force verify that JSONStore implements Store.
+var _ Store = (*JSONStore)(nil)
+
+// A JSONStore persists each collection as a single JSON file within the data directory.
+type JSONStore struct {
+	sync.Mutex
+	dataPath string
+}
+
+// NewJSONStore returns a new JSONStore for the data directory.
+func NewJSONStore(dataPath string) *JSONStore {
+	return &JSONStore{
+		dataPath: dataPath,
+	}
+}
+
+// Load returns every record within the collection's JSON file.
+func (s *JSONStore) Load(c StoreCollection) ([]StoreRecord, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.load(c)
+}
+
+// Save overwrites the collection's JSON file with the records.
+func (s *JSONStore) Save(c StoreCollection, records []StoreRecord) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.save(c, records)
+}
+
+// SaveRecord rewrites the collection's JSON file with the record created or updated.
+func (s *JSONStore) SaveRecord(c StoreCollection, r StoreRecord) error {
+	s.Lock()
+	defer s.Unlock()
+
+	records, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, existing := range records {
+		if existing.Key == r.Key {
+			records[i] = r
+			replaced = true
+			break
+		}
+	}
+
+	if !replaced {
+		records = append(records, r)
+	}
+
+	return s.save(c, records)
+}
+
+// DeleteRecord rewrites the collection's JSON file without the record.
+func (s *JSONStore) DeleteRecord(c StoreCollection, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	records, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]StoreRecord, 0, len(records))
+	for _, r := range records {
+		if r.Key != key {
+			kept = append(kept, r)
+		}
+	}
+
+	return s.save(c, kept)
+}
+
+// Close is a no-op, since files are not held open between operations.
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+func (s *JSONStore) load(c StoreCollection) ([]StoreRecord, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.dataPath, c.File))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeJSONStoreFile(c, b)
+}
+
+func (s *JSONStore) save(c StoreCollection, records []StoreRecord) error {
+	b, err := encodeJSONStoreFile(c, records)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(s.dataPath, c.File), b)
+}
+
+// decodeJSONStoreFile returns the records within the contents of a collection's JSON file.
+func decodeJSONStoreFile(c StoreCollection, b []byte) ([]StoreRecord, error) {
+	contents := make(map[string][]json.RawMessage)
+	if err := json.Unmarshal(b, &contents); err != nil {
+		return nil, err
+	}
+
+	records := make([]StoreRecord, 0, len(contents[c.Name]))
+	for _, raw := range contents[c.Name] {
+		r, err := newStoreRecordFromRaw(c, raw)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// encodeJSONStoreFile returns the contents of a collection's JSON file holding the records.
+func encodeJSONStoreFile(c StoreCollection, records []StoreRecord) ([]byte, error) {
+	raws := make([]json.RawMessage, len(records))
+	for i, r := range records {
+		raws[i] = r.Data
+	}
+
+	return json.Marshal(map[string][]json.RawMessage{c.Name: raws})
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it over the destination, so
+// that a crash mid-write never leaves a partially-written file behind.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
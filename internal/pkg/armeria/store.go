@@ -0,0 +1,259 @@
+package armeria
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// A Store persists the game data on behalf of the managers.
+type Store interface {
+	// Load returns every record within the collection.
+	Load(c StoreCollection) ([]StoreRecord, error)
+	// Save replaces the contents of the collection with the records.
+	Save(c StoreCollection, records []StoreRecord) error
+	// SaveRecord creates or updates a single record within the collection.
+	SaveRecord(c StoreCollection, r StoreRecord) error
+	// DeleteRecord removes a single record from the collection.
+	DeleteRecord(c StoreCollection, key string) error
+	// Close releases any resources held by the Store.
+	Close() error
+}
+
+// A StoreCollection is a set of objects of the same type that are persisted together.
+type StoreCollection struct {
+	Name     string
+	File     string
+	KeyField string
+}
+
+// A StoreRecord is a single JSON-encoded object within a StoreCollection.
+type StoreRecord struct {
+	Key  string
+	Data json.RawMessage
+}
+
+// Store types
+const (
+	StoreTypeJSON string = "json"
+	StoreTypeBolt string = "bolt"
+)
+
+// Store collections
+var (
+	CollectionAccounts   = StoreCollection{Name: "accounts", File: "accounts.json", KeyField: "uuid"}
+	CollectionSessions   = StoreCollection{Name: "sessions", File: "sessions.json", KeyField: "uuid"}
+	CollectionCharacters = StoreCollection{Name: "characters", File: "characters.json", KeyField: "uuid"}
+	CollectionWorld      = StoreCollection{Name: "world", File: "world.json", KeyField: "uuid"}
+	CollectionMobs       = StoreCollection{Name: "mobs", File: "mobs.json", KeyField: "name"}
+	CollectionItems      = StoreCollection{Name: "items", File: "items.json", KeyField: "name"}
+	CollectionLedgers    = StoreCollection{Name: "ledgers", File: "ledgers.json", KeyField: "name"}
+	CollectionQuests     = StoreCollection{Name: "quests", File: "quests.json", KeyField: "name"}
+	CollectionRoles      = StoreCollection{Name: "roles", File: "roles.json", KeyField: "name"}
+)
+
+// StoreCollections returns all of the collections persisted by the game.
+func StoreCollections() []StoreCollection {
+	return []StoreCollection{
+		CollectionAccounts,
+		CollectionSessions,
+		CollectionCharacters,
+		CollectionWorld,
+		CollectionMobs,
+		CollectionItems,
+		CollectionLedgers,
+		CollectionQuests,
+		CollectionRoles,
+	}
+}
+
+// NewStore returns the Store matching the store type.
+func NewStore(storeType, dataPath string) (Store, error) {
+	switch storeType {
+	case StoreTypeJSON, "":
+		return NewJSONStore(dataPath), nil
+	case StoreTypeBolt:
+		return NewBoltStore(dataPath)
+	}
+
+	return nil, fmt.Errorf("unknown store type: %s", storeType)
+}
+
+// NewStoreRecord encodes a single object as a StoreRecord.
+func NewStoreRecord(c StoreCollection, o interface{}) (StoreRecord, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return StoreRecord{}, err
+	}
+
+	return newStoreRecordFromRaw(c, data)
+}
+
+// newStoreRecordFromRaw returns a StoreRecord for an already-encoded object, using the collection's key field.
+func newStoreRecordFromRaw(c StoreCollection, data json.RawMessage) (StoreRecord, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return StoreRecord{}, err
+	}
+
+	var key string
+	if err := json.Unmarshal(fields[c.KeyField], &key); err != nil || len(key) == 0 {
+		return StoreRecord{}, fmt.Errorf("record in %s is missing key field %s", c.Name, c.KeyField)
+	}
+
+	return StoreRecord{Key: key, Data: data}, nil
+}
+
+// EncodeStoreRecords encodes a slice of objects as StoreRecords.
+func EncodeStoreRecords(c StoreCollection, objects interface{}) ([]StoreRecord, error) {
+	b, err := json.Marshal(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	var raws []json.RawMessage
+	if err := json.Unmarshal(b, &raws); err != nil {
+		return nil, err
+	}
+
+	records := make([]StoreRecord, 0, len(raws))
+	for _, raw := range raws {
+		r, err := newStoreRecordFromRaw(c, raw)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// DecodeStoreRecords decodes StoreRecords into a pointer to a slice of objects.
+func DecodeStoreRecords(records []StoreRecord, objects interface{}) error {
+	raws := make([][]byte, len(records))
+	for i, r := range records {
+		raws[i] = r.Data
+	}
+
+	b := append([]byte("["), bytes.Join(raws, []byte(","))...)
+	b = append(b, ']')
+
+	return json.Unmarshal(b, objects)
+}
+
+// persistRecord immediately saves a single object to the store, outside of the periodic game save.
+func persistRecord(c StoreCollection, o interface{}) {
+	r, err := NewStoreRecord(c, o)
+	if err == nil {
+		err = Armeria.store.SaveRecord(c, r)
+	}
+
+	if err != nil {
+		Armeria.log.Error("failed to persist record",
+			zap.String("collection", c.Name),
+			zap.Error(err),
+		)
+	}
+}
+
+// removeRecord immediately removes a single object from the store, outside of the periodic game save.
+func removeRecord(c StoreCollection, key string) {
+	if err := Armeria.store.DeleteRecord(c, key); err != nil {
+		Armeria.log.Error("failed to remove record",
+			zap.String("collection", c.Name),
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+}
+
+// persistObjects immediately saves the records that hold each of the objects, saving each record only once. A Room
+// is stored within its Area, and a mob or item instance within its Mob or Item.
+func persistObjects(objects ...interface{}) {
+	saved := make(map[interface{}]bool)
+	for _, o := range objects {
+		switch obj := o.(type) {
+		case *Room:
+			if obj.ParentArea == nil {
+				continue
+			}
+			o = obj.ParentArea
+		case *MobInstance:
+			if obj.Parent == nil {
+				continue
+			}
+			o = obj.Parent
+		case *ItemInstance:
+			if obj.Parent == nil {
+				continue
+			}
+			o = obj.Parent
+		}
+
+		if o == nil || saved[o] {
+			continue
+		}
+		saved[o] = true
+
+		switch obj := o.(type) {
+		case *Area:
+			Armeria.worldManager.SaveArea(obj)
+		case *Mob:
+			Armeria.mobManager.SaveMob(obj)
+		case *Item:
+			Armeria.itemManager.SaveItem(obj)
+		case *Ledger:
+			Armeria.ledgerManager.SaveLedger(obj)
+		case *Quest:
+			Armeria.questManager.SaveQuest(obj)
+		case *Character:
+			Armeria.characterManager.SaveCharacter(obj)
+		case *Account:
+			Armeria.accountManager.SaveAccount(obj)
+		}
+	}
+}
+
+// ConvertStore copies every collection from the configured store into a store of another type.
+func ConvertStore(toType string) {
+	if toType == Armeria.storeType {
+		Armeria.log.Fatal("cannot convert a store into the same type",
+			zap.String("type", toType),
+		)
+	}
+
+	from, err := NewStore(Armeria.storeType, Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Fatal("error opening store", zap.String("type", Armeria.storeType), zap.Error(err))
+	}
+	defer from.Close()
+
+	to, err := NewStore(toType, Armeria.dataPath)
+	if err != nil {
+		Armeria.log.Fatal("error opening store", zap.String("type", toType), zap.Error(err))
+	}
+	defer to.Close()
+
+	for _, c := range StoreCollections() {
+		records, err := from.Load(c)
+		if err != nil {
+			Armeria.log.Fatal("error loading collection", zap.String("collection", c.Name), zap.Error(err))
+		}
+
+		if err := to.Save(c, records); err != nil {
+			Armeria.log.Fatal("error saving collection", zap.String("collection", c.Name), zap.Error(err))
+		}
+
+		Armeria.log.Info("collection converted",
+			zap.String("collection", c.Name),
+			zap.Int("records", len(records)),
+		)
+	}
+
+	Armeria.log.Info("store conversion complete",
+		zap.String("from", Armeria.storeType),
+		zap.String("to", toType),
+	)
+}
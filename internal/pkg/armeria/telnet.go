@@ -0,0 +1,45 @@
+package armeria
+
+import (
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+)
+
+// TelnetWelcome is shown to telnet clients as soon as they connect, since they have no login screen.
+const TelnetWelcome string = "Welcome to Armeria! Log in using /login [account], and enter your password when asked."
+
+// ServeTelnet connects a new Player over the telnet connection.
+func ServeTelnet(conn net.Conn) {
+	p := Armeria.playerManager.NewPlayer(NewTelnetTransport(conn))
+	p.SetupPumps()
+	p.Connected()
+	p.client.ShowText(TextStyle(TelnetWelcome, WithBold()))
+}
+
+// InitTelnet will initialize the telnet server, for MUD clients such as Mudlet and TinTin++.
+func InitTelnet(port int) {
+	Armeria.log.Info("serving telnet connections",
+		zap.Int("port", port),
+	)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		Armeria.log.Fatal("error listening to telnet",
+			zap.Error(err),
+		)
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			Armeria.log.Error("error accepting telnet connection",
+				zap.Error(err),
+			)
+			continue
+		}
+
+		ServeTelnet(conn)
+	}
+}
@@ -0,0 +1,5 @@
+function character_said(text)
+  if text == "shop" then
+    shop("General")
+  end
+end
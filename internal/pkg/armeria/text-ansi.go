@@ -0,0 +1,197 @@
+package armeria
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ColorDepth is the number of colors a terminal can display.
+type ColorDepth int
+
+const (
+	ColorDepth16 ColorDepth = iota
+	ColorDepth256
+	ColorDepthTrueColor
+)
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// ansi16Colors are the RGB values of the 16 basic terminal colors, in SGR order.
+var ansi16Colors = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// ansiStyle is the styling in effect for a run of text.
+type ansiStyle struct {
+	bold      bool
+	italic    bool
+	underline bool
+	fg        string
+	bg        string
+}
+
+// writeStyled writes text wrapped in the escape codes for the style.
+func writeStyled(sb *strings.Builder, text string, style ansiStyle, depth ColorDepth) {
+	codes := style.codes(depth)
+	if len(codes) == 0 {
+		sb.WriteString(text)
+		return
+	}
+
+	sb.WriteString("\x1b[" + codes + "m" + text + "\x1b[0m")
+}
+
+// codes returns the SGR parameters for the style.
+func (s ansiStyle) codes(depth ColorDepth) string {
+	var codes []string
+	if s.bold {
+		codes = append(codes, "1")
+	}
+	if s.italic {
+		codes = append(codes, "3")
+	}
+	if s.underline {
+		codes = append(codes, "4")
+	}
+	if r, g, b, ok := parseHexColor(s.fg); ok {
+		codes = append(codes, colorCode(r, g, b, depth, false))
+	}
+	if r, g, b, ok := parseHexColor(s.bg); ok {
+		codes = append(codes, colorCode(r, g, b, depth, true))
+	}
+
+	return strings.Join(codes, ";")
+}
+
+// parseHexColor parses a CSS color such as "#ff5722", "fff" or "#fff".
+func parseHexColor(color string) (int, int, int, bool) {
+	color = strings.TrimPrefix(strings.TrimSpace(color), "#")
+	if len(color) == 3 {
+		color = string([]byte{color[0], color[0], color[1], color[1], color[2], color[2]})
+	}
+	if len(color) != 6 {
+		return 0, 0, 0, false
+	}
+
+	v, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+// colorCode returns the SGR parameter for a foreground or background color at the color depth.
+func colorCode(r, g, b int, depth ColorDepth, background bool) string {
+	base := 38
+	if background {
+		base = 48
+	}
+
+	switch depth {
+	case ColorDepthTrueColor:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base, r, g, b)
+	case ColorDepth256:
+		return fmt.Sprintf("%d;5;%d", base, nearest256Color(r, g, b))
+	}
+
+	best, bestDist := 0, -1
+	for i, c := range ansi16Colors {
+		dr, dg, db := r-c[0], g-c[1], b-c[2]
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+
+	if best < 8 {
+		return strconv.Itoa(base - 8 + best)
+	}
+	return strconv.Itoa(base + 52 + best - 8)
+}
+
+// nearest256Color returns the index of the closest color in the 6x6x6 cube or grayscale ramp of the 256 color
+// palette.
+func nearest256Color(r, g, b int) int {
+	level := func(v int) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	steps := [6]int{0, 95, 135, 175, 215, 255}
+
+	cr, cg, cb := level(r), level(g), level(b)
+	cube := 16 + 36*cr + 6*cg + cb
+	dr, dg, db := r-steps[cr], g-steps[cg], b-steps[cb]
+	cubeDist := dr*dr + dg*dg + db*db
+
+	avg := (r + g + b) / 3
+	gray := 23
+	if avg < 238 {
+		gray = (avg - 3) / 10
+	}
+	if gray < 0 {
+		gray = 0
+	}
+	gv := 8 + gray*10
+	dr, dg, db = r-gv, g-gv, b-gv
+	if dr*dr+dg*dg+db*db < cubeDist {
+		return 232 + gray
+	}
+
+	return cube
+}
+
+// visibleLength returns the number of characters in the text, ignoring escape codes.
+func visibleLength(text string) int {
+	return utf8.RuneCountInString(ansiPattern.ReplaceAllString(text, ""))
+}
+
+// ensureNewline starts a new line unless the builder is empty or already at the start of one.
+func ensureNewline(sb *strings.Builder) {
+	s := sb.String()
+	if len(s) > 0 && !strings.HasSuffix(s, "\n") {
+		sb.WriteString("\n")
+	}
+}
+
+// WrapANSI wraps text styled with ANSI escape codes at word boundaries so that no line is wider than the width.
+// Words longer than the width are left whole.
+func WrapANSI(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if visibleLength(line) <= width {
+			continue
+		}
+
+		var wrapped []string
+		var current string
+		for _, word := range strings.Split(line, " ") {
+			if len(current) > 0 && visibleLength(current)+1+visibleLength(word) > width {
+				wrapped = append(wrapped, current)
+				current = word
+				continue
+			}
+			if len(current) > 0 {
+				current += " "
+			}
+			current += word
+		}
+		lines[i] = strings.Join(append(wrapped, current), "\n")
+	}
+
+	return strings.Join(lines, "\n")
+}
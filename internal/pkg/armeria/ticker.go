@@ -23,13 +23,14 @@ type Ticker struct {
 }
 
 type TickManager struct {
-	Tickers []*Ticker
+	sync.RWMutex
+	unsafeTickers []*Ticker
 }
 
 // NewTickManager creates a new TickManager.
 func NewTickManager() *TickManager {
 	m := &TickManager{
-		Tickers: []*Ticker{
+		unsafeTickers: []*Ticker{
 			{
 				Name:      "WipeDanglingInstances",
 				Handler:   WipeDanglingInstances,
@@ -51,17 +52,51 @@ func NewTickManager() *TickManager {
 				Handler:  MobMovement,
 				Interval: 5 * time.Second,
 			},
+			{
+				Name:     "CombatRound",
+				Handler:  CombatRound,
+				Interval: 3 * time.Second,
+			},
+			{
+				Name:     "RoomTick",
+				Handler:  RoomTick,
+				Interval: 10 * time.Second,
+			},
+			{
+				Name:      "PruneExpiredSessions",
+				Handler:   PruneExpiredSessions,
+				Interval:  1 * time.Hour,
+				RunAtBoot: true,
+			},
 		},
 	}
 
-	m.Start()
+	if Armeria.snapshotInterval > 0 {
+		m.unsafeTickers = append(m.unsafeTickers, &Ticker{
+			Name:     "PeriodicSnapshot",
+			Handler:  PeriodicSnapshot,
+			Interval: Armeria.snapshotInterval,
+		})
+	}
 
 	return m
 }
 
+// Tickers returns all of the tickers.
+func (m *TickManager) Tickers() []*Ticker {
+	m.RLock()
+	defer m.RUnlock()
+
+	tickers := make([]*Ticker, len(m.unsafeTickers))
+	copy(tickers, m.unsafeTickers)
+
+	return tickers
+}
+
 // Start starts the tickers and immediately runs anything designated to run at boot.
 func (m *TickManager) Start() {
-	for _, ticker := range m.Tickers {
+	tickers := m.Tickers()
+	for _, ticker := range tickers {
 		if ticker.RunAtBoot {
 			ticker.Run()
 		}
@@ -75,16 +110,23 @@ func (m *TickManager) Start() {
 	}
 
 	Armeria.log.Info("tickers started",
-		zap.Int("count", len(m.Tickers)),
+		zap.Int("count", len(tickers)),
 	)
 }
 
+// Run runs the ticker's handler once. The lock is only held while updating the statistics, so they can be read
+// while the handler is running.
 func (t *Ticker) Run() {
+	start := time.Now()
+	t.Lock()
+	t.LastStart = start
+	t.Unlock()
+
+	Armeria.RunEvent(EventTick, t.Name, t.Handler)
+
 	t.Lock()
 	defer t.Unlock()
-	t.LastStart = time.Now()
-	t.Handler()
-	t.LastDuration = time.Since(t.LastStart)
+	t.LastDuration = time.Since(start)
 	t.Iterations = t.Iterations + 1
 }
 
@@ -149,6 +191,36 @@ func PeriodicGameSave() {
 	Armeria.Save()
 }
 
+// PruneExpiredSessions removes expired session tokens, both from memory and from the store.
+func PruneExpiredSessions() {
+	pruned := Armeria.sessionManager.PruneExpiredSessions()
+	if pruned > 0 {
+		Armeria.log.Info("expired sessions pruned",
+			zap.Int("count", pruned),
+		)
+	}
+}
+
+// RoomTick calls room_tick() within every room and area script that defines it.
+func RoomTick() {
+	for _, a := range Armeria.worldManager.Areas() {
+		if misc.Contains(a.ScriptFuncs(), "room_tick") {
+			QueueScriptFunc(nil, a, "room_tick")
+		}
+
+		for _, r := range a.Rooms() {
+			if misc.Contains(r.ScriptFuncs(), "room_tick") {
+				QueueScriptFunc(nil, r, "room_tick")
+			}
+		}
+	}
+}
+
+// CombatRound processes a single round of combat.
+func CombatRound() {
+	Armeria.combatManager.Round()
+}
+
 // MobSpawner handles the spawning of mobs into the game world from mob spawners.
 func MobSpawner() {
 	mobSpawnerItems := Armeria.itemManager.ItemsByAttribute(AttributeType, ItemTypeMobSpawner)
@@ -176,16 +248,17 @@ func MobSpawner() {
 				continue
 			}
 			// Check that the mob spawner is in a room (and not on a character, etc).
-			if inst.Room() == nil {
+			room := inst.Room()
+			if room == nil {
 				continue
 			}
 			// Spawn the mob.
 			mobInst := mob.CreateInstance()
 			mobInst.SetMobSpawnerUUID(inst.ID())
-			_ = inst.Room().Here().Add(mobInst.ID())
+			_ = room.Here().Add(mobInst.ID())
 			// Refresh the room.
 			spawnSFX := mob.Attribute(AttributeSpawnSFX)
-			for _, c := range inst.Room().Here().Characters(true) {
+			for _, c := range room.Here().Characters(true) {
 				c.Player().client.ShowText(
 					fmt.Sprintf("With a flash of light, a %s appeared out of nowhere!", mobInst.FormattedName()),
 				)
@@ -205,6 +278,10 @@ func MobMovement() {
 			if len(mi.Attribute(AttributeFollowCrumb)) == 0 {
 				continue
 			}
+			// Mobs stand their ground while fighting.
+			if Armeria.combatManager.InCombat(mi) {
+				continue
+			}
 
 			// Increment the ticks and determine if we should attempt mob movement.
 			mi.IncMoveTicks()
@@ -214,7 +291,11 @@ func MobMovement() {
 			// Reset the tick counter and attempt movement.
 			mi.ResetMoveTicks()
 			// Find a new random direction, following the crumb.
-			possibleRooms := mi.Room().AdjacentRoomsWithItem(mi.Attribute(AttributeFollowCrumb))
+			room := mi.Room()
+			if room == nil {
+				continue
+			}
+			possibleRooms := room.AdjacentRoomsWithItem(mi.Attribute(AttributeFollowCrumb))
 			dirStr, newRoom := possibleRooms.Random()
 			if newRoom == nil {
 				// Let builders know.
@@ -229,35 +310,7 @@ func MobMovement() {
 				continue
 			}
 			// Move the mob.
-			oldRoom := mi.Room()
-			oldRoom.Here().Remove(mi.ID())
-			newRoom.Here().Add(mi.ID())
-			mobNameString := fmt.Sprintf("A %s", mi.FormattedName())
-			if mi.Attribute(AttributeGender) != "thing" {
-				mobNameString = mi.FormattedName()
-			}
-			for _, c := range oldRoom.Here().Characters(true) {
-				c.Player().client.ShowText(
-					TextStyle(
-						fmt.Sprintf("%s travels %s.", mobNameString, misc.MoveToStringFromDir("to the", dirStr)),
-						WithUserColor(c, ColorMovement),
-					),
-				)
-				c.Player().client.SyncRoomObjects()
-			}
-			for _, c := range newRoom.Here().Characters(true) {
-				c.Player().client.ShowText(
-					TextStyle(
-						fmt.Sprintf(
-							"%s entered from %s.",
-							mobNameString,
-							misc.MoveToStringFromDir("the", misc.OppositeDirection(dirStr)),
-						),
-						WithUserColor(c, ColorMovement),
-					),
-				)
-				c.Player().client.SyncRoomObjects()
-			}
+			mi.Move(newRoom, dirStr)
 		}
 	}
 }
@@ -0,0 +1,447 @@
+package armeria
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bytefmt"
+	"go.uber.org/zap"
+)
+
+// Telnet commands and options, from RFC 854 and the MUD protocol extensions.
+const (
+	telnetSE   byte = 240
+	telnetSB   byte = 250
+	telnetWILL byte = 251
+	telnetWONT byte = 252
+	telnetDO   byte = 253
+	telnetDONT byte = 254
+	telnetIAC  byte = 255
+
+	telnetOptEcho  byte = 1
+	telnetOptTTYPE byte = 24
+	telnetOptNAWS  byte = 31
+	telnetOptGMCP  byte = 201
+
+	telnetTTYPEIs   byte = 0
+	telnetTTYPESend byte = 1
+)
+
+// Bits of the MUD Terminal Type Standard (MTTS) bitvector sent by MUD clients as their last terminal type.
+const (
+	mttsColors256 = 8
+	mttsTrueColor = 256
+)
+
+// telnetMaxLineSize is the longest line or subnegotiation read from a telnet client; anything beyond it is dropped.
+var telnetMaxLineSize = 512 * bytefmt.KILOBYTE
+
+// telnetGMCPPackages maps the client actions that have no text equivalent to the GMCP packages they are sent as.
+// Actions that only make sense within the web client, such as the object editor, are not sent at all.
+var telnetGMCPPackages = map[string]string{
+	"setRoomObjects":       "Room.Objects",
+	"setMapData":           "Room.Map",
+	"setCharacterLocation": "Room.Location",
+	"setRoomTitle":         "Room.Title",
+	"setInventory":         "Char.Items",
+	"setMoney":             "Char.Money",
+	"setPlayerInfo":        "Char.Info",
+	"setPermissions":       "Char.Permissions",
+	"setSettings":          "Char.Settings",
+	"setCommandDictionary": "Client.Commands",
+	"playSFX":              "Client.Sound",
+}
+
+// Force verify that TelnetTransport implements Transport.
+var _ Transport = (*TelnetTransport)(nil)
+
+// Force verify that TelnetTransport implements InputHider.
+var _ InputHider = (*TelnetTransport)(nil)
+
+// A TelnetTransport carries messages between a Player and a telnet or MUD client, such as Mudlet or TinTin++. Text
+// is rendered to ANSI, and client actions are sent as GMCP packages to clients that support it.
+type TelnetTransport struct {
+	sync.RWMutex
+	conn                net.Conn
+	reader              *bufio.Reader
+	writeMutex          sync.Mutex
+	unsafeWidth         int
+	unsafeTerminalTypes []string
+	unsafeMTTS          int
+	unsafeGMCP          bool
+	unsafeHideInput     bool
+}
+
+// NewTelnetTransport returns a new TelnetTransport for the connection, and starts negotiating the window size,
+// terminal type and GMCP with the client.
+func NewTelnetTransport(conn net.Conn) *TelnetTransport {
+	t := &TelnetTransport{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+
+	_ = t.write([]byte{
+		telnetIAC, telnetDO, telnetOptNAWS,
+		telnetIAC, telnetDO, telnetOptTTYPE,
+		telnetIAC, telnetWILL, telnetOptGMCP,
+	})
+
+	return t
+}
+
+// ReadMessage reads the next line typed by the client. Lines are sent as commands the same way the web client sends
+// them: an empty line is a /look, and a line without a leading slash is a /say. A /login without a password asks
+// for the password on its own line, which the client is asked not to show.
+func (t *TelnetTransport) ReadMessage() (*IncomingDataStructure, error) {
+	line, err := t.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := telnetCommand(line)
+	if args := strings.Fields(cmd); len(args) == 2 && strings.EqualFold(args[0], "/login") {
+		_ = t.write([]byte("Password: "))
+		t.HideInput(true)
+		password, err := t.readLine()
+		t.HideInput(false)
+		if err != nil {
+			return nil, err
+		}
+		cmd = cmd + " " + strings.TrimSpace(password)
+	}
+
+	return NewIncomingMessage("command", cmd), nil
+}
+
+// readLine reads the next line typed by the client, handling any telnet negotiation along the way.
+func (t *TelnetTransport) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := t.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch b {
+		case telnetIAC:
+			literal, err := t.readCommand()
+			if err != nil {
+				return "", err
+			}
+			if literal && len(line) < telnetMaxLineSize {
+				line = append(line, telnetIAC)
+			}
+		case '\r', 0:
+		case '\n':
+			// The client doesn't show the end of a line it was asked to hide, so the server moves on to the next.
+			if t.InputHidden() {
+				_ = t.write([]byte("\r\n"))
+			}
+			return string(line), nil
+		default:
+			if len(line) < telnetMaxLineSize {
+				line = append(line, b)
+			}
+		}
+	}
+}
+
+// telnetCommand converts a line typed by the client into a slash command.
+func telnetCommand(line string) string {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 {
+		return "/look"
+	} else if !strings.HasPrefix(line, "/") {
+		return "/say " + line
+	}
+
+	return line
+}
+
+// readCommand reads the rest of a telnet command following an IAC, returning true if it was an escaped 255 byte
+// that belongs to the line.
+func (t *TelnetTransport) readCommand() (bool, error) {
+	cmd, err := t.reader.ReadByte()
+	if err != nil {
+		return false, err
+	}
+
+	switch cmd {
+	case telnetIAC:
+		return true, nil
+	case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+		opt, err := t.reader.ReadByte()
+		if err != nil {
+			return false, err
+		}
+		t.negotiate(cmd, opt)
+	case telnetSB:
+		data, err := t.readSubnegotiation()
+		if err != nil {
+			return false, err
+		}
+		t.subnegotiate(data)
+	}
+
+	return false, nil
+}
+
+// readSubnegotiation reads the data of a subnegotiation, up to and excluding the IAC SE that ends it.
+func (t *TelnetTransport) readSubnegotiation() ([]byte, error) {
+	var data []byte
+	for {
+		b, err := t.reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == telnetIAC {
+			b, err = t.reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if b == telnetSE {
+				return data, nil
+			}
+		}
+
+		if len(data) < telnetMaxLineSize {
+			data = append(data, b)
+		}
+	}
+}
+
+// negotiate handles the client's answer to an option. Options the server never asked for are refused.
+func (t *TelnetTransport) negotiate(cmd, opt byte) {
+	switch {
+	case cmd == telnetWILL && opt == telnetOptTTYPE:
+		_ = t.write([]byte{telnetIAC, telnetSB, telnetOptTTYPE, telnetTTYPESend, telnetIAC, telnetSE})
+	case cmd == telnetWILL && opt == telnetOptNAWS:
+		// The client sends its window size straight away.
+	case cmd == telnetDO && opt == telnetOptGMCP:
+		t.Lock()
+		t.unsafeGMCP = true
+		t.Unlock()
+	case cmd == telnetDONT && opt == telnetOptGMCP:
+		t.Lock()
+		t.unsafeGMCP = false
+		t.Unlock()
+	case opt == telnetOptEcho && (cmd == telnetDO || cmd == telnetDONT):
+		// The client's answer to HideInput, which needs no reply.
+	case cmd == telnetWILL:
+		_ = t.write([]byte{telnetIAC, telnetDONT, opt})
+	case cmd == telnetDO:
+		_ = t.write([]byte{telnetIAC, telnetWONT, opt})
+	}
+}
+
+// subnegotiate handles the data the client sent for an option.
+func (t *TelnetTransport) subnegotiate(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	switch data[0] {
+	case telnetOptNAWS:
+		if len(data) < 5 {
+			return
+		}
+		// Only the width is kept, for wrapping text; MUD clients scroll, so the height doesn't matter.
+		t.Lock()
+		t.unsafeWidth = int(data[1])<<8 | int(data[2])
+		t.Unlock()
+	case telnetOptTTYPE:
+		if len(data) < 2 || data[1] != telnetTTYPEIs {
+			return
+		}
+		t.addTerminalType(strings.ToUpper(string(data[2:])))
+	case telnetOptGMCP:
+		pkg := strings.SplitN(string(data[1:]), " ", 2)[0]
+		if strings.EqualFold(pkg, "Core.Ping") {
+			_ = t.writeGMCP("Core.Ping", nil)
+		}
+	}
+}
+
+// addTerminalType records a terminal type sent by the client. Clients send a different type each time they are
+// asked, repeating the last one once they have run out, so the server keeps asking until a type repeats.
+func (t *TelnetTransport) addTerminalType(ttype string) {
+	t.Lock()
+	types := t.unsafeTerminalTypes
+	if len(types) > 0 && types[len(types)-1] == ttype {
+		t.Unlock()
+		return
+	}
+	t.unsafeTerminalTypes = append(types, ttype)
+	if strings.HasPrefix(ttype, "MTTS ") {
+		t.unsafeMTTS, _ = strconv.Atoi(strings.TrimPrefix(ttype, "MTTS "))
+	}
+	count := len(t.unsafeTerminalTypes)
+	t.Unlock()
+
+	Armeria.log.Debug("telnet terminal type received",
+		zap.String("ip", t.RemoteAddr()),
+		zap.String("ttype", ttype),
+	)
+
+	if count < 3 {
+		_ = t.write([]byte{telnetIAC, telnetSB, telnetOptTTYPE, telnetTTYPESend, telnetIAC, telnetSE})
+	}
+}
+
+// Width returns the width of the client's window, or 0 if the client hasn't sent it.
+func (t *TelnetTransport) Width() int {
+	t.RLock()
+	defer t.RUnlock()
+	return t.unsafeWidth
+}
+
+// GMCP returns whether the client has agreed to receive GMCP packages.
+func (t *TelnetTransport) GMCP() bool {
+	t.RLock()
+	defer t.RUnlock()
+	return t.unsafeGMCP
+}
+
+// HideInput asks the client to stop showing what is typed, by offering to echo it from the server instead, or to
+// start showing it again. The server never actually echoes, so what is typed isn't shown at all.
+func (t *TelnetTransport) HideInput(hidden bool) {
+	t.Lock()
+	changed := t.unsafeHideInput != hidden
+	t.unsafeHideInput = hidden
+	t.Unlock()
+
+	if !changed {
+		return
+	} else if hidden {
+		_ = t.write([]byte{telnetIAC, telnetWILL, telnetOptEcho})
+	} else {
+		_ = t.write([]byte{telnetIAC, telnetWONT, telnetOptEcho})
+	}
+}
+
+// InputHidden returns whether the client has been asked not to show what is typed.
+func (t *TelnetTransport) InputHidden() bool {
+	t.RLock()
+	defer t.RUnlock()
+	return t.unsafeHideInput
+}
+
+// ColorDepth returns the number of colors the client can display, based on its terminal types. Clients that don't
+// say are assumed to support 256 colors.
+func (t *TelnetTransport) ColorDepth() ColorDepth {
+	t.RLock()
+	defer t.RUnlock()
+
+	if t.unsafeMTTS&mttsTrueColor != 0 {
+		return ColorDepthTrueColor
+	} else if t.unsafeMTTS&mttsColors256 != 0 {
+		return ColorDepth256
+	}
+
+	for _, ttype := range t.unsafeTerminalTypes {
+		if strings.Contains(ttype, "TRUECOLOR") {
+			return ColorDepthTrueColor
+		} else if strings.Contains(ttype, "256") {
+			return ColorDepth256
+		} else if ttype == "ANSI" || ttype == "VT100" {
+			return ColorDepth16
+		}
+	}
+
+	return ColorDepth256
+}
+
+// Dumb returns whether the client's terminal can't display any styling at all.
+func (t *TelnetTransport) Dumb() bool {
+	t.RLock()
+	defer t.RUnlock()
+
+	for _, ttype := range t.unsafeTerminalTypes {
+		if ttype == "DUMB" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteMessage renders text to ANSI (or plain text, for dumb terminals) for the client, and sends other client
+// actions as GMCP packages.
+func (t *TelnetTransport) WriteMessage(m *OutgoingDataStructure) error {
+	switch m.Action {
+	case "showText":
+		rt, ok := m.Payload.(*RichText)
+		if !ok {
+			rt = ParseRichText(fmt.Sprintf("%v", m.Payload))
+		}
+
+		var text string
+		if t.Dumb() {
+			text = rt.Plain()
+		} else {
+			text = rt.ANSI(t.ColorDepth())
+		}
+		text = WrapANSI(text, t.Width())
+		return t.write([]byte(strings.Replace(text+"\n", "\n", "\r\n", -1)))
+	case "disconnect":
+		return t.Close()
+	}
+
+	if pkg, ok := telnetGMCPPackages[m.Action]; ok && t.GMCP() {
+		return t.writeGMCP(pkg, m.Payload)
+	}
+
+	return nil
+}
+
+// writeGMCP sends a GMCP package to the client. Payloads that are already JSON are sent as they are.
+func (t *TelnetTransport) writeGMCP(pkg string, payload interface{}) error {
+	msg := []byte{telnetIAC, telnetSB, telnetOptGMCP}
+	msg = append(msg, pkg...)
+
+	if payload != nil {
+		data, ok := payload.(string)
+		if !ok || !json.Valid([]byte(data)) {
+			j, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			data = string(j)
+		}
+		msg = append(msg, ' ')
+		msg = append(msg, data...)
+	}
+
+	return t.write(append(msg, telnetIAC, telnetSE))
+}
+
+// write writes raw bytes to the connection, giving up if it takes longer than 10 seconds. Negotiation is answered by
+// the read pump while the write pump is sending messages, so writes are serialized.
+func (t *TelnetTransport) write(b []byte) error {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+
+	if err := t.conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return err
+	}
+
+	_, err := t.conn.Write(b)
+	return err
+}
+
+// Close closes the telnet connection.
+func (t *TelnetTransport) Close() error {
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the telnet client.
+func (t *TelnetTransport) RemoteAddr() string {
+	return t.conn.RemoteAddr().String()
+}
@@ -0,0 +1,207 @@
+package armeria
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelnetNegotiation(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	// The pipe is synchronous, so everything the server writes is drained in the background.
+	received := make(chan []byte, 64)
+	go func() {
+		for {
+			b := make([]byte, 1024)
+			n, err := client.Read(b)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- b[:n]
+		}
+	}()
+
+	tt := NewTelnetTransport(server)
+	defer tt.Close()
+
+	go func() {
+		_, _ = client.Write([]byte{
+			telnetIAC, telnetWILL, telnetOptNAWS,
+			telnetIAC, telnetSB, telnetOptNAWS, 0, 100, 0, 40, telnetIAC, telnetSE,
+			telnetIAC, telnetDO, telnetOptGMCP,
+			telnetIAC, telnetSB, telnetOptTTYPE, telnetTTYPEIs, 'M', 'U', 'D', 'L', 'E', 'T', telnetIAC, telnetSE,
+			telnetIAC, telnetSB, telnetOptTTYPE, telnetTTYPEIs, 'M', 'T', 'T', 'S', ' ', '2', '6', '9', telnetIAC, telnetSE,
+		})
+		_, _ = client.Write([]byte("north\r\n"))
+		_, _ = client.Write([]byte("/move north\r\n"))
+	}()
+
+	for _, expected := range []string{"/say north", "/move north"} {
+		m, err := tt.ReadMessage()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		msg, err := DecodeIncomingMessage(m)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if cmd, ok := msg.(*CommandMessage); !ok || string(*cmd) != expected {
+			t.Errorf("expected command %q, got %s %s", expected, m.Type, m.Payload)
+		}
+	}
+
+	if tt.Width() != 100 {
+		t.Errorf("expected a width of 100, got %d", tt.Width())
+	}
+	if !tt.GMCP() {
+		t.Error("expected GMCP to be enabled")
+	}
+	if tt.ColorDepth() != ColorDepthTrueColor {
+		t.Errorf("expected true color, got %d", tt.ColorDepth())
+	}
+
+	if err := tt.WriteMessage(&OutgoingDataStructure{Action: "setRoomTitle", Payload: "Town Square"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var out []byte
+	deadline := time.After(5 * time.Second)
+	for !strings.Contains(string(out), `Room.Title "Town Square"`) {
+		select {
+		case b := <-received:
+			out = append(out, b...)
+		case <-deadline:
+			t.Fatalf("timed out waiting for the GMCP package, got %q", out)
+		}
+	}
+}
+
+func TestWrapANSI(t *testing.T) {
+	text := "\x1b[1mThe\x1b[0m quick brown fox jumps"
+	expected := "\x1b[1mThe\x1b[0m quick\nbrown fox\njumps"
+
+	if actual := WrapANSI(text, 10); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+// drainTelnet reads everything the server writes to the client end of a pipe in the background, since the pipe is
+// synchronous.
+func drainTelnet(client net.Conn) <-chan []byte {
+	received := make(chan []byte, 64)
+	go func() {
+		for {
+			b := make([]byte, 1024)
+			n, err := client.Read(b)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- b[:n]
+		}
+	}()
+
+	return received
+}
+
+// waitForTelnet reads what the server sent until it contains the bytes, and returns everything read.
+func waitForTelnet(t *testing.T, received <-chan []byte, expected []byte) []byte {
+	t.Helper()
+
+	var out []byte
+	deadline := time.After(5 * time.Second)
+	for !bytes.Contains(out, expected) {
+		select {
+		case b := <-received:
+			out = append(out, b...)
+		case <-deadline:
+			t.Fatalf("timed out waiting for %q, got %q", expected, out)
+		}
+	}
+
+	return out
+}
+
+func TestTelnetLoginPassword(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	server, client := net.Pipe()
+	defer client.Close()
+	received := drainTelnet(client)
+
+	tt := NewTelnetTransport(server)
+	defer tt.Close()
+
+	messages := make(chan *IncomingDataStructure, 1)
+	go func() {
+		m, err := tt.ReadMessage()
+		if err != nil {
+			close(messages)
+			return
+		}
+		messages <- m
+	}()
+
+	// The password is asked for on its own line, which the client is asked not to show.
+	_, _ = client.Write([]byte("/login tester\r\n"))
+	waitForTelnet(t, received, append([]byte("Password: "), telnetIAC, telnetWILL, telnetOptEcho))
+	_, _ = client.Write([]byte{telnetIAC, telnetDO, telnetOptEcho})
+	_, _ = client.Write([]byte("secret\r\n"))
+	out := waitForTelnet(t, received, []byte{telnetIAC, telnetWONT, telnetOptEcho})
+	if !bytes.HasPrefix(out, []byte("\r\n")) {
+		t.Errorf("expected a new line once the password was entered, got %q", out)
+	}
+
+	m, ok := <-messages
+	if !ok {
+		t.Fatal("expected a message")
+	}
+	msg, err := DecodeIncomingMessage(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cmd, ok := msg.(*CommandMessage); !ok || string(*cmd) != "/login tester secret" {
+		t.Errorf("expected the login command, got %s %s", m.Type, m.Payload)
+	}
+}
+
+func TestTelnetCreatePassword(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	server, client := net.Pipe()
+	defer client.Close()
+	received := drainTelnet(client)
+
+	tt := NewTelnetTransport(server)
+	defer tt.Close()
+
+	// The Player's pumps aren't started, so that nothing is left running once the test is over.
+	p := Armeria.playerManager.NewPlayer(tt)
+	send := func(command string) {
+		Armeria.RunEvent(EventMessage, "command", func() {
+			Armeria.commandManager.ProcessCommand(p, command, true)
+		})
+		p.FlushWrites()
+	}
+
+	// The client is asked not to show what is typed while the wizard asks for the password.
+	send("create Dave")
+	waitForTelnet(t, received, []byte{telnetIAC, telnetWILL, telnetOptEcho})
+
+	send("create secret")
+	out := waitForTelnet(t, received, []byte("Confirm your password"))
+	if bytes.Contains(out, []byte{telnetIAC, telnetWONT, telnetOptEcho}) {
+		t.Errorf("expected the password to stay hidden while it is confirmed, got %q", out)
+	}
+
+	send("create secret")
+	waitForTelnet(t, received, []byte{telnetIAC, telnetWONT, telnetOptEcho})
+}
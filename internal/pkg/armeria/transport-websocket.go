@@ -0,0 +1,106 @@
+package armeria
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bytefmt"
+	"github.com/gorilla/websocket"
+)
+
+// Force verify that WebsocketTransport implements Transport.
+var _ Transport = (*WebsocketTransport)(nil)
+
+// Force verify that WebsocketTransport implements TextFormatSelector.
+var _ TextFormatSelector = (*WebsocketTransport)(nil)
+
+// Force verify that WebsocketTransport implements ProtocolNegotiator.
+var _ ProtocolNegotiator = (*WebsocketTransport)(nil)
+
+// A WebsocketTransport carries JSON messages between a Player and the web client. Text is sent as HTML unless the
+// client asks for another format, and messages are encoded for the oldest version of the protocol until the client
+// negotiates a newer one.
+type WebsocketTransport struct {
+	sync.RWMutex
+	conn                  *websocket.Conn
+	unsafeTextFormat      TextFormat
+	unsafeProtocolVersion int
+}
+
+// NewWebsocketTransport returns a new WebsocketTransport for the upgraded connection.
+func NewWebsocketTransport(conn *websocket.Conn) *WebsocketTransport {
+	// Set max size of a single message to 512KB
+	conn.SetReadLimit(512 * bytefmt.KILOBYTE)
+
+	return &WebsocketTransport{
+		conn:                  conn,
+		unsafeTextFormat:      TextFormatHTML,
+		unsafeProtocolVersion: MinProtocolVersion,
+	}
+}
+
+// ReadMessage reads the next JSON message from the web client.
+func (t *WebsocketTransport) ReadMessage() (*IncomingDataStructure, error) {
+	m := &IncomingDataStructure{}
+	if err := t.conn.ReadJSON(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// SetTextFormat sets the format the client wants text sent in.
+func (t *WebsocketTransport) SetTextFormat(f TextFormat) {
+	t.Lock()
+	defer t.Unlock()
+	t.unsafeTextFormat = f
+}
+
+// TextFormat returns the format text is sent to the client in.
+func (t *WebsocketTransport) TextFormat() TextFormat {
+	t.RLock()
+	defer t.RUnlock()
+	return t.unsafeTextFormat
+}
+
+// SetProtocolVersion sets the version of the protocol to speak with the client.
+func (t *WebsocketTransport) SetProtocolVersion(v int) {
+	t.Lock()
+	defer t.Unlock()
+	t.unsafeProtocolVersion = v
+}
+
+// ProtocolVersion returns the version of the protocol spoken with the client.
+func (t *WebsocketTransport) ProtocolVersion() int {
+	t.RLock()
+	defer t.RUnlock()
+	return t.unsafeProtocolVersion
+}
+
+// WriteMessage writes a JSON message to the web client, giving up if it takes longer than 10 seconds.
+func (t *WebsocketTransport) WriteMessage(m *OutgoingDataStructure) error {
+	if rt, ok := m.Payload.(*RichText); ok {
+		m = &OutgoingDataStructure{Action: m.Action, Payload: rt.Render(t.TextFormat())}
+	}
+
+	m, err := EncodeForProtocolVersion(m, t.ProtocolVersion())
+	if err != nil {
+		return err
+	}
+
+	if err := t.conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return err
+	}
+
+	return t.conn.WriteJSON(m)
+}
+
+// Close closes the websocket connection.
+func (t *WebsocketTransport) Close() error {
+	return t.conn.Close()
+}
+
+// RemoteAddr returns the address of the web client.
+func (t *WebsocketTransport) RemoteAddr() string {
+	return t.conn.RemoteAddr().String()
+}
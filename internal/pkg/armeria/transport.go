@@ -0,0 +1,38 @@
+package armeria
+
+// A Transport carries messages between a Player and their client, such as over a websocket. Text is shown with a
+// *RichText payload, which the Transport renders however suits its client. ReadMessage is only ever called by the
+// Player's read pump, and WriteMessage by its write pump (or while flushing writes), so an implementation does not
+// need to support concurrent reads or concurrent writes. Close may be called at any time, and must cause a blocked
+// ReadMessage to return an error.
+type Transport interface {
+	// ReadMessage blocks until the client sends a message, returning an error once the connection is closed.
+	ReadMessage() (*IncomingDataStructure, error)
+	// WriteMessage sends a message to the client.
+	WriteMessage(m *OutgoingDataStructure) error
+	// Close closes the connection.
+	Close() error
+	// RemoteAddr returns the address of the client.
+	RemoteAddr() string
+}
+
+// A TextFormatSelector is a Transport whose client can choose the format that text is sent in.
+type TextFormatSelector interface {
+	Transport
+	// SetTextFormat sets the format the client wants text sent in.
+	SetTextFormat(f TextFormat)
+}
+
+// An InputHider is a Transport whose client can be asked not to show what is typed, such as a password.
+type InputHider interface {
+	Transport
+	// HideInput asks the client to stop showing what is typed, or to start showing it again.
+	HideInput(hidden bool)
+}
+
+// A ProtocolNegotiator is a Transport whose client can negotiate the version of the protocol spoken with it.
+type ProtocolNegotiator interface {
+	Transport
+	// SetProtocolVersion sets the version of the protocol to speak with the client.
+	SetProtocolVersion(v int)
+}
@@ -0,0 +1,106 @@
+package armeria
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A chanTransport is a Transport whose reads and writes go through channels, so that a test can play the part of
+// the client.
+type chanTransport struct {
+	in     chan *IncomingDataStructure
+	out    chan *OutgoingDataStructure
+	closed chan struct{}
+}
+
+func newChanTransport() *chanTransport {
+	return &chanTransport{
+		in:     make(chan *IncomingDataStructure),
+		out:    make(chan *OutgoingDataStructure, 256),
+		closed: make(chan struct{}),
+	}
+}
+
+func (t *chanTransport) ReadMessage() (*IncomingDataStructure, error) {
+	select {
+	case m := <-t.in:
+		return m, nil
+	case <-t.closed:
+		return nil, io.EOF
+	}
+}
+
+func (t *chanTransport) WriteMessage(m *OutgoingDataStructure) error {
+	t.out <- m
+	return nil
+}
+
+func (t *chanTransport) Close() error {
+	select {
+	case <-t.closed:
+	default:
+		close(t.closed)
+	}
+	return nil
+}
+
+func (t *chanTransport) RemoteAddr() string {
+	return "test"
+}
+
+// expectWrite waits for the next message written to the transport, failing the test unless it is for the action.
+func expectWrite(t *testing.T, ct *chanTransport, action string) *OutgoingDataStructure {
+	t.Helper()
+	select {
+	case m := <-ct.out:
+		if m.Action != action {
+			t.Fatalf("expected %s, got %s", action, m.Action)
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", action)
+	}
+	return nil
+}
+
+func TestTransportPumps(t *testing.T) {
+	defer os.RemoveAll(useHeadlessState(t))
+
+	ct := newChanTransport()
+	p := Armeria.playerManager.NewPlayer(ct)
+
+	// The pumps are started by hand, rather than with SetupPumps, so the test can wait for them to stop.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.readPump()
+	}()
+	go func() {
+		defer wg.Done()
+		p.writePump()
+	}()
+
+	ct.in <- NewIncomingMessage("ping", nil)
+	expectWrite(t, ct, "pong")
+
+	ct.in <- NewIncomingMessage("command", "/login tester secret")
+	m := expectWrite(t, ct, "showText")
+	if text := m.Payload.(*RichText).Plain(); !strings.Contains(text, "You've logged in to tester.") {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	// Closing the transport stops both pumps and disconnects the player.
+	_ = ct.Close()
+	waitOrDeadlock(t, &wg)
+
+	Armeria.playerManager.RLock()
+	defer Armeria.playerManager.RUnlock()
+	if Armeria.playerManager.players[p] {
+		t.Error("expected the player to be disconnected")
+	}
+}
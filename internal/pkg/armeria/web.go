@@ -11,86 +11,177 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// AccessTokenHeader is the HTTP header containing the session token for authenticated routes.
+const AccessTokenHeader string = "X-Access-Token"
+
+// characterFromRequest returns the online Character belonging to the Account of the request's session token.
+func characterFromRequest(r *http.Request) *Character {
+	s := Armeria.sessionManager.SessionByToken(r.Header.Get(AccessTokenHeader))
+	if s == nil {
+		return nil
+	}
+
+	a := Armeria.accountManager.AccountById(s.AccountID())
+	if a == nil || a.Banned() {
+		return nil
+	}
+
+	for _, c := range a.Characters() {
+		if c.Online() && c.HasPermission(PermissionBuild) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// HandleScriptRead responds with the script of a mob, item, room or area. The game objects are only read within
+// an event, so that the request is ordered along with everything else that touches them.
 func HandleScriptRead(w http.ResponseWriter, r *http.Request) {
 	v := mux.Vars(r)
-	ot := v["objectType"]
-	on := v["objectName"]
-	an := v["accessName"]
-	ak := v["accessKey"]
 
-	c := Armeria.characterManager.CharacterByName(an)
-	if c == nil {
-		w.WriteHeader(http.StatusUnauthorized)
+	var s string
+	var status int
+	Armeria.RunEvent(EventHTTP, "script read", func() {
+		s, status = readScript(r, v["objectType"], v["objectName"])
+	})
+
+	if status != http.StatusOK {
+		w.WriteHeader(status)
 		return
 	}
 
-	if c.PasswordHash() != ak {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+	_, _ = w.Write([]byte(s))
+}
+
+// readScript returns the script of the object, along with the status to respond with.
+func readScript(r *http.Request, ot string, on string) (string, int) {
+	c := characterFromRequest(r)
+	if c == nil {
+		return "", http.StatusUnauthorized
 	}
 
-	if ot != "mob" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	switch ot {
+	case "mob":
+		m := Armeria.mobManager.MobByName(on)
+		if m == nil {
+			return "", http.StatusBadRequest
+		}
+		return ReadMobScript(m), http.StatusOK
+	case "item":
+		i := Armeria.itemManager.ItemByName(on)
+		if i == nil {
+			return "", http.StatusBadRequest
+		}
+		return ReadItemScript(i), http.StatusOK
+	case "room":
+		rm, rt := Armeria.registry.Get(on)
+		if rt != RegistryTypeRoom {
+			return "", http.StatusBadRequest
+		}
+		return ReadRoomScript(rm.(*Room)), http.StatusOK
+	case "area":
+		a := Armeria.worldManager.AreaByName(on)
+		if a == nil {
+			return "", http.StatusBadRequest
+		}
+		return ReadAreaScript(a), http.StatusOK
 	}
 
-	m := Armeria.mobManager.MobByName(on)
-	if m == nil {
+	return "", http.StatusBadRequest
+}
+
+// HandleScriptWrite replaces the script of a mob, item, room or area. The request body is read first, and the
+// script is then written within an event, like any other change to the game world.
+func HandleScriptWrite(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+
+	script, err := ioutil.ReadAll(r.Body)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	s := ReadMobScript(m)
+	var status int
+	Armeria.RunEvent(EventHTTP, "script write", func() {
+		status = writeScript(r, v["objectType"], v["objectName"], string(script))
+	})
 
-	_, _ = w.Write([]byte(s))
+	w.WriteHeader(status)
 }
 
-func HandleScriptWrite(w http.ResponseWriter, r *http.Request) {
-	v := mux.Vars(r)
-	ot := v["objectType"]
-	on := v["objectName"]
-	an := v["accessName"]
-	ak := v["accessKey"]
-
-	c := Armeria.characterManager.CharacterByName(an)
+// writeScript replaces the script of the object, returning the status to respond with.
+func writeScript(r *http.Request, ot string, on string, script string) int {
+	c := characterFromRequest(r)
 	if c == nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+		return http.StatusUnauthorized
 	}
 
-	if c.PasswordHash() != ak {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+	var m *Mob
+	var i *Item
+	var rm *Room
+	var a *Area
+	switch ot {
+	case "mob":
+		m = Armeria.mobManager.MobByName(on)
+	case "item":
+		i = Armeria.itemManager.ItemByName(on)
+	case "room":
+		if o, rt := Armeria.registry.Get(on); rt == RegistryTypeRoom {
+			rm = o.(*Room)
+		}
+	case "area":
+		a = Armeria.worldManager.AreaByName(on)
 	}
 
-	if ot != "mob" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	if m == nil && i == nil && rm == nil && a == nil {
+		return http.StatusBadRequest
 	}
 
-	m := Armeria.mobManager.MobByName(on)
-	if m == nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	if (m != nil && !m.CanBuild(c)) ||
+		(i != nil && !i.CanBuild(c)) ||
+		(rm != nil && !rm.ParentArea.CanBuild(c)) ||
+		(a != nil && !a.CanBuild(c)) {
+		return http.StatusForbidden
 	}
 
-	script, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	Armeria.AnnotateEvent(func(e *Event) {
+		e.Character = c.Name()
+	})
+
+	var name string
+	var change *AuditChange
+	if m != nil {
+		WriteMobScript(m, script)
+		name = m.Name()
+		change = NewAuditChange(ObjectTypeMob, m)
+	} else if i != nil {
+		WriteItemScript(i, script)
+		name = i.Name()
+		change = NewAuditChange(ObjectTypeItem, i)
+	} else if rm != nil {
+		WriteRoomScript(rm, script)
+		name = rm.Name()
+		change = NewAuditChange(ObjectTypeRoom, rm)
+	} else {
+		WriteAreaScript(a, script)
+		name = a.Name()
+		change = NewAuditChange(ObjectTypeArea, a)
 	}
 
-	WriteMobScript(m, string(script))
+	// Scripts are too long to be copied into the audit log, so only the fact that one changed is recorded.
+	change.Attribute = "script"
+	Armeria.auditLog.Record(NewAuditEntry(c, "script editor", change))
 
 	cp := c.Player()
 	if cp != nil {
 		cp.client.ShowColorizedText(
-			fmt.Sprintf("The script has been saved to %s.", TextStyle(m.UnsafeName, WithBold())),
+			fmt.Sprintf("The script has been saved to %s.", TextStyle(name, WithBold())),
 			ColorSuccess,
 		)
 	}
 
-	w.WriteHeader(http.StatusOK)
+	return http.StatusOK
 }
 
 // InitWeb will initialize the HTTP web server, for serving the web client
@@ -118,8 +209,8 @@ func InitWeb(port int) {
 		r.PathPrefix(route).Handler(http.FileServer(http.Dir(Armeria.publicPath)))
 	}
 	r.PathPrefix("/oi/").Handler(http.StripPrefix("/oi/", http.FileServer(http.Dir(Armeria.objectImagesPath))))
-	r.HandleFunc("/script/{objectType}/{objectName}/{accessName}/{accessKey}", HandleScriptRead).Methods("GET")
-	r.HandleFunc("/script/{objectType}/{objectName}/{accessName}/{accessKey}", HandleScriptWrite).Methods("POST")
+	r.HandleFunc("/script/{objectType}/{objectName}", HandleScriptRead).Methods("GET")
+	r.HandleFunc("/script/{objectType}/{objectName}", HandleScriptWrite).Methods("POST")
 	r.PathPrefix("/ws").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ServeWs(w, r)
 	})
@@ -128,7 +219,10 @@ func InitWeb(port int) {
 	})
 
 	err := http.ListenAndServe(fmt.Sprintf(":%d", port),
-		handlers.CORS(handlers.AllowedOrigins([]string{"*"}))(r),
+		handlers.CORS(
+			handlers.AllowedOrigins([]string{"*"}),
+			handlers.AllowedHeaders([]string{"Content-Type", AccessTokenHeader}),
+		)(r),
 	)
 
 	if err != nil {
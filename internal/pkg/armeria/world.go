@@ -1,9 +1,6 @@
 package armeria
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
 	"strings"
 	"sync"
 
@@ -14,14 +11,11 @@ import (
 
 type WorldManager struct {
 	sync.RWMutex
-	dataFile    string
 	UnsafeWorld []*Area `json:"world"`
 }
 
 func NewWorldManager() *WorldManager {
-	m := &WorldManager{
-		dataFile: fmt.Sprintf("%s/world.json", Armeria.dataPath),
-	}
+	m := &WorldManager{}
 
 	m.LoadWorld()
 
@@ -32,22 +26,18 @@ func (m *WorldManager) LoadWorld() {
 	m.Lock()
 	defer m.Unlock()
 
-	worldFile, err := os.Open(m.dataFile)
-	defer worldFile.Close()
-
+	records, err := Armeria.store.Load(CollectionWorld)
 	if err != nil {
-		Armeria.log.Fatal("failed to load data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to load data from store",
+			zap.String("collection", CollectionWorld.Name),
 			zap.Error(err),
 		)
 	}
 
-	jsonParser := json.NewDecoder(worldFile)
-
-	err = jsonParser.Decode(m)
+	err = DecodeStoreRecords(records, &m.UnsafeWorld)
 	if err != nil {
-		Armeria.log.Fatal("failed to decode data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to decode data from store",
+			zap.String("collection", CollectionWorld.Name),
 			zap.Error(err),
 		)
 	}
@@ -69,32 +59,32 @@ func (m *WorldManager) SaveWorld() {
 	m.RLock()
 	defer m.RUnlock()
 
-	worldFile, err := os.Create(m.dataFile)
-	defer worldFile.Close()
-
-	raw, err := json.Marshal(m)
+	records, err := EncodeStoreRecords(CollectionWorld, m.UnsafeWorld)
 	if err != nil {
 		Armeria.log.Fatal("failed to marshal data",
 			zap.Error(err),
 		)
 	}
 
-	bytes, err := worldFile.Write(raw)
+	err = Armeria.store.Save(CollectionWorld, records)
 	if err != nil {
-		Armeria.log.Fatal("failed to write data file",
-			zap.String("file", m.dataFile),
+		Armeria.log.Fatal("failed to write data to store",
+			zap.String("collection", CollectionWorld.Name),
 			zap.Error(err),
 		)
 	}
 
-	_ = worldFile.Sync()
-
-	Armeria.log.Info("wrote data to file",
-		zap.String("file", m.dataFile),
-		zap.Int("bytes", bytes),
+	Armeria.log.Info("wrote data to store",
+		zap.String("collection", CollectionWorld.Name),
+		zap.Int("records", len(records)),
 	)
 }
 
+// SaveArea immediately persists a single Area, along with its rooms, to the store.
+func (m *WorldManager) SaveArea(a *Area) {
+	persistRecord(CollectionWorld, a)
+}
+
 func (m *WorldManager) CreateRoom(a *Area, c *Coords) *Room {
 	r := &Room{
 		UUID:             uuid.New().String(),
@@ -108,9 +98,6 @@ func (m *WorldManager) CreateRoom(a *Area, c *Coords) *Room {
 }
 
 func (m *WorldManager) CreateArea(name string) *Area {
-	m.Lock()
-	defer m.Unlock()
-
 	a := &Area{
 		UUID:             uuid.New().String(),
 		UnsafeName:       name,
@@ -121,7 +108,11 @@ func (m *WorldManager) CreateArea(name string) *Area {
 
 	_ = m.CreateRoom(a, NewCoords(0, 0, 0, 0))
 
+	m.Lock()
 	m.UnsafeWorld = append(m.UnsafeWorld, a)
+	m.Unlock()
+
+	m.SaveArea(a)
 
 	return a
 }
@@ -139,9 +130,33 @@ func (m *WorldManager) AreaByName(name string) *Area {
 	return nil
 }
 
+// Areas returns all of the in-memory Areas.
 func (m *WorldManager) Areas() []*Area {
 	m.RLock()
 	defer m.RUnlock()
 
-	return m.UnsafeWorld
+	areas := make([]*Area, len(m.UnsafeWorld))
+	copy(areas, m.UnsafeWorld)
+
+	return areas
+}
+
+// RoomFromLocationString returns the Room matching a location string in the format of [area],[x],[y],[z].
+func (m *WorldManager) RoomFromLocationString(loc string) *Room {
+	sections := strings.Split(loc, ",")
+	if len(sections) != 4 {
+		return nil
+	}
+
+	a := m.AreaByName(sections[0])
+	if a == nil {
+		return nil
+	}
+
+	c := NewCoordsFromString(strings.Join(sections[1:], ","))
+	if c == nil {
+		return nil
+	}
+
+	return a.RoomAt(c)
 }
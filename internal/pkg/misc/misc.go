@@ -110,9 +110,14 @@ func MoveFromStringFromDir(prefix, dir string) string {
 	}
 }
 
+// The random number generator is seeded once, rather than on every call, so that numbers drawn within the same
+// second aren't all the same.
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
 // RandomInt returns an int between [0,max].
 func RandomInt(max int) int {
-	rand.Seed(time.Now().Unix())
 	return rand.Intn(max)
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type ValidationResult struct {
@@ -25,6 +26,12 @@ const (
 	Num = "num"
 	// Empty checks if the input string is empty.
 	Empty = "empty"
+	// Alpha checks if the input string only contains the letters a-z and A-Z.
+	Alpha = "alpha"
+	// MinLen checks if the input string is at least the min length (inclusive).
+	MinLen = "minlen"
+	// MaxLen checks if the input string is at most the max length (inclusive).
+	MaxLen = "maxlen"
 )
 
 func Check(str, validatorString string) ValidationResult {
@@ -48,6 +55,12 @@ func Check(str, validatorString string) ValidationResult {
 			validate(&result, Num, checkNum(str))
 		case Empty:
 			validate(&result, Empty, checkEmpty(str))
+		case Alpha:
+			validate(&result, Alpha, checkAlpha(str))
+		case MinLen:
+			validate(&result, MinLen, checkMinLen(str, sections[1]))
+		case MaxLen:
+			validate(&result, MaxLen, checkMaxLen(str, sections[1]))
 		}
 	}
 
@@ -148,6 +161,44 @@ func checkEmpty(str string) string {
 	return ""
 }
 
+// checkAlpha only allows ASCII letters, so that a name can't get past a check against reserved names by using a
+// letter from another alphabet that looks the same.
+func checkAlpha(str string) string {
+	for _, r := range str {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return "contains non-letter characters"
+		}
+	}
+
+	return ""
+}
+
+func checkMinLen(str, min string) string {
+	m, err := strconv.Atoi(min)
+	if err != nil {
+		return "min length not an int"
+	}
+
+	if utf8.RuneCountInString(str) < m {
+		return fmt.Sprintf("shorter than %d characters", m)
+	}
+
+	return ""
+}
+
+func checkMaxLen(str, max string) string {
+	m, err := strconv.Atoi(max)
+	if err != nil {
+		return "max length not an int"
+	}
+
+	if utf8.RuneCountInString(str) > m {
+		return fmt.Sprintf("longer than %d characters", m)
+	}
+
+	return ""
+}
+
 // OnlyErrors returns a slice of only the error strings. Useful for sending back to the client when needed.
 func (vr ValidationResult) OnlyErrors() []string {
 	errors := make([]string, 0)
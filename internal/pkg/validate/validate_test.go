@@ -101,3 +101,47 @@ func TestEmpty(t *testing.T) {
 	check(shouldPass, true, t)
 	check(shouldFail, false, t)
 }
+
+func TestAlpha(t *testing.T) {
+	shouldPass := []ValidationResult{
+		Check("Ethryx", "alpha"),
+	}
+	shouldFail := []ValidationResult{
+		Check("Ethryx1", "alpha"),
+		Check("Eth ryx", "alpha"),
+		Check("Еthryx", "alpha"),
+		Check("Ethrýx", "alpha"),
+	}
+
+	check(shouldPass, true, t)
+	check(shouldFail, false, t)
+}
+
+func TestMinLen(t *testing.T) {
+	shouldPass := []ValidationResult{
+		Check("abc", "minlen:3"),
+		Check("abcd", "minlen:3"),
+		Check("äöü", "minlen:3"),
+	}
+	shouldFail := []ValidationResult{
+		Check("ab", "minlen:3"),
+		Check("äö", "minlen:3"),
+	}
+
+	check(shouldPass, true, t)
+	check(shouldFail, false, t)
+}
+
+func TestMaxLen(t *testing.T) {
+	shouldPass := []ValidationResult{
+		Check("abc", "maxlen:3"),
+		Check("", "maxlen:3"),
+		Check("äöü", "maxlen:3"),
+	}
+	shouldFail := []ValidationResult{
+		Check("abcd", "maxlen:3"),
+	}
+
+	check(shouldPass, true, t)
+	check(shouldFail, false, t)
+}
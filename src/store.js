@@ -10,6 +10,7 @@ export default new Vuex.Store({
     deployAppName: process.env.VUE_APP_HEROKU_APP_NAME,
     deployVersion: process.env.VUE_APP_HEROKU_RELEASE_VERSION,
     isConnected: false,
+    protocolVersion: 1,
     gameText: [],
     allowGlobalHotkeys: true,
     forceInputFocus: { forced: false, text: '' },
@@ -71,9 +72,18 @@ export default new Vuex.Store({
     },
 
     SOCKET_ONOPEN: (state) => {
+      // Negotiate the protocol version before anything else is sent.
+      Vue.prototype.$socket.sendObj({
+        type: 'hello',
+        payload: { version: 2 },
+      });
       state.isConnected = true;
     },
 
+    SET_PROTOCOL_VERSION: (state, version) => {
+      state.protocolVersion = version;
+    },
+
     SOCKET_ONCLOSE: (state) => {
       if (state.isConnected) {
         state.isConnected = false;
@@ -325,20 +335,24 @@ export default new Vuex.Store({
     // Server-triggered actions below
     //
 
+    hello: ({ commit }, payload) => {
+      commit('SET_PROTOCOL_VERSION', payload.data.version);
+    },
+
     showText: ({ commit }, payload) => {
       commit('ADD_GAME_TEXT', payload.data);
     },
 
     setMapData: ({ commit }, payload) => {
-      commit('SET_MINIMAP_DATA', JSON.parse(payload.data));
+      commit('SET_MINIMAP_DATA', payload.data);
     },
 
     setCharacterLocation: ({ commit }, payload) => {
-      commit('SET_CHARACTER_LOCATION', JSON.parse(payload.data));
+      commit('SET_CHARACTER_LOCATION', payload.data);
     },
 
     setRoomObjects: ({ commit }, payload) => {
-      commit('SET_ROOM_OBJECTS', JSON.parse(payload.data));
+      commit('SET_ROOM_OBJECTS', payload.data);
     },
 
     setRoomTitle: ({ commit }, payload) => {
@@ -346,7 +360,7 @@ export default new Vuex.Store({
     },
 
     setObjectEditorData: ({ commit }, payload) => {
-      commit('SET_OBJECT_EDITOR_DATA', JSON.parse(payload.data));
+      commit('SET_OBJECT_EDITOR_DATA', payload.data);
       commit('SET_OBJECT_EDITOR_OPEN', true);
     },
 
@@ -367,7 +381,7 @@ export default new Vuex.Store({
     },
 
     setInventory: ({ commit }, payload) => {
-      commit('SET_INVENTORY', JSON.parse(payload.data) || []);
+      commit('SET_INVENTORY', payload.data || []);
     },
 
     setPermissions: ({ commit }, payload) => {
@@ -375,15 +389,15 @@ export default new Vuex.Store({
     },
 
     setPlayerInfo: ({ commit }, payload) => {
-      commit('SET_PLAYER_INFO', JSON.parse(payload.data));
+      commit('SET_PLAYER_INFO', payload.data);
     },
 
     setItemTooltipHTML: ({ commit }, payload) => {
-      commit('SET_ITEM_TOOLTIP_HTML', JSON.parse(payload.data));
+      commit('SET_ITEM_TOOLTIP_HTML', payload.data);
     },
 
     setCommandDictionary: ({ commit }, payload) => {
-      commit('SET_COMMAND_DICTIONARY', JSON.parse(payload.data));
+      commit('SET_COMMAND_DICTIONARY', payload.data);
     },
 
     setMoney: ({ commit }, payload) => {
@@ -391,12 +405,12 @@ export default new Vuex.Store({
     },
 
     playSFX: (_, payload) => {
-      const sfx = JSON.parse(payload.data);
+      const sfx = payload.data;
       Vue.prototype.$soundEvent(sfx.id, sfx.volume);
     },
 
     setSettings: ({ commit }, payload) => {
-      commit('SET_SETTINGS', JSON.parse(payload.data));
+      commit('SET_SETTINGS', payload.data);
     },
   }
 })